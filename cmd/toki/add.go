@@ -1,5 +1,5 @@
 // ABOUTME: Todo add command with git-aware context detection
-// ABOUTME: Creates todos with metadata (priority, tags, notes, due date)
+// ABOUTME: Creates todos with metadata (priority, tags, notes, due date, estimate)
 
 package main
 
@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"github.com/fatih/color"
-	"github.com/harper/toki/internal/db"
 	"github.com/harper/toki/internal/models"
 	"github.com/spf13/cobra"
 )
@@ -20,6 +19,7 @@ var addCmd = &cobra.Command{
 	Short:   "Add a new todo",
 	Args:    cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		description := strings.Join(args, " ")
 
 		if len(description) < 3 {
@@ -27,13 +27,20 @@ var addCmd = &cobra.Command{
 		}
 
 		projectFlag, _ := cmd.Flags().GetString("project")
-		projectID, err := getProjectID(projectFlag)
+		projectID, err := getProjectID(ctx, projectFlag)
 		if err != nil {
 			return err
 		}
 
 		todo := models.NewTodo(*projectID, description)
 
+		branchFlag, _ := cmd.Flags().GetBool("branch")
+		branch, err := branchForNewTodo(branchFlag)
+		if err != nil {
+			return err
+		}
+		todo.Branch = branch
+
 		// Handle optional flags
 		if priority, _ := cmd.Flags().GetString("priority"); priority != "" {
 			priority = strings.ToLower(priority)
@@ -55,26 +62,33 @@ var addCmd = &cobra.Command{
 			todo.DueDate = &dueDate
 		}
 
-		// Create todo
-		if err := db.CreateTodo(dbConn, todo); err != nil {
-			return fmt.Errorf("failed to create todo: %w", err)
+		if estimateStr, _ := cmd.Flags().GetString("estimate"); estimateStr != "" {
+			estimate, err := models.ParseEstimate(estimateStr)
+			if err != nil {
+				return err
+			}
+			todo.Estimate = estimate
 		}
 
-		// Handle tags
+		var tags []string
 		if tagsStr, _ := cmd.Flags().GetString("tags"); tagsStr != "" {
-			tags := strings.Split(tagsStr, ",")
-			for _, tag := range tags {
-				tag = strings.TrimSpace(tag)
-				if tag != "" {
-					if err := db.AddTagToTodo(dbConn, todo.ID, tag); err != nil {
-						return fmt.Errorf("failed to add tag: %w", err)
-					}
+			for _, tag := range strings.Split(tagsStr, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
 				}
 			}
 		}
 
+		// Create the todo and its tags together
+		if err := todoStore.CreateTodoWithTags(ctx, todo, tags); err != nil {
+			return fmt.Errorf("failed to create todo: %w", err)
+		}
+
 		color.Green("✓ Added todo")
 		fmt.Printf("  %s %s\n", color.New(color.Faint).Sprint(todo.ID.String()[:6]), description)
+		if todo.Branch != nil {
+			fmt.Printf("  Branch: %s\n", *todo.Branch)
+		}
 
 		return nil
 	},
@@ -86,6 +100,8 @@ func init() {
 	addCmd.Flags().String("tags", "", "comma-separated tags")
 	addCmd.Flags().String("notes", "", "additional notes")
 	addCmd.Flags().String("due", "", "due date (YYYY-MM-DD)")
+	addCmd.Flags().String("estimate", "", "estimate in points (3, 5pt) or time (2h, 30m)")
+	addCmd.Flags().Bool("branch", false, "scope the todo to the current git branch")
 
 	rootCmd.AddCommand(addCmd)
 }
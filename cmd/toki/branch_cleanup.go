@@ -0,0 +1,183 @@
+// ABOUTME: Branch cleanup command for branch-scoped todos
+// ABOUTME: Completes or moves pending todos whose git branch was deleted or merged
+
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+	"github.com/spf13/cobra"
+)
+
+// staleBranch is a branch with pending todos that no longer needs them scoped to it.
+type staleBranch struct {
+	name   string
+	reason string
+	count  int
+}
+
+var branchCleanupCmd = &cobra.Command{
+	Use:   "branch-cleanup",
+	Short: "Complete or move todos of deleted and merged branches",
+	Long: `Find pending todos scoped to git branches that were deleted or merged
+into the default branch, and offer to complete them or move them to
+another branch.
+
+Use --complete or --move-to to act on every stale branch without prompting.
+Pass --move-to "" to make the todos project-wide.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		projectFlag, _ := cmd.Flags().GetString("project")
+		project, err := projectFromFlagOrContext(ctx, projectFlag)
+		if err != nil {
+			return err
+		}
+		if project.DirectoryPath == nil {
+			return fmt.Errorf("project '%s' has no directory path; set one with 'toki project set-path'", project.Name)
+		}
+
+		stale, err := findStaleBranches(ctx, project, *project.DirectoryPath)
+		if err != nil {
+			return err
+		}
+		if len(stale) == 0 {
+			fmt.Println("No todos on deleted or merged branches")
+			return nil
+		}
+
+		complete, _ := cmd.Flags().GetBool("complete")
+		moveSet := cmd.Flags().Changed("move-to")
+		moveTo, _ := cmd.Flags().GetString("move-to")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if complete && moveSet {
+			return fmt.Errorf("--complete and --move-to cannot be used together")
+		}
+
+		if !complete && !moveSet && !dryRun && !prompter.Interactive() {
+			fmt.Println("Skipping every branch without a terminal; pass --complete or --move-to to act on them")
+		}
+
+		for _, branch := range stale {
+			fmt.Printf("Branch '%s' %s: %d pending todo(s)\n", branch.name, branch.reason, branch.count)
+			if dryRun {
+				continue
+			}
+
+			action, target := "skip", moveTo
+			switch {
+			case complete:
+				action = "complete"
+			case moveSet:
+				action = "move"
+			default:
+				if action, target, err = askBranchAction(); err != nil {
+					return err
+				}
+			}
+
+			if err := applyBranchCleanup(ctx, project, branch.name, action, target); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+// findStaleBranches returns the branches with pending todos that were deleted or merged.
+func findStaleBranches(ctx context.Context, project *models.Project, root string) ([]staleBranch, error) {
+	branches, err := todoStore.ListTodoBranches(ctx, project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without a default branch only deletions can be detected
+	defaultBranch, _ := git.DefaultBranch(root)
+
+	pending := false
+	var stale []staleBranch
+	for _, name := range branches {
+		var reason string
+		if !git.BranchExists(root, name) {
+			reason = "was deleted"
+		} else if defaultBranch != "" && name != defaultBranch {
+			merged, err := git.IsMerged(root, name, defaultBranch)
+			if err != nil {
+				return nil, err
+			}
+			if merged {
+				reason = "was merged into " + defaultBranch
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		branch := name
+		todos, err := todoStore.ListTodos(ctx, &project.ID, &pending, nil, nil, &branch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list todos: %w", err)
+		}
+		stale = append(stale, staleBranch{name: name, reason: reason, count: len(todos)})
+	}
+
+	return stale, nil
+}
+
+// askBranchAction asks what to do with one stale branch's todos and, for a move, where
+// to move them.
+func askBranchAction() (string, string, error) {
+	action, err := prompter.Select("  Complete, move, or skip?", []string{"complete", "move", "skip"}, "skip")
+	if err != nil {
+		return "", "", err
+	}
+	if action != "move" {
+		return action, "", nil
+	}
+
+	target, err := prompter.Input("  Move to branch (empty for project-wide)", "")
+	if err != nil {
+		return "", "", err
+	}
+	return action, target, nil
+}
+
+func applyBranchCleanup(ctx context.Context, project *models.Project, branch, action, target string) error {
+	switch action {
+	case "complete":
+		count, err := todoStore.CompleteBranchTodos(ctx, project.ID, branch)
+		if err != nil {
+			return err
+		}
+		color.Green("  ✓ Completed %d todo(s)", count)
+	case "move":
+		var to *string
+		label := "project-wide"
+		if target != "" {
+			to = &target
+			label = "branch '" + target + "'"
+		}
+		count, err := todoStore.MoveBranchTodos(ctx, project.ID, branch, to)
+		if err != nil {
+			return err
+		}
+		color.Green("  ✓ Moved %d todo(s) to %s", count, label)
+	default:
+		fmt.Println("  Skipped")
+	}
+	return nil
+}
+
+func init() {
+	branchCleanupCmd.Flags().StringP("project", "p", "", "project to clean up")
+	branchCleanupCmd.Flags().Bool("complete", false, "complete todos of every stale branch without prompting")
+	branchCleanupCmd.Flags().String("move-to", "", "move todos of every stale branch to this branch without prompting")
+	branchCleanupCmd.Flags().Bool("dry-run", false, "only list stale branches")
+
+	rootCmd.AddCommand(branchCleanupCmd)
+}
@@ -0,0 +1,178 @@
+// ABOUTME: Config commands for reading and editing ~/.config/toki/config.toml
+// ABOUTME: Shows effective settings for a directory and writes global or per-directory values
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change settings",
+	Long: `Show and change settings in the config file, by default
+~/.config/toki/config.toml ($XDG_CONFIG_HOME and $TOKI_CONFIG are honored).
+
+Settings apply everywhere unless set with --dir, which overrides them for
+one directory tree; the most specific directory wins:
+
+  toki config set list.sort urgency
+  toki config set default_project work --dir ~/work
+  toki config set auto_create always --dir ~/src
+
+Keys:
+` + configKeysHelp() + `
+
+'toki config set' rewrites the file, so comments in it are not kept.`,
+	// Config commands work without a database, and must work when the file is invalid
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		effective, err := loadConfigFor(cmd)
+		if err != nil {
+			return err
+		}
+
+		value, err := config.Get(effective, args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := configDirFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := config.Set(config.Path(), dir, args[0], args[1]); err != nil {
+			return err
+		}
+
+		color.Green("✓ Set %s = %s", args[0], args[1])
+		if dir != "" {
+			fmt.Printf("  for %s\n", dir)
+		}
+		return nil
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a setting so the default applies",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, err := configDirFlag(cmd)
+		if err != nil {
+			return err
+		}
+
+		if err := config.Unset(config.Path(), dir, args[0]); err != nil {
+			return err
+		}
+
+		color.Yellow("✓ Unset %s", args[0])
+		if dir != "" {
+			fmt.Printf("  for %s\n", dir)
+		}
+		return nil
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "Show every setting in effect",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		effective, err := loadConfigFor(cmd)
+		if err != nil {
+			return err
+		}
+
+		_, _ = color.New(color.Bold).Println("CONFIG")
+		fmt.Println(color.New(color.Faint).Sprint(config.Path()))
+		for _, key := range config.Keys() {
+			value, _ := config.Get(effective, key)
+			fmt.Printf("  %-16s %s\n", key, value)
+		}
+		return nil
+	},
+}
+
+// configDirFlag returns the absolute --dir path, or "" when the flag is not given.
+func configDirFlag(cmd *cobra.Command) (string, error) {
+	dir, _ := cmd.Flags().GetString("dir")
+	if dir == "" {
+		return "", nil
+	}
+
+	abs, err := filepath.Abs(config.ExpandPath(dir))
+	if err != nil {
+		return "", fmt.Errorf("invalid directory '%s': %w", dir, err)
+	}
+	return abs, nil
+}
+
+// loadConfigFor loads the settings in effect for --dir or the current directory.
+func loadConfigFor(cmd *cobra.Command) (config.Settings, error) {
+	dir, err := configDirFlag(cmd)
+	if err != nil {
+		return config.Settings{}, err
+	}
+	if dir == "" {
+		if dir, err = os.Getwd(); err != nil {
+			return config.Settings{}, fmt.Errorf("failed to get working directory: %w", err)
+		}
+	}
+
+	return config.Load(config.Path(), dir)
+}
+
+// configKeysHelp lists every config key with its description, from the same table
+// 'toki config set' checks keys against.
+func configKeysHelp() string {
+	width := 0
+	for _, key := range config.Keys() {
+		width = max(width, len(key))
+	}
+	lines := make([]string, 0, len(config.Keys()))
+	for _, key := range config.Keys() {
+		lines = append(lines, fmt.Sprintf("  %-*s  %s", width, key, config.Help(key)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{configGetCmd, configListCmd} {
+		cmd.Flags().String("dir", "", "show the settings in effect for this directory")
+	}
+	for _, cmd := range []*cobra.Command{configSetCmd, configUnsetCmd} {
+		cmd.Flags().String("dir", "", "change the override for this directory instead of the global setting")
+	}
+
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	rootCmd.AddCommand(configCmd)
+}
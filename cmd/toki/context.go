@@ -1,74 +1,166 @@
 // ABOUTME: Context detection for git-aware project lookup
-// ABOUTME: Determines current project from git repo or prompts user
+// ABOUTME: Determines current project from git repo, creating it per the auto_create setting
 
 package main
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/google/uuid"
+	"github.com/harper/toki/internal/config"
 	"github.com/harper/toki/internal/db"
 	"github.com/harper/toki/internal/git"
 	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/prompt"
+	"github.com/harper/toki/internal/store"
 )
 
 // detectProjectContext attempts to find project from current directory.
-func detectProjectContext() (*uuid.UUID, error) {
+func detectProjectContext(ctx context.Context) (*uuid.UUID, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return nil, err
 	}
 
-	// Try to find git root
-	gitRoot, err := git.FindGitRoot(cwd)
-	if err != nil {
+	// Look up project by git remote or root
+	project, repo, err := todoStore.FindProjectForDir(ctx, cwd, gitOptions)
+	if err == nil {
+		return &project.ID, nil
+	}
+	if repo == nil {
 		// Not in a git repo
 		return nil, nil //nolint:nilerr,nilnil // Intentional: not being in a git repo is not an error
 	}
-
-	// Look up project by path
-	project, err := db.GetProjectByPath(dbConn, gitRoot)
-	if err == nil {
-		return &project.ID, nil
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
 	}
 
-	// Project not found - offer to create
+	// Project not found - create it as the auto_create setting says
+	gitRoot := repo.Root
 	projectName := filepath.Base(gitRoot)
-	fmt.Printf("Git repository detected: %s\n", gitRoot)
-	fmt.Printf("Create project '%s'? [Y/n]: ", projectName)
-
-	reader := bufio.NewReader(os.Stdin)
-	response, _ := reader.ReadString('\n')
-	response = strings.TrimSpace(strings.ToLower(response))
+	create := settings.AutoCreate == config.AutoCreateAlways
+	if settings.AutoCreate == config.AutoCreateAsk {
+		if create, err = confirmCreateProject(gitRoot, projectName); err != nil {
+			return nil, err
+		}
+	}
 
-	if response == "" || response == "y" || response == "yes" {
+	if create {
 		project := models.NewProject(projectName, &gitRoot)
-		if err := db.CreateProject(dbConn, project); err != nil {
+		if repo.RemoteURL != "" {
+			project.RemoteURL = &repo.RemoteURL
+		}
+		if err := todoStore.CreateProject(ctx, project); err != nil {
 			return nil, fmt.Errorf("failed to create project: %w", err)
 		}
 		fmt.Printf("✓ Created project '%s'\n", projectName)
 		return &project.ID, nil
 	}
 
-	return nil, nil //nolint:nilnil // Intentional: user declined project creation, not an error
+	return nil, nil //nolint:nilnil // Intentional: project creation declined, not an error
+}
+
+// confirmCreateProject asks whether to register an unknown git repository as a project.
+// Without anyone to ask, the project is only created with --yes.
+func confirmCreateProject(gitRoot, projectName string) (bool, error) {
+	if prompter.Interactive() {
+		_, _ = fmt.Fprintf(os.Stderr, "Git repository detected: %s\n", gitRoot)
+	}
+
+	create, err := prompter.Confirm(fmt.Sprintf("Create project '%s'?", projectName), true)
+	if errors.Is(err, prompt.ErrNoAnswer) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !create && !prompter.Interactive() {
+		_, _ = fmt.Fprintf(os.Stderr, "Not creating project '%s' for %s without a terminal; pass --yes or set auto_create\n", projectName, gitRoot)
+	}
+	return create, nil
+}
+
+// currentBranch returns the git branch checked out in the current directory.
+func currentBranch() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	branch, err := git.CurrentBranch(cwd)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect current branch: %w", err)
+	}
+	return branch, nil
+}
+
+// branchForNewTodo returns the branch to record on a new todo: the current branch when
+// requested explicitly or when the branches.track setting, or failing that the
+// repository's toki.trackBranches, turns tracking on; otherwise nil.
+func branchForNewTodo(explicit bool) (*string, error) {
+	if !explicit && !trackBranches() {
+		return nil, nil //nolint:nilnil // Intentional: branch tracking is opt-in
+	}
+
+	branch, err := currentBranch()
+	if err != nil {
+		if explicit {
+			return nil, err
+		}
+		// Detached HEAD while tracking branches: record the todo project-wide
+		return nil, nil //nolint:nilerr,nilnil // Intentional: automatic tracking is best-effort
+	}
+	return &branch, nil
+}
+
+// trackBranches reports whether to record the current branch on every new todo. The
+// branches.track setting, when set, takes precedence over the repository's git config.
+func trackBranches() bool {
+	if settings.Branches.Track != nil {
+		return *settings.Branches.Track
+	}
+	cwd, err := os.Getwd()
+	return err == nil && git.TrackBranches(cwd)
+}
+
+// projectFromFlagOrContext resolves a --project flag, or else the project of the current
+// directory. Unlike getProjectID it never falls back to the default project.
+func projectFromFlagOrContext(ctx context.Context, projectFlag string) (*models.Project, error) {
+	if projectFlag != "" {
+		return todoStore.ResolveProjectRef(ctx, projectFlag)
+	}
+
+	projectID, err := detectProjectContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if projectID == nil {
+		return nil, fmt.Errorf("not in a project repository; use --project to choose one")
+	}
+	return todoStore.GetProjectByID(ctx, *projectID)
 }
 
 // getProjectID gets project ID from flag or context.
-func getProjectID(projectFlag string) (*uuid.UUID, error) {
+func getProjectID(ctx context.Context, projectFlag string) (*uuid.UUID, error) {
 	if projectFlag != "" {
-		project, err := db.GetProjectByName(dbConn, projectFlag)
+		project, err := todoStore.ResolveProjectRef(ctx, projectFlag)
 		if err != nil {
+			var ambiguous *db.AmbiguousRefError
+			if errors.As(err, &ambiguous) {
+				return nil, err
+			}
 			return nil, fmt.Errorf("project '%s' not found", projectFlag)
 		}
 		return &project.ID, nil
 	}
 
 	// Try context detection
-	projectID, err := detectProjectContext()
+	projectID, err := detectProjectContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -77,14 +169,24 @@ func getProjectID(projectFlag string) (*uuid.UUID, error) {
 		return projectID, nil
 	}
 
-	// No project context - use or create "default" project
-	project, err := db.GetProjectByName(dbConn, "default")
-	if err != nil {
+	// No project context - use or create the default project, in a transaction so that
+	// concurrent processes create it once
+	var project *models.Project
+	err = todoStore.Tx(ctx, func(tx store.Store) error {
+		var err error
+		project, err = tx.GetProjectByName(ctx, settings.DefaultProject)
+		if !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
 		// Create default project if it doesn't exist
-		project = models.NewProject("default", nil)
-		if err := db.CreateProject(dbConn, project); err != nil {
-			return nil, fmt.Errorf("failed to create default project: %w", err)
+		project = models.NewProject(settings.DefaultProject, nil)
+		if err := tx.CreateProject(ctx, project); err != nil {
+			return fmt.Errorf("failed to create default project: %w", err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &project.ID, nil
@@ -0,0 +1,299 @@
+// ABOUTME: Database maintenance commands: backup, restore, backups, check, export, and import
+// ABOUTME: Copies the database while in use, restores validated backups, and reports corruption and orphans
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/prompt"
+	"github.com/harper/toki/internal/secret"
+	"github.com/harper/toki/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Back up, restore, and check the database",
+	Long: `Back up, restore, and check the toki database.
+
+Backups are consistent copies taken while toki keeps running, by default
+in a backups directory next to the database (the backup.dir setting
+changes it):
+
+  toki db backup                  # backups/toki-<hash>-<time>-manual.db
+  toki db backup ~/toki-copy.db
+  toki db backups
+  toki db restore                 # the newest backup
+  toki db restore ~/toki-copy.db
+  toki db check
+  toki db export ~/toki.enc       # encrypted with the encryption key
+  toki db import ~/toki.enc
+
+With backup.auto set, toki also backs up automatically before it upgrades
+the database schema and on the first use each day, keeping the newest
+backup.keep (7) automatic backups:
+
+  toki config set backup.auto true`,
+}
+
+var dbBackupCmd = &cobra.Command{
+	Use:   "backup [path]",
+	Short: "Save a copy of the database",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dest := db.BackupPath(backupDir(), db.BackupName(dbPath), db.BackupManual, time.Now())
+		if len(args) == 1 {
+			dest = args[0]
+		}
+
+		if err := db.Backup(cmd.Context(), dbConn, dest); err != nil {
+			return err
+		}
+
+		color.Green("✓ Backed up database")
+		fmt.Printf("  Path: %s\n", dest)
+		return nil
+	},
+}
+
+var dbBackupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "List backups in the backup directory",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := backupDir()
+		backups, err := db.ListBackups(dir, db.BackupName(dbPath))
+		if err != nil {
+			return err
+		}
+
+		if len(backups) == 0 {
+			fmt.Printf("No backups in %s yet. Take one with 'toki db backup'\n", dir)
+			return nil
+		}
+
+		_, _ = color.New(color.Bold).Println("BACKUPS")
+		fmt.Println(color.New(color.Faint).Sprint(dir))
+		for i := len(backups) - 1; i >= 0; i-- {
+			backup := backups[i]
+			fmt.Printf("  %s  %-12s %s\n", backup.Time.Format(ui.DateLayout+" 15:04"), backup.Reason, filepath.Base(backup.Path))
+		}
+		return nil
+	},
+}
+
+var dbRestoreCmd = &cobra.Command{
+	Use:   "restore [path]",
+	Short: "Replace the database with a backup",
+	Long: `Replace the database with a backup, by default the newest one in the
+backup directory. The backup must be an intact toki database from this
+version of toki or an older one; older backups are upgraded after
+restoring. The current database is backed up first, so a restore can be
+undone by restoring that copy.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var src string
+		if len(args) == 1 {
+			src = args[0]
+		} else {
+			backups, err := db.ListBackups(backupDir(), db.BackupName(dbPath))
+			if err != nil {
+				return err
+			}
+			if len(backups) == 0 {
+				return fmt.Errorf("no backups in %s; pass the backup to restore", backupDir())
+			}
+			src = backups[len(backups)-1].Path
+		}
+
+		return restoreFrom(cmd.Context(), src, src)
+	},
+}
+
+// restoreFrom replaces the database with the backup at src, named label to the user,
+// after confirming and backing up the current database.
+func restoreFrom(ctx context.Context, src, label string) error {
+	version, err := db.ValidateBackup(ctx, src)
+	if err != nil {
+		if sealed, _ := isSealedFile(src); sealed {
+			return fmt.Errorf("%s is an encrypted export; import it with 'toki db import'", src)
+		}
+		return err
+	}
+
+	restore, err := prompter.Confirm(fmt.Sprintf("Replace the database with %s?", label), false)
+	if err != nil && !errors.Is(err, prompt.ErrNoAnswer) {
+		return err
+	}
+	if !restore {
+		return errors.New("restore cancelled (pass --yes to restore without asking)")
+	}
+
+	safety := db.BackupPath(backupDir(), db.BackupName(dbPath), db.BackupRestore, time.Now())
+	if err := db.Backup(ctx, dbConn, safety); err != nil {
+		return fmt.Errorf("failed to back up the current database before restoring: %w", err)
+	}
+
+	if err := db.Restore(ctx, dbConn, src); err != nil {
+		return err
+	}
+
+	color.Green("✓ Restored database from %s", label)
+	if version < db.SchemaVersion {
+		fmt.Printf("  Upgraded from schema version %d to %d\n", version, db.SchemaVersion)
+	}
+	fmt.Printf("  Previous database saved to %s\n", safety)
+	return nil
+}
+
+// isSealedFile reports whether the file at path is an encrypted export.
+func isSealedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+	header := make([]byte, 16)
+	n, _ := io.ReadFull(f, header)
+	return secret.IsSealedFile(header[:n]), nil
+}
+
+var dbExportCmd = &cobra.Command{
+	Use:   "export <path>",
+	Short: "Save an encrypted copy of the database",
+	Long: `Save a copy of the database encrypted with the encryption key, for
+keeping or moving it somewhere untrusted. Unlike the database itself,
+where only todo descriptions and notes are encrypted, the whole export
+is. Read it back with 'toki db import'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if textKey == nil {
+			return errors.New("exports are encrypted, but no key is set; create one with 'toki key generate'")
+		}
+		dest := args[0]
+		if _, err := os.Stat(dest); err == nil {
+			return fmt.Errorf("%s already exists", dest)
+		}
+
+		plain, err := tempPath("toki-export-*.db")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.Remove(plain) }()
+		if err := db.Backup(cmd.Context(), dbConn, plain); err != nil {
+			return err
+		}
+		data, err := os.ReadFile(plain)
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create export: %w", err)
+		}
+		if _, err := f.Write(textKey.SealFile(data)); err != nil {
+			_ = f.Close()
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+
+		color.Green("✓ Exported encrypted database")
+		fmt.Printf("  Path: %s\n", dest)
+		fmt.Printf("  Key:  %s\n", textKey.ID())
+		return nil
+	},
+}
+
+var dbImportCmd = &cobra.Command{
+	Use:   "import <path>",
+	Short: "Replace the database with an encrypted export",
+	Long: `Replace the database with an export made by 'toki db export', decrypting
+it with the encryption key. As with 'toki db restore', the current
+database is backed up first.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src := args[0]
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return fmt.Errorf("failed to read export: %w", err)
+		}
+		plain, err := secret.OpenFile(textKey, data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+
+		path, err := tempPath("toki-import-*.db")
+		if err != nil {
+			return err
+		}
+		defer func() { _ = os.Remove(path) }()
+		if err := os.WriteFile(path, plain, 0600); err != nil {
+			return fmt.Errorf("failed to write decrypted export: %w", err)
+		}
+		return restoreFrom(cmd.Context(), path, src)
+	},
+}
+
+// tempPath returns the path of a new private temporary file next to the database, so
+// decrypted copies stay where the database already is.
+func tempPath(pattern string) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(dbPath), pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	path := f.Name()
+	_ = f.Close()
+	// db.Backup refuses to overwrite a file
+	if err := os.Remove(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+var dbCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the database for corruption and orphaned rows",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := db.Check(cmd.Context(), dbConn)
+		if err != nil {
+			return err
+		}
+
+		if report.OK() {
+			color.Green("✓ Database is intact")
+			fmt.Printf("  Path: %s\n", dbPath)
+			return nil
+		}
+
+		for _, problem := range report.Problems {
+			color.Red("✗ %s", problem)
+		}
+		for _, orphan := range report.Orphans {
+			color.Yellow("✗ %s row %d refers to a missing %s row", orphan.Table, orphan.RowID, orphan.Parent)
+		}
+		return fmt.Errorf("database check found %d problems and %d orphaned rows", len(report.Problems), len(report.Orphans))
+	},
+}
+
+func init() {
+	dbCmd.AddCommand(dbBackupCmd)
+	dbCmd.AddCommand(dbBackupsCmd)
+	dbCmd.AddCommand(dbRestoreCmd)
+	dbCmd.AddCommand(dbCheckCmd)
+	dbCmd.AddCommand(dbExportCmd)
+	dbCmd.AddCommand(dbImportCmd)
+	rootCmd.AddCommand(dbCmd)
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 
 	"github.com/fatih/color"
-	"github.com/harper/toki/internal/db"
 	"github.com/spf13/cobra"
 )
 
@@ -17,15 +16,16 @@ var doneCmd = &cobra.Command{
 	Short:   "Mark todos as done",
 	Args:    cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		for _, prefix := range args {
-			todo, err := db.GetTodoByPrefix(dbConn, prefix)
+			todo, err := todoStore.ResolveTodoRef(ctx, prefix)
 			if err != nil {
 				return err
 			}
 
 			todo.MarkDone()
 
-			if err := db.UpdateTodo(dbConn, todo); err != nil {
+			if err := todoStore.UpdateTodo(ctx, todo); err != nil {
 				return fmt.Errorf("failed to update todo: %w", err)
 			}
 
@@ -43,15 +43,16 @@ var undoneCmd = &cobra.Command{
 	Short:   "Mark todos as not done",
 	Args:    cobra.MinimumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		for _, prefix := range args {
-			todo, err := db.GetTodoByPrefix(dbConn, prefix)
+			todo, err := todoStore.ResolveTodoRef(ctx, prefix)
 			if err != nil {
 				return err
 			}
 
 			todo.MarkUndone()
 
-			if err := db.UpdateTodo(dbConn, todo); err != nil {
+			if err := todoStore.UpdateTodo(ctx, todo); err != nil {
 				return fmt.Errorf("failed to update todo: %w", err)
 			}
 
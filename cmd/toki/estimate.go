@@ -0,0 +1,65 @@
+// ABOUTME: Estimate command for setting or clearing a todo's estimate
+// ABOUTME: Accepts story points or a duration, as the add command's --estimate flag does
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var estimateCmd = &cobra.Command{
+	Use:   "estimate <uuid-prefix> [estimate]",
+	Short: "Set or clear a todo's estimate",
+	Long: `Set a todo's estimate in story points (3, 0.5, 5pt) or as a duration
+(2h, 1h30m). Estimates feed 'toki stats velocity' and 'toki stats burndown'.
+
+  toki estimate a1b2c3 5pt
+  toki estimate a1b2c3 2h
+  toki estimate a1b2c3 --clear`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		clearEstimate, _ := cmd.Flags().GetBool("clear")
+		if clearEstimate && len(args) == 2 {
+			return fmt.Errorf("give an estimate or --clear, not both")
+		}
+		if !clearEstimate && len(args) == 1 {
+			return fmt.Errorf("give an estimate like 3, 5pt, or 2h, or --clear to remove it")
+		}
+
+		todo, err := todoStore.ResolveTodoRef(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		var estimate *models.Estimate
+		if !clearEstimate {
+			if estimate, err = models.ParseEstimate(args[1]); err != nil {
+				return err
+			}
+		}
+		todo.Estimate = estimate
+
+		if err := todoStore.UpdateTodo(ctx, todo); err != nil {
+			return fmt.Errorf("failed to update todo: %w", err)
+		}
+
+		if estimate == nil {
+			color.Yellow("✓ Cleared estimate")
+		} else {
+			color.Green("✓ Estimated %s", estimate)
+		}
+		fmt.Printf("  %s %s\n", todo.ID.String()[:6], todo.Description)
+		return nil
+	},
+}
+
+func init() {
+	estimateCmd.Flags().Bool("clear", false, "remove the estimate")
+
+	rootCmd.AddCommand(estimateCmd)
+}
@@ -0,0 +1,48 @@
+// ABOUTME: Help topic describing the filter expression language
+// ABOUTME: Shown by 'toki help filters' and referenced from list and search
+
+package main
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var filtersHelpCmd = &cobra.Command{
+	Use:   "filters",
+	Short: "Filter expression syntax for list --filter and search",
+	Long: `Filter expressions select todos by combining terms with and, or, not,
+and parentheses. Adjacent terms are and-ed; and binds tighter than or.
+
+  project:api and (tag:bug or priority:high) and due<+7d and not done
+
+Terms:
+  project:<name|id>      todos in a project; a name in another case matches
+                         when no project has it exactly
+  tag:<name>             todos with a tag (tag:none, tag:any)
+  priority:<level>       low, medium, high, or none; also <, <=, >, >=
+  due, created,          dates compared with :, =, !=, <, <=, >, >=
+  updated, completed     (due:none and due:any test for a missing date)
+  branch:<name>          todos scoped to a git branch (branch:none)
+  text:<words>           description or notes contain the words
+  desc=<text>            description is exactly the text
+  id:<prefix>            UUID starts with the prefix
+  done, pending          completion status (done:false is pending)
+  overdue                pending with a due date in the past
+
+Any other bare word or "quoted string" is a text search.
+
+Dates are YYYY-MM-DD, today, tomorrow, yesterday, now, or an offset
+from today such as +7d, -2w, +1m, or 1y. A date covers the whole day,
+so due:today matches anything due today and due<+7d anything due before
+the day a week from now.
+
+Operators:
+  :   equals (contains for text)
+  =   equals
+  !=  does not equal
+  < <= > >=   before/after, for dates and priority`,
+}
+
+func init() {
+	rootCmd.AddCommand(filtersHelpCmd)
+}
@@ -0,0 +1,279 @@
+// ABOUTME: Git hook management commands
+// ABOUTME: Installs, removes, and runs hooks that close todos from commit messages
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/git"
+	"github.com/spf13/cobra"
+)
+
+const (
+	hookPostCommit = "post-commit"
+	hookCommitMsg  = "commit-msg"
+
+	// hookMarker identifies hooks written by toki so they are never mistaken for user hooks.
+	hookMarker = "# Installed by toki."
+	// hookBackupSuffix is appended to a user hook replaced by --force.
+	hookBackupSuffix = ".pre-toki"
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage git hooks that close todos from commit messages",
+	Long: `Manage git hooks that act on todo references in commit messages.
+
+The post-commit hook marks todos done for "closes toki:a3f2b9" and records
+"refs toki:a3f2b9", storing the commit SHA against the todo. The commit-msg
+hook rejects commits that reference todos which do not exist.`,
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Install toki git hooks into the current repository",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hookFlag, _ := cmd.Flags().GetString("hook")
+		hooks, err := parseHookNames(hookFlag)
+		if err != nil {
+			return err
+		}
+		force, _ := cmd.Flags().GetBool("force")
+
+		hooksDir, err := currentHooksDir()
+		if err != nil {
+			return err
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("failed to locate toki binary: %w", err)
+		}
+		dbArg := ""
+		if cmd.Flags().Changed("db") {
+			dbArg = " --db " + shellQuote(dbPath)
+		}
+
+		if err := os.MkdirAll(hooksDir, 0750); err != nil {
+			return fmt.Errorf("failed to create hooks directory: %w", err)
+		}
+
+		for _, hook := range hooks {
+			path := filepath.Join(hooksDir, hook)
+			if err := installHook(path, hookScript(hook, shellQuote(exe)+dbArg), force); err != nil {
+				return err
+			}
+			color.Green("✓ Installed %s hook", hook)
+			fmt.Printf("  %s\n", path)
+		}
+
+		return nil
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove toki git hooks from the current repository",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		hooksDir, err := currentHooksDir()
+		if err != nil {
+			return err
+		}
+
+		removed := 0
+		for _, hook := range []string{hookPostCommit, hookCommitMsg} {
+			path := filepath.Join(hooksDir, hook)
+			ok, err := uninstallHook(path)
+			if err != nil {
+				return err
+			}
+			if ok {
+				removed++
+				color.Yellow("✓ Removed %s hook", hook)
+			}
+		}
+
+		if removed == 0 {
+			fmt.Println("No toki hooks installed")
+		}
+		return nil
+	},
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:    "run <hook> [args...]",
+	Short:  "Run a toki git hook (called by git)",
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		switch args[0] {
+		case hookPostCommit:
+			// Hooks run from the worktree, whose HEAD is the commit just made
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			commit, err := git.HeadCommit(cwd)
+			if err != nil {
+				return err
+			}
+			project, err := repoProject(ctx, cwd)
+			if err != nil {
+				return err
+			}
+			_, err = applyCommitRefs(ctx, project, *commit)
+			return err
+		case hookCommitMsg:
+			if len(args) < 2 {
+				return fmt.Errorf("commit-msg hook requires the message file")
+			}
+			return checkCommitMessage(ctx, args[1])
+		default:
+			return fmt.Errorf("unknown hook '%s'", args[0])
+		}
+	},
+}
+
+func parseHookNames(name string) ([]string, error) {
+	switch name {
+	case hookPostCommit, hookCommitMsg:
+		return []string{name}, nil
+	case "all":
+		return []string{hookPostCommit, hookCommitMsg}, nil
+	default:
+		return nil, fmt.Errorf("invalid hook '%s': must be post-commit, commit-msg, or all", name)
+	}
+}
+
+// currentHooksDir returns the hooks directory of the repository containing the current
+// directory. Submodules always get their own hooks.
+func currentHooksDir() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	root, err := git.FindRepoRoot(cwd, git.Options{Submodules: git.SubmodulesSeparate})
+	if err != nil {
+		return "", err
+	}
+	return git.HooksDir(root), nil
+}
+
+func hookScript(hook, command string) string {
+	var body string
+	switch hook {
+	case hookCommitMsg:
+		body = command + ` hooks run commit-msg "$1"`
+	default:
+		// post-commit cannot undo a commit, so never fail it
+		body = command + " hooks run post-commit || true"
+	}
+
+	return fmt.Sprintf("#!/bin/sh\n%s Remove with 'toki hooks uninstall'.\n%s\n", hookMarker, body)
+}
+
+// installHook writes a hook, refusing to replace a user hook unless force is set, in
+// which case the user hook is kept alongside with hookBackupSuffix.
+func installHook(path, script string, force bool) error {
+	existing, err := os.ReadFile(path) //nolint:gosec // Reading a hook in the user's own repository
+	switch {
+	case err == nil && !strings.Contains(string(existing), hookMarker):
+		if !force {
+			return fmt.Errorf("%s already exists; use --force to replace it (the original is kept as %s)", path, filepath.Base(path)+hookBackupSuffix)
+		}
+		if err := os.Rename(path, path+hookBackupSuffix); err != nil {
+			return fmt.Errorf("failed to back up existing hook: %w", err)
+		}
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("failed to read existing hook: %w", err)
+	}
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil { //nolint:gosec // Git hooks must be executable
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+	return nil
+}
+
+// uninstallHook removes a toki hook and restores any user hook it replaced. Hooks not
+// written by toki are left alone. Reports whether a toki hook was removed.
+func uninstallHook(path string) (bool, error) {
+	existing, err := os.ReadFile(path) //nolint:gosec // Reading a hook in the user's own repository
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read hook: %w", err)
+	}
+	if !strings.Contains(string(existing), hookMarker) {
+		return false, nil
+	}
+
+	if err := os.Remove(path); err != nil {
+		return false, fmt.Errorf("failed to remove hook: %w", err)
+	}
+	if _, err := os.Stat(path + hookBackupSuffix); err == nil {
+		if err := os.Rename(path+hookBackupSuffix, path); err != nil {
+			return true, fmt.Errorf("failed to restore original hook: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// checkCommitMessage fails when the message references a todo that does not resolve in
+// the repository being committed to, which git runs the hook from.
+func checkCommitMessage(ctx context.Context, messageFile string) error {
+	data, err := os.ReadFile(messageFile) //nolint:gosec // Path provided by git to the commit-msg hook
+	if err != nil {
+		return fmt.Errorf("failed to read commit message: %w", err)
+	}
+
+	// git strips comment lines from the final message
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	project, err := repoProject(ctx, cwd)
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range git.ParseTodoRefs(strings.Join(lines, "\n")) {
+		if _, err := resolveCommitRef(ctx, project, ref.Ref); err != nil {
+			return fmt.Errorf("commit message references toki:%s: %w", ref.Ref, err)
+		}
+	}
+	return nil
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func init() {
+	hooksInstallCmd.Flags().String("hook", hookPostCommit, "hook to install (post-commit, commit-msg, all)")
+	hooksInstallCmd.Flags().Bool("force", false, "replace an existing hook, keeping a backup")
+
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+	hooksCmd.AddCommand(hooksRunCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
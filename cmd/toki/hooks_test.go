@@ -0,0 +1,61 @@
+// ABOUTME: Tests for git hook installation
+// ABOUTME: Verifies existing user hooks are preserved and restored
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallHookPreservesUserHook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "post-commit")
+	userHook := "#!/bin/sh\necho user\n"
+	if err := os.WriteFile(path, []byte(userHook), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	script := hookScript(hookPostCommit, shellQuote("/usr/local/bin/toki"))
+	if err := installHook(path, script, false); err == nil {
+		t.Fatal("Expected install over a user hook to fail without --force")
+	}
+	if err := installHook(path, script, true); err != nil {
+		t.Fatalf("Failed to force install: %v", err)
+	}
+
+	backup, err := os.ReadFile(path + hookBackupSuffix)
+	if err != nil || string(backup) != userHook {
+		t.Fatalf("Expected user hook backup, got %q, %v", backup, err)
+	}
+
+	// Reinstalling over a toki hook needs no --force
+	if err := installHook(path, script, false); err != nil {
+		t.Fatalf("Failed to reinstall: %v", err)
+	}
+
+	removed, err := uninstallHook(path)
+	if err != nil || !removed {
+		t.Fatalf("Expected toki hook to be removed, got %v, %v", removed, err)
+	}
+	restored, err := os.ReadFile(path)
+	if err != nil || string(restored) != userHook {
+		t.Errorf("Expected user hook to be restored, got %q, %v", restored, err)
+	}
+
+	removed, err = uninstallHook(path)
+	if err != nil || removed {
+		t.Errorf("Expected user hook to be left alone, got %v, %v", removed, err)
+	}
+}
+
+func TestHookScript(t *testing.T) {
+	script := hookScript(hookCommitMsg, shellQuote("/opt/it's/toki"))
+	if !strings.Contains(script, `'/opt/it'\''s/toki' hooks run commit-msg "$1"`) {
+		t.Errorf("Unexpected commit-msg script:\n%s", script)
+	}
+	if !strings.Contains(hookScript(hookPostCommit, "toki"), "|| true") {
+		t.Error("post-commit hook must never fail the commit")
+	}
+}
@@ -0,0 +1,228 @@
+// ABOUTME: Encryption key commands: generate, status, encrypt, decrypt, and rotate
+// ABOUTME: Creates keys and re-encrypts the todo text already in the database
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/prompt"
+	"github.com/harper/toki/internal/secret"
+	"github.com/harper/toki/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var keyCmd = &cobra.Command{
+	Use:   "key",
+	Short: "Manage the key that encrypts todo text",
+	Long: `Manage the key that encrypts todo descriptions and notes in the database.
+
+With a key set, toki encrypts the text of every todo it writes and
+decrypts it when reading. The key comes from, in order:
+
+  TOKI_KEY               the key itself
+  TOKI_KEY_FILE          a file holding the key
+  encryption.key_file    the same, from the config
+
+Getting started:
+
+  toki key generate ~/.config/toki/toki.key
+  toki config set encryption.key_file ~/.config/toki/toki.key
+  toki key encrypt                 # encrypt the todos already there
+
+Without the key, encrypted text can't be read: keep a copy of it
+somewhere safe. Project names, tags, and dates are not encrypted; for a
+fully encrypted copy of the database use 'toki db export'.`,
+}
+
+var keyGenerateCmd = &cobra.Command{
+	Use:   "generate [path]",
+	Short: "Create a new key, printing it or saving it to a file",
+	Args:  cobra.MaximumNArgs(1),
+	// A new key needs no database
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+	RunE: func(cmd *cobra.Command, args []string) error {
+		text := secret.GenerateKey()
+		if len(args) == 0 {
+			fmt.Println(text)
+			return nil
+		}
+
+		if err := secret.WriteKeyFile(args[0], text); err != nil {
+			return err
+		}
+		key, err := secret.ParseKey(text)
+		if err != nil {
+			return err
+		}
+		color.Green("✓ Created key %s", key.ID())
+		fmt.Printf("  Path: %s\n", args[0])
+		fmt.Printf("  Use it with 'toki config set encryption.key_file %s',\n", args[0])
+		fmt.Println("  then encrypt existing todos with 'toki key encrypt'")
+		return nil
+	},
+}
+
+var keyStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the key in use and how much todo text is encrypted",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		// The raw store shows text as it is kept
+		todos, err := store.NewSQLite(dbConn).QueryTodos(cmd.Context(), nil)
+		if err != nil {
+			return err
+		}
+		plain, sealed := 0, 0
+		other := map[string]int{}
+		for _, todo := range todos {
+			if !secret.IsSealed(todo.Description) {
+				plain++
+				continue
+			}
+			sealed++
+			if id := secret.SealedKeyID(todo.Description); textKey == nil || id != textKey.ID() {
+				other[id]++
+			}
+		}
+
+		if textKey == nil {
+			fmt.Println("Key:       none")
+		} else {
+			fmt.Printf("Key:       %s (from %s)\n", textKey.ID(), keySource)
+		}
+		fmt.Printf("Encrypted: %d todos\n", sealed)
+		fmt.Printf("Plain:     %d todos\n", plain)
+		for id, count := range other {
+			color.Yellow("  %d todos are encrypted with another key, %s", count, id)
+		}
+		if textKey != nil && plain > 0 {
+			fmt.Println("  Encrypt them with 'toki key encrypt'")
+		}
+		return nil
+	},
+}
+
+var keyEncryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the text of todos stored unencrypted",
+	Long: `Encrypt the descriptions and notes of every todo with the current key.
+Todos written before the key was set are stored unencrypted until this
+runs. Afterwards the database is vacuumed, so the unencrypted text is gone
+from the file, and toki offers to delete the backups that still hold it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if textKey == nil {
+			return errors.New("no key is set; create one with 'toki key generate'")
+		}
+		count, err := store.Rekey(cmd.Context(), store.NewSQLite(dbConn), textKey, textKey)
+		if err != nil {
+			return err
+		}
+		color.Green("✓ Encrypted %d todos with key %s", count, textKey.ID())
+		return scrubOldText(cmd)
+	},
+}
+
+var keyDecryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Store the text of every todo unencrypted again",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		count, err := store.Rekey(cmd.Context(), store.NewSQLite(dbConn), textKey, nil)
+		if err != nil {
+			return err
+		}
+		color.Green("✓ Decrypted %d todos", count)
+		if textKey != nil {
+			fmt.Printf("  Unset %s, %s, and encryption.key_file to keep new todos unencrypted\n", secret.KeyEnv, secret.KeyFileEnv)
+		}
+		return nil
+	},
+}
+
+var keyRotateCmd = &cobra.Command{
+	Use:   "rotate <new-key-file>",
+	Short: "Re-encrypt todo text with a new key",
+	Long: `Re-encrypt the descriptions and notes of every todo with the key in
+new-key-file, creating that file with a new key if it doesn't exist.
+Everything is re-encrypted in one transaction. Afterwards, point
+encryption.key_file or TOKI_KEY_FILE at the new key file; exports made
+with the old key still need the old one. As with 'toki key encrypt', the
+database is vacuumed and toki offers to delete older backups.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if textKey == nil {
+			return errors.New("no key is set; to start encrypting, use 'toki key generate' and 'toki key encrypt'")
+		}
+
+		path := args[0]
+		if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+			if err := secret.WriteKeyFile(path, secret.GenerateKey()); err != nil {
+				return err
+			}
+		}
+		newKey, err := secret.ReadKeyFile(path)
+		if err != nil {
+			return err
+		}
+		if newKey.ID() == textKey.ID() {
+			return fmt.Errorf("%s holds the key already in use", path)
+		}
+
+		count, err := store.Rekey(cmd.Context(), store.NewSQLite(dbConn), textKey, newKey)
+		if err != nil {
+			return err
+		}
+		color.Green("✓ Re-encrypted %d todos with key %s", count, newKey.ID())
+		fmt.Printf("  Now use the new key: toki config set encryption.key_file %s\n", path)
+		if keySource != keyFileSetting() {
+			fmt.Printf("  The old key came from %s, which takes precedence: update it too\n", keySource)
+		}
+		return scrubOldText(cmd)
+	},
+}
+
+// scrubOldText removes the text a rekey replaced from the database file and its
+// write-ahead log, then offers to delete the backups that still hold it.
+func scrubOldText(cmd *cobra.Command) error {
+	if err := db.Scrub(cmd.Context(), dbConn); errors.Is(err, db.ErrScrubIncomplete) {
+		color.Yellow("  %v", err)
+	} else if err != nil {
+		return err
+	}
+
+	dir := backupDir()
+	backups, err := db.ListBackups(dir, db.BackupName(dbPath))
+	if err != nil || len(backups) == 0 {
+		return err
+	}
+	remove, err := prompter.Confirm(fmt.Sprintf("Delete the %d backups in %s, which still hold the old text?", len(backups), dir), false)
+	if err != nil && !errors.Is(err, prompt.ErrNoAnswer) {
+		return err
+	}
+	if !remove {
+		color.Yellow("  %d backups in %s still hold the old text; delete them when you no longer need them", len(backups), dir)
+		return nil
+	}
+	for _, backup := range backups {
+		if err := os.Remove(backup.Path); err != nil {
+			return fmt.Errorf("failed to delete backup: %w", err)
+		}
+	}
+	fmt.Printf("  Deleted %d backups\n", len(backups))
+	return nil
+}
+
+func init() {
+	keyCmd.AddCommand(keyGenerateCmd)
+	keyCmd.AddCommand(keyStatusCmd)
+	keyCmd.AddCommand(keyEncryptCmd)
+	keyCmd.AddCommand(keyDecryptCmd)
+	keyCmd.AddCommand(keyRotateCmd)
+	rootCmd.AddCommand(keyCmd)
+}
@@ -1,131 +1,537 @@
 // ABOUTME: Todo list command with filtering and formatting
-// ABOUTME: Supports project, tag, status, and priority filters
+// ABOUTME: Supports flag and expression filters, sort orders, and grouped output
 
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/harper/toki/internal/config"
 	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
 	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/sorting"
 	"github.com/harper/toki/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+// currentBranchFlag is the --branch value used when the flag is given without a name.
+const currentBranchFlag = "@current"
+
 var listCmd = &cobra.Command{
 	Use:     "list",
 	Aliases: []string{"ls", "l"},
 	Short:   "List todos",
+	Long: `List todos, by default the pending todos of the current project.
+
+Use --branch alone to show todos scoped to the current git branch, or
+--branch <name> for another branch.
+
+Use --sort to order by due date, priority, creation, last update, or
+urgency, a score that weighs priority, how soon a todo is due, its age,
+and its tags. Todos without a due date or priority sort last. Override
+urgency coefficients with the urgency setting, or TOKI_URGENCY, for example:
+  toki config set urgency "due=8,priority.high=10,tag.next=15"
+
+Use --filter for anything the flags cannot express, for example:
+  toki list --filter 'project:api and (tag:bug or priority:high) and due<+7d'
+See 'toki help filters' for the full syntax. A filter that mentions
+project or done replaces the current-project and pending-only defaults.
+
+Use --limit to show only the first todos in the chosen order, and --page
+with it to step through the rest, for example:
+  toki list --sort due --limit 20 --page 2`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		// Parse filters
-		var projectID *uuid.UUID
-		var done *bool
-		var priority *string
-
-		projectFlag, _ := cmd.Flags().GetString("project")
-		if projectFlag != "" {
-			id, err := getProjectID(projectFlag)
-			if err != nil {
-				return err
-			}
-			projectID = id
-		} else {
-			// Try context detection
-			id, _ := detectProjectContext()
-			if id != nil {
-				projectID = id
-			}
+		ctx := cmd.Context()
+		expr, done, err := listQuery(cmd, args, true)
+		if err != nil {
+			return err
+		}
+
+		sortKey, reverse, coefficients, err := sortOptions(cmd)
+		if err != nil {
+			return err
+		}
+		sortKey, reverse, err = savedSort(cmd, sortKey, reverse, settings.List.Sort, settings.List.Reverse)
+		if err != nil {
+			return err
+		}
+
+		page, err := pageFlags(cmd)
+		if err != nil {
+			return err
 		}
 
-		if cmd.Flags().Changed("done") {
-			doneVal := true
-			done = &doneVal
-		} else if cmd.Flags().Changed("pending") {
-			pendingVal := false
-			done = &pendingVal
+		// Get todos, only those on the page when paging
+		var todos []*models.Todo
+		var todoTags map[uuid.UUID][]*models.Tag
+		if page.Size == 0 {
+			todos, todoTags, err = todoStore.QueryTodosWithTags(ctx, expr)
 		} else {
-			// Default: show only pending todos
-			pendingVal := false
-			done = &pendingVal
+			todos, todoTags, err = queryListPage(ctx, expr, sortKey, reverse, coefficients, &page)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list todos: %w", err)
 		}
 
-		if priorityFlag, _ := cmd.Flags().GetString("priority"); priorityFlag != "" {
-			priority = &priorityFlag
+		statusText := "matching"
+		if done != nil {
+			statusText = "pending"
+			if *done {
+				statusText = "completed"
+			}
 		}
+		return printTodos(ctx, todos, todoTags, sortKey, reverse, coefficients, page, statusText, "No todos found. Add one with 'toki add <description>'")
+	},
+}
 
-		var tag *string
-		if tagFlag, _ := cmd.Flags().GetString("tag"); tagFlag != "" {
-			tag = &tagFlag
+// listQuery turns list flags into one filter expression, also returning the status
+// filter applied. With useDefaults, a missing --project falls back to the project of
+// the current directory and the list settings from the config apply; without them,
+// as for saved views, the project is named rather than given by ID.
+func listQuery(cmd *cobra.Command, args []string, useDefaults bool) (filter.Node, *bool, error) {
+	ctx := cmd.Context()
+	expr, err := parseFilterFlag(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	if useDefaults && !cmd.Flags().Changed("filter") && settings.List.Filter != "" {
+		if expr, err = parseFilter(settings.List.Filter); err != nil {
+			return nil, nil, fmt.Errorf("invalid list.filter in config: %w", err)
 		}
+	}
 
-		// Get todos
-		todos, err := db.ListTodos(dbConn, projectID, done, priority, tag)
+	var criteria filter.Criteria
+	var projectID *uuid.UUID
+
+	projectFlag, _ := cmd.Flags().GetString("project")
+	if projectFlag != "" {
+		id, err := getProjectID(ctx, projectFlag)
 		if err != nil {
-			return fmt.Errorf("failed to list todos: %w", err)
+			return nil, nil, err
+		}
+		projectID = id
+	} else if useDefaults && !filter.References(expr, filter.FieldProject) {
+		// Try context detection
+		if projectID, err = detectProjectContext(ctx); err != nil {
+			return nil, nil, err
 		}
+	}
+	if projectID != nil {
+		// By ID, since another project's name may differ only in case
+		criteria.Project = projectID.String()
+	}
+	if projectID != nil && !useDefaults {
+		// Saved views name the project as the user would; the exact name still
+		// resolves to this project alone
+		project, err := todoStore.GetProjectByID(ctx, *projectID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get project: %w", err)
+		}
+		criteria.Project = project.Name
+	}
+
+	// Default: show only pending todos, unless the config or the filter picks a status
+	status := "pending"
+	if useDefaults {
+		status = settings.List.Status
+	}
+	switch {
+	case cmd.Flags().Changed("done"):
+		status = "done"
+	case cmd.Flags().Changed("pending"):
+		status = "pending"
+	case filter.References(expr, filter.FieldDone):
+		status = "all"
+	}
+	if status != "all" {
+		doneVal := status == "done"
+		criteria.Done = &doneVal
+	}
+
+	if priorityFlag, _ := cmd.Flags().GetString("priority"); priorityFlag != "" {
+		criteria.Priority = &priorityFlag
+	}
 
-		if len(todos) == 0 {
-			fmt.Println("No todos found. Add one with 'toki add <description>'")
-			return nil
+	if tagFlag, _ := cmd.Flags().GetString("tag"); tagFlag != "" {
+		criteria.Tag = &tagFlag
+	}
+
+	criteria.Branch, err = branchFilter(cmd, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return filter.AndAll(criteria.Node(), expr), criteria.Done, nil
+}
+
+// parseFilterFlag parses --filter, showing where in the expression any error is.
+func parseFilterFlag(cmd *cobra.Command) (filter.Node, error) {
+	value, _ := cmd.Flags().GetString("filter")
+	if !cmd.Flags().Changed("filter") {
+		return nil, nil //nolint:nilnil // nil means no filter
+	}
+	return parseFilter(value)
+}
+
+func parseFilter(value string) (filter.Node, error) {
+	expr, err := filter.Parse(value)
+	if err != nil {
+		var syntaxErr *filter.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%w\n%s", err, syntaxErr.Context())
 		}
+		return nil, err
+	}
+	return expr, nil
+}
 
-		// Group by project
-		projectTodos := make(map[uuid.UUID][]*struct {
-			todo *models.Todo
-			tags []*models.Tag
-		})
+// listPage is the page of a sorted list to print: page Number, counting from 1, of
+// Size todos out of Total. A zero Size prints every todo.
+type listPage struct {
+	Size   int
+	Number int
+	Total  int
+}
 
-		for _, todo := range todos {
-			tags, _ := db.GetTodoTags(dbConn, todo.ID)
-			if projectTodos[todo.ProjectID] == nil {
-				projectTodos[todo.ProjectID] = []*struct {
-					todo *models.Todo
-					tags []*models.Tag
-				}{}
-			}
-			projectTodos[todo.ProjectID] = append(projectTodos[todo.ProjectID], &struct {
-				todo *models.Todo
-				tags []*models.Tag
-			}{todo, tags})
-		}
-
-		// Display grouped by project
-		totalCount := 0
-		for projID, items := range projectTodos {
-			project, err := db.GetProjectByID(dbConn, projID)
-			if err != nil {
-				continue
-			}
+// pageFlags reads --limit and --page.
+func pageFlags(cmd *cobra.Command) (listPage, error) {
+	limit, _ := cmd.Flags().GetInt("limit")
+	number, _ := cmd.Flags().GetInt("page")
+	switch {
+	case limit < 0:
+		return listPage{}, fmt.Errorf("invalid --limit %d: must be at least 1", limit)
+	case number < 1:
+		return listPage{}, fmt.Errorf("invalid --page %d: pages count from 1", number)
+	case cmd.Flags().Changed("page") && limit == 0:
+		return listPage{}, errors.New("--page needs --limit to set the page size")
+	}
+	return listPage{Size: limit, Number: number}, nil
+}
 
-			fmt.Println(ui.FormatProjectHeader(project))
-			fmt.Println(ui.FormatSeparator())
+// pages returns how many pages there are in all.
+func (p listPage) pages() int {
+	return (p.Total + p.Size - 1) / p.Size
+}
 
-			for _, item := range items {
-				fmt.Print(ui.FormatTodo(item.todo, item.tags))
-				totalCount++
-			}
+// queryListPage reads only the todos on page, in order, and sets its Total.
+func queryListPage(ctx context.Context, expr filter.Node, sortKey sorting.Key, reverse bool, coefficients sorting.Coefficients, page *listPage) ([]*models.Todo, map[uuid.UUID][]*models.Tag, error) {
+	order := db.Order{Key: sortKey, Reverse: reverse, Urgency: coefficients, Now: time.Now()}
+	todos, todoTags, _, err := todoStore.QueryTodosPage(ctx, expr, order, db.Page{Limit: page.Size, Offset: (page.Number - 1) * page.Size})
+	if err != nil {
+		return nil, nil, err
+	}
+	page.Total, err = todoStore.CountTodos(ctx, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return todos, todoTags, nil
+}
+
+// printTodos sorts todos and prints them in the configured output mode: grouped by
+// project with a summary line, or as JSON. With a page Size, todos are the page,
+// already in order. Text output shows the empty message when there are no todos.
+func printTodos(ctx context.Context, todos []*models.Todo, todoTags map[uuid.UUID][]*models.Tag, sortKey sorting.Key, reverse bool, coefficients sorting.Coefficients, page listPage, statusText, empty string) error {
+	total := len(todos)
+	if page.Size > 0 {
+		total = page.Total
+	}
+	if total == 0 && settings.Output != config.OutputJSON {
+		fmt.Println(empty)
+		return nil
+	}
+
+	now := time.Now()
+	urgency := func(todo *models.Todo) float64 {
+		return sorting.Urgency(todo, todoTags[todo.ID], coefficients, now)
+	}
+	if sortKey != "" && page.Size == 0 {
+		sorting.Sort(todos, sortKey, reverse, urgency)
+	}
+
+	if len(todos) == 0 && settings.Output != config.OutputJSON {
+		fmt.Printf("No todos on page %d; there are %d page(s) of %d.\n", page.Number, page.pages(), page.Size)
+		return nil
+	}
+
+	tracked, err := trackedTime(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	projects, err := todoStore.GetProjectMap(ctx)
+	if err != nil {
+		return err
+	}
+
+	if settings.Output == config.OutputJSON {
+		return printTodosJSON(todos, todoTags, projects, tracked, sortKey, urgency)
+	}
 
-			fmt.Println()
+	// Group by project, in order of each project's first todo
+	var projectOrder []uuid.UUID
+	projectTodos := make(map[uuid.UUID][]*models.Todo)
+	for _, todo := range todos {
+		if projectTodos[todo.ProjectID] == nil {
+			projectOrder = append(projectOrder, todo.ProjectID)
 		}
+		projectTodos[todo.ProjectID] = append(projectTodos[todo.ProjectID], todo)
+	}
 
+	// Display grouped by project
+	totalCount := 0
+	for _, projID := range projectOrder {
+		project, ok := projects[projID]
+		if !ok {
+			continue
+		}
+
+		fmt.Println(ui.FormatProjectHeader(project))
 		fmt.Println(ui.FormatSeparator())
-		statusText := "pending"
-		if done != nil && *done {
-			statusText = "completed"
+
+		for _, todo := range projectTodos[projID] {
+			fmt.Print(ui.FormatTodo(todo, todoTags[todo.ID]))
+			if sortKey == sorting.KeyUrgency {
+				fmt.Print(ui.FormatUrgency(urgency(todo)))
+			}
+			if t, ok := tracked[todo.ID]; ok {
+				fmt.Print(ui.FormatTracked(t.total, t.running))
+			}
+			totalCount++
 		}
-		fmt.Printf("%d %s todo(s) across %d project(s)\n", totalCount, statusText, len(projectTodos))
 
+		fmt.Println()
+	}
+
+	fmt.Println(ui.FormatSeparator())
+	if page.Size == 0 {
+		fmt.Printf("%d %s todo(s) across %d project(s)\n", totalCount, statusText, len(projectTodos))
 		return nil
-	},
+	}
+
+	first := (page.Number-1)*page.Size + 1
+	fmt.Printf("%d-%d of %d %s todo(s), page %d of %d\n", first, first+totalCount-1, total, statusText, page.Number, page.pages())
+	if page.Number < page.pages() {
+		fmt.Printf("Use --page %d for more.\n", page.Number+1)
+	}
+	return nil
+}
+
+// projectName returns the name of a project in projects, or "" if it is missing.
+func projectName(projects map[uuid.UUID]*models.Project, id uuid.UUID) string {
+	if project, ok := projects[id]; ok {
+		return project.Name
+	}
+	return ""
+}
+
+// trackedTodo is the time tracked on a todo and whether its timer is running.
+type trackedTodo struct {
+	total   time.Duration
+	running bool
+}
+
+// trackedTime returns the time tracked per todo, for todos with any.
+func trackedTime(ctx context.Context, now time.Time) (map[uuid.UUID]trackedTodo, error) {
+	totals, err := todoStore.TrackedTime(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	active, err := todoStore.ActiveTimer(ctx)
+	if err != nil && !errors.Is(err, db.ErrNoTimer) {
+		return nil, err
+	}
+
+	tracked := make(map[uuid.UUID]trackedTodo, len(totals))
+	for id, total := range totals {
+		tracked[id] = trackedTodo{total: total, running: active != nil && active.TodoID == id}
+	}
+	return tracked, nil
+}
+
+// todoJSON is the JSON output form of a todo.
+type todoJSON struct {
+	ID          string     `json:"id"`
+	Project     string     `json:"project"`
+	Description string     `json:"description"`
+	Done        bool       `json:"done"`
+	Priority    *string    `json:"priority,omitempty"`
+	Notes       *string    `json:"notes,omitempty"`
+	Tags        []string   `json:"tags"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Branch      *string    `json:"branch,omitempty"`
+	Estimate    string     `json:"estimate,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Urgency     *float64   `json:"urgency,omitempty"`
+	// TrackedSeconds is the time tracked on the todo, including a running timer.
+	TrackedSeconds int64 `json:"tracked_seconds,omitempty"`
+	TimerRunning   bool  `json:"timer_running,omitempty"`
+}
+
+// printTodosJSON prints todos as an indented JSON array.
+func printTodosJSON(todos []*models.Todo, todoTags map[uuid.UUID][]*models.Tag, projects map[uuid.UUID]*models.Project, tracked map[uuid.UUID]trackedTodo, sortKey sorting.Key, urgency func(*models.Todo) float64) error {
+	output := make([]todoJSON, 0, len(todos))
+	for _, todo := range todos {
+		tags := make([]string, 0, len(todoTags[todo.ID]))
+		for _, tag := range todoTags[todo.ID] {
+			tags = append(tags, tag.Name)
+		}
+
+		item := todoJSON{
+			ID:          todo.ID.String(),
+			Project:     projectName(projects, todo.ProjectID),
+			Description: todo.Description,
+			Done:        todo.Done,
+			Priority:    todo.Priority,
+			Notes:       todo.Notes,
+			Tags:        tags,
+			DueDate:     todo.DueDate,
+			Branch:      todo.Branch,
+			CreatedAt:   todo.CreatedAt,
+			UpdatedAt:   todo.UpdatedAt,
+			CompletedAt: todo.CompletedAt,
+		}
+		if todo.Estimate != nil {
+			item.Estimate = todo.Estimate.String()
+		}
+		if t, ok := tracked[todo.ID]; ok {
+			item.TrackedSeconds = int64(t.total / time.Second)
+			item.TimerRunning = t.running
+		}
+		if sortKey == sorting.KeyUrgency {
+			score := math.Round(urgency(todo)*100) / 100
+			item.Urgency = &score
+		}
+		output = append(output, item)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(output); err != nil {
+		return fmt.Errorf("failed to encode todos: %w", err)
+	}
+	return nil
+}
+
+// urgencyCoefficients returns the coefficients the urgency setting gives, or else
+// $TOKI_URGENCY.
+func urgencyCoefficients() (sorting.Coefficients, error) {
+	if settings.Urgency != "" {
+		coefficients, err := sorting.ParseCoefficients(settings.Urgency, sorting.DefaultCoefficients())
+		if err != nil {
+			return coefficients, fmt.Errorf("invalid urgency setting: %w", err)
+		}
+		return coefficients, nil
+	}
+
+	coefficients, err := sorting.CoefficientsFromEnv()
+	if err != nil {
+		return coefficients, fmt.Errorf("invalid %s: %w", sorting.UrgencyEnv, err)
+	}
+	return coefficients, nil
+}
+
+// sortOptions reads --sort and --reverse along with the urgency coefficients. An empty
+// key keeps the default newest-first order.
+func sortOptions(cmd *cobra.Command) (sorting.Key, bool, sorting.Coefficients, error) {
+	reverse, _ := cmd.Flags().GetBool("reverse")
+
+	coefficients, err := urgencyCoefficients()
+	if err != nil {
+		return "", false, coefficients, err
+	}
+
+	sortFlag, _ := cmd.Flags().GetString("sort")
+	if sortFlag == "" {
+		if reverse {
+			return sorting.KeyCreated, true, coefficients, nil
+		}
+		return "", false, coefficients, nil
+	}
+
+	key, err := sorting.ParseKey(sortFlag)
+	if err != nil {
+		return "", false, coefficients, err
+	}
+	return key, reverse, coefficients, nil
+}
+
+// savedSort applies a sort order saved in a view or the config when --sort is not given.
+// --reverse then flips the saved direction.
+func savedSort(cmd *cobra.Command, key sorting.Key, reverse bool, sortName string, sortReverse bool) (sorting.Key, bool, error) {
+	if cmd.Flags().Changed("sort") || (sortName == "" && !sortReverse) {
+		return key, reverse, nil
+	}
+
+	reverse = sortReverse != cmd.Flags().Changed("reverse")
+	if sortName == "" {
+		if reverse {
+			return sorting.KeyCreated, true, nil
+		}
+		return "", false, nil
+	}
+
+	key, err := sorting.ParseKey(sortName)
+	if err != nil {
+		return "", false, err
+	}
+	return key, reverse, nil
+}
+
+// branchFilter resolves --branch: a bare flag means the current branch, and since a bare
+// flag cannot take a separate value, "--branch <name>" arrives as a positional argument.
+func branchFilter(cmd *cobra.Command, args []string) (*string, error) {
+	if !cmd.Flags().Changed("branch") {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("unexpected argument '%s'", args[0])
+		}
+		return nil, nil //nolint:nilnil // nil means no branch filter
+	}
+
+	branch, _ := cmd.Flags().GetString("branch")
+	if branch != currentBranchFlag {
+		return &branch, nil
+	}
+	if len(args) > 0 {
+		return &args[0], nil
+	}
+
+	current, err := currentBranch()
+	if err != nil {
+		return nil, err
+	}
+	return &current, nil
+}
+
+// addListFlags registers the filter and sort flags shared by list and view save.
+func addListFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("project", "p", "", "filter by project")
+	cmd.Flags().StringP("tag", "t", "", "filter by tag")
+	cmd.Flags().Bool("done", false, "show completed todos")
+	cmd.Flags().Bool("pending", false, "show pending todos only")
+	cmd.Flags().String("priority", "", "filter by priority")
+	cmd.Flags().String("branch", "", "filter by git branch (current branch if no name is given)")
+	cmd.Flags().Lookup("branch").NoOptDefVal = currentBranchFlag
+	cmd.Flags().String("sort", "", "sort by "+strings.Join(sorting.KeyNames(), ", ")+" (default newest first)")
+	cmd.Flags().Bool("reverse", false, "reverse the sort order")
+	cmd.Flags().StringP("filter", "f", "", "filter expression, e.g. 'tag:bug or priority:high'")
 }
 
 func init() {
-	listCmd.Flags().StringP("project", "p", "", "filter by project")
-	listCmd.Flags().StringP("tag", "t", "", "filter by tag")
-	listCmd.Flags().Bool("done", false, "show completed todos")
-	listCmd.Flags().Bool("pending", false, "show pending todos only")
-	listCmd.Flags().String("priority", "", "filter by priority")
+	addListFlags(listCmd)
+	listCmd.Flags().Int("limit", 0, "show at most this many todos")
+	listCmd.Flags().Int("page", 1, "page of --limit todos to show")
 
 	rootCmd.AddCommand(listCmd)
 }
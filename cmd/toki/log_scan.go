@@ -0,0 +1,165 @@
+// ABOUTME: Log scan command that applies commit message todo references
+// ABOUTME: Closes or links todos named by "closes toki:<id>" / "refs toki:<id>" in git history
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var logScanCmd = &cobra.Command{
+	Use:   "log-scan <rev-range>",
+	Short: "Close and link todos referenced in git history",
+	Long: `Scan the commits in a revision range for todo references and apply them,
+as the post-commit hook would have.
+
+  closes toki:a3f2b9   marks the todo done and records the commit
+  refs toki:a3f2b9     records the commit against the todo
+
+"fixes" and "resolves" work like "closes". Commits already recorded for a
+todo are skipped, so scanning the same range twice is safe. Short IDs
+only match todos of the repository's project; use full IDs to reference
+todos of other projects.
+
+Examples:
+  toki log-scan main..HEAD
+  toki log-scan HEAD~20..`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		commits, err := git.Log(cwd, args[0])
+		if err != nil {
+			return err
+		}
+
+		project, err := repoProject(ctx, cwd)
+		if err != nil {
+			return err
+		}
+
+		applied := 0
+		for _, commit := range commits {
+			count, err := applyCommitRefs(ctx, project, commit)
+			if err != nil {
+				return err
+			}
+			applied += count
+		}
+
+		fmt.Printf("Scanned %d commit(s), applied %d todo reference(s)\n", len(commits), applied)
+		return nil
+	},
+}
+
+// applyCommitRefs records a commit against every todo its message references and closes
+// the todos it closes, resolving references as resolveCommitRef does for project, the
+// repository's project. Unknown references are reported and skipped. Returns the number
+// of references that were newly applied.
+func applyCommitRefs(ctx context.Context, project *models.Project, commit git.Commit) (int, error) {
+	short := commit.SHA
+	if len(short) > 7 {
+		short = short[:7]
+	}
+
+	applied := 0
+	for _, ref := range git.ParseTodoRefs(commit.Message) {
+		todo, err := resolveCommitRef(ctx, project, ref.Ref)
+		if err != nil {
+			var ambiguous *db.AmbiguousRefError
+			if errors.Is(err, db.ErrNotFound) || errors.As(err, &ambiguous) {
+				color.Yellow("⚠ %s: skipping toki:%s: %v", short, ref.Ref, err)
+				continue
+			}
+			return applied, err
+		}
+
+		added, err := todoStore.AddTodoCommit(ctx, todo.ID, commit.SHA, ref.Action)
+		if err != nil {
+			return applied, err
+		}
+		if !added {
+			continue
+		}
+		applied++
+
+		// Only a newly recorded commit closes a todo, so reopened todos stay open on rescans
+		if ref.Action == git.ActionCloses && !todo.Done {
+			todo.MarkDone()
+			if err := todoStore.UpdateTodo(ctx, todo); err != nil {
+				return applied, fmt.Errorf("failed to update todo: %w", err)
+			}
+			color.Green("✓ Closed by %s", short)
+		} else {
+			color.Green("✓ Referenced by %s", short)
+		}
+		fmt.Printf("  %s %s\n", todo.ID.String()[:6], todo.Description)
+	}
+
+	return applied, nil
+}
+
+// repoProject returns the project registered for the git repository at dir, or nil when
+// it has none.
+func repoProject(ctx context.Context, dir string) (*models.Project, error) {
+	project, _, err := todoStore.FindProjectForDir(ctx, dir, gitOptions)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, nil //nolint:nilnil // Intentional: an unregistered repository is not an error
+	}
+	return project, err
+}
+
+// resolveCommitRef finds the todo a commit message reference names. A prefix only
+// matches todos of project, the repository's project, so a short ID in one repository
+// can't close a todo of another; a full ID matches any todo.
+func resolveCommitRef(ctx context.Context, project *models.Project, ref string) (*models.Todo, error) {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if id, err := uuid.Parse(ref); err == nil {
+		return todoStore.GetTodoByID(ctx, id)
+	}
+	if project == nil {
+		return nil, fmt.Errorf("todo %w: %s (this repository has no toki project, so use the full todo ID)", db.ErrNotFound, ref)
+	}
+
+	todos, err := todoStore.ListTodos(ctx, &project.ID, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var matches []*models.Todo
+	for _, todo := range todos {
+		if strings.HasPrefix(todo.ID.String(), ref) {
+			matches = append(matches, todo)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("todo %w in project '%s' with prefix: %s", db.ErrNotFound, project.Name, ref)
+	case 1:
+		return matches[0], nil
+	}
+	candidates := make([]db.RefCandidate, len(matches))
+	for i, todo := range matches {
+		candidates[i] = db.RefCandidate{ID: todo.ID.String(), Label: todo.Description}
+	}
+	return nil, &db.AmbiguousRefError{Kind: "todo", Ref: ref, Candidates: candidates}
+}
+
+func init() {
+	rootCmd.AddCommand(logScanCmd)
+}
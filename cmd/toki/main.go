@@ -4,8 +4,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 )
 
 var (
@@ -16,7 +19,18 @@ var (
 )
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// Interrupting cancels the command's context, which aborts its database calls. A
+	// second interrupt kills toki as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	rootCmd.SetArgs(expandViewShortcut(os.Args[1:]))
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
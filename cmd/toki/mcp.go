@@ -4,12 +4,10 @@
 package main
 
 import (
-	"context"
 	"fmt"
-	"os"
-	"os/signal"
-	"syscall"
 
+	"github.com/harper/toki/internal/config"
+	"github.com/harper/toki/internal/git"
 	"github.com/harper/toki/internal/mcp"
 	"github.com/spf13/cobra"
 )
@@ -22,27 +20,57 @@ var mcpCmd = &cobra.Command{
 The MCP server communicates via stdio, allowing AI agents like Claude
 to interact with your toki tasks through a standardized protocol.
 
+When a tool omits project_id, the project is detected from the git
+repository of the tool's cwd argument, the --cwd flag, or the client's
+roots, in that order, falling back to the default project (the
+default_project config setting). With auto_create = "always" in the
+config, unregistered repositories get their own project as if
+--auto-create-projects were given.
+
+The server keeps to one database for its whole run: --workspace scopes
+an agent to that workspace, otherwise it is the workspace in use where
+the server starts. Tool cwd arguments and client roots never switch
+workspaces.
+
 This command will run continuously until interrupted (Ctrl+C).`,
 	RunE: runMCP,
 }
 
 func init() {
+	mcpCmd.Flags().String("cwd", "", "directory used to detect the project when tools omit project_id")
+	mcpCmd.Flags().Bool("auto-create-projects", false, "create projects for unregistered git repositories instead of using the default project")
 	rootCmd.AddCommand(mcpCmd)
 }
 
 func runMCP(cmd *cobra.Command, args []string) error {
-	// Context with signal handling for graceful shutdown
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer cancel()
+	// Cancelled on interrupt by main, for graceful shutdown
+	ctx := cmd.Context()
 
-	// Database is initialized by root command's PersistentPreRunE
-	// and available via the global dbConn variable
-	if dbConn == nil {
+	// The store is opened by root command's PersistentPreRunE
+	if todoStore == nil {
 		return fmt.Errorf("database connection not initialized")
 	}
 
-	// Create MCP server with database connection
-	server, err := mcp.NewServer(dbConn)
+	opts := mcp.Options{Git: gitOptions, DefaultProject: settings.DefaultProject}
+	opts.AutoCreateProjects = settings.AutoCreate == config.AutoCreateAlways
+	if cmd.Flags().Changed("auto-create-projects") {
+		opts.AutoCreateProjects, _ = cmd.Flags().GetBool("auto-create-projects")
+	}
+	if cwd, _ := cmd.Flags().GetString("cwd"); cwd != "" {
+		normalized, err := git.NormalizePath(cwd)
+		if err != nil {
+			return fmt.Errorf("invalid cwd: %w", err)
+		}
+		opts.Cwd = normalized
+	}
+	coefficients, err := urgencyCoefficients()
+	if err != nil {
+		return err
+	}
+	opts.Urgency = &coefficients
+
+	// Create MCP server over the store
+	server, err := mcp.NewServerWithOptions(todoStore, opts)
 	if err != nil {
 		return err
 	}
@@ -1,13 +1,13 @@
 // ABOUTME: Project management commands
-// ABOUTME: Handles add, list, set-path, and remove operations
+// ABOUTME: Handles add, list, path, remote linking, and remove operations
 
 package main
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/fatih/color"
-	"github.com/harper/toki/internal/db"
 	"github.com/harper/toki/internal/git"
 	"github.com/harper/toki/internal/models"
 	"github.com/spf13/cobra"
@@ -25,6 +25,7 @@ var projectAddCmd = &cobra.Command{
 	Short:   "Add a new project",
 	Args:    cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		name := args[0]
 
 		pathFlag, _ := cmd.Flags().GetString("path")
@@ -39,8 +40,13 @@ var projectAddCmd = &cobra.Command{
 		}
 
 		project := models.NewProject(name, dirPath)
+		if dirPath != nil {
+			if repo, err := git.DetectRepo(*dirPath, gitOptions); err == nil && repo.RemoteURL != "" {
+				project.RemoteURL = &repo.RemoteURL
+			}
+		}
 
-		if err := db.CreateProject(dbConn, project); err != nil {
+		if err := todoStore.CreateProject(ctx, project); err != nil {
 			return fmt.Errorf("failed to create project: %w", err)
 		}
 
@@ -48,6 +54,9 @@ var projectAddCmd = &cobra.Command{
 		if dirPath != nil {
 			fmt.Printf("  Path: %s\n", *dirPath)
 		}
+		if project.RemoteURL != nil {
+			fmt.Printf("  Remote: %s\n", *project.RemoteURL)
+		}
 
 		return nil
 	},
@@ -58,7 +67,8 @@ var projectListCmd = &cobra.Command{
 	Aliases: []string{"ls", "l"},
 	Short:   "List all projects",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		projects, err := db.ListProjects(dbConn)
+		ctx := cmd.Context()
+		projects, err := todoStore.ListProjects(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to list projects: %w", err)
 		}
@@ -76,6 +86,16 @@ var projectListCmd = &cobra.Command{
 			if p.DirectoryPath != nil {
 				fmt.Printf("  %s\n", color.New(color.Faint).Sprint(*p.DirectoryPath))
 			}
+			paths, err := todoStore.ListProjectPaths(ctx, p.ID)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				fmt.Printf("  %s\n", color.New(color.Faint).Sprint(path))
+			}
+			if p.RemoteURL != nil {
+				fmt.Printf("  %s\n", color.New(color.Faint).Sprint("remote: "+*p.RemoteURL))
+			}
 		}
 
 		return nil
@@ -88,12 +108,13 @@ var projectSetPathCmd = &cobra.Command{
 	Short:   "Set directory path for a project",
 	Args:    cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		name := args[0]
 		pathArg := args[1]
 
-		project, err := db.GetProjectByName(dbConn, name)
+		project, err := todoStore.ResolveProjectRef(ctx, name)
 		if err != nil {
-			return fmt.Errorf("project not found: %w", err)
+			return err
 		}
 
 		normalized, err := git.NormalizePath(pathArg)
@@ -101,35 +122,135 @@ var projectSetPathCmd = &cobra.Command{
 			return fmt.Errorf("invalid path: %w", err)
 		}
 
-		if err := db.UpdateProjectPath(dbConn, project.ID, &normalized); err != nil {
+		if err := todoStore.UpdateProjectPath(ctx, project.ID, &normalized); err != nil {
 			return fmt.Errorf("failed to update path: %w", err)
 		}
 
-		color.Green("✓ Updated path for '%s'", name)
+		color.Green("✓ Updated path for '%s'", project.Name)
+		fmt.Printf("  Path: %s\n", normalized)
+
+		return nil
+	},
+}
+
+var projectAddPathCmd = &cobra.Command{
+	Use:   "add-path <name> <path>",
+	Short: "Associate an additional directory with a project",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, err := todoStore.ResolveProjectRef(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		normalized, err := git.NormalizePath(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+
+		if err := todoStore.AddProjectPath(ctx, project.ID, normalized); err != nil {
+			return fmt.Errorf("failed to add path: %w", err)
+		}
+
+		color.Green("✓ Added path for '%s'", project.Name)
 		fmt.Printf("  Path: %s\n", normalized)
 
 		return nil
 	},
 }
 
+var projectRemovePathCmd = &cobra.Command{
+	Use:   "remove-path <name> <path>",
+	Short: "Remove an additional directory from a project",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, err := todoStore.ResolveProjectRef(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		normalized, err := git.NormalizePath(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+
+		if err := todoStore.RemoveProjectPath(ctx, project.ID, normalized); err != nil {
+			return fmt.Errorf("failed to remove path: %w", err)
+		}
+
+		color.Yellow("✓ Removed path for '%s'", project.Name)
+		fmt.Printf("  Path: %s\n", normalized)
+
+		return nil
+	},
+}
+
+var projectLinkRemoteCmd = &cobra.Command{
+	Use:   "link-remote <name> [remote-url]",
+	Short: "Match a project by git remote URL instead of path",
+	Long: `Link a project to a git remote so that any clone of the repository,
+at any path or on any machine, resolves to the same project.
+
+If no URL is given, the origin URL of the current directory's repository
+is used. ssh and https forms of the same URL are treated as equal.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		project, err := todoStore.ResolveProjectRef(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		var remoteURL string
+		if len(args) == 2 {
+			remoteURL = git.NormalizeRemoteURL(args[1])
+		} else {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			repo, err := git.DetectRepo(cwd, gitOptions)
+			if err != nil {
+				return err
+			}
+			if repo.RemoteURL == "" {
+				return fmt.Errorf("repository %s has no origin remote; pass the URL explicitly", repo.Root)
+			}
+			remoteURL = repo.RemoteURL
+		}
+
+		if err := todoStore.UpdateProjectRemote(ctx, project.ID, &remoteURL); err != nil {
+			return fmt.Errorf("failed to link remote: %w", err)
+		}
+
+		color.Green("✓ Linked '%s' to remote", project.Name)
+		fmt.Printf("  Remote: %s\n", remoteURL)
+
+		return nil
+	},
+}
+
 var projectRemoveCmd = &cobra.Command{
 	Use:     "remove <name>",
 	Aliases: []string{"rm", "r"},
 	Short:   "Remove a project",
 	Args:    cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		name := args[0]
 
-		project, err := db.GetProjectByName(dbConn, name)
+		project, err := todoStore.ResolveProjectRef(ctx, name)
 		if err != nil {
-			return fmt.Errorf("project not found: %w", err)
+			return err
 		}
 
-		if err := db.DeleteProject(dbConn, project.ID); err != nil {
+		if err := todoStore.DeleteProject(ctx, project.ID); err != nil {
 			return fmt.Errorf("failed to delete project: %w", err)
 		}
 
-		color.Yellow("✓ Removed project '%s' and all its todos", name)
+		color.Yellow("✓ Removed project '%s' and all its todos", project.Name)
 
 		return nil
 	},
@@ -141,6 +262,9 @@ func init() {
 	projectCmd.AddCommand(projectAddCmd)
 	projectCmd.AddCommand(projectListCmd)
 	projectCmd.AddCommand(projectSetPathCmd)
+	projectCmd.AddCommand(projectAddPathCmd)
+	projectCmd.AddCommand(projectRemovePathCmd)
+	projectCmd.AddCommand(projectLinkRemoteCmd)
 	projectCmd.AddCommand(projectRemoveCmd)
 	rootCmd.AddCommand(projectCmd)
 }
@@ -0,0 +1,75 @@
+// ABOUTME: Tests for the CLI's questions answered through a scripted prompter
+// ABOUTME: Covers project creation confirmation and branch cleanup actions
+
+package main
+
+import (
+	"testing"
+
+	"github.com/harper/toki/internal/prompt"
+)
+
+func usePrompter(t *testing.T, p prompt.Prompter) {
+	t.Helper()
+	saved := prompter
+	prompter = p
+	t.Cleanup(func() { prompter = saved })
+}
+
+func TestConfirmCreateProject(t *testing.T) {
+	scripted := &prompt.Scripted{Answers: []string{"", "n"}}
+	usePrompter(t, scripted)
+
+	if create, err := confirmCreateProject("/src/app", "app"); err != nil || !create {
+		t.Errorf("Expected an empty answer to create the project, got %v, %v", create, err)
+	}
+	if create, err := confirmCreateProject("/src/app", "app"); err != nil || create {
+		t.Errorf("Expected 'n' to decline, got %v, %v", create, err)
+	}
+	if len(scripted.Questions) != 2 || scripted.Questions[0] != "Create project 'app'?" {
+		t.Errorf("Unexpected questions: %q", scripted.Questions)
+	}
+
+	usePrompter(t, &prompt.Scripted{})
+	if create, err := confirmCreateProject("/src/app", "app"); err != nil || create {
+		t.Errorf("Expected end of input to decline, got %v, %v", create, err)
+	}
+
+	usePrompter(t, &prompt.NonInteractive{})
+	if create, _ := confirmCreateProject("/src/app", "app"); create {
+		t.Error("Expected no project without a terminal")
+	}
+	usePrompter(t, &prompt.NonInteractive{Yes: true})
+	if create, _ := confirmCreateProject("/src/app", "app"); !create {
+		t.Error("Expected --yes to create the project")
+	}
+}
+
+func TestAskBranchAction(t *testing.T) {
+	tests := []struct {
+		answers    []string
+		wantAction string
+		wantTarget string
+	}{
+		{[]string{"c"}, "complete", ""},
+		{[]string{""}, "skip", ""},
+		{[]string{"m", "main"}, "move", "main"},
+		{[]string{"move", ""}, "move", ""},
+	}
+
+	for _, tt := range tests {
+		usePrompter(t, &prompt.Scripted{Answers: tt.answers})
+		action, target, err := askBranchAction()
+		if err != nil {
+			t.Fatalf("askBranchAction(%q) error: %v", tt.answers, err)
+		}
+		if action != tt.wantAction || target != tt.wantTarget {
+			t.Errorf("askBranchAction(%q) = %q, %q; want %q, %q", tt.answers, action, target, tt.wantAction, tt.wantTarget)
+		}
+	}
+
+	usePrompter(t, &prompt.NonInteractive{Yes: true})
+	if action, _, _ := askBranchAction(); action != "skip" {
+		t.Errorf("Expected non-interactive cleanup to skip, got %q", action)
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 
 	"github.com/fatih/color"
-	"github.com/harper/toki/internal/db"
 	"github.com/spf13/cobra"
 )
 
@@ -17,16 +16,17 @@ var removeCmd = &cobra.Command{
 	Short:   "Remove a todo",
 	Args:    cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		prefix := args[0]
 
-		todo, err := db.GetTodoByPrefix(dbConn, prefix)
+		todo, err := todoStore.ResolveTodoRef(ctx, prefix)
 		if err != nil {
 			return err
 		}
 
 		desc := todo.Description
 
-		if err := db.DeleteTodo(dbConn, todo.ID); err != nil {
+		if err := todoStore.DeleteTodo(ctx, todo.ID); err != nil {
 			return fmt.Errorf("failed to delete todo: %w", err)
 		}
 
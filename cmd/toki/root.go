@@ -5,15 +5,36 @@ package main
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
 
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/config"
 	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/prompt"
+	"github.com/harper/toki/internal/secret"
+	"github.com/harper/toki/internal/store"
+	"github.com/harper/toki/internal/ui"
 	"github.com/spf13/cobra"
 )
 
 var (
 	dbPath string
 	dbConn *sql.DB
+	// todoStore keeps projects, todos, and tags; the rest lives directly in dbConn.
+	todoStore store.Store
+	// textKey encrypts todo descriptions and notes in todoStore; nil stores them as is.
+	textKey    *secret.Key
+	keySource  string
+	submodules string
+	gitOptions git.Options
+	settings   = config.Default()
+	assumeYes  bool
+	noInput    bool
+	// prompter asks every question the CLI has for the user; tests replace it.
+	prompter prompt.Prompter = &prompt.NonInteractive{}
 )
 
 var rootCmd = &cobra.Command{
@@ -33,12 +54,34 @@ Toki is a CLI todo manager that organizes tasks by project,
 supports rich metadata (priority, tags, notes, due dates),
 and automatically detects project context from git repositories.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		mode, err := git.ParseSubmoduleMode(submodules)
+		if err != nil {
+			return err
+		}
+		gitOptions.Submodules = mode
+		prompter = prompt.New(prompt.Options{Yes: assumeYes, NoInput: noInput})
+
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
+
 		// Initialize database connection
-		var err error
-		dbConn, err = db.InitDB(dbPath)
+		// The db commands take their own backups; an automatic one taken here would
+		// become the newest backup, which restore picks by default
+		var opts db.Options
+		if cmd.Parent() != dbCmd {
+			opts.AutoBackup = autoBackup()
+		}
+		dbConn, err = db.Open(cmd.Context(), dbPath, opts)
 		if err != nil {
 			return fmt.Errorf("failed to initialize database: %w", err)
 		}
+
+		textKey, keySource, err = secret.LoadKey(keyFileSetting())
+		if err != nil {
+			return err
+		}
+		todoStore = store.NewEncrypted(store.NewSQLite(dbConn), textKey)
 		return nil
 	},
 	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
@@ -50,7 +93,76 @@ and automatically detects project context from git repositories.`,
 	},
 }
 
+// loadSettings reads the config file for the current directory and applies the
+// settings that affect every command: database path (from --workspace, $TOKI_WORKSPACE,
+// or the config), colors, theme, and date format.
+func loadSettings(cmd *cobra.Command) error {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+
+	workspace, _ := cmd.Flags().GetString("workspace")
+	if cmd.Flags().Changed("db") {
+		if workspace != "" {
+			return errors.New("--db and --workspace can't be used together")
+		}
+		// --db overrides any workspace, including $TOKI_WORKSPACE
+		workspace = config.DefaultWorkspace
+	}
+	if workspace != "" {
+		settings, err = config.LoadWorkspace(config.Path(), cwd, workspace)
+	} else {
+		settings, err = config.Load(config.Path(), cwd)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("db") && settings.DBPath != "" {
+		dbPath = config.ExpandPath(settings.DBPath)
+	}
+
+	switch settings.Color {
+	case config.ColorAlways:
+		color.NoColor = false
+	case config.ColorNever:
+		color.NoColor = true
+	}
+	ui.DateLayout = settings.DateLayout()
+	return ui.SetTheme(settings.Theme)
+}
+
+// autoBackup returns the automatic backup policy the settings ask for, or nil when
+// backup.auto is off.
+func autoBackup() *db.AutoBackup {
+	if !settings.Backup.Auto {
+		return nil
+	}
+	return &db.AutoBackup{Dir: backupDir(), Name: db.BackupName(dbPath), Keep: settings.Backup.Keep}
+}
+
+// backupDir returns the backup.dir setting, or the backups directory next to the database.
+func backupDir() string {
+	if settings.Backup.Dir != "" {
+		return config.ExpandPath(settings.Backup.Dir)
+	}
+	return db.DefaultBackupDir(dbPath)
+}
+
+// keyFileSetting returns the encryption.key_file setting with ~ expanded.
+func keyFileSetting() string {
+	if settings.Encryption.KeyFile == "" {
+		return ""
+	}
+	return config.ExpandPath(settings.Encryption.KeyFile)
+}
+
 func init() {
 	defaultPath := db.GetDefaultDBPath()
 	rootCmd.PersistentFlags().StringVar(&dbPath, "db", defaultPath, "database file path")
+	rootCmd.PersistentFlags().String("workspace", "", "use this workspace's database (also $"+config.WorkspaceEnv+")")
+	rootCmd.PersistentFlags().StringVar(&submodules, "submodules", string(git.SubmodulesSeparate), "how git submodules map to projects (separate, superproject)")
+	rootCmd.PersistentFlags().BoolVarP(&assumeYes, "yes", "y", false, "answer yes to every confirmation instead of prompting")
+	rootCmd.PersistentFlags().BoolVar(&noInput, "no-input", false, "never prompt; decline confirmations and take defaults (also $"+prompt.NonInteractiveEnv+")")
 }
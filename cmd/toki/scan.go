@@ -0,0 +1,120 @@
+// ABOUTME: Source scan command that syncs TODO/FIXME/HACK comments into todos
+// ABOUTME: Walks the project directory and upserts code todos tagged "code"
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/config"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/scan"
+	"github.com/harper/toki/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Sync TODO/FIXME/HACK comments from source code into todos",
+	Long: `Scan the project's directory for TODO-style comments and sync them into
+todos tagged "code", with the file and line in the notes. Files ignored by
+.gitignore are skipped.
+
+Repeated scans update existing todos instead of duplicating them. When a
+comment disappears from the code its todo is marked done, and reopened if
+the comment comes back.
+
+Markers and languages default to TODO, FIXME, HACK in every known language.
+Override them per run with --markers and --languages, or with the settings:
+  toki config set scan.markers TODO,FIXME,XXX
+  toki config set scan.languages go,python --dir ~/src/api
+Without them, the repository's git config toki.scanMarkers and
+toki.scanLanguages are used.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		projectFlag, _ := cmd.Flags().GetString("project")
+		project, err := projectFromFlagOrContext(ctx, projectFlag)
+		if err != nil {
+			return err
+		}
+		if project.DirectoryPath == nil {
+			return fmt.Errorf("project '%s' has no directory path; set one with 'toki project set-path'", project.Name)
+		}
+		dir := *project.DirectoryPath
+
+		// Settings for the project's directory, which may not be the current one
+		dirSettings, err := config.LoadWorkspace(config.Path(), dir, currentWorkspace())
+		if err != nil {
+			return err
+		}
+		markers := scanSetting(cmd, "markers", dirSettings.Scan.Markers, dir, "scanmarkers")
+		languages := scanSetting(cmd, "languages", dirSettings.Scan.Languages, dir, "scanlanguages")
+		scanConfig, err := scan.NewConfig(markers, languages)
+		if err != nil {
+			return err
+		}
+
+		comments, err := scan.Dir(dir, scanConfig)
+		if err != nil {
+			return err
+		}
+
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			for _, c := range comments {
+				fmt.Printf("%s  %s %s\n", color.New(color.Faint).Sprint(c.Location()), c.Marker, c.Text)
+			}
+			fmt.Printf("%d comment(s) found\n", len(comments))
+			return nil
+		}
+
+		found := make([]*models.CodeComment, len(comments))
+		for i, c := range comments {
+			found[i] = &models.CodeComment{
+				Fingerprint: c.Fingerprint,
+				File:        c.File,
+				Line:        c.Line,
+				Marker:      c.Marker,
+				Text:        c.Text,
+			}
+		}
+
+		result, err := store.SyncCodeComments(ctx, todoStore, project.ID, found)
+		if err != nil {
+			return err
+		}
+
+		color.Green("✓ Scanned '%s': %d comment(s)", project.Name, len(comments))
+		fmt.Printf("  %d added, %d moved, %d closed, %d reopened\n", result.Added, result.Moved, result.Closed, result.Reopened)
+
+		return nil
+	},
+}
+
+// scanSetting returns a comma-separated list from a flag, or else from the setting, or
+// else from the repository's toki.<key> git config.
+func scanSetting(cmd *cobra.Command, flag, setting, dir, key string) []string {
+	value, _ := cmd.Flags().GetString(flag)
+	if !cmd.Flags().Changed(flag) {
+		value = setting
+	}
+	if !cmd.Flags().Changed(flag) && value == "" {
+		value = git.ConfigValue(dir, "toki", key)
+	}
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+func init() {
+	scanCmd.Flags().StringP("project", "p", "", "project to scan")
+	scanCmd.Flags().String("markers", "", "comma-separated comment markers (default TODO,FIXME,HACK)")
+	scanCmd.Flags().String("languages", "", "comma-separated languages to scan (default all: "+strings.Join(scan.LanguageNames(), ",")+")")
+	scanCmd.Flags().Bool("dry-run", false, "list comments without syncing todos")
+
+	rootCmd.AddCommand(scanCmd)
+}
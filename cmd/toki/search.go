@@ -0,0 +1,50 @@
+// ABOUTME: Search command that finds todos across projects with a filter expression
+// ABOUTME: Unlike list it includes completed todos and ignores the current project
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+	Use:   "search <expression>...",
+	Short: "Search all todos with a filter expression",
+	Long: `Search todos in every project, pending and completed, with a filter
+expression. Arguments are joined with spaces, so plain words search
+descriptions and notes:
+
+  toki search login timeout
+  toki search 'tag:bug and created>-2w'
+
+See 'toki help filters' for the syntax.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		expr, err := parseFilter(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+
+		sortKey, reverse, coefficients, err := sortOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		todos, todoTags, err := todoStore.QueryTodosWithTags(ctx, expr)
+		if err != nil {
+			return fmt.Errorf("failed to search todos: %w", err)
+		}
+		return printTodos(ctx, todos, todoTags, sortKey, reverse, coefficients, listPage{}, "matching", "No todos match.")
+	},
+}
+
+func init() {
+	searchCmd.Flags().String("sort", "", "sort order (see 'toki list --help')")
+	searchCmd.Flags().Bool("reverse", false, "reverse the sort order")
+
+	rootCmd.AddCommand(searchCmd)
+}
@@ -0,0 +1,290 @@
+// ABOUTME: Stats commands: summary counts, weekly velocity, and daily burndown
+// ABOUTME: Renders the shared stats report with ui colors or as JSON, and charts estimates
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/config"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/stats"
+	"github.com/harper/toki/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+// chartWidth is the width of the longest bar in stats charts.
+const chartWidth = 40
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show todo statistics",
+	Long: `Show todo statistics across all projects: totals, counts by priority,
+project, and tag, how old pending todos are, and recent throughput. The
+same report is available to MCP clients as the toki://stats resource.
+
+The subcommands chart how fast todos get done, counted in todos, story
+points, or estimated hours. Set estimates with 'toki add --estimate' or
+'toki estimate'.
+
+  toki stats
+  toki stats --json
+  toki stats velocity --weeks 8
+  toki stats burndown --project api --since 2025-01-06 --unit points`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		report, err := stats.Load(ctx, todoStore, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if asJSON, _ := cmd.Flags().GetBool("json"); asJSON || settings.Output == config.OutputJSON {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return fmt.Errorf("failed to encode stats: %w", err)
+			}
+			return nil
+		}
+
+		printStatsReport(report)
+		return nil
+	},
+}
+
+var statsVelocityCmd = &cobra.Command{
+	Use:   "velocity",
+	Short: "Chart the work completed each week",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		weeks, _ := cmd.Flags().GetInt("weeks")
+		if weeks < 1 {
+			return fmt.Errorf("--weeks must be at least 1")
+		}
+		unit, err := statsUnit(cmd)
+		if err != nil {
+			return err
+		}
+		todos, project, err := statsTodos(cmd)
+		if err != nil {
+			return err
+		}
+
+		velocity := stats.Velocity(todos, weeks, time.Now())
+		bars := make([]ui.Bar, len(velocity))
+		for i, week := range velocity {
+			bars[i] = workBar("Week of "+week.Start.Format("Jan 02"), week.Completed, unit)
+		}
+
+		fmt.Println("VELOCITY: " + ui.BoldCyan.Sprint(project) + ui.Faint.Sprintf(" (last %d weeks, %s)", weeks, unit))
+		fmt.Println(ui.FormatSeparator())
+		fmt.Print(ui.BarChart(bars, chartWidth))
+		fmt.Println(ui.FormatSeparator())
+
+		todosPerWeek, pointsPerWeek, estimatedPerWeek := stats.AverageCompleted(velocity)
+		switch unit {
+		case unitPoints:
+			fmt.Printf("Average: %s points/week\n", formatAmount(pointsPerWeek))
+		case unitHours:
+			fmt.Printf("Average: %s estimated/week\n", ui.FormatDuration(estimatedPerWeek.Round(time.Minute)))
+		default:
+			fmt.Printf("Average: %s todos/week\n", formatAmount(todosPerWeek))
+		}
+		return nil
+	},
+}
+
+var statsBurndownCmd = &cobra.Command{
+	Use:   "burndown",
+	Short: "Chart the work remaining each day",
+	Long: `Chart the work remaining at the end of each day since a date: todos
+created by then and not yet completed.
+
+--since takes a day like 2025-01-06, today, yesterday, or a relative
+date like -2w or -10d.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		now := time.Now()
+		sinceFlag, _ := cmd.Flags().GetString("since")
+		since, _, err := filter.ResolveDate(sinceFlag, now)
+		if err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+		if since.After(now) {
+			return fmt.Errorf("--since must not be in the future")
+		}
+		unit, err := statsUnit(cmd)
+		if err != nil {
+			return err
+		}
+		todos, project, err := statsTodos(cmd)
+		if err != nil {
+			return err
+		}
+
+		days := stats.Burndown(todos, since, now)
+		bars := make([]ui.Bar, len(days))
+		for i, day := range days {
+			bars[i] = workBar(day.Date.Format("Mon Jan 02"), day.Remaining, unit)
+		}
+
+		fmt.Println("BURNDOWN: " + ui.BoldCyan.Sprint(project) + ui.Faint.Sprintf(" (since %s, %s remaining)", since.Format(ui.DateLayout), unit))
+		fmt.Println(ui.FormatSeparator())
+		fmt.Print(ui.BarChart(bars, chartWidth))
+		return nil
+	},
+}
+
+func printStatsReport(report *stats.Report) {
+	summary := report.Summary
+	overdue := fmt.Sprintf("%d overdue", summary.Overdue)
+	if summary.Overdue > 0 {
+		overdue = ui.Red.Sprint(overdue)
+	}
+	fmt.Println("STATS")
+	fmt.Println(ui.FormatSeparator())
+	fmt.Printf("%d todos: %d pending, %d completed, %s\n", summary.TotalTodos, summary.Pending, summary.Completed, overdue)
+	if summary.TotalTodos == 0 {
+		return
+	}
+	fmt.Println()
+
+	heading := color.New(color.Bold)
+	_, _ = heading.Println("BY PRIORITY")
+	for _, priority := range []string{"high", "medium", "low", "none"} {
+		count, ok := report.ByPriority[priority]
+		if !ok {
+			continue
+		}
+		label := fmt.Sprintf("%-6s", priority)
+		if c := ui.PriorityColor(priority); c != nil {
+			label = c.Sprint(label)
+		}
+		fmt.Printf("  %s %5d\n", label, count)
+	}
+	fmt.Println()
+
+	_, _ = heading.Println("BY PROJECT")
+	for _, project := range report.ByProject {
+		line := fmt.Sprintf("  %5d  %s", project.TodoCount, ui.BoldCyan.Sprint(project.ProjectName))
+		line += ui.Faint.Sprintf(" (%d pending)", project.Pending)
+		if project.Overdue > 0 {
+			line += " " + ui.Red.Sprintf("%d overdue", project.Overdue)
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+
+	if len(report.ByTag) > 0 {
+		_, _ = heading.Println("BY TAG")
+		for _, tag := range report.ByTag {
+			fmt.Printf("  %5d  %s%s\n", tag.TodoCount, tag.Name, ui.Faint.Sprintf(" (%d pending)", tag.Pending))
+		}
+		fmt.Println()
+	}
+
+	if summary.Pending > 0 {
+		_, _ = heading.Println("AGE OF PENDING TODOS")
+		bars := make([]ui.Bar, len(report.Ages))
+		for i, bucket := range report.Ages {
+			bars[i] = ui.Bar{Label: bucket.Label, Value: float64(bucket.Count), Text: strconv.Itoa(bucket.Count)}
+		}
+		fmt.Print(ui.BarChart(bars, chartWidth/2))
+		if oldest := report.OldestPending; oldest != nil {
+			fmt.Printf("  Oldest: %s %s %s\n", ui.Faint.Sprint(oldest.ID[:6]), oldest.Description, ui.Faint.Sprintf("(%d days)", oldest.AgeDays))
+		}
+		fmt.Println()
+	}
+
+	trends := report.Trends
+	completed := 0
+	for _, week := range trends.Weeks {
+		completed += week.Completed
+	}
+	fmt.Println(ui.FormatSeparator())
+	fmt.Printf("Last %d weeks: %d completed, %s/week", len(trends.Weeks), completed, formatAmount(float64(completed)/float64(len(trends.Weeks))))
+	if trends.CycleTimeSamples > 0 {
+		cycleTime := time.Duration(trends.AverageCycleTimeHours * float64(time.Hour))
+		fmt.Printf(", average cycle time %s", ui.FormatDuration(cycleTime))
+	}
+	fmt.Println()
+}
+
+const (
+	unitTodos  = "todos"
+	unitPoints = "points"
+	unitHours  = "hours"
+)
+
+func statsUnit(cmd *cobra.Command) (string, error) {
+	unit, _ := cmd.Flags().GetString("unit")
+	switch unit {
+	case unitTodos, unitPoints, unitHours:
+		return unit, nil
+	}
+	return "", fmt.Errorf("invalid --unit '%s' (use todos, points, or hours)", unit)
+}
+
+// statsTodos loads the todos of the --project project, or of every project.
+func statsTodos(cmd *cobra.Command) ([]*models.Todo, string, error) {
+	ctx := cmd.Context()
+	projectFlag, _ := cmd.Flags().GetString("project")
+	if projectFlag == "" {
+		todos, err := todoStore.ListTodos(ctx, nil, nil, nil, nil, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list todos: %w", err)
+		}
+		return todos, "all projects", nil
+	}
+
+	projectID, err := getProjectID(ctx, projectFlag)
+	if err != nil {
+		return nil, "", err
+	}
+	project, err := todoStore.GetProjectByID(ctx, *projectID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get project: %w", err)
+	}
+	todos, err := todoStore.ListTodos(ctx, projectID, nil, nil, nil, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list todos: %w", err)
+	}
+	return todos, project.Name, nil
+}
+
+func workBar(label string, work stats.Work, unit string) ui.Bar {
+	switch unit {
+	case unitPoints:
+		return ui.Bar{Label: label, Value: work.Points, Text: formatAmount(work.Points) + "pt"}
+	case unitHours:
+		return ui.Bar{Label: label, Value: work.Estimated.Hours(), Text: ui.FormatDuration(work.Estimated)}
+	default:
+		return ui.Bar{Label: label, Value: float64(work.Todos), Text: strconv.Itoa(work.Todos)}
+	}
+}
+
+// formatAmount formats a count or point total with at most one decimal.
+func formatAmount(value float64) string {
+	return strconv.FormatFloat(float64(int(value*10+0.5))/10, 'f', -1, 64)
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{statsVelocityCmd, statsBurndownCmd} {
+		cmd.Flags().StringP("project", "p", "", "project name (default all projects)")
+		cmd.Flags().String("unit", unitTodos, "count todos, points, or hours of estimates")
+	}
+	statsCmd.Flags().Bool("json", false, "print the report as JSON")
+	statsVelocityCmd.Flags().Int("weeks", 8, "number of weeks to chart, including this one")
+	statsBurndownCmd.Flags().String("since", "-2w", "first day to chart")
+
+	statsCmd.AddCommand(statsVelocityCmd)
+	statsCmd.AddCommand(statsBurndownCmd)
+	rootCmd.AddCommand(statsCmd)
+}
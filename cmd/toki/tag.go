@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/fatih/color"
-	"github.com/harper/toki/internal/db"
 	"github.com/spf13/cobra"
 )
 
@@ -23,15 +22,16 @@ var tagAddCmd = &cobra.Command{
 	Short:   "Add a tag to a todo",
 	Args:    cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		prefix := args[0]
 		tagName := strings.ToLower(args[1])
 
-		todo, err := db.GetTodoByPrefix(dbConn, prefix)
+		todo, err := todoStore.ResolveTodoRef(ctx, prefix)
 		if err != nil {
 			return err
 		}
 
-		if err := db.AddTagToTodo(dbConn, todo.ID, tagName); err != nil {
+		if err := todoStore.AddTagToTodo(ctx, todo.ID, tagName); err != nil {
 			return fmt.Errorf("failed to add tag: %w", err)
 		}
 
@@ -48,15 +48,16 @@ var tagRemoveCmd = &cobra.Command{
 	Short:   "Remove a tag from a todo",
 	Args:    cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
 		prefix := args[0]
 		tagName := strings.ToLower(args[1])
 
-		todo, err := db.GetTodoByPrefix(dbConn, prefix)
+		todo, err := todoStore.ResolveTodoRef(ctx, prefix)
 		if err != nil {
 			return err
 		}
 
-		if err := db.RemoveTagFromTodo(dbConn, todo.ID, tagName); err != nil {
+		if err := todoStore.RemoveTagFromTodo(ctx, todo.ID, tagName); err != nil {
 			return fmt.Errorf("failed to remove tag: %w", err)
 		}
 
@@ -71,7 +72,8 @@ var tagsListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all tags",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		tags, err := db.ListAllTags(dbConn)
+		ctx := cmd.Context()
+		tags, err := todoStore.ListAllTags(ctx)
 		if err != nil {
 			return fmt.Errorf("failed to list tags: %w", err)
 		}
@@ -0,0 +1,205 @@
+// ABOUTME: Timer commands for tracking time spent on todos
+// ABOUTME: Starts and stops the active timer, logs durations, and reports totals by project and tag
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var timerCmd = &cobra.Command{
+	Use:   "timer",
+	Short: "Track time spent on todos",
+	Long: `Track time spent on todos with a timer or by logging durations.
+
+One timer runs at a time; starting a timer stops the one already running.
+Tracked time shows up next to each todo in 'toki list'.
+
+  toki timer start a1b2c3
+  toki timer stop
+  toki timer log a1b2c3 1h30m
+  toki timer report --range this-week`,
+}
+
+var timerStartCmd = &cobra.Command{
+	Use:   "start <uuid-prefix>",
+	Short: "Start the timer on a todo",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		todo, err := todoStore.ResolveTodoRef(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		_, stopped, err := todoStore.StartTimer(ctx, todo.ID, time.Now())
+		if err != nil {
+			return err
+		}
+
+		if stopped != nil {
+			printStoppedTimer(ctx, stopped)
+		}
+		color.Green("✓ Started timer")
+		fmt.Printf("  %s %s\n", todo.ID.String()[:6], todo.Description)
+		return nil
+	},
+}
+
+var timerStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running timer",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		stopped, err := todoStore.StopTimer(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		printStoppedTimer(ctx, stopped)
+		return nil
+	},
+}
+
+var timerStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the running timer",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		active, err := todoStore.ActiveTimer(ctx)
+		if errors.Is(err, db.ErrNoTimer) {
+			fmt.Println("No timer running")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		todo, err := todoStore.GetTodoByID(ctx, active.TodoID)
+		if err != nil {
+			return fmt.Errorf("failed to get todo: %w", err)
+		}
+		fmt.Printf("Timer running for %s\n", ui.FormatDuration(active.Elapsed(time.Now())))
+		fmt.Printf("  %s %s\n", todo.ID.String()[:6], todo.Description)
+		return nil
+	},
+}
+
+var timerLogCmd = &cobra.Command{
+	Use:   "log <uuid-prefix> <duration>",
+	Short: "Log time spent on a todo",
+	Long: `Log time spent on a todo without running a timer. The duration uses
+Go's format: 1h30m, 45m, or 2h. The entry ends now.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		todo, err := todoStore.ResolveTodoRef(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		duration, err := time.ParseDuration(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid duration '%s' (expected something like 1h30m or 45m)", args[1])
+		}
+
+		entry, err := todoStore.LogTime(ctx, todo.ID, duration, time.Now())
+		if err != nil {
+			return err
+		}
+
+		color.Green("✓ Logged %s", ui.FormatDuration(entry.Duration))
+		fmt.Printf("  %s %s\n", todo.ID.String()[:6], todo.Description)
+		return nil
+	},
+}
+
+var timerReportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Show time tracked by project and tag",
+	Long: `Show the time tracked in a range, totaled by project and by tag. A todo
+with several tags counts toward each of them.
+
+Ranges: ` + strings.Join(filter.Periods, ", ") + `, a day like 2025-01-31,
+or two days like 2025-01-01..2025-01-15. Weeks start on Monday.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		period, _ := cmd.Flags().GetString("range")
+		now := time.Now()
+		from, to, err := filter.ResolvePeriod(period, now)
+		if err != nil {
+			return err
+		}
+
+		report, err := todoStore.GetTimeReport(ctx, from, to, now)
+		if err != nil {
+			return err
+		}
+
+		printTimeReport(period, report)
+		return nil
+	},
+}
+
+func printStoppedTimer(ctx context.Context, entry *models.TimeEntry) {
+	color.Yellow("✓ Stopped timer after %s", ui.FormatDuration(entry.Duration))
+	if todo, err := todoStore.GetTodoByID(ctx, entry.TodoID); err == nil {
+		fmt.Printf("  %s %s\n", todo.ID.String()[:6], todo.Description)
+	}
+}
+
+func printTimeReport(period string, report *models.TimeReport) {
+	header := "TIME REPORT: " + ui.BoldCyan.Sprint(period)
+	if !report.From.IsZero() {
+		last := report.To.AddDate(0, 0, -1)
+		header += ui.Faint.Sprintf(" (%s – %s)", report.From.Format(ui.DateLayout), last.Format(ui.DateLayout))
+	}
+	fmt.Println(header)
+	fmt.Println(ui.FormatSeparator())
+
+	if report.Total == 0 {
+		fmt.Println("No time tracked.")
+		return
+	}
+
+	printTotals := func(title, empty string, totals []models.TimeTotal) {
+		_, _ = color.New(color.Bold).Println(title)
+		for _, total := range totals {
+			name := total.Name
+			if name == "" {
+				name = ui.Faint.Sprint(empty)
+			}
+			fmt.Printf("  %8s  %s\n", ui.FormatDuration(total.Duration), name)
+		}
+		fmt.Println()
+	}
+	printTotals("BY PROJECT", "", report.Projects)
+	printTotals("BY TAG", "(untagged)", report.Tags)
+
+	fmt.Println(ui.FormatSeparator())
+	fmt.Printf("%s tracked\n", ui.FormatDuration(report.Total))
+}
+
+func init() {
+	timerReportCmd.Flags().String("range", "this-week", "period to report on")
+
+	timerCmd.AddCommand(timerStartCmd)
+	timerCmd.AddCommand(timerStopCmd)
+	timerCmd.AddCommand(timerStatusCmd)
+	timerCmd.AddCommand(timerLogCmd)
+	timerCmd.AddCommand(timerReportCmd)
+	rootCmd.AddCommand(timerCmd)
+}
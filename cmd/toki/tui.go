@@ -0,0 +1,70 @@
+// ABOUTME: TUI command that opens the full-screen interactive interface
+// ABOUTME: Starts with the current directory's project selected when one is known
+
+package main
+
+import (
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/tui"
+	"github.com/spf13/cobra"
+)
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Browse and edit todos in a full-screen interface",
+	Long: `Open a full-screen interface with a project sidebar, a filterable todo
+list, and a detail pane showing the selected todo's notes.
+
+Keys:
+  tab        switch between the sidebar and the todo list
+  j/k        move down/up (arrow keys work too)
+  space, x   toggle done
+  e          edit description
+  n          edit notes
+  t          add tags (comma-separated; prefix with - to remove)
+  p          cycle priority
+  d          delete (asks for confirmation)
+  a          add a todo to the selected project
+  /          filter by text, tag, or branch (esc clears)
+  c          show or hide completed todos
+  q          quit
+
+Changes made by other toki processes, such as the MCP server, appear
+automatically.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		projectFlag, _ := cmd.Flags().GetString("project")
+
+		var projectID *uuid.UUID
+		if projectFlag != "" {
+			project, err := todoStore.ResolveProjectRef(ctx, projectFlag)
+			if err != nil {
+				return err
+			}
+			projectID = &project.ID
+		} else if cwd, err := os.Getwd(); err == nil {
+			// Select the directory's project without prompting to create one
+			if project, _, err := todoStore.FindProjectForDir(ctx, cwd, gitOptions); err == nil {
+				projectID = &project.ID
+			}
+		}
+
+		watcher, err := db.NewChangeWatcher(ctx, dbConn)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = watcher.Close() }()
+
+		return tui.Run(ctx, todoStore, watcher, projectID, settings.DefaultProject)
+	},
+}
+
+func init() {
+	tuiCmd.Flags().StringP("project", "p", "", "project to select initially")
+
+	rootCmd.AddCommand(tuiCmd)
+}
@@ -0,0 +1,210 @@
+// ABOUTME: Saved view commands and the @name shortcut
+// ABOUTME: Saves list flag combinations as named filters and runs them later
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var viewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Manage saved views",
+	Long: `Saved views are named list filters. Save the flags you keep retyping
+and run them by name, or with the @name shortcut:
+
+  toki view save bugs -p api -t bug --priority high
+  toki view run bugs
+  toki @bugs
+
+The built-in views pending, overdue, and high-priority mirror the MCP
+resources of the same names. Every view is also available to MCP clients
+as toki://views/<name>.`,
+}
+
+var viewSaveCmd = &cobra.Command{
+	Use:   "save <name> [list flags]",
+	Short: "Save list flags as a named view",
+	Long: `Save a combination of list flags as a named view, replacing any saved
+view with the same name. Views take the same flags as 'toki list'. A
+view only covers the current project if saved with --project, and like
+list it shows pending todos unless --done or the filter says otherwise.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		name := args[0]
+		if err := db.ValidateViewName(name); err != nil {
+			return err
+		}
+
+		expr, _, err := listQuery(cmd, args[1:], false)
+		if err != nil {
+			return err
+		}
+		sortKey, reverse, _, err := sortOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		view := &models.View{Name: name, Sort: string(sortKey), Reverse: reverse}
+		if expr != nil {
+			view.Filter = expr.String()
+		}
+
+		replaced, err := todoStore.SaveView(ctx, view)
+		if err != nil {
+			return err
+		}
+
+		if replaced {
+			color.Green("✓ Updated view '%s'", name)
+		} else {
+			color.Green("✓ Saved view '%s'", name)
+		}
+		fmt.Printf("  %s\n", describeView(view))
+
+		return nil
+	},
+}
+
+var viewListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List built-in and saved views",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		views, err := todoStore.ListViews(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, _ = color.New(color.Bold).Println("VIEWS")
+		for _, view := range views {
+			name := "@" + view.Name
+			if view.Builtin {
+				name += color.New(color.Faint).Sprint(" (built-in)")
+			}
+			fmt.Printf("  • %s\n    %s\n", name, describeView(view))
+		}
+
+		return nil
+	},
+}
+
+var viewRunCmd = &cobra.Command{
+	Use:   "run <name>",
+	Short: "List the todos matching a view",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		view, err := todoStore.GetView(ctx, strings.TrimPrefix(args[0], "@"))
+		if err != nil {
+			return err
+		}
+		return runView(cmd, view)
+	},
+}
+
+var viewDeleteCmd = &cobra.Command{
+	Use:     "delete <name>",
+	Aliases: []string{"rm"},
+	Short:   "Delete a saved view",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		name := strings.TrimPrefix(args[0], "@")
+		if err := todoStore.DeleteView(ctx, name); err != nil {
+			return err
+		}
+
+		color.Yellow("✓ Deleted view '%s'", name)
+		return nil
+	},
+}
+
+// runView prints the todos matching a view. --sort and --reverse override the view's
+// own sort order.
+func runView(cmd *cobra.Command, view *models.View) error {
+	ctx := cmd.Context()
+	var parsed filter.Node
+	if view.Filter != "" {
+		expr, err := parseFilter(view.Filter)
+		if err != nil {
+			return fmt.Errorf("view '%s' has an invalid filter: %w", view.Name, err)
+		}
+		parsed = expr
+	}
+
+	sortKey, reverse, coefficients, err := sortOptions(cmd)
+	if err != nil {
+		return err
+	}
+	sortKey, reverse, err = savedSort(cmd, sortKey, reverse, view.Sort, view.Reverse)
+	if err != nil {
+		return fmt.Errorf("view '%s' has an invalid sort: %w", view.Name, err)
+	}
+
+	todos, todoTags, err := todoStore.QueryTodosWithTags(ctx, parsed)
+	if err != nil {
+		return fmt.Errorf("failed to list todos: %w", err)
+	}
+
+	return printTodos(ctx, todos, todoTags, sortKey, reverse, coefficients, listPage{}, "matching", fmt.Sprintf("No todos match view '%s'.", view.Name))
+}
+
+// describeView summarizes a view's filter and sort order on one line.
+func describeView(view *models.View) string {
+	description := view.Filter
+	if description == "" {
+		description = "all todos"
+	}
+	if view.Sort != "" {
+		description += " (sort: " + view.Sort
+		if view.Reverse {
+			description += ", reversed"
+		}
+		description += ")"
+	}
+	return description
+}
+
+// expandViewShortcut rewrites "toki @name ..." to "toki view run name ...". Global flags
+// may come before the shortcut.
+func expandViewShortcut(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if len(arg) > 1 && arg[0] == '@' {
+				expanded := append([]string{}, args[:i]...)
+				expanded = append(expanded, "view", "run", arg[1:])
+				return append(expanded, args[i+1:]...)
+			}
+			return args
+		}
+		// Skip the value of a global flag given as a separate argument
+		name := strings.TrimLeft(arg, "-")
+		if flag := rootCmd.PersistentFlags().Lookup(name); flag != nil && !strings.Contains(arg, "=") && flag.NoOptDefVal == "" {
+			i++
+		}
+	}
+	return args
+}
+
+func init() {
+	addListFlags(viewSaveCmd)
+	viewRunCmd.Flags().String("sort", "", "override the view's sort order")
+	viewRunCmd.Flags().Bool("reverse", false, "reverse the sort order")
+
+	viewCmd.AddCommand(viewSaveCmd)
+	viewCmd.AddCommand(viewListCmd)
+	viewCmd.AddCommand(viewRunCmd)
+	viewCmd.AddCommand(viewDeleteCmd)
+	rootCmd.AddCommand(viewCmd)
+}
@@ -0,0 +1,32 @@
+// ABOUTME: Tests for the saved view command helpers
+// ABOUTME: Covers @name shortcut expansion around global flags
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandViewShortcut(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"@today"}, []string{"view", "run", "today"}},
+		{[]string{"@today", "--sort", "due"}, []string{"view", "run", "today", "--sort", "due"}},
+		{[]string{"--db", "x.db", "@today"}, []string{"--db", "x.db", "view", "run", "today"}},
+		{[]string{"--db=x.db", "@today"}, []string{"--db=x.db", "view", "run", "today"}},
+		{[]string{"list", "@today"}, []string{"list", "@today"}},
+		{[]string{"add", "email @bob"}, []string{"add", "email @bob"}},
+		{[]string{"@"}, []string{"@"}},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		got := expandViewShortcut(tt.args)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("expandViewShortcut(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
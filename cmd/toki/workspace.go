@@ -0,0 +1,247 @@
+// ABOUTME: Workspace commands for keeping separate databases under names
+// ABOUTME: Adds, lists, selects, and removes workspaces and maps directories to them
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/config"
+	"github.com/harper/toki/internal/db"
+	"github.com/spf13/cobra"
+)
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Keep separate databases under names",
+	Long: `Keep separate databases, such as one for work and one for personal todos,
+under names. The workspace in use is, in order:
+
+  --workspace <name>     for one command
+  $TOKI_WORKSPACE
+  a directory override   toki workspace use work --dir ~/work
+  the config             toki workspace use personal
+
+The workspace named "default" is the database toki uses without one: the
+db_path setting or the standard location. --db overrides any workspace.
+
+  toki workspace add work --dir ~/work   # ~/.local/share/toki/work.db
+  toki workspace add personal --db ~/sync/personal.db
+  toki workspace use personal
+  toki workspace list
+  toki mcp --workspace work              # an agent sees only work todos`,
+	// Workspaces live in the config file; the database isn't needed
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error { return nil },
+}
+
+var workspaceAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add a workspace with its own database",
+	Long: `Add a workspace. Its database is the --db file, by default <name>.db next
+to the default database, and is created on first use. Each --dir maps a
+directory tree to the workspace, like 'toki workspace use --dir'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		path, _ := cmd.Flags().GetString("db")
+		if !cmd.Flags().Changed("db") {
+			path = filepath.Join(filepath.Dir(db.GetDefaultDBPath()), name+".db")
+		}
+		path, err := filepath.Abs(config.ExpandPath(path))
+		if err != nil {
+			return fmt.Errorf("invalid database path: %w", err)
+		}
+
+		dirs, err := workspaceDirs(cmd)
+		if err != nil {
+			return err
+		}
+		if err := config.AddWorkspace(config.Path(), config.Workspace{Name: name, DBPath: path}); err != nil {
+			return err
+		}
+		for _, dir := range dirs {
+			if err := config.Set(config.Path(), dir, "workspace", name); err != nil {
+				return err
+			}
+		}
+
+		color.Green("✓ Added workspace %s", name)
+		fmt.Printf("  Database: %s\n", path)
+		for _, dir := range dirs {
+			fmt.Printf("  Used in:  %s\n", dir)
+		}
+		if len(dirs) == 0 {
+			fmt.Printf("  Switch to it with 'toki workspace use %s'\n", name)
+		}
+		return nil
+	},
+}
+
+var workspaceUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Switch to a workspace, everywhere or in a directory",
+	Long: `Make a workspace the one in use, or with --dir the one used in that
+directory tree. "default" switches back to the default database.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if name != config.DefaultWorkspace {
+			if _, err := config.FindWorkspace(config.Path(), name); err != nil {
+				return err
+			}
+		}
+
+		dirs, err := workspaceDirs(cmd)
+		if err != nil {
+			return err
+		}
+		if len(dirs) == 0 {
+			// Everywhere not mapped to another workspace
+			if name == config.DefaultWorkspace {
+				err = config.Unset(config.Path(), "", "workspace")
+			} else {
+				err = config.Set(config.Path(), "", "workspace", name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		for _, dir := range dirs {
+			if err := config.Set(config.Path(), dir, "workspace", name); err != nil {
+				return err
+			}
+		}
+
+		color.Green("✓ Using workspace %s", name)
+		for _, dir := range dirs {
+			fmt.Printf("  in %s\n", dir)
+		}
+		if os.Getenv(config.WorkspaceEnv) != "" {
+			color.Yellow("  $%s is set and takes precedence", config.WorkspaceEnv)
+		}
+		return nil
+	},
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List workspaces, marking the one in use here",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		workspaces, err := config.Workspaces(config.Path())
+		if err != nil {
+			return err
+		}
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
+		current := currentWorkspace()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get working directory: %w", err)
+		}
+		defaults, err := config.LoadWorkspace(config.Path(), cwd, config.DefaultWorkspace)
+		if err != nil {
+			return err
+		}
+		defaultPath := db.GetDefaultDBPath()
+		if defaults.DBPath != "" {
+			defaultPath = config.ExpandPath(defaults.DBPath)
+		}
+
+		_, _ = color.New(color.Bold).Println("WORKSPACES")
+		printWorkspace(config.Workspace{Name: config.DefaultWorkspace, DBPath: defaultPath}, current)
+		for _, ws := range workspaces {
+			printWorkspace(ws, current)
+		}
+		return nil
+	},
+}
+
+var workspaceCurrentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Show the workspace in use here and its database",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadSettings(cmd); err != nil {
+			return err
+		}
+		fmt.Println(currentWorkspace())
+		fmt.Printf("  Database: %s\n", dbPath)
+		return nil
+	},
+}
+
+var workspaceRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Aliases: []string{"rm"},
+	Short:   "Remove a workspace from the config, keeping its database",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ws, err := config.FindWorkspace(config.Path(), args[0])
+		if err != nil {
+			return err
+		}
+		if err := config.RemoveWorkspace(config.Path(), ws.Name); err != nil {
+			return err
+		}
+
+		color.Yellow("✓ Removed workspace %s", ws.Name)
+		fmt.Printf("  Its database is still at %s\n", ws.DBPath)
+		return nil
+	},
+}
+
+// currentWorkspace returns the name of the workspace the loaded settings use.
+func currentWorkspace() string {
+	if settings.Workspace == "" {
+		return config.DefaultWorkspace
+	}
+	return settings.Workspace
+}
+
+func printWorkspace(ws config.Workspace, current string) {
+	marker := "  "
+	name := fmt.Sprintf("%-12s", ws.Name)
+	if ws.Name == current {
+		marker = "* "
+		name = color.New(color.Bold).Sprint(name)
+	}
+	fmt.Printf("%s%s %s\n", marker, name, ws.DBPath)
+	if len(ws.Dirs) > 0 {
+		fmt.Println(color.New(color.Faint).Sprintf("    used in %s", strings.Join(ws.Dirs, ", ")))
+	}
+}
+
+// workspaceDirs returns the absolute --dir paths.
+func workspaceDirs(cmd *cobra.Command) ([]string, error) {
+	dirs, _ := cmd.Flags().GetStringArray("dir")
+	abs := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		path, err := filepath.Abs(config.ExpandPath(dir))
+		if err != nil {
+			return nil, fmt.Errorf("invalid directory '%s': %w", dir, err)
+		}
+		abs = append(abs, path)
+	}
+	return abs, nil
+}
+
+func init() {
+	workspaceAddCmd.Flags().String("db", "", "database file for the workspace (default: <name>.db next to the default database)")
+	workspaceAddCmd.Flags().StringArray("dir", nil, "use the workspace in this directory tree (repeatable)")
+	workspaceUseCmd.Flags().StringArray("dir", nil, "use the workspace only in this directory tree (repeatable)")
+
+	workspaceCmd.AddCommand(workspaceAddCmd)
+	workspaceCmd.AddCommand(workspaceUseCmd)
+	workspaceCmd.AddCommand(workspaceListCmd)
+	workspaceCmd.AddCommand(workspaceCurrentCmd)
+	workspaceCmd.AddCommand(workspaceRemoveCmd)
+	rootCmd.AddCommand(workspaceCmd)
+}
@@ -0,0 +1,256 @@
+// ABOUTME: User configuration loaded from ~/.config/toki/config.toml
+// ABOUTME: Provides defaults, per-directory overrides, and validated settings
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// PathEnv overrides the config file location.
+const PathEnv = "TOKI_CONFIG"
+
+// Auto-create policies for unregistered git repositories.
+const (
+	AutoCreateAsk    = "ask"
+	AutoCreateAlways = "always"
+	AutoCreateNever  = "never"
+)
+
+// Output modes for commands that print todos.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Color modes.
+const (
+	ColorAuto   = "auto"
+	ColorAlways = "always"
+	ColorNever  = "never"
+)
+
+// Settings are the effective configuration for a directory.
+type Settings struct {
+	// DefaultProject receives todos added outside any registered project.
+	DefaultProject string `toml:"default_project"`
+	// DBPath replaces the default database location when --db is not given.
+	DBPath string `toml:"db_path"`
+	// Workspace names the workspace whose database replaces DBPath; empty or
+	// DefaultWorkspace keeps DBPath.
+	Workspace  string `toml:"workspace"`
+	AutoCreate string `toml:"auto_create"`
+	// DateFormat is a preset (iso, us, eu, long) or a Go time layout.
+	DateFormat string `toml:"date_format"`
+	Output     string `toml:"output"`
+	Color      string `toml:"color"`
+	Theme      string `toml:"theme"`
+	// Urgency overrides urgency coefficients, like "due=8,priority.high=10"; when
+	// empty, $TOKI_URGENCY does.
+	Urgency    string     `toml:"urgency"`
+	List       List       `toml:"list"`
+	Branches   Branches   `toml:"branches"`
+	Scan       Scan       `toml:"scan"`
+	Backup     Backup     `toml:"backup"`
+	Encryption Encryption `toml:"encryption"`
+}
+
+// List holds the defaults for toki list.
+type List struct {
+	// Status is pending, done, or all.
+	Status string `toml:"status"`
+	// Filter is a filter expression applied when --filter is not given.
+	Filter  string `toml:"filter"`
+	Sort    string `toml:"sort"`
+	Reverse bool   `toml:"reverse"`
+}
+
+// Branches holds the git branch settings.
+type Branches struct {
+	// Track records the current branch on every new todo. When nil, the repository's
+	// toki.trackBranches git config decides.
+	Track *bool `toml:"track"`
+}
+
+// Scan holds the defaults for toki scan. Empty lists fall back to the repository's
+// toki.scanMarkers and toki.scanLanguages git config.
+type Scan struct {
+	// Markers and Languages are comma-separated, like "TODO,FIXME".
+	Markers   string `toml:"markers"`
+	Languages string `toml:"languages"`
+}
+
+// Backup holds the automatic backup settings.
+type Backup struct {
+	// Auto backs the database up before migrating it and on the first use each day. It
+	// is off unless the config turns it on.
+	Auto bool `toml:"auto"`
+	// Keep is how many automatic backups to keep.
+	Keep int `toml:"keep"`
+	// Dir replaces the backups directory next to the database.
+	Dir string `toml:"dir"`
+}
+
+// Encryption holds the settings for encrypting todo text.
+type Encryption struct {
+	// KeyFile holds the key when $TOKI_KEY and $TOKI_KEY_FILE are not set.
+	KeyFile string `toml:"key_file"`
+}
+
+// Default returns the settings used when the config file leaves a key unset.
+func Default() Settings {
+	return Settings{
+		DefaultProject: "default",
+		AutoCreate:     AutoCreateAsk,
+		DateFormat:     "iso",
+		Output:         OutputText,
+		Color:          ColorAuto,
+		Theme:          "default",
+		List:           List{Status: "pending"},
+		Backup:         Backup{Keep: 7},
+	}
+}
+
+// dateFormats are the named date format presets.
+var dateFormats = map[string]string{
+	"iso":  "2006-01-02",
+	"us":   "01/02/2006",
+	"eu":   "02/01/2006",
+	"long": "Jan 2, 2006",
+}
+
+// DateLayout returns the Go time layout for the date format.
+func (s Settings) DateLayout() string {
+	if layout, ok := dateFormats[s.DateFormat]; ok {
+		return layout
+	}
+	return s.DateFormat
+}
+
+// Validate checks every setting against its allowed values.
+func (s Settings) Validate() error {
+	for _, k := range keys {
+		if err := k.validate(k.get(&s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Path returns the config file location: $TOKI_CONFIG, else toki/config.toml under
+// $XDG_CONFIG_HOME or ~/.config.
+func Path() string {
+	if path := os.Getenv(PathEnv); path != "" {
+		return path
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			homeDir = "."
+		}
+		configDir = filepath.Join(homeDir, ".config")
+	}
+
+	return filepath.Join(configDir, "toki", "config.toml")
+}
+
+// file is the layout of config.toml: top-level settings plus [dir."<path>"] tables that
+// override them for a directory tree.
+type file struct {
+	Settings
+	Dir        map[string]toml.Primitive `toml:"dir"`
+	Workspaces map[string]Workspace      `toml:"workspaces"`
+}
+
+// Load reads the config file at path and returns the settings for dir, applying every
+// directory override that contains dir, least specific first, then the workspace they
+// select or $TOKI_WORKSPACE names. A missing file yields the defaults.
+func Load(path, dir string) (Settings, error) {
+	return LoadWorkspace(path, dir, os.Getenv(WorkspaceEnv))
+}
+
+// LoadWorkspace is Load with the workspace named workspace, when not empty, in place of
+// the one the config file selects.
+func LoadWorkspace(path, dir, workspace string) (Settings, error) {
+	f := file{Settings: Default()}
+	md, err := toml.DecodeFile(path, &f)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f.useWorkspace(path, workspace)
+	}
+	if err != nil {
+		return Settings{}, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	// Check every override, then apply the ones containing dir
+	var matches []string
+	for overridePath, primitive := range f.Dir {
+		scratch := Default()
+		if err := md.PrimitiveDecode(primitive, &scratch); err != nil {
+			return Settings{}, fmt.Errorf("invalid config %s: dir %q: %w", path, overridePath, err)
+		}
+		if err := scratch.Validate(); err != nil {
+			return Settings{}, fmt.Errorf("invalid config %s: dir %q: %w", path, overridePath, err)
+		}
+		if contains(ExpandPath(overridePath), dir) {
+			matches = append(matches, overridePath)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return len(ExpandPath(matches[i])) < len(ExpandPath(matches[j]))
+	})
+	for _, overridePath := range matches {
+		if err := md.PrimitiveDecode(f.Dir[overridePath], &f.Settings); err != nil {
+			return Settings{}, fmt.Errorf("invalid config %s: dir %q: %w", path, overridePath, err)
+		}
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return Settings{}, fmt.Errorf("invalid config %s: unknown key '%s'", path, undecoded[0])
+	}
+	if err := f.Settings.Validate(); err != nil {
+		return Settings{}, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	for name, ws := range f.Workspaces {
+		if err := ws.validate(name); err != nil {
+			return Settings{}, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+	}
+
+	return f.useWorkspace(path, workspace)
+}
+
+// ExpandPath expands a leading ~ and cleans the path.
+func ExpandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	return filepath.Clean(path)
+}
+
+// contains reports whether dir is root or inside it, resolving symlinks where possible.
+func contains(root, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	rel, err := filepath.Rel(root, dir)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,159 @@
+// ABOUTME: Tests for loading the config file
+// ABOUTME: Covers defaults, directory overrides, and validation errors
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadMissingFileUsesDefaults(t *testing.T) {
+	settings, err := Load(filepath.Join(t.TempDir(), "missing.toml"), "/tmp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings != Default() {
+		t.Errorf("Expected defaults, got %+v", settings)
+	}
+	if settings.Backup.Auto {
+		t.Error("Expected automatic backups to be off until turned on")
+	}
+}
+
+func TestLoadDirectoryOverrides(t *testing.T) {
+	root := t.TempDir()
+	work := filepath.Join(root, "work")
+	api := filepath.Join(work, "api")
+	if err := os.MkdirAll(api, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	path := writeConfig(t, `
+default_project = "inbox"
+color = "never"
+
+[list]
+sort = "due"
+
+[dir."`+work+`"]
+default_project = "work"
+list.status = "all"
+
+[dir."`+api+`"]
+output = "json"
+`)
+
+	tests := []struct {
+		dir     string
+		project string
+		status  string
+		output  string
+	}{
+		{root, "inbox", "pending", OutputText},
+		{work, "work", "all", OutputText},
+		{api, "work", "all", OutputJSON},
+		{work + "-other", "inbox", "pending", OutputText},
+	}
+	for _, tt := range tests {
+		settings, err := Load(path, tt.dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings.DefaultProject != tt.project || settings.List.Status != tt.status || settings.Output != tt.output {
+			t.Errorf("Load(%s) = %+v, want project %s, status %s, output %s", tt.dir, settings, tt.project, tt.status, tt.output)
+		}
+		if settings.Color != ColorNever || settings.List.Sort != "due" {
+			t.Errorf("Expected top-level settings to carry into %s, got %+v", tt.dir, settings)
+		}
+	}
+}
+
+func TestLoadToolSettings(t *testing.T) {
+	repo := t.TempDir()
+	path := writeConfig(t, `
+urgency = "due=8"
+
+[branches]
+track = false
+
+[dir."`+repo+`"]
+branches.track = true
+scan.markers = "TODO,XXX"
+`)
+
+	settings, err := Load(path, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Urgency != "due=8" || settings.Branches.Track == nil || *settings.Branches.Track || settings.Scan.Markers != "" {
+		t.Errorf("Unexpected top-level settings: %+v", settings)
+	}
+
+	settings, err = Load(path, repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !*settings.Branches.Track || settings.Scan.Markers != "TODO,XXX" {
+		t.Errorf("Expected the override for %s, got %+v", repo, settings)
+	}
+
+	// Unset, branch tracking falls back to git config
+	if settings, _ := Load(filepath.Join(t.TempDir(), "missing.toml"), repo); settings.Branches.Track != nil {
+		t.Errorf("Expected branches.track unset by default, got %v", *settings.Branches.Track)
+	}
+}
+
+func TestLoadInvalidConfig(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{`colour = "never"`, "unknown key 'colour'"},
+		{`auto_create = "sometimes"`, "invalid auto_create 'sometimes'"},
+		{"[list]\nfilter = \"tag:\"", "invalid list.filter"},
+		{"[dir.\"/x\"]\ntheme = \"neon\"", "invalid theme 'neon'"},
+		{"[dir.\"/x\"]\nbogus = 1", "unknown key"},
+		{`output = `, "failed to read config"},
+	}
+	for _, tt := range tests {
+		_, err := Load(writeConfig(t, tt.content), "/")
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%q) error = %v, want %q", tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	settings := Default()
+	if settings.DateLayout() != "2006-01-02" {
+		t.Errorf("Expected iso layout, got %s", settings.DateLayout())
+	}
+	settings.DateFormat = "Jan 2"
+	if settings.DateLayout() != "Jan 2" {
+		t.Errorf("Expected custom layout, got %s", settings.DateLayout())
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv(PathEnv, "")
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got := Path(); got != filepath.Join("/xdg", "toki", "config.toml") {
+		t.Errorf("Expected XDG path, got %s", got)
+	}
+	t.Setenv(PathEnv, "/custom.toml")
+	if got := Path(); got != "/custom.toml" {
+		t.Errorf("Expected %s override, got %s", PathEnv, got)
+	}
+}
@@ -0,0 +1,362 @@
+// ABOUTME: Named config keys for toki config get/set/list
+// ABOUTME: Validates values and edits the TOML file at the top level or per directory
+
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/scan"
+	"github.com/harper/toki/internal/sorting"
+)
+
+// Themes are the color themes accepted by the theme key.
+var Themes = []string{"default", "light", "mono"}
+
+type key struct {
+	name string
+	help string
+	// values lists the allowed values; nil allows any value that passes check.
+	values []string
+	check  func(string) error
+	get    func(*Settings) string
+	set    func(*Settings, string)
+	// boolean and integer keys are written to the file as TOML booleans and integers.
+	boolean bool
+	integer bool
+}
+
+var keys = []key{
+	{
+		name: "default_project", help: "project for todos added outside a registered project",
+		check: nonEmpty,
+		get:   func(s *Settings) string { return s.DefaultProject },
+		set:   func(s *Settings, v string) { s.DefaultProject = v },
+	},
+	{
+		name: "db_path", help: "database file used when --db is not given",
+		get: func(s *Settings) string { return s.DBPath },
+		set: func(s *Settings, v string) { s.DBPath = v },
+	},
+	{
+		name: "workspace", help: "workspace whose database is used when --db is not given",
+		check: func(v string) error {
+			if v == "" {
+				return nil
+			}
+			return checkWorkspaceName(v)
+		},
+		get: func(s *Settings) string { return s.Workspace },
+		set: func(s *Settings, v string) { s.Workspace = v },
+	},
+	{
+		name: "auto_create", help: "create projects for unregistered git repositories",
+		values: []string{AutoCreateAsk, AutoCreateAlways, AutoCreateNever},
+		get:    func(s *Settings) string { return s.AutoCreate },
+		set:    func(s *Settings, v string) { s.AutoCreate = v },
+	},
+	{
+		name: "date_format", help: "iso, us, eu, long, or a Go time layout like 'Jan 2'",
+		check: nonEmpty,
+		get:   func(s *Settings) string { return s.DateFormat },
+		set:   func(s *Settings, v string) { s.DateFormat = v },
+	},
+	{
+		name: "output", help: "how list, search, and views print todos",
+		values: []string{OutputText, OutputJSON},
+		get:    func(s *Settings) string { return s.Output },
+		set:    func(s *Settings, v string) { s.Output = v },
+	},
+	{
+		name: "color", help: "colored output (auto follows the terminal and NO_COLOR)",
+		values: []string{ColorAuto, ColorAlways, ColorNever},
+		get:    func(s *Settings) string { return s.Color },
+		set:    func(s *Settings, v string) { s.Color = v },
+	},
+	{
+		name: "theme", help: "color theme",
+		values: Themes,
+		get:    func(s *Settings) string { return s.Theme },
+		set:    func(s *Settings, v string) { s.Theme = v },
+	},
+	{
+		name: "urgency", help: "urgency coefficients like 'due=8,tag.next=15' (default: $TOKI_URGENCY)",
+		check: func(v string) error {
+			_, err := sorting.ParseCoefficients(v, sorting.DefaultCoefficients())
+			return err
+		},
+		get: func(s *Settings) string { return s.Urgency },
+		set: func(s *Settings, v string) { s.Urgency = v },
+	},
+	{
+		name: "list.status", help: "todos list shows without --done or --pending",
+		values: []string{"pending", "done", "all"},
+		get:    func(s *Settings) string { return s.List.Status },
+		set:    func(s *Settings, v string) { s.List.Status = v },
+	},
+	{
+		name: "list.filter", help: "filter expression list applies without --filter",
+		check: func(v string) error {
+			if v == "" {
+				return nil
+			}
+			_, err := filter.Parse(v)
+			return err
+		},
+		get: func(s *Settings) string { return s.List.Filter },
+		set: func(s *Settings, v string) { s.List.Filter = v },
+	},
+	{
+		name: "list.sort", help: "sort order list uses without --sort",
+		check: func(v string) error {
+			if v == "" {
+				return nil
+			}
+			_, err := sorting.ParseKey(v)
+			return err
+		},
+		get: func(s *Settings) string { return s.List.Sort },
+		set: func(s *Settings, v string) { s.List.Sort = v },
+	},
+	{
+		name: "list.reverse", help: "reverse the default list order",
+		values:  []string{"true", "false"},
+		boolean: true,
+		get:     func(s *Settings) string { return strconv.FormatBool(s.List.Reverse) },
+		set:     func(s *Settings, v string) { s.List.Reverse = v == "true" },
+	},
+	{
+		name: "branches.track", help: "record the current branch on new todos (default: git config toki.trackBranches)",
+		boolean: true,
+		check: func(v string) error {
+			if v != "" && v != "true" && v != "false" {
+				return errors.New("expected true or false")
+			}
+			return nil
+		},
+		get: func(s *Settings) string {
+			if s.Branches.Track == nil {
+				return ""
+			}
+			return strconv.FormatBool(*s.Branches.Track)
+		},
+		set: func(s *Settings, v string) {
+			s.Branches.Track = nil
+			if v != "" {
+				track := v == "true"
+				s.Branches.Track = &track
+			}
+		},
+	},
+	{
+		name: "scan.markers", help: "comma-separated markers toki scan looks for (default: git config toki.scanMarkers)",
+		get: func(s *Settings) string { return s.Scan.Markers },
+		set: func(s *Settings, v string) { s.Scan.Markers = v },
+	},
+	{
+		name: "scan.languages", help: "comma-separated languages toki scan reads (default: git config toki.scanLanguages)",
+		check: func(v string) error {
+			_, err := scan.NewConfig(nil, strings.Split(v, ","))
+			return err
+		},
+		get: func(s *Settings) string { return s.Scan.Languages },
+		set: func(s *Settings, v string) { s.Scan.Languages = v },
+	},
+	{
+		name: "backup.auto", help: "back up before schema upgrades and once a day",
+		values:  []string{"true", "false"},
+		boolean: true,
+		get:     func(s *Settings) string { return strconv.FormatBool(s.Backup.Auto) },
+		set:     func(s *Settings, v string) { s.Backup.Auto = v == "true" },
+	},
+	{
+		name: "backup.keep", help: "how many automatic backups to keep",
+		integer: true,
+		check: func(v string) error {
+			if n, err := strconv.Atoi(v); err != nil || n < 1 {
+				return errors.New("expected a positive number")
+			}
+			return nil
+		},
+		get: func(s *Settings) string { return strconv.Itoa(s.Backup.Keep) },
+		set: func(s *Settings, v string) { s.Backup.Keep, _ = strconv.Atoi(v) },
+	},
+	{
+		name: "backup.dir", help: "directory for backups (default: next to the database)",
+		get: func(s *Settings) string { return s.Backup.Dir },
+		set: func(s *Settings, v string) { s.Backup.Dir = v },
+	},
+	{
+		name: "encryption.key_file", help: "file with the key encrypting todo descriptions and notes",
+		get: func(s *Settings) string { return s.Encryption.KeyFile },
+		set: func(s *Settings, v string) { s.Encryption.KeyFile = v },
+	},
+}
+
+func nonEmpty(value string) error {
+	if value == "" {
+		return errors.New("value cannot be empty")
+	}
+	return nil
+}
+
+func (k key) validate(value string) error {
+	if k.values != nil && !slices.Contains(k.values, value) {
+		return fmt.Errorf("invalid %s '%s' (expected %s)", k.name, value, strings.Join(k.values, ", "))
+	}
+	if k.check != nil {
+		if err := k.check(value); err != nil {
+			return fmt.Errorf("invalid %s '%s': %w", k.name, value, err)
+		}
+	}
+	return nil
+}
+
+func lookupKey(name string) (key, error) {
+	for _, k := range keys {
+		if k.name == name {
+			return k, nil
+		}
+	}
+	return key{}, fmt.Errorf("unknown config key '%s' (expected one of %s)", name, strings.Join(Keys(), ", "))
+}
+
+// Keys returns every config key name in display order.
+func Keys() []string {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		names = append(names, k.name)
+	}
+	return names
+}
+
+// Help returns the one-line description of a key.
+func Help(name string) string {
+	k, err := lookupKey(name)
+	if err != nil {
+		return ""
+	}
+	return k.help
+}
+
+// Get returns the value of a key in s.
+func Get(s Settings, name string) (string, error) {
+	k, err := lookupKey(name)
+	if err != nil {
+		return "", err
+	}
+	return k.get(&s), nil
+}
+
+// Set validates value and writes it to the config file at path: at the top level, or in
+// the override for dir when dir is not empty.
+func Set(path, dir, name, value string) error {
+	k, err := lookupKey(name)
+	if err != nil {
+		return err
+	}
+	if err := k.validate(value); err != nil {
+		return err
+	}
+	if k.boolean && value == "" {
+		// An empty value means unset, which Unset writes
+		return fmt.Errorf("invalid %s '': expected true or false", name)
+	}
+
+	return editFile(path, func(raw map[string]any) {
+		table, leaf := keyTable(raw, dir, name, true)
+		switch {
+		case k.boolean:
+			table[leaf] = value == "true"
+		case k.integer:
+			table[leaf], _ = strconv.ParseInt(value, 10, 64)
+		default:
+			table[leaf] = value
+		}
+	})
+}
+
+// Unset removes a key from the config file at path, at the top level or in the override
+// for dir, so the default or a broader setting applies again.
+func Unset(path, dir, name string) error {
+	if _, err := lookupKey(name); err != nil {
+		return err
+	}
+
+	return editFile(path, func(raw map[string]any) {
+		if table, leaf := keyTable(raw, dir, name, false); table != nil {
+			delete(table, leaf)
+		}
+		pruneEmpty(raw)
+	})
+}
+
+// keyTable returns the table holding a dotted key and the key's last part, creating
+// missing tables when create is set.
+func keyTable(raw map[string]any, dir, name string, create bool) (map[string]any, string) {
+	parts := strings.Split(name, ".")
+	var path []string
+	if dir != "" {
+		path = append(path, "dir", dir)
+	}
+	path = append(path, parts[:len(parts)-1]...)
+
+	table := raw
+	for _, part := range path {
+		next, ok := table[part].(map[string]any)
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+			next = map[string]any{}
+			table[part] = next
+		}
+		table = next
+	}
+	return table, parts[len(parts)-1]
+}
+
+// pruneEmpty removes tables left empty by Unset.
+func pruneEmpty(table map[string]any) {
+	for name, value := range table {
+		if sub, ok := value.(map[string]any); ok {
+			pruneEmpty(sub)
+			if len(sub) == 0 {
+				delete(table, name)
+			}
+		}
+	}
+}
+
+// editFile applies edit to the decoded config file and writes it back. Comments and
+// formatting in the file are not preserved.
+func editFile(path string, edit func(map[string]any)) error {
+	raw := map[string]any{}
+	if _, err := toml.DecodeFile(path, &raw); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	edit(raw)
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(raw); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write config %s: %w", path, err)
+	}
+	return nil
+}
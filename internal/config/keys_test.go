@@ -0,0 +1,112 @@
+// ABOUTME: Tests for config key lookup and file editing
+// ABOUTME: Covers set, unset, validation, and directory overrides
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetAndUnset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "toki", "config.toml")
+	dir := t.TempDir()
+
+	if err := Set(path, "", "list.sort", "urgency"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "", "list.reverse", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, dir, "default_project", "scratch"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "", "backup.keep", "3"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "reverse = true") {
+		t.Errorf("Expected boolean to be written as a TOML boolean:\n%s", content)
+	}
+	if !strings.Contains(string(content), "keep = 3") {
+		t.Errorf("Expected number to be written as a TOML integer:\n%s", content)
+	}
+
+	settings, err := Load(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.List.Sort != "urgency" || !settings.List.Reverse || settings.DefaultProject != "scratch" || settings.Backup.Keep != 3 {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+	if settings, _ := Load(path, "/"); settings.DefaultProject != "default" {
+		t.Errorf("Expected override to apply only inside %s, got %s", dir, settings.DefaultProject)
+	}
+
+	if err := Unset(path, dir, "default_project"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Unset(path, "", "list.sort"); err != nil {
+		t.Fatal(err)
+	}
+	settings, err = Load(path, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.DefaultProject != "default" || settings.List.Sort != "" {
+		t.Errorf("Expected unset keys to fall back to defaults, got %+v", settings)
+	}
+	content, _ = os.ReadFile(path)
+	if strings.Contains(string(content), "[dir") {
+		t.Errorf("Expected empty override table to be removed:\n%s", content)
+	}
+}
+
+func TestSetRejectsInvalidValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.toml")
+
+	tests := []struct{ key, value, want string }{
+		{"colour", "never", "unknown config key"},
+		{"color", "sometimes", "expected auto, always, never"},
+		{"list.sort", "size", "invalid list.sort"},
+		{"list.filter", "(tag:bug", "invalid list.filter"},
+		{"default_project", "", "cannot be empty"},
+		{"backup.keep", "0", "invalid backup.keep"},
+		{"urgency", "due=soon", "invalid urgency"},
+		{"branches.track", "", "invalid branches.track"},
+		{"scan.languages", "go,cobol", "invalid scan.languages"},
+	}
+	for _, tt := range tests {
+		err := Set(path, "", tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Set(%s, %q) error = %v, want %q", tt.key, tt.value, err, tt.want)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected rejected values not to create the file")
+	}
+}
+
+func TestGet(t *testing.T) {
+	settings := Default()
+	for _, name := range Keys() {
+		if _, err := Get(settings, name); err != nil {
+			t.Errorf("Get(%s) failed: %v", name, err)
+		}
+		if Help(name) == "" {
+			t.Errorf("Expected help for %s", name)
+		}
+	}
+	if value, _ := Get(settings, "list.status"); value != "pending" {
+		t.Errorf("Expected pending, got %s", value)
+	}
+	if _, err := Get(settings, "nope"); err == nil {
+		t.Error("Expected unknown key to fail")
+	}
+}
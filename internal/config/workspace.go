@@ -0,0 +1,156 @@
+// ABOUTME: Named workspaces, each with its own database, selected in the config file
+// ABOUTME: Adds, lists, and removes [workspaces.<name>] tables and the directories mapped to them
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+)
+
+// WorkspaceEnv names the workspace to use, in place of the one the config file selects.
+const WorkspaceEnv = "TOKI_WORKSPACE"
+
+// DefaultWorkspace is the workspace using the db_path setting or the default database.
+const DefaultWorkspace = "default"
+
+// Workspace is a named database, kept in a [workspaces.<name>] table.
+type Workspace struct {
+	Name   string `toml:"-"`
+	DBPath string `toml:"db_path"`
+	// Dirs are the directories whose override selects the workspace.
+	Dirs []string `toml:"-"`
+}
+
+var workspaceName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// checkWorkspaceName rejects names that can't be written as a bare TOML key.
+func checkWorkspaceName(name string) error {
+	if !workspaceName.MatchString(name) {
+		return fmt.Errorf("invalid workspace name '%s' (use letters, digits, - and _)", name)
+	}
+	return nil
+}
+
+func (w Workspace) validate(name string) error {
+	if err := checkWorkspaceName(name); err != nil {
+		return err
+	}
+	if name == DefaultWorkspace {
+		return fmt.Errorf("workspace '%s' is reserved for the default database", DefaultWorkspace)
+	}
+	if w.DBPath == "" {
+		return fmt.Errorf("workspace '%s' has no db_path", name)
+	}
+	return nil
+}
+
+// useWorkspace applies the workspace named workspace, or else the one the settings
+// select, replacing DBPath with its database.
+func (f *file) useWorkspace(path, workspace string) (Settings, error) {
+	if workspace != "" {
+		if err := checkWorkspaceName(workspace); err != nil {
+			return Settings{}, err
+		}
+		f.Settings.Workspace = workspace
+	}
+
+	name := f.Settings.Workspace
+	if name == "" || name == DefaultWorkspace {
+		return f.Settings, nil
+	}
+	ws, ok := f.Workspaces[name]
+	if !ok {
+		return Settings{}, fmt.Errorf("unknown workspace '%s' in %s (add it with 'toki workspace add %s')", name, path, name)
+	}
+	f.Settings.DBPath = ws.DBPath
+	return f.Settings, nil
+}
+
+// Workspaces returns the workspaces in the config file at path, sorted by name.
+func Workspaces(path string) ([]Workspace, error) {
+	var f struct {
+		Workspaces map[string]Workspace `toml:"workspaces"`
+		Dir        map[string]struct {
+			Workspace string `toml:"workspace"`
+		} `toml:"dir"`
+	}
+	if _, err := toml.DecodeFile(path, &f); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+	}
+
+	workspaces := make([]Workspace, 0, len(f.Workspaces))
+	for name, ws := range f.Workspaces {
+		ws.Name = name
+		for dir, override := range f.Dir {
+			if override.Workspace == name {
+				ws.Dirs = append(ws.Dirs, dir)
+			}
+		}
+		sort.Strings(ws.Dirs)
+		workspaces = append(workspaces, ws)
+	}
+	sort.Slice(workspaces, func(i, j int) bool { return workspaces[i].Name < workspaces[j].Name })
+	return workspaces, nil
+}
+
+// FindWorkspace returns the workspace named name in the config file at path.
+func FindWorkspace(path, name string) (Workspace, error) {
+	workspaces, err := Workspaces(path)
+	if err != nil {
+		return Workspace{}, err
+	}
+	for _, ws := range workspaces {
+		if ws.Name == name {
+			return ws, nil
+		}
+	}
+	return Workspace{}, fmt.Errorf("unknown workspace '%s' (add it with 'toki workspace add %s')", name, name)
+}
+
+// AddWorkspace writes a new workspace to the config file at path.
+func AddWorkspace(path string, ws Workspace) error {
+	if err := ws.validate(ws.Name); err != nil {
+		return err
+	}
+	if _, err := FindWorkspace(path, ws.Name); err == nil {
+		return fmt.Errorf("workspace '%s' already exists", ws.Name)
+	}
+	return editFile(path, func(raw map[string]any) {
+		workspaces, ok := raw["workspaces"].(map[string]any)
+		if !ok {
+			workspaces = map[string]any{}
+			raw["workspaces"] = workspaces
+		}
+		workspaces[ws.Name] = map[string]any{"db_path": ws.DBPath}
+	})
+}
+
+// RemoveWorkspace removes a workspace from the config file at path, along with every
+// setting that selects it. Its database is left alone.
+func RemoveWorkspace(path, name string) error {
+	if _, err := FindWorkspace(path, name); err != nil {
+		return err
+	}
+	return editFile(path, func(raw map[string]any) {
+		if workspaces, ok := raw["workspaces"].(map[string]any); ok {
+			delete(workspaces, name)
+		}
+		if raw["workspace"] == name {
+			delete(raw, "workspace")
+		}
+		if dirs, ok := raw["dir"].(map[string]any); ok {
+			for _, value := range dirs {
+				if override, ok := value.(map[string]any); ok && override["workspace"] == name {
+					delete(override, "workspace")
+				}
+			}
+		}
+		pruneEmpty(raw)
+	})
+}
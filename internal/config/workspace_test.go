@@ -0,0 +1,112 @@
+// ABOUTME: Tests for named workspaces
+// ABOUTME: Covers selecting workspaces by config, directory, and environment, and editing them
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadWorkspace(t *testing.T) {
+	t.Setenv(WorkspaceEnv, "")
+	work := t.TempDir()
+	path := writeConfig(t, `
+db_path = "/data/toki.db"
+workspace = "personal"
+
+[workspaces.personal]
+db_path = "/data/personal.db"
+
+[workspaces.work]
+db_path = "/data/work.db"
+
+[dir."`+work+`"]
+workspace = "work"
+`)
+
+	tests := []struct {
+		dir       string
+		workspace string
+		want      string
+	}{
+		{"/", "", "/data/personal.db"},
+		{work, "", "/data/work.db"},
+		{work, "personal", "/data/personal.db"},
+		{work, DefaultWorkspace, "/data/toki.db"},
+	}
+	for _, tt := range tests {
+		settings, err := LoadWorkspace(path, tt.dir, tt.workspace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if settings.DBPath != tt.want {
+			t.Errorf("LoadWorkspace(%s, %q) database = %s, want %s", tt.dir, tt.workspace, settings.DBPath, tt.want)
+		}
+	}
+
+	// $TOKI_WORKSPACE wins over directories
+	t.Setenv(WorkspaceEnv, "personal")
+	if settings, err := Load(path, work); err != nil || settings.DBPath != "/data/personal.db" {
+		t.Errorf("Expected %s to select personal, got %s (%v)", WorkspaceEnv, settings.DBPath, err)
+	}
+
+	t.Setenv(WorkspaceEnv, "missing")
+	if _, err := Load(path, work); err == nil || !strings.Contains(err.Error(), "unknown workspace 'missing'") {
+		t.Errorf("Expected an unknown workspace error, got %v", err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.toml"), "/"); err == nil {
+		t.Error("Expected an unknown workspace error without a config file")
+	}
+}
+
+func TestAddAndRemoveWorkspace(t *testing.T) {
+	t.Setenv(WorkspaceEnv, "")
+	path := filepath.Join(t.TempDir(), "config.toml")
+	dir := t.TempDir()
+
+	if err := AddWorkspace(path, Workspace{Name: "work", DBPath: "/data/work.db"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddWorkspace(path, Workspace{Name: "work", DBPath: "/data/other.db"}); err == nil {
+		t.Error("Expected adding an existing workspace to fail")
+	}
+	for _, name := range []string{DefaultWorkspace, "my work", ""} {
+		if err := AddWorkspace(path, Workspace{Name: name, DBPath: "/data/x.db"}); err == nil {
+			t.Errorf("Expected workspace name %q to be rejected", name)
+		}
+	}
+	if err := Set(path, dir, "workspace", "work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Set(path, "", "workspace", "work"); err != nil {
+		t.Fatal(err)
+	}
+
+	workspaces, err := Workspaces(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(workspaces) != 1 || workspaces[0].Name != "work" || len(workspaces[0].Dirs) != 1 || workspaces[0].Dirs[0] != dir {
+		t.Errorf("Unexpected workspaces: %+v", workspaces)
+	}
+
+	if err := RemoveWorkspace(path, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveWorkspace(path, "work"); err == nil {
+		t.Error("Expected removing a missing workspace to fail")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "work") {
+		t.Errorf("Expected every mention of the workspace to be removed:\n%s", content)
+	}
+	if _, err := Load(path, dir); err != nil {
+		t.Errorf("Expected the config to load after removing the workspace: %v", err)
+	}
+}
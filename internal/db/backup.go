@@ -0,0 +1,450 @@
+// ABOUTME: Database backups, restores, and integrity checks
+// ABOUTME: Copies with VACUUM INTO, restores through SQLite's online backup, and rotates automatic backups
+
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+)
+
+// Reasons for a backup, recorded in its file name.
+const (
+	BackupManual    = "manual"
+	BackupDaily     = "daily"
+	BackupMigration = "pre-migrate"
+	BackupRestore   = "pre-restore"
+)
+
+// backupTimeLayout dates backup file names, in local time so they sort by age.
+const backupTimeLayout = "20060102-150405"
+
+// BackupFile is a backup found in a backup directory.
+type BackupFile struct {
+	Path   string
+	Reason string
+	Time   time.Time
+}
+
+// Automatic reports whether the backup was taken by AutoBackup and is rotated.
+func (f BackupFile) Automatic() bool {
+	return f.Reason == BackupDaily || f.Reason == BackupMigration
+}
+
+// DefaultBackupDir returns where backups of the database at dbPath go by default: a
+// backups directory next to it.
+func DefaultBackupDir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "backups")
+}
+
+// BackupName returns the name that starts the file names of backups of the database at
+// dbPath: its file name without the extension and a short hash of its absolute path, so
+// that databases sharing a backup directory, such as workspaces, keep their backups
+// apart even when their files have the same name.
+func BackupName(dbPath string) string {
+	if abs, err := filepath.Abs(dbPath); err == nil {
+		dbPath = abs
+	}
+	sum := sha256.Sum256([]byte(dbPath))
+	base := filepath.Base(dbPath)
+	return strings.TrimSuffix(base, filepath.Ext(base)) + "-" + hex.EncodeToString(sum[:4])
+}
+
+// BackupPath returns the file name for a backup of the database called name, in dir,
+// taken at now for reason.
+func BackupPath(dir, name, reason string, now time.Time) string {
+	return filepath.Join(dir, name+"-"+now.Format(backupTimeLayout)+"-"+reason+".db")
+}
+
+// ListBackups returns the backups of the database called name in dir, as named by
+// BackupPath, oldest first. A missing directory has none.
+func ListBackups(dir, name string) ([]BackupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+
+	var backups []BackupFile
+	for _, entry := range entries {
+		// Backups of another database fail to parse: its name is followed by more than a time
+		rest, ok := strings.CutSuffix(entry.Name(), ".db")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		rest, ok = strings.CutPrefix(rest, name+"-")
+		if !ok || len(rest) < len(backupTimeLayout)+2 {
+			continue
+		}
+		taken, err := time.ParseInLocation(backupTimeLayout, rest[:len(backupTimeLayout)], time.Local)
+		if err != nil || rest[len(backupTimeLayout)] != '-' {
+			continue
+		}
+		backups = append(backups, BackupFile{
+			Path:   filepath.Join(dir, entry.Name()),
+			Reason: rest[len(backupTimeLayout)+1:],
+			Time:   taken,
+		})
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Time.Before(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// Backup writes a consistent copy of database to dest, which must not exist yet. Other
+// connections and processes keep reading and writing meanwhile.
+func Backup(ctx context.Context, database *sql.DB, dest string) error {
+	if _, err := os.Stat(dest); err == nil {
+		return fmt.Errorf("backup %s: %w", dest, fs.ErrExist)
+	}
+	dir := filepath.Dir(dest)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	// VACUUM INTO writes into a missing or empty file. Filling a private temporary file
+	// and renaming it means dest never holds a partial backup.
+	tmp, err := os.CreateTemp(dir, ".toki-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+	_ = tmp.Close()
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	err = retryBusy(ctx, func() error {
+		_, err := database.ExecContext(ctx, `VACUUM INTO ?`, tmp.Name())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dest); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+	return nil
+}
+
+// ValidateBackup checks that path holds an intact toki database this version can
+// restore, and returns its schema version.
+func ValidateBackup(ctx context.Context, path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	backup, err := sql.Open("sqlite", path+"?_pragma=query_only(1)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer func() { _ = backup.Close() }()
+
+	var result string
+	if err := backup.QueryRowContext(ctx, `PRAGMA quick_check`).Scan(&result); err != nil {
+		return 0, fmt.Errorf("%s is not a toki database: %w", path, err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("backup %s is corrupt: %s", path, result)
+	}
+
+	for _, table := range []string{"projects", "todos"} {
+		exists, err := tableExists(ctx, backup, table)
+		if err != nil {
+			return 0, err
+		}
+		if !exists {
+			return 0, fmt.Errorf("%s is not a toki database: no %s table", path, table)
+		}
+	}
+
+	version, err := schemaVersion(ctx, backup)
+	if err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion {
+		return 0, fmt.Errorf("backup %s has schema version %d, newer than this toki's %d; upgrade toki to restore it", path, version, SchemaVersion)
+	}
+	return version, nil
+}
+
+// Restore replaces the contents of database with the backup at src, after validating it,
+// and migrates it to the current schema. Other processes see the restored data once it
+// is complete.
+func Restore(ctx context.Context, database *sql.DB, src string) error {
+	if _, err := ValidateBackup(ctx, src); err != nil {
+		return err
+	}
+	if err := restorePages(ctx, database, src); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", src, err)
+	}
+
+	// Bring a backup from an older toki up to date
+	err := WithTx(ctx, database, func(tx *sql.Tx) error {
+		return runMigrations(ctx, tx)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to migrate restored database: %w", err)
+	}
+	return nil
+}
+
+// restorer is the driver connection's side of SQLite's online backup API.
+type restorer interface {
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// restorePages copies every page of src over database as this process's writer.
+func restorePages(ctx context.Context, database *sql.DB, src string) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	conn, err := database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	return retryBusy(ctx, func() error {
+		return conn.Raw(func(driverConn any) error {
+			r, ok := driverConn.(restorer)
+			if !ok {
+				return errors.New("database driver cannot restore backups")
+			}
+			restore, err := r.NewRestore(src)
+			if err != nil {
+				return err
+			}
+			if _, err := restore.Step(-1); err != nil {
+				_ = restore.Finish()
+				return err
+			}
+			return restore.Finish()
+		})
+	})
+}
+
+// Orphan is a row whose foreign key points at a row that no longer exists.
+type Orphan struct {
+	Table  string
+	RowID  int64
+	Parent string
+}
+
+// CheckReport is what Check found.
+type CheckReport struct {
+	// Problems lists the corruption PRAGMA integrity_check reports.
+	Problems []string
+	Orphans  []Orphan
+}
+
+// OK reports whether the check found nothing wrong.
+func (r CheckReport) OK() bool {
+	return len(r.Problems) == 0 && len(r.Orphans) == 0
+}
+
+// ErrScrubIncomplete is returned by Scrub when another connection kept the write-ahead
+// log from being emptied.
+var ErrScrubIncomplete = errors.New("another process has the database open, so its write-ahead log may still hold old data; close it and run the command again")
+
+// Scrub rewrites database so that data overwritten or deleted earlier is gone from the
+// file and its write-ahead log rather than merely unused: it checkpoints the log, runs
+// VACUUM, and empties the log again.
+func Scrub(ctx context.Context, database *sql.DB) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	if err := checkpoint(ctx, database); err != nil {
+		return err
+	}
+	err := retryBusy(ctx, func() error {
+		_, err := database.ExecContext(ctx, `VACUUM`)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return checkpoint(ctx, database)
+}
+
+// checkpoint copies the write-ahead log into the database and truncates it.
+func checkpoint(ctx context.Context, database *sql.DB) error {
+	var busy, frames, copied int
+	err := retryBusy(ctx, func() error {
+		return database.QueryRowContext(ctx, `PRAGMA wal_checkpoint(TRUNCATE)`).Scan(&busy, &frames, &copied)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+	if busy != 0 {
+		return ErrScrubIncomplete
+	}
+	return nil
+}
+
+// Check runs SQLite's integrity and foreign key checks on db.
+func Check(ctx context.Context, db Querier) (CheckReport, error) {
+	problems, err := integrityProblems(ctx, db)
+	if err != nil {
+		return CheckReport{}, err
+	}
+	orphans, err := findOrphans(ctx, db)
+	if err != nil {
+		return CheckReport{}, err
+	}
+	return CheckReport{Problems: problems, Orphans: orphans}, nil
+}
+
+func integrityProblems(ctx context.Context, db Querier) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `PRAGMA integrity_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, fmt.Errorf("failed to scan integrity check: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	return problems, nil
+}
+
+func findOrphans(ctx context.Context, db Querier) ([]Orphan, error) {
+	rows, err := db.QueryContext(ctx, `PRAGMA foreign_key_check`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var orphans []Orphan
+	for rows.Next() {
+		var orphan Orphan
+		var rowID sql.NullInt64
+		var fkid int
+		if err := rows.Scan(&orphan.Table, &rowID, &orphan.Parent, &fkid); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key check: %w", err)
+		}
+		orphan.RowID = rowID.Int64
+		orphans = append(orphans, orphan)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	return orphans, nil
+}
+
+// AutoBackup takes rotating backups of a database into Dir when it is opened: before
+// migrating it and on the first use each day. Only the newest Keep automatic backups
+// are kept, or all of them when Keep is not positive; other backups in Dir, including
+// those of other databases, are left alone.
+type AutoBackup struct {
+	Dir string
+	// Name is the BackupName of the database.
+	Name string
+	Keep int
+}
+
+// beforeMigrations backs database up if runMigrations is about to change it.
+func (a *AutoBackup) beforeMigrations(ctx context.Context, database *sql.DB) error {
+	version, err := schemaVersion(ctx, database)
+	if err != nil || version >= SchemaVersion {
+		return err
+	}
+	// A database being created has nothing to lose
+	if exists, err := tableExists(ctx, database, "todos"); err != nil || !exists {
+		return err
+	}
+	return a.take(ctx, database, BackupMigration, time.Now())
+}
+
+// daily backs database up unless Dir already has a backup of it from now's day, or it
+// has no todos yet.
+func (a *AutoBackup) daily(ctx context.Context, database *sql.DB, now time.Time) error {
+	backups, err := ListBackups(a.Dir, a.Name)
+	if err != nil {
+		return err
+	}
+	if n := len(backups); n > 0 {
+		y1, m1, d1 := backups[n-1].Time.Date()
+		y2, m2, d2 := now.Date()
+		if y1 == y2 && m1 == m2 && d1 == d2 {
+			return nil
+		}
+	}
+
+	var hasTodos bool
+	if err := database.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM todos)`).Scan(&hasTodos); err != nil {
+		return fmt.Errorf("failed to check for todos: %w", err)
+	}
+	if !hasTodos {
+		return nil
+	}
+	return a.take(ctx, database, BackupDaily, now)
+}
+
+// take backs database up for reason and prunes old automatic backups. Another process
+// taking the same backup at the same moment is not an error.
+func (a *AutoBackup) take(ctx context.Context, database *sql.DB, reason string, now time.Time) error {
+	err := Backup(ctx, database, BackupPath(a.Dir, a.Name, reason, now))
+	if err != nil && !errors.Is(err, fs.ErrExist) {
+		return fmt.Errorf("automatic %s backup failed: %w", reason, err)
+	}
+	return a.prune()
+}
+
+// prune deletes the oldest automatic backups beyond Keep.
+func (a *AutoBackup) prune() error {
+	if a.Keep <= 0 {
+		return nil
+	}
+	backups, err := ListBackups(a.Dir, a.Name)
+	if err != nil {
+		return err
+	}
+
+	var automatic []BackupFile
+	for _, backup := range backups {
+		if backup.Automatic() {
+			automatic = append(automatic, backup)
+		}
+	}
+	for len(automatic) > a.Keep {
+		if err := os.Remove(automatic[0].Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("failed to remove old backup: %w", err)
+		}
+		automatic = automatic[1:]
+	}
+	return nil
+}
+
+// tableExists reports whether db has a table named name.
+func tableExists(ctx context.Context, db Querier, name string) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)`, name).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check for %s table: %w", name, err)
+	}
+	return exists, nil
+}
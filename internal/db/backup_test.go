@@ -0,0 +1,296 @@
+// ABOUTME: Tests for backups, restores, and integrity checks
+// ABOUTME: Covers round trips, backup validation, orphan reports, and automatic backup rotation
+
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	kept := models.NewTodo(project.ID, "kept")
+	if err := CreateTodo(t.Context(), db, kept); err != nil {
+		t.Fatal(err)
+	}
+
+	backupPath := filepath.Join(t.TempDir(), "backup.db")
+	if err := Backup(t.Context(), db, backupPath); err != nil {
+		t.Fatalf("Failed to back up: %v", err)
+	}
+	if err := Backup(t.Context(), db, backupPath); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Expected backing up over an existing file to fail, got %v", err)
+	}
+
+	// Changes after the backup are lost by restoring it
+	if err := DeleteTodo(t.Context(), db, kept.ID); err != nil {
+		t.Fatal(err)
+	}
+	added := models.NewTodo(project.ID, "added later")
+	if err := CreateTodo(t.Context(), db, added); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Restore(t.Context(), db, backupPath); err != nil {
+		t.Fatalf("Failed to restore: %v", err)
+	}
+
+	if _, err := GetTodoByID(t.Context(), db, kept.ID); err != nil {
+		t.Errorf("Expected restored todo: %v", err)
+	}
+	if _, err := GetTodoByID(t.Context(), db, added.ID); err == nil {
+		t.Error("Expected todo added after the backup to be gone")
+	}
+}
+
+func TestValidateBackup(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.db")
+	if err := Backup(t.Context(), db, valid); err != nil {
+		t.Fatal(err)
+	}
+	version, err := ValidateBackup(t.Context(), valid)
+	if err != nil || version != SchemaVersion {
+		t.Errorf("Expected valid backup at version %d, got %d (%v)", SchemaVersion, version, err)
+	}
+
+	notSQLite := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notSQLite, []byte(strings.Repeat("not a database\n", 100)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateBackup(t.Context(), notSQLite); err == nil {
+		t.Error("Expected a text file to be rejected")
+	}
+
+	other := filepath.Join(dir, "other.db")
+	otherDB, err := sql.Open("sqlite", other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := otherDB.Exec(`CREATE TABLE notes (body TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	_ = otherDB.Close()
+	if _, err := ValidateBackup(t.Context(), other); err == nil || !strings.Contains(err.Error(), "not a toki database") {
+		t.Errorf("Expected a foreign database to be rejected, got %v", err)
+	}
+
+	if _, err := db.Exec(`PRAGMA user_version = 999`); err != nil {
+		t.Fatal(err)
+	}
+	newer := filepath.Join(dir, "newer.db")
+	if err := Backup(t.Context(), db, newer); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateBackup(t.Context(), newer); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Expected a backup from a newer schema to be rejected, got %v", err)
+	}
+	if err := Restore(t.Context(), db, newer); err == nil {
+		t.Error("Expected restoring a newer schema to fail")
+	}
+}
+
+func TestCheckReportsOrphans(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	report, err := Check(t.Context(), db)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	if !report.OK() {
+		t.Errorf("Expected a new database to pass, got %+v", report)
+	}
+
+	// Orphans appear when foreign keys were off, e.g. in an older toki or another tool
+	conn, err := db.Conn(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+	if _, err := conn.ExecContext(t.Context(), `PRAGMA foreign_keys = OFF`); err != nil {
+		t.Fatal(err)
+	}
+	_, err = conn.ExecContext(t.Context(), `INSERT INTO todos (id, project_id, description, created_at, updated_at)
+		VALUES ('orphan', 'missing-project', 'lost', datetime('now'), datetime('now'))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.ExecContext(t.Context(), `PRAGMA foreign_keys = ON`); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err = Check(t.Context(), db)
+	if err != nil {
+		t.Fatalf("Failed to check: %v", err)
+	}
+	if report.OK() || len(report.Orphans) != 1 {
+		t.Fatalf("Expected one orphan, got %+v", report)
+	}
+	if orphan := report.Orphans[0]; orphan.Table != "todos" || orphan.Parent != "projects" {
+		t.Errorf("Unexpected orphan: %+v", orphan)
+	}
+}
+
+func TestAutoBackup(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "toki.db")
+	backupDir := filepath.Join(dir, "backups")
+	auto := &AutoBackup{Dir: backupDir, Name: "toki", Keep: 2}
+
+	// A new database is not backed up
+	db, err := Open(t.Context(), dbPath, Options{AutoBackup: auto})
+	if err != nil {
+		t.Fatalf("Failed to open: %v", err)
+	}
+	if backups, _ := ListBackups(backupDir, "toki"); len(backups) != 0 {
+		t.Errorf("Expected no backups of a new database, got %v", backups)
+	}
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateTodo(t.Context(), db, models.NewTodo(project.ID, "task")); err != nil {
+		t.Fatal(err)
+	}
+
+	// One daily backup per day, rotated down to Keep
+	day := time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local)
+	for _, now := range []time.Time{day, day.Add(time.Hour), day.AddDate(0, 0, 1), day.AddDate(0, 0, 2)} {
+		if err := auto.daily(t.Context(), db, now); err != nil {
+			t.Fatalf("Failed daily backup: %v", err)
+		}
+	}
+	manual := BackupPath(backupDir, "toki", BackupManual, day.AddDate(0, 0, -1))
+	if err := Backup(t.Context(), db, manual); err != nil {
+		t.Fatal(err)
+	}
+	if err := auto.prune(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := ListBackups(backupDir, "toki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reasons []string
+	for _, backup := range backups {
+		reasons = append(reasons, backup.Time.Format("0102")+" "+backup.Reason)
+	}
+	if got, want := strings.Join(reasons, ", "), "0228 manual, 0302 daily, 0303 daily"; got != want {
+		t.Errorf("Expected backups %q, got %q", want, got)
+	}
+
+	// Reopening a database an older toki left behind backs it up before migrating
+	if _, err := db.Exec(`PRAGMA user_version = 0`); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	db, err = Open(t.Context(), dbPath, Options{AutoBackup: &AutoBackup{Dir: backupDir, Name: "toki"}})
+	if err != nil {
+		t.Fatalf("Failed to reopen: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	backups, _ = ListBackups(backupDir, "toki")
+	found := false
+	for _, backup := range backups {
+		if backup.Reason == BackupMigration {
+			found = true
+			if version, err := ValidateBackup(t.Context(), backup.Path); err != nil || version != 0 {
+				t.Errorf("Expected the pre-migration backup at version 0, got %d (%v)", version, err)
+			}
+		}
+	}
+	if !found {
+		t.Errorf("Expected a pre-migration backup, got %v", backups)
+	}
+}
+
+func TestListBackupsKeepsDatabasesApart(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.Local)
+	for _, name := range []string{"toki", "work", "toki-work"} {
+		if err := os.WriteFile(BackupPath(dir, name, BackupDaily, now), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"toki", "work", "toki-work"} {
+		backups, err := ListBackups(dir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backups) != 1 || filepath.Base(backups[0].Path) != filepath.Base(BackupPath(dir, name, BackupDaily, now)) {
+			t.Errorf("ListBackups(%s) = %v, want only its own backup", name, backups)
+		}
+	}
+}
+
+func TestBackupNameKeepsSameNamedDatabasesApart(t *testing.T) {
+	work := BackupName("/data/work/toki.db")
+	home := BackupName("/data/home/toki.db")
+	if !strings.HasPrefix(work, "toki-") || !strings.HasPrefix(home, "toki-") {
+		t.Errorf("BackupName() = %s, %s, want names starting with the file name", work, home)
+	}
+	if work == home {
+		t.Errorf("BackupName() = %s for both databases, want different names", work)
+	}
+	if again := BackupName("/data/work/../work/toki.db"); again != work {
+		t.Errorf("BackupName() = %s for the same database, want %s", again, work)
+	}
+}
+
+func TestScrubRemovesOverwrittenText(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "toki.db")
+	db, err := InitDB(t.Context(), dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo(project.ID, "plaintext-secret-"+strings.Repeat("x", 64))
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
+		t.Fatal(err)
+	}
+	todo.Description = "replaced"
+	if err := UpdateTodo(t.Context(), db, todo); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Scrub(t.Context(), db); err != nil {
+		t.Fatalf("Failed to scrub: %v", err)
+	}
+	for _, path := range []string{dbPath, dbPath + "-wal"} {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "plaintext-secret") {
+			t.Errorf("Expected the overwritten text gone from %s", filepath.Base(path))
+		}
+	}
+}
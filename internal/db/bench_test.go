@@ -0,0 +1,145 @@
+// ABOUTME: Benchmarks for loading large numbers of todos with their tags and projects
+// ABOUTME: Seeds 10k and 100k todos to guard the batched loaders against N+1 regressions
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+// seedTodos inserts n todos spread over 10 projects, each with zero to two of 20 tags,
+// in one transaction.
+func seedTodos(tb testing.TB, db *sql.DB, n int) []*models.Todo {
+	tb.Helper()
+
+	var projects []*models.Project
+	for i := range 10 {
+		project := models.NewProject(fmt.Sprintf("project-%d", i), nil)
+		if err := CreateProject(tb.Context(), db, project); err != nil {
+			tb.Fatal(err)
+		}
+		projects = append(projects, project)
+	}
+	var tags []*models.Tag
+	for i := range 20 {
+		tag, err := GetOrCreateTag(tb.Context(), db, fmt.Sprintf("tag-%d", i))
+		if err != nil {
+			tb.Fatal(err)
+		}
+		tags = append(tags, tag)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	insertTodo, err := tx.Prepare(`INSERT INTO todos (id, project_id, description, done, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	insertTag, err := tx.Prepare(`INSERT INTO todo_tags (todo_id, tag_id) VALUES (?, ?)`)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	start := time.Now().Add(-time.Duration(n) * time.Minute)
+	todos := make([]*models.Todo, n)
+	for i := range todos {
+		todo := models.NewTodo(projects[i%len(projects)].ID, fmt.Sprintf("todo %d", i))
+		todo.Done = i%4 == 0
+		todo.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		todo.UpdatedAt = todo.CreatedAt
+		if _, err := insertTodo.Exec(todo.ID.String(), todo.ProjectID.String(), todo.Description, todo.Done, todo.CreatedAt, todo.UpdatedAt); err != nil {
+			tb.Fatal(err)
+		}
+		for j := range i % 3 {
+			if _, err := insertTag.Exec(todo.ID.String(), tags[(i+j)%len(tags)].ID); err != nil {
+				tb.Fatal(err)
+			}
+		}
+		todos[i] = todo
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatal(err)
+	}
+	return todos
+}
+
+func BenchmarkQueryTodosWithTags(b *testing.B) {
+	for _, n := range []int{10_000, 100_000} {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			db := setupTestDB(b)
+			defer func() { _ = db.Close() }()
+			seedTodos(b, db, n)
+
+			b.ResetTimer()
+			for range b.N {
+				todos, todoTags, err := QueryTodosWithTags(b.Context(), db, nil)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(todos) != n || len(todoTags) == 0 {
+					b.Fatalf("Expected %d todos with tags, got %d todos and %d tagged", n, len(todos), len(todoTags))
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetTagsForTodos(b *testing.B) {
+	for _, n := range []int{10_000, 100_000} {
+		b.Run(fmt.Sprintf("%dk", n/1000), func(b *testing.B) {
+			db := setupTestDB(b)
+			defer func() { _ = db.Close() }()
+			todos := seedTodos(b, db, n)
+
+			b.Run("all", func(b *testing.B) {
+				for range b.N {
+					if _, err := GetTagsForTodos(b.Context(), db, todos); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+			b.Run("page", func(b *testing.B) {
+				page := todos[:50]
+				for range b.N {
+					if _, err := GetTagsForTodos(b.Context(), db, page); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGetProjectMap(b *testing.B) {
+	db := setupTestDB(b)
+	defer func() { _ = db.Close() }()
+	seedTodos(b, db, 10_000)
+
+	b.ResetTimer()
+	for range b.N {
+		projects, err := GetProjectMap(b.Context(), db)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(projects) != 10 {
+			b.Fatalf("Expected 10 projects, got %d", len(projects))
+		}
+	}
+}
+
+// uuidSet returns the IDs of todos, for comparing loaded maps.
+func uuidSet(todos []*models.Todo) map[uuid.UUID]bool {
+	set := make(map[uuid.UUID]bool, len(todos))
+	for _, todo := range todos {
+		set[todo.ID] = true
+	}
+	return set
+}
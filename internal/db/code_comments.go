@@ -0,0 +1,68 @@
+// ABOUTME: Code comment database operations
+// ABOUTME: Records the TODO-style source comments behind code todos
+
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+// ListCodeComments returns the code comments recorded for a project.
+func ListCodeComments(ctx context.Context, db Querier, projectID uuid.UUID) ([]*models.CodeComment, error) {
+	query := `SELECT fingerprint, todo_id, file, line, marker, missing FROM code_comments
+	          WHERE project_id = ? ORDER BY file, line`
+
+	rows, err := db.QueryContext(ctx, query, projectID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list code comments: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var comments []*models.CodeComment
+	for rows.Next() {
+		comment := &models.CodeComment{ProjectID: projectID}
+		var todoIDStr string
+		if err := rows.Scan(&comment.Fingerprint, &todoIDStr, &comment.File, &comment.Line, &comment.Marker, &comment.Missing); err != nil {
+			return nil, fmt.Errorf("failed to scan code comment: %w", err)
+		}
+		comment.TodoID, _ = uuid.Parse(todoIDStr)
+		comments = append(comments, comment)
+	}
+
+	return comments, nil
+}
+
+// AddCodeComment records a comment and the todo created for it.
+func AddCodeComment(ctx context.Context, db Querier, comment *models.CodeComment) error {
+	query := `INSERT INTO code_comments (project_id, fingerprint, todo_id, file, line, marker, missing)
+	          VALUES (?, ?, ?, ?, ?, ?, ?)`
+	_, err := exec(ctx, db, query, comment.ProjectID.String(), comment.Fingerprint, comment.TodoID.String(),
+		comment.File, comment.Line, comment.Marker, comment.Missing)
+	if err != nil {
+		return fmt.Errorf("failed to add code comment: %w", err)
+	}
+	return nil
+}
+
+// UpdateCodeComment stores a recorded comment's location and whether it is missing.
+func UpdateCodeComment(ctx context.Context, db Querier, comment *models.CodeComment) error {
+	query := `UPDATE code_comments SET file = ?, line = ?, missing = ? WHERE project_id = ? AND fingerprint = ?`
+	_, err := exec(ctx, db, query, comment.File, comment.Line, comment.Missing, comment.ProjectID.String(), comment.Fingerprint)
+	if err != nil {
+		return fmt.Errorf("failed to update code comment: %w", err)
+	}
+	return nil
+}
+
+// RenameCodeComment records a project's comment under a new fingerprint.
+func RenameCodeComment(ctx context.Context, db Querier, projectID uuid.UUID, from, to string) error {
+	query := `UPDATE code_comments SET fingerprint = ? WHERE project_id = ? AND fingerprint = ?`
+	if _, err := exec(ctx, db, query, to, projectID.String(), from); err != nil {
+		return fmt.Errorf("failed to update code comment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// ABOUTME: Tests for code comment records
+// ABOUTME: Covers adding, updating, renaming, and listing the comments behind code todos
+
+package db
+
+import (
+	"testing"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func TestCodeComments(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	var comments []*models.CodeComment
+	for i, file := range []string{"util.go", "main.go"} {
+		todo := models.NewTodo(project.ID, file)
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+		comment := &models.CodeComment{ProjectID: project.ID, Fingerprint: file, TodoID: todo.ID, File: file, Line: i + 1, Marker: "TODO"}
+		if err := AddCodeComment(t.Context(), db, comment); err != nil {
+			t.Fatal(err)
+		}
+		comments = append(comments, comment)
+	}
+	if err := AddCodeComment(t.Context(), db, comments[0]); err == nil {
+		t.Error("Expected a second comment under the same fingerprint to fail")
+	}
+
+	comments[0].Line = 9
+	comments[0].Missing = true
+	if err := UpdateCodeComment(t.Context(), db, comments[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameCodeComment(t.Context(), db, project.ID, "main.go", "renamed"); err != nil {
+		t.Fatal(err)
+	}
+
+	listed, err := ListCodeComments(t.Context(), db, project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed) != 2 || listed[0].Fingerprint != "renamed" || listed[0].TodoID != comments[1].TodoID {
+		t.Fatalf("Expected the renamed main.go comment first, got %+v", listed)
+	}
+	if listed[1].Line != 9 || !listed[1].Missing {
+		t.Errorf("Expected util.go moved and missing, got %+v", listed[1])
+	}
+}
@@ -0,0 +1,53 @@
+// ABOUTME: Todo commit link database operations
+// ABOUTME: Records which git commits closed or referenced a todo
+
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+// AddTodoCommit records a commit against a todo. Returns false if the commit was
+// already recorded for that todo.
+func AddTodoCommit(ctx context.Context, db Querier, todoID uuid.UUID, sha, action string) (bool, error) {
+	query := `INSERT INTO todo_commits (todo_id, sha, action, created_at) VALUES (?, ?, ?, ?)
+	          ON CONFLICT(todo_id, sha) DO NOTHING`
+
+	result, err := exec(ctx, db, query, todoID.String(), sha, action, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to add todo commit: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to add todo commit: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// ListTodoCommits returns the commits recorded for a todo, oldest first.
+func ListTodoCommits(ctx context.Context, db Querier, todoID uuid.UUID) ([]*models.TodoCommit, error) {
+	query := `SELECT sha, action, created_at FROM todo_commits WHERE todo_id = ? ORDER BY created_at`
+
+	rows, err := db.QueryContext(ctx, query, todoID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todo commits: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var commits []*models.TodoCommit
+	for rows.Next() {
+		commit := &models.TodoCommit{TodoID: todoID}
+		if err := rows.Scan(&commit.SHA, &commit.Action, &commit.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan todo commit: %w", err)
+		}
+		commits = append(commits, commit)
+	}
+
+	return commits, nil
+}
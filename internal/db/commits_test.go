@@ -0,0 +1,44 @@
+// ABOUTME: Tests for todo commit link operations
+// ABOUTME: Covers recording, deduplication, and listing of commits
+
+package db
+
+import (
+	"testing"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func TestTodoCommits(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo(project.ID, "linked")
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := AddTodoCommit(t.Context(), db, todo.ID, "1111111", "refs")
+	if err != nil || !added {
+		t.Fatalf("Expected commit to be added, got %v, %v", added, err)
+	}
+	added, err = AddTodoCommit(t.Context(), db, todo.ID, "1111111", "closes")
+	if err != nil || added {
+		t.Fatalf("Expected duplicate commit to be ignored, got %v, %v", added, err)
+	}
+	if _, err := AddTodoCommit(t.Context(), db, todo.ID, "2222222", "closes"); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := ListTodoCommits(t.Context(), db, todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 2 || commits[0].SHA != "1111111" || commits[0].Action != "refs" || commits[1].Action != "closes" {
+		t.Errorf("Unexpected commits: %+v", commits)
+	}
+}
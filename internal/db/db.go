@@ -4,40 +4,102 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"time"
+)
+
+// Querier runs statements for the record functions: a *sql.DB, or a *sql.Tx to make
+// several calls atomic.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
-	_ "modernc.org/sqlite"
+// connectionPragmas are run on every connection the pool opens.
+var connectionPragmas = []string{
+	// Wait briefly for another process's lock instead of failing. SQLite can't be
+	// interrupted while it waits, so the longer wait is retryBusy's, which a
+	// cancelled context ends
+	"busy_timeout(250)",
+	"foreign_keys(1)",
+	// Readers don't block the writer, nor the writer readers
+	"journal_mode(WAL)",
+	// Safe with WAL: a power loss may drop the last commits but never corrupts
+	"synchronous(NORMAL)",
+}
+
+// Pool limits: readers run in parallel on up to maxOpenConns connections, while writes
+// take turns; see write.
+const (
+	maxOpenConns    = 8
+	maxIdleConns    = 4
+	connMaxIdleTime = 5 * time.Minute
 )
 
+// Options configures Open.
+type Options struct {
+	// AutoBackup, when set, backs the database up before migrating it and on the first
+	// use each day.
+	AutoBackup *AutoBackup
+}
+
 // InitDB initializes the database connection and runs migrations.
-func InitDB(dbPath string) (*sql.DB, error) {
+func InitDB(ctx context.Context, dbPath string) (*sql.DB, error) {
+	return Open(ctx, dbPath, Options{})
+}
+
+// Open initializes the database connection, taking the automatic backups opts asks
+// for, and runs migrations. ctx bounds the backups and migrations, not the connection.
+func Open(ctx context.Context, dbPath string, opts Options) (*sql.DB, error) {
 	// Ensure directory exists
 	dir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dir, 0750); err != nil {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	// Open database connection
-	db, err := sql.Open("sqlite", dbPath)
+	// Open database connection. Transactions begin IMMEDIATE, taking the write lock up
+	// front, so they wait in busy_timeout rather than fail when they first write. Times
+	// are written as "2006-01-02 15:04:05.999999999-07:00", which SQLite's date
+	// functions read.
+	params := url.Values{"_pragma": connectionPragmas, "_txlock": {"immediate"}, "_time_format": {"sqlite"}}
+	db, err := sql.Open("sqlite", dbPath+"?"+params.Encode())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	db.SetMaxOpenConns(maxOpenConns)
+	db.SetMaxIdleConns(maxIdleConns)
+	db.SetConnMaxIdleTime(connMaxIdleTime)
 
-	// Enable foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	if opts.AutoBackup != nil {
+		if err := opts.AutoBackup.beforeMigrations(ctx, db); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
 	}
 
-	// Run migrations
-	if err := runMigrations(db); err != nil {
+	// Run migrations in one transaction, so that processes starting together apply
+	// each of them once
+	err = WithTx(ctx, db, func(tx *sql.Tx) error {
+		return runMigrations(ctx, tx)
+	})
+	if err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if opts.AutoBackup != nil {
+		if err := opts.AutoBackup.daily(ctx, db, time.Now()); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+
 	return db, nil
 }
 
@@ -7,6 +7,9 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/harper/toki/internal/models"
 )
 
 func TestInitDB(t *testing.T) {
@@ -14,7 +17,7 @@ func TestInitDB(t *testing.T) {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
 
-	db, err := InitDB(dbPath)
+	db, err := InitDB(t.Context(), dbPath)
 	if err != nil {
 		t.Fatalf("Failed to init DB: %v", err)
 	}
@@ -39,6 +42,75 @@ func TestInitDB(t *testing.T) {
 	}
 }
 
+func TestMigrationRewritesLegacyTimes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	db, err := InitDB(t.Context(), dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := models.NewProject("legacy", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	// As written by earlier versions, through time.String, before schema version 2
+	created := time.Date(2025, 1, 6, 9, 30, 0, 250_000_000, time.FixedZone("EST", -5*3600))
+	legacy := created.String() + " m=+0.001"
+	if _, err := db.Exec(`UPDATE projects SET created_at = ?`, legacy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`PRAGMA user_version = 1`); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	db, err = InitDB(t.Context(), dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var stored string
+	var day float64
+	if err := db.QueryRow(`SELECT created_at || '', julianday(created_at) FROM projects`).Scan(&stored, &day); err != nil {
+		t.Fatal(err)
+	}
+	if stored != "2025-01-06 09:30:00.25-05:00" {
+		t.Errorf("Expected the time rewritten, got %q", stored)
+	}
+	if day != julianDay(created) {
+		t.Errorf("Expected SQLite's Julian day %v to match %v", day, julianDay(created))
+	}
+
+	loaded, err := GetProjectByID(t.Context(), db, project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.CreatedAt.Equal(created) {
+		t.Errorf("Expected %v, got %v", created, loaded.CreatedAt)
+	}
+
+	// Once migrated, opening leaves times alone, and queries still read ones an older
+	// toki writes afterwards
+	if _, err := db.Exec(`UPDATE projects SET created_at = ?`, legacy); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	db, err = InitDB(t.Context(), dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.QueryRow(`SELECT created_at || '', `+sqlJulianDay("created_at")+` FROM projects`).Scan(&stored, &day); err != nil {
+		t.Fatal(err)
+	}
+	if stored != legacy {
+		t.Errorf("Expected the time left as written, got %q", stored)
+	}
+	if day != julianDay(created) {
+		t.Errorf("Expected the legacy time read as Julian day %v, got %v", julianDay(created), day)
+	}
+}
+
 func TestGetDefaultDBPath(t *testing.T) {
 	path := GetDefaultDBPath()
 
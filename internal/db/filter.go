@@ -0,0 +1,396 @@
+// ABOUTME: Compiles filter expressions into parameterized SQL over the todos table
+// ABOUTME: Backs list --filter, the MCP list_todos filter argument, and toki://query
+
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+)
+
+// QueryTodos returns todos matching a filter expression, newest first. A nil expression
+// matches every todo.
+func QueryTodos(ctx context.Context, db Querier, expr filter.Node) ([]*models.Todo, error) {
+	where, args, err := filterWhere(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + todoColumns + `
+	          FROM todos t
+	          WHERE ` + where + `
+	          ORDER BY t.created_at DESC`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query todos: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var todos []*models.Todo
+	for rows.Next() {
+		todo, err := scanTodoFromRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+
+	return todos, rows.Err()
+}
+
+// QueryTodosWithTags is QueryTodos that also returns each todo's tags, sorted by name,
+// from the same query. Todos without tags are absent from the map.
+func QueryTodosWithTags(ctx context.Context, db Querier, expr filter.Node) ([]*models.Todo, map[uuid.UUID][]*models.Tag, error) {
+	todos, todoTags, _, err := QueryTodosPage(ctx, db, expr, Order{}, Page{})
+	return todos, todoTags, err
+}
+
+// CountTodos returns how many todos match a filter expression.
+func CountTodos(ctx context.Context, db Querier, expr filter.Node) (int, error) {
+	where, args, err := filterWhere(expr)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos t WHERE `+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count todos: %w", err)
+	}
+	return count, nil
+}
+
+// QueryTodosPage is QueryTodosWithTags limited to one page in order. It returns the
+// cursor of the next page, empty on the last one. Pages are keyset-based, so todos
+// added or removed between requests don't shift later pages, and only the rows of the
+// page are read, sorted or not.
+func QueryTodosPage(ctx context.Context, db Querier, expr filter.Node, order Order, page Page) ([]*models.Todo, map[uuid.UUID][]*models.Tag, string, error) {
+	where, args, err := filterWhere(expr)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	// created_at is compared as stored text, the same order ORDER BY uses
+	from, sorted := "todos t", order.Key != ""
+	columns, desc := []string{"t.created_at", "t.id"}, []bool{true, true}
+	var values []any
+	if sorted {
+		if order.Now.IsZero() {
+			order.Now = time.Now()
+		}
+		sortBy, err := order.sortSQL()
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if page.Cursor != "" {
+			// The cursor's scoring time changes the urgency expression, so read it first
+			if order, values, err = decodeSortedCursor(page.Cursor, order); err != nil {
+				return nil, nil, "", err
+			}
+			if sortBy, err = order.sortSQL(); err != nil {
+				return nil, nil, "", err
+			}
+		}
+		from = `(SELECT t.*, (` + sortBy.missing + `) AS sort_missing, COALESCE(` + sortBy.value + `, 0) AS sort_value
+		         FROM todos t
+		         WHERE ` + where + `) t`
+		args = append(sortBy.args, args...)
+		where = "1"
+		columns = append([]string{"t.sort_missing", "t.sort_value"}, columns...)
+		desc = append([]bool{false, sortBy.desc}, desc...)
+	} else if page.Cursor != "" {
+		fields, err := decodeCursor(page.Cursor, keysetCursor, 2)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		values = []any{fields[0], fields[1]}
+	}
+	if values != nil {
+		after, afterArgs := sqlAfter(columns, desc, values)
+		where = "(" + where + ") AND " + after
+		args = append(args, afterArgs...)
+	}
+
+	orderBy := make([]string, len(columns))
+	for i, column := range columns {
+		orderBy[i] = column
+		if desc[i] {
+			orderBy[i] += " DESC"
+		}
+	}
+	extra := ""
+	if sorted {
+		extra = ", t.sort_missing, t.sort_value"
+	}
+	query := `SELECT ` + todoColumns + `,
+	                 (SELECT json_group_array(json_array(tg.id, tg.name))
+	                  FROM todo_tags tt INNER JOIN tags tg ON tg.id = tt.tag_id
+	                  WHERE tt.todo_id = t.id),
+	                 CAST(t.created_at AS TEXT)` + extra + `
+	          FROM ` + from + `
+	          WHERE ` + where + `
+	          ORDER BY ` + strings.Join(orderBy, ", ")
+	if page.Limit > 0 || page.Offset > 0 {
+		// One extra row tells whether there is a next page
+		limit := -1
+		if page.Limit > 0 {
+			limit = page.Limit + 1
+		}
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, max(page.Offset, 0))
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to query todos: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var todos []*models.Todo
+	todoTags := make(map[uuid.UUID][]*models.Tag)
+	tags := make(map[int64]*models.Tag)
+	var next, lastCreated string
+	var lastMissing bool
+	var lastValue float64
+	for rows.Next() {
+		var aggregated, createdText string
+		var missing bool
+		var value float64
+		dest := []any{&aggregated, &createdText}
+		if sorted {
+			dest = append(dest, &missing, &value)
+		}
+		todo, err := scanTodoFromRows(rows, dest...)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if page.Limit > 0 && len(todos) == page.Limit {
+			last := todos[len(todos)-1].ID.String()
+			if sorted {
+				next = encodeSortedCursor(order, lastMissing, lastValue, lastCreated, last)
+			} else {
+				next = encodeCursor(keysetCursor, lastCreated, last)
+			}
+			break
+		}
+		todos = append(todos, todo)
+		lastCreated, lastMissing, lastValue = createdText, missing, value
+		if aggregated == "[]" {
+			continue
+		}
+
+		var pairs [][2]any
+		if err := json.Unmarshal([]byte(aggregated), &pairs); err != nil {
+			return nil, nil, "", fmt.Errorf("failed to parse tags of todo %s: %w", todo.ID, err)
+		}
+		for _, pair := range pairs {
+			id, _ := pair[0].(float64)
+			tag := tags[int64(id)]
+			if tag == nil {
+				name, _ := pair[1].(string)
+				tag = &models.Tag{ID: int64(id), Name: name}
+				tags[tag.ID] = tag
+			}
+			todoTags[todo.ID] = append(todoTags[todo.ID], tag)
+		}
+		sort.Slice(todoTags[todo.ID], func(i, j int) bool {
+			return todoTags[todo.ID][i].Name < todoTags[todo.ID][j].Name
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, "", fmt.Errorf("failed to query todos: %w", err)
+	}
+
+	return todos, todoTags, next, nil
+}
+
+// filterWhere compiles an expression into a WHERE condition, matching everything when
+// it is nil.
+func filterWhere(expr filter.Node) (string, []any, error) {
+	if expr == nil {
+		return "1=1", nil, nil
+	}
+	return compileFilter(expr, time.Now())
+}
+
+// dateColumns maps date fields to their todos column.
+var dateColumns = map[filter.Field]string{
+	filter.FieldDue:       "t.due_date",
+	filter.FieldCreated:   "t.created_at",
+	filter.FieldUpdated:   "t.updated_at",
+	filter.FieldCompleted: "t.completed_at",
+}
+
+// compileFilter translates an expression into a WHERE condition on todos aliased as t.
+// Values are always bound as parameters.
+func compileFilter(n filter.Node, now time.Time) (string, []any, error) {
+	switch n := n.(type) {
+	case filter.And:
+		return compileBinary("AND", n.Left, n.Right, now)
+	case filter.Or:
+		return compileBinary("OR", n.Left, n.Right, now)
+	case filter.Not:
+		cond, args, err := compileFilter(n.X, now)
+		if err != nil {
+			return "", nil, err
+		}
+		return negate(cond), args, nil
+	case filter.Term:
+		if n.Op == filter.OpNe {
+			positive := n
+			positive.Op = filter.OpMatch
+			cond, args, err := compileTerm(positive, now)
+			if err != nil {
+				return "", nil, err
+			}
+			return negate(cond), args, nil
+		}
+		return compileTerm(n, now)
+	default:
+		return "", nil, fmt.Errorf("unsupported filter node %T", n)
+	}
+}
+
+func compileBinary(op string, left, right filter.Node, now time.Time) (string, []any, error) {
+	leftCond, leftArgs, err := compileFilter(left, now)
+	if err != nil {
+		return "", nil, err
+	}
+	rightCond, rightArgs, err := compileFilter(right, now)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + leftCond + " " + op + " " + rightCond + ")", append(leftArgs, rightArgs...), nil
+}
+
+// negate inverts a condition, treating NULL as false so todos missing a value match
+// negated tests like "not due<today".
+func negate(cond string) string {
+	return "(NOT COALESCE(" + cond + ", 0))"
+}
+
+func compileTerm(term filter.Term, now time.Time) (string, []any, error) {
+	value := term.Value
+
+	switch term.Field {
+	case filter.FieldProject:
+		// One project: by ID, else by name, preferring the exact case
+		return `t.project_id = (SELECT id FROM projects WHERE id = ? OR name = ? COLLATE NOCASE
+		        ORDER BY id = ? DESC, name = ? DESC, name LIMIT 1)`, []any{value, value, value, value}, nil
+	case filter.FieldTag:
+		switch value {
+		case filter.ValueNone:
+			return "NOT EXISTS (SELECT 1 FROM todo_tags tt WHERE tt.todo_id = t.id)", nil, nil
+		case filter.ValueAny:
+			return "EXISTS (SELECT 1 FROM todo_tags tt WHERE tt.todo_id = t.id)", nil, nil
+		}
+		return "EXISTS (SELECT 1 FROM todo_tags tt JOIN tags tg ON tg.id = tt.tag_id WHERE tt.todo_id = t.id AND tg.name = ?)", []any{value}, nil
+	case filter.FieldBranch:
+		return compileOptional("t.branch", value, "t.branch = ?")
+	case filter.FieldID:
+		return `t.id LIKE ? ESCAPE '\'`, []any{escapeLike(value) + "%"}, nil
+	case filter.FieldText:
+		if term.Op == filter.OpEq {
+			return "t.description = ? COLLATE NOCASE", []any{value}, nil
+		}
+		pattern := "%" + escapeLike(value) + "%"
+		return `(t.description LIKE ? ESCAPE '\' OR COALESCE(t.notes, '') LIKE ? ESCAPE '\')`, []any{pattern, pattern}, nil
+	case filter.FieldDone:
+		return "t.done = ?", []any{value == "true"}, nil
+	case filter.FieldOverdue:
+		cond := "(t.done = 0 AND " + sqlJulianDay("t.due_date") + " < ?)"
+		if value == "true" {
+			return cond, []any{julianDay(now)}, nil
+		}
+		return negate(cond), []any{julianDay(now)}, nil
+	case filter.FieldPriority:
+		return compilePriority(term.Op, value)
+	}
+
+	if column, ok := dateColumns[term.Field]; ok {
+		return compileDate(column, term.Op, value, now)
+	}
+	return "", nil, fmt.Errorf("unsupported filter field '%s'", term.Field)
+}
+
+// compileOptional handles none and any for nullable columns, else uses cond with value.
+func compileOptional(column, value, cond string) (string, []any, error) {
+	switch value {
+	case filter.ValueNone:
+		return column + " IS NULL", nil, nil
+	case filter.ValueAny:
+		return column + " IS NOT NULL", nil, nil
+	}
+	return cond, []any{value}, nil
+}
+
+func compilePriority(op filter.Op, value string) (string, []any, error) {
+	switch op {
+	case filter.OpMatch, filter.OpEq:
+		return compileOptional("t.priority", value, "t.priority = ?")
+	}
+
+	rank := "(CASE t.priority WHEN 'low' THEN 1 WHEN 'medium' THEN 2 WHEN 'high' THEN 3 END)"
+	return rank + " " + string(op) + " ?", []any{filter.PriorityRank(value)}, nil
+}
+
+// compileDate compares a date column with the day range a value covers. Equality means
+// within the day; < means before it starts and <= before it ends.
+func compileDate(column string, op filter.Op, value string, now time.Time) (string, []any, error) {
+	if op == filter.OpMatch || op == filter.OpEq {
+		switch value {
+		case filter.ValueNone:
+			return column + " IS NULL", nil, nil
+		case filter.ValueAny:
+			return column + " IS NOT NULL", nil, nil
+		}
+	}
+
+	start, end, err := filter.ResolveDate(value, now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	at := sqlJulianDay(column)
+	switch op {
+	case filter.OpMatch, filter.OpEq:
+		return "(" + at + " >= ? AND " + at + " < ?)", []any{julianDay(start), julianDay(end)}, nil
+	case filter.OpLt:
+		return at + " < ?", []any{julianDay(start)}, nil
+	case filter.OpLe:
+		return at + " < ?", []any{julianDay(end)}, nil
+	case filter.OpGt:
+		return at + " >= ?", []any{julianDay(end)}, nil
+	case filter.OpGe:
+		return at + " >= ?", []any{julianDay(start)}, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported operator '%s' for dates", op)
+	}
+}
+
+// sqlJulianDay converts a timestamp column to a Julian day number, to the millisecond.
+func sqlJulianDay(column string) string {
+	return "julianday(" + sqlTime(column) + ")"
+}
+
+// julianDay returns the Julian day number of t as SQLite computes it, from milliseconds
+// since the Julian epoch, for comparing with sqlJulianDay.
+func julianDay(t time.Time) float64 {
+	return float64(t.UnixMilli()+julianEpochMillis) / 86400000
+}
+
+// julianEpochMillis is the Unix epoch in milliseconds since the Julian epoch.
+const julianEpochMillis = 210866760000000
+
+// escapeLike escapes LIKE wildcards so values match literally.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
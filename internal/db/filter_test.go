@@ -0,0 +1,201 @@
+// ABOUTME: Tests for filter expressions compiled to SQL
+// ABOUTME: Runs parsed expressions against a small fixture set of todos
+
+package db
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+)
+
+func TestQueryTodos(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	api := models.NewProject("api", nil)
+	web := models.NewProject("web", nil)
+	for _, p := range []*models.Project{api, web} {
+		if err := CreateProject(t.Context(), db, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	high, low := "high", "low"
+	feature := "feature/login"
+	soon := now.Add(3 * 24 * time.Hour)
+	late := now.Add(-2 * 24 * time.Hour)
+	notes := "stack trace in 100% of runs"
+
+	crash := models.NewTodo(api.ID, "fix crash")
+	crash.Priority = &high
+	crash.DueDate = &late
+	crash.Notes = &notes
+
+	docs := models.NewTodo(api.ID, "write docs")
+	docs.Priority = &low
+	docs.DueDate = &soon
+
+	login := models.NewTodo(web.ID, "login page")
+	login.Branch = &feature
+
+	shipped := models.NewTodo(web.ID, "ship v1")
+	shipped.MarkDone()
+
+	for _, todo := range []*models.Todo{crash, docs, login, shipped} {
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddTagToTodo(t.Context(), db, crash.ID, "bug"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddTagToTodo(t.Context(), db, login.ID, "ui"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"project:api", []string{"fix crash", "write docs"}},
+		{"project:API and not done", []string{"fix crash", "write docs"}},
+		{"project:api and (tag:bug or priority:high)", []string{"fix crash"}},
+		{"tag:bug or tag:ui", []string{"fix crash", "login page"}},
+		{"tag:none", []string{"ship v1", "write docs"}},
+		{"priority>=low", []string{"fix crash", "write docs"}},
+		{"priority>low", []string{"fix crash"}},
+		{"priority:none", []string{"login page", "ship v1"}},
+		{"due<+7d", []string{"fix crash", "write docs"}},
+		{"due<today", []string{"fix crash"}},
+		{"due:none", []string{"login page", "ship v1"}},
+		{"not due<today", []string{"login page", "ship v1", "write docs"}},
+		{"overdue", []string{"fix crash"}},
+		{"done", []string{"ship v1"}},
+		{"pending web", []string{}},
+		{"pending login", []string{"login page"}},
+		{"branch:feature/login", []string{"login page"}},
+		{"branch!=feature/login project:web", []string{"ship v1"}},
+		{`"100%"`, []string{"fix crash"}},
+		{"text:crash", []string{"fix crash"}},
+		{"desc=\"WRITE DOCS\"", []string{"write docs"}},
+		{"created>=today", []string{"fix crash", "login page", "ship v1", "write docs"}},
+		{"completed:today", []string{"ship v1"}},
+		{"id:" + crash.ID.String()[:8], []string{"fix crash"}},
+		{"project:" + web.ID.String() + " and tag:any", []string{"login page"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := filter.Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			todos, err := QueryTodos(t.Context(), db, expr)
+			if err != nil {
+				t.Fatalf("Failed to query: %v", err)
+			}
+
+			got := make([]string, 0, len(todos))
+			for _, todo := range todos {
+				got = append(got, todo.Description)
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	all, err := QueryTodos(t.Context(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 {
+		t.Errorf("Expected nil filter to match all 4 todos, got %d", len(all))
+	}
+}
+
+func TestCompileFilterUsesParameters(t *testing.T) {
+	expr, err := filter.Parse(`tag:"x' OR 1=1 --"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	where, args, err := compileFilter(expr, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(where, "OR 1=1") {
+		t.Errorf("Expected value to be bound as a parameter, got %s", where)
+	}
+	if len(args) != 1 || args[0] != "x' OR 1=1 --" {
+		t.Errorf("Unexpected args: %v", args)
+	}
+}
+
+func TestQueryTodosWithTags(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("api", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	tagged := models.NewTodo(project.ID, "tagged")
+	tagged.CreatedAt = time.Now().Add(-time.Hour)
+	untagged := models.NewTodo(project.ID, "untagged")
+	for _, todo := range []*models.Todo{tagged, untagged} {
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Names that would break a delimited aggregate
+	names := []string{"ops:infra", `say "hi"`, "b,c", "ünïcode"}
+	for _, name := range names {
+		if err := AddTagToTodo(t.Context(), db, tagged.ID, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todos, todoTags, err := QueryTodosWithTags(t.Context(), db, nil)
+	if err != nil {
+		t.Fatalf("Failed to query todos: %v", err)
+	}
+	if len(todos) != 2 || todos[0].ID != untagged.ID {
+		t.Fatalf("Expected both todos newest first, got %v", todos)
+	}
+	if _, ok := todoTags[untagged.ID]; ok {
+		t.Errorf("Expected no entry for the untagged todo, got %v", todoTags[untagged.ID])
+	}
+
+	want, err := GetTodoTags(t.Context(), db, tagged.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := todoTags[tagged.ID]
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d tags, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if *got[i] != *want[i] {
+			t.Errorf("Tag %d: expected %+v, got %+v", i, *want[i], *got[i])
+		}
+	}
+
+	expr, err := filter.Parse(`tag:"ops:infra"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	todos, _, err = QueryTodosWithTags(t.Context(), db, expr)
+	if err != nil {
+		t.Fatalf("Failed to query todos: %v", err)
+	}
+	if len(todos) != 1 || todos[0].ID != tagged.ID {
+		t.Errorf("Expected the filter to apply, got %v", todos)
+	}
+}
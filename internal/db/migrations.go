@@ -1,21 +1,33 @@
 // ABOUTME: Database schema migrations
-// ABOUTME: Creates tables for projects, todos, tags, and relationships
+// ABOUTME: Creates tables for projects, todos, tags, commits, views, time entries, and relationships
 
 package db
 
 import (
-	"database/sql"
+	"context"
 	"fmt"
+	"strings"
 )
 
+// SchemaVersion is the schema runMigrations produces, recorded in PRAGMA user_version.
+// Bump it with every migration added below; restores refuse backups from a newer schema.
+const SchemaVersion = 2
+
 const schema = `
 CREATE TABLE IF NOT EXISTS projects (
 	id TEXT PRIMARY KEY,
 	name TEXT UNIQUE NOT NULL,
 	directory_path TEXT,
+	remote_url TEXT,
 	created_at DATETIME NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS project_paths (
+	path TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS todos (
 	id TEXT PRIMARY KEY,
 	project_id TEXT NOT NULL,
@@ -27,6 +39,9 @@ CREATE TABLE IF NOT EXISTS todos (
 	updated_at DATETIME NOT NULL,
 	completed_at DATETIME,
 	due_date DATETIME,
+	branch TEXT,
+	estimate_points REAL,
+	estimate_seconds INTEGER,
 	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
 );
 
@@ -43,14 +58,57 @@ CREATE TABLE IF NOT EXISTS todo_tags (
 	FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
 );
 
+CREATE TABLE IF NOT EXISTS todo_commits (
+	todo_id TEXT NOT NULL,
+	sha TEXT NOT NULL,
+	action TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (todo_id, sha),
+	FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS code_comments (
+	project_id TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	todo_id TEXT NOT NULL UNIQUE,
+	file TEXT NOT NULL,
+	line INTEGER NOT NULL,
+	marker TEXT NOT NULL,
+	missing BOOLEAN NOT NULL DEFAULT 0,
+	PRIMARY KEY (project_id, fingerprint),
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS views (
+	name TEXT PRIMARY KEY,
+	filter TEXT NOT NULL,
+	sort TEXT NOT NULL DEFAULT '',
+	reverse BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS time_entries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id TEXT NOT NULL,
+	started_at DATETIME NOT NULL,
+	ended_at DATETIME,
+	seconds INTEGER NOT NULL DEFAULT 0,
+	FOREIGN KEY (todo_id) REFERENCES todos(id) ON DELETE CASCADE
+);
+
 CREATE INDEX IF NOT EXISTS idx_todos_project_id ON todos(project_id);
 CREATE INDEX IF NOT EXISTS idx_todos_done ON todos(done);
 CREATE INDEX IF NOT EXISTS idx_projects_directory_path ON projects(directory_path);
+CREATE INDEX IF NOT EXISTS idx_project_paths_project_id ON project_paths(project_id);
+CREATE INDEX IF NOT EXISTS idx_time_entries_todo_id ON time_entries(todo_id);
+-- At most one timer runs at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries((ended_at IS NULL)) WHERE ended_at IS NULL;
 `
 
-func runMigrations(db *sql.DB) error {
+func runMigrations(ctx context.Context, db Querier) error {
 	// Run the main schema
-	_, err := db.Exec(schema)
+	_, err := db.ExecContext(ctx, schema)
 	if err != nil {
 		return fmt.Errorf("failed to execute schema: %w", err)
 	}
@@ -58,24 +116,144 @@ func runMigrations(db *sql.DB) error {
 	// Migration: Add updated_at column if it doesn't exist
 	// Check if column exists first
 	var columnCount int
-	err = db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('todos') WHERE name='updated_at'`).Scan(&columnCount)
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('todos') WHERE name='updated_at'`).Scan(&columnCount)
 	if err != nil {
 		return fmt.Errorf("failed to check for updated_at column: %w", err)
 	}
 
 	if columnCount == 0 {
 		// Add the column with default value of created_at for existing rows
-		_, err = db.Exec(`ALTER TABLE todos ADD COLUMN updated_at DATETIME`)
+		_, err = db.ExecContext(ctx, `ALTER TABLE todos ADD COLUMN updated_at DATETIME`)
 		if err != nil {
 			return fmt.Errorf("failed to add updated_at column: %w", err)
 		}
 
 		// Set updated_at to created_at for existing rows
-		_, err = db.Exec(`UPDATE todos SET updated_at = created_at WHERE updated_at IS NULL`)
+		_, err = db.ExecContext(ctx, `UPDATE todos SET updated_at = created_at WHERE updated_at IS NULL`)
 		if err != nil {
 			return fmt.Errorf("failed to populate updated_at column: %w", err)
 		}
 	}
 
+	// Migration: Add remote_url column to projects if it doesn't exist
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('projects') WHERE name='remote_url'`).Scan(&columnCount)
+	if err != nil {
+		return fmt.Errorf("failed to check for remote_url column: %w", err)
+	}
+
+	if columnCount == 0 {
+		if _, err := db.ExecContext(ctx, `ALTER TABLE projects ADD COLUMN remote_url TEXT`); err != nil {
+			return fmt.Errorf("failed to add remote_url column: %w", err)
+		}
+	}
+
+	// Migration: Add branch column to todos if it doesn't exist
+	err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('todos') WHERE name='branch'`).Scan(&columnCount)
+	if err != nil {
+		return fmt.Errorf("failed to check for branch column: %w", err)
+	}
+
+	if columnCount == 0 {
+		if _, err := db.ExecContext(ctx, `ALTER TABLE todos ADD COLUMN branch TEXT`); err != nil {
+			return fmt.Errorf("failed to add branch column: %w", err)
+		}
+	}
+
+	// Migration: Add estimate columns to todos if they don't exist
+	for _, column := range []string{"estimate_points REAL", "estimate_seconds INTEGER"} {
+		name := strings.Fields(column)[0]
+		err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info('todos') WHERE name=?`, name).Scan(&columnCount)
+		if err != nil {
+			return fmt.Errorf("failed to check for %s column: %w", name, err)
+		}
+
+		if columnCount == 0 {
+			if _, err := db.ExecContext(ctx, `ALTER TABLE todos ADD COLUMN `+column); err != nil {
+				return fmt.Errorf("failed to add %s column: %w", name, err)
+			}
+		}
+	}
+
+	// Indexes created here so they run after the columns exist on upgraded databases
+	if _, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_projects_remote_url ON projects(remote_url)`); err != nil {
+		return fmt.Errorf("failed to create remote_url index: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `CREATE INDEX IF NOT EXISTS idx_todos_branch ON todos(project_id, branch)`); err != nil {
+		return fmt.Errorf("failed to create branch index: %w", err)
+	}
+
+	version, err := schemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	// Migration 2: rewrite times stored in Go's time.String format, which SQLite's date
+	// functions can't read. Older versions of toki may still write that format
+	// afterwards, so queries read both through sqlTime.
+	if version < 2 {
+		if err := normalizeTimes(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	// Record the version, unless a newer toki already migrated this database further
+	if version < SchemaVersion {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`PRAGMA user_version = %d`, SchemaVersion)); err != nil {
+			return fmt.Errorf("failed to record schema version: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// timeColumns are the DATETIME columns, by table.
+var timeColumns = map[string][]string{
+	"projects":     {"created_at"},
+	"todos":        {"created_at", "updated_at", "completed_at", "due_date"},
+	"todo_commits": {"created_at"},
+	"views":        {"created_at"},
+	"time_entries": {"started_at", "ended_at"},
+}
+
+// legacyTime matches times in time.String format: "2006-01-02 15:04:05.999999999 -0700
+// MST", possibly followed by a monotonic clock reading.
+const legacyTime = `[0-9][0-9][0-9][0-9]-[0-9][0-9]-[0-9][0-9] [0-9][0-9]:[0-9][0-9]:[0-9][0-9]* [+-][0-9][0-9][0-9][0-9] *`
+
+// sqlLegacyTime converts a legacyTime column to "2006-01-02 15:04:05.999999999-07:00",
+// the format the driver now writes, keeping the date, time of day, and zone offset.
+func sqlLegacyTime(column string) string {
+	// "15:04:05.999 -0700 MST" after the date, split at its first space
+	clock := "substr(" + column + ", 12)"
+	space := "instr(" + clock + ", ' ')"
+	offset := "substr(" + clock + ", " + space + " + 1, 5)"
+	return "substr(" + column + ", 1, 11) || substr(" + clock + ", 1, " + space + " - 1)" +
+		" || substr(" + offset + ", 1, 3) || ':' || substr(" + offset + ", 4, 2)"
+}
+
+// sqlTime reads a time column in either format, for SQLite's date functions.
+func sqlTime(column string) string {
+	return "(CASE WHEN " + column + " GLOB '" + legacyTime + "' THEN " + sqlLegacyTime(column) + " ELSE " + column + " END)"
+}
+
+// normalizeTimes rewrites the legacyTime values in every time column.
+func normalizeTimes(ctx context.Context, db Querier) error {
+	for table, columns := range timeColumns {
+		for _, column := range columns {
+			query := `UPDATE ` + table + ` SET ` + column + ` = ` + sqlLegacyTime(column) + ` WHERE ` + column + ` GLOB ?`
+			if _, err := db.ExecContext(ctx, query, legacyTime); err != nil {
+				return fmt.Errorf("failed to rewrite %s.%s: %w", table, column, err)
+			}
+		}
+	}
 	return nil
 }
+
+// schemaVersion returns the schema version recorded in the database: 0 for a new
+// database or one last migrated before versions were recorded.
+func schemaVersion(ctx context.Context, db Querier) (int, error) {
+	var version int
+	if err := db.QueryRowContext(ctx, `PRAGMA user_version`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
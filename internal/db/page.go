@@ -0,0 +1,80 @@
+// ABOUTME: Pagination of todo lists with opaque cursors
+// ABOUTME: Keyset cursors from the sort columns for queried pages, offsets for sorted slices
+
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Page asks for at most Limit todos after Cursor, skipping Offset todos first. A zero
+// Limit means no limit and an empty Cursor starts at the first todo. Offset suits
+// numbered pages; cursors don't shift when todos are added or removed between pages.
+type Page struct {
+	Limit  int
+	Cursor string
+	Offset int
+}
+
+// ErrInvalidCursor is returned for cursors that did not come from a previous page of
+// the same listing.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursors are base64 so clients treat them as opaque: "k" cursors hold the created_at
+// text and id of the last todo in newest-first order, "s" cursors the order and the
+// sort columns of the last todo in a sorted query, and "o" cursors an offset into a
+// sorted list.
+const (
+	keysetCursor = "k"
+	sortedCursor = "s"
+	offsetCursor = "o"
+)
+
+func encodeCursor(kind string, fields ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + "\x00" + strings.Join(fields, "\x00")))
+}
+
+func decodeCursor(cursor, kind string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w '%s'", ErrInvalidCursor, cursor)
+	}
+	fields := strings.Split(string(raw), "\x00")
+	if len(fields) != n+1 || fields[0] != kind {
+		if fields[0] == keysetCursor || fields[0] == sortedCursor || fields[0] == offsetCursor {
+			return nil, fmt.Errorf("%w '%s': it belongs to a listing in a different order", ErrInvalidCursor, cursor)
+		}
+		return nil, fmt.Errorf("%w '%s'", ErrInvalidCursor, cursor)
+	}
+	return fields[1:], nil
+}
+
+// PageSlice returns the page of items, which are already in their final order, and the
+// cursor of the next page, empty on the last one. Its cursors are offsets, so they
+// can't be passed to keyset-paginated queries.
+func PageSlice[T any](items []T, page Page) ([]T, string, error) {
+	start := 0
+	if page.Cursor != "" {
+		fields, err := decodeCursor(page.Cursor, offsetCursor, 1)
+		if err != nil {
+			return nil, "", err
+		}
+		if start, err = strconv.Atoi(fields[0]); err != nil || start < 0 {
+			return nil, "", fmt.Errorf("%w '%s'", ErrInvalidCursor, page.Cursor)
+		}
+	}
+	start += max(page.Offset, 0)
+	if start >= len(items) {
+		return items[:0], "", nil
+	}
+
+	items = items[start:]
+	if page.Limit <= 0 || len(items) <= page.Limit {
+		return items, "", nil
+	}
+	return items[:page.Limit], encodeCursor(offsetCursor, strconv.Itoa(start+page.Limit)), nil
+}
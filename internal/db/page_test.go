@@ -0,0 +1,197 @@
+// ABOUTME: Tests for paging through todos with cursors
+// ABOUTME: Covers keyset pages of plain and sorted queries and offset pages of slices
+
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/sorting"
+)
+
+func TestQueryTodosPage(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("api", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two todos share a creation time, so the id has to break the tie
+	base := time.Now().Add(-time.Hour)
+	var created []*models.Todo
+	for i, offset := range []int{0, 1, 2, 2, 3} {
+		todo := models.NewTodo(project.ID, "todo")
+		todo.CreatedAt = base.Add(time.Duration(offset) * time.Minute)
+		if i == 4 {
+			todo.MarkDone()
+		}
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+		if err := AddTagToTodo(t.Context(), db, todo.ID, "bug"); err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, todo)
+	}
+
+	all, _, err := QueryTodosWithTags(t.Context(), db, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paged []*models.Todo
+	page := Page{Limit: 2}
+	for pages := 0; ; pages++ {
+		if pages == len(created) {
+			t.Fatal("Paging did not stop")
+		}
+		todos, todoTags, next, err := QueryTodosPage(t.Context(), db, nil, Order{}, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) > 2 {
+			t.Fatalf("Expected at most 2 todos per page, got %d", len(todos))
+		}
+		for _, todo := range todos {
+			if len(todoTags[todo.ID]) != 1 {
+				t.Errorf("Expected the tag of todo %s", todo.ID)
+			}
+		}
+		paged = append(paged, todos...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+
+	if len(paged) != len(all) {
+		t.Fatalf("Expected %d todos across pages, got %d", len(all), len(paged))
+	}
+	for i := range all {
+		if paged[i].ID != all[i].ID {
+			t.Errorf("Todo %d: expected %s, got %s", i, all[i].ID, paged[i].ID)
+		}
+	}
+
+	// A filter applies on every page
+	pending := filter.Criteria{Done: new(bool)}.Node()
+	todos, _, next, err := QueryTodosPage(t.Context(), db, pending, Order{}, Page{Limit: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 4 || next != "" {
+		t.Errorf("Expected the 4 pending todos on one page, got %d with cursor %q", len(todos), next)
+	}
+
+	// An exact fit has no next page
+	if _, _, next, err := QueryTodosPage(t.Context(), db, nil, Order{}, Page{Limit: 5}); err != nil || next != "" {
+		t.Errorf("Expected no next page, got cursor %q and error %v", next, err)
+	}
+}
+
+func TestQueryTodosPageSorted(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("api", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	add := func(description, priority string) {
+		t.Helper()
+		todo := models.NewTodo(project.ID, description)
+		if priority != "" {
+			todo.Priority = &priority
+		}
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("none", "")
+	add("low", "low")
+	add("high", "high")
+	add("medium", "medium")
+
+	order := Order{Key: sorting.KeyPriority}
+	todos, _, next, err := QueryTodosPage(t.Context(), db, nil, order, Page{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].Description != "high" || todos[1].Description != "medium" || next == "" {
+		t.Fatalf("Expected high and medium on the first page, got %v with cursor %q", todos, next)
+	}
+
+	// A todo sorting before the cursor doesn't shift the next page
+	add("urgent", "high")
+	todos, _, next, err = QueryTodosPage(t.Context(), db, nil, order, Page{Limit: 2, Cursor: next})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].Description != "low" || todos[1].Description != "none" || next != "" {
+		t.Errorf("Expected low and then none, without a priority, on the last page, got %v with cursor %q", todos, next)
+	}
+
+	// Another order can't resume the listing
+	_, _, cursor, err := QueryTodosPage(t.Context(), db, nil, order, Page{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range []Order{{}, {Key: sorting.KeyDue}, {Key: sorting.KeyPriority, Reverse: true}} {
+		if _, _, _, err := QueryTodosPage(t.Context(), db, nil, other, Page{Limit: 1, Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Order %+v: expected ErrInvalidCursor, got %v", other, err)
+		}
+	}
+}
+
+func TestQueryTodosPageInvalidCursor(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	_, offset, err := PageSlice([]int{1, 2, 3}, Page{Limit: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cursor := range []string{"not a cursor!", "bm9wZQ", offset} {
+		if _, _, _, err := QueryTodosPage(t.Context(), db, nil, Order{}, Page{Limit: 1, Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Cursor %q: expected ErrInvalidCursor, got %v", cursor, err)
+		}
+		if _, _, _, err := QueryTodosPage(t.Context(), db, nil, Order{Key: sorting.KeyUrgency}, Page{Limit: 1, Cursor: cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Cursor %q: expected ErrInvalidCursor sorted, got %v", cursor, err)
+		}
+	}
+}
+
+func TestPageSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	var got []int
+	page := Page{Limit: 2}
+	for {
+		slice, next, err := PageSlice(items, page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, slice...)
+		if next == "" {
+			break
+		}
+		page.Cursor = next
+	}
+	if len(got) != len(items) {
+		t.Errorf("Expected %v across pages, got %v", items, got)
+	}
+
+	if slice, next, err := PageSlice(items, Page{}); err != nil || len(slice) != 5 || next != "" {
+		t.Errorf("Expected every item without a limit, got %v, %q, %v", slice, next, err)
+	}
+	if _, _, err := PageSlice(items, Page{Cursor: "bm9wZQ"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
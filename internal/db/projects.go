@@ -4,17 +4,20 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/harper/toki/internal/git"
 	"github.com/harper/toki/internal/models"
 )
 
 // CreateProject inserts a new project into the database.
-func CreateProject(db *sql.DB, project *models.Project) error {
-	query := `INSERT INTO projects (id, name, directory_path, created_at) VALUES (?, ?, ?, ?)`
-	_, err := db.Exec(query, project.ID.String(), project.Name, project.DirectoryPath, project.CreatedAt)
+func CreateProject(ctx context.Context, db Querier, project *models.Project) error {
+	query := `INSERT INTO projects (id, name, directory_path, remote_url, created_at) VALUES (?, ?, ?, ?, ?)`
+	_, err := exec(ctx, db, query, project.ID.String(), project.Name, project.DirectoryPath, project.RemoteURL, project.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
@@ -22,16 +25,16 @@ func CreateProject(db *sql.DB, project *models.Project) error {
 }
 
 // GetProjectByID retrieves a project by its UUID.
-func GetProjectByID(db *sql.DB, id uuid.UUID) (*models.Project, error) {
-	query := `SELECT id, name, directory_path, created_at FROM projects WHERE id = ?`
+func GetProjectByID(ctx context.Context, db Querier, id uuid.UUID) (*models.Project, error) {
+	query := `SELECT id, name, directory_path, remote_url, created_at FROM projects WHERE id = ?`
 
 	var project models.Project
 	var idStr string
 
-	err := db.QueryRow(query, id.String()).Scan(&idStr, &project.Name, &project.DirectoryPath, &project.CreatedAt)
+	err := db.QueryRowContext(ctx, query, id.String()).Scan(&idStr, &project.Name, &project.DirectoryPath, &project.RemoteURL, &project.CreatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("project not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("project %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
@@ -41,16 +44,16 @@ func GetProjectByID(db *sql.DB, id uuid.UUID) (*models.Project, error) {
 }
 
 // GetProjectByName retrieves a project by its name.
-func GetProjectByName(db *sql.DB, name string) (*models.Project, error) {
-	query := `SELECT id, name, directory_path, created_at FROM projects WHERE name = ?`
+func GetProjectByName(ctx context.Context, db Querier, name string) (*models.Project, error) {
+	query := `SELECT id, name, directory_path, remote_url, created_at FROM projects WHERE name = ?`
 
 	var project models.Project
 	var idStr string
 
-	err := db.QueryRow(query, name).Scan(&idStr, &project.Name, &project.DirectoryPath, &project.CreatedAt)
+	err := db.QueryRowContext(ctx, query, name).Scan(&idStr, &project.Name, &project.DirectoryPath, &project.RemoteURL, &project.CreatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("project not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("project %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
@@ -59,17 +62,20 @@ func GetProjectByName(db *sql.DB, name string) (*models.Project, error) {
 	return &project, nil
 }
 
-// GetProjectByPath retrieves a project by its directory path.
-func GetProjectByPath(db *sql.DB, path string) (*models.Project, error) {
-	query := `SELECT id, name, directory_path, created_at FROM projects WHERE directory_path = ?`
+// GetProjectByPath retrieves a project by its directory path or one of its additional paths.
+func GetProjectByPath(ctx context.Context, db Querier, path string) (*models.Project, error) {
+	query := `SELECT id, name, directory_path, remote_url, created_at FROM projects
+	          WHERE directory_path = ? OR id IN (SELECT project_id FROM project_paths WHERE path = ?)
+	          ORDER BY directory_path = ? DESC
+	          LIMIT 1`
 
 	var project models.Project
 	var idStr string
 
-	err := db.QueryRow(query, path).Scan(&idStr, &project.Name, &project.DirectoryPath, &project.CreatedAt)
+	err := db.QueryRowContext(ctx, query, path, path, path).Scan(&idStr, &project.Name, &project.DirectoryPath, &project.RemoteURL, &project.CreatedAt)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("project not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("project %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to get project: %w", err)
 	}
@@ -78,11 +84,78 @@ func GetProjectByPath(db *sql.DB, path string) (*models.Project, error) {
 	return &project, nil
 }
 
+// GetProjectByRemote retrieves the oldest project linked to a normalized remote URL.
+func GetProjectByRemote(ctx context.Context, db Querier, remoteURL string) (*models.Project, error) {
+	query := `SELECT id, name, directory_path, remote_url, created_at FROM projects
+	          WHERE remote_url = ? ORDER BY created_at LIMIT 1`
+
+	var project models.Project
+	var idStr string
+
+	err := db.QueryRowContext(ctx, query, remoteURL).Scan(&idStr, &project.Name, &project.DirectoryPath, &project.RemoteURL, &project.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("project %w", ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	project.ID, _ = uuid.Parse(idStr)
+	return &project, nil
+}
+
+// FindProjectForDir looks up the project for the git repository containing dir, preferring
+// a project linked to the repository's origin URL over one registered for its path. When a
+// project is matched by remote from a new location, that location is recorded as an
+// additional project path, unless a project already has it. The detected repository is returned alongside ErrNotFound when
+// no project matches, and is nil when dir is not inside a git repository.
+func FindProjectForDir(ctx context.Context, db Querier, dir string, opts git.Options) (*models.Project, *git.Repo, error) {
+	repo, err := git.DetectRepo(dir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if repo.RemoteURL != "" {
+		project, err := GetProjectByRemote(ctx, db, repo.RemoteURL)
+		if err == nil {
+			if err := recordRemotePath(ctx, db, project.ID, repo.Root); err != nil {
+				return nil, repo, err
+			}
+			return project, repo, nil
+		}
+		if !errors.Is(err, ErrNotFound) {
+			return nil, repo, err
+		}
+	}
+
+	project, err := GetProjectByPath(ctx, db, repo.Root)
+	if err != nil {
+		return nil, repo, err
+	}
+
+	return project, repo, nil
+}
+
+// recordRemotePath records path for the project found by remote, unless any project
+// already has it: lookups stay reads once a location is known, and a path registered
+// for another project is never taken from it.
+func recordRemotePath(ctx context.Context, db Querier, id uuid.UUID, path string) error {
+	if _, err := GetProjectByPath(ctx, db, path); !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	// Another process may record the path in the meantime; the first one wins
+	query := `INSERT INTO project_paths (path, project_id) VALUES (?, ?) ON CONFLICT(path) DO NOTHING`
+	if _, err := exec(ctx, db, query, path, id.String()); err != nil {
+		return fmt.Errorf("failed to add project path: %w", err)
+	}
+	return nil
+}
+
 // ListProjects returns all projects.
-func ListProjects(db *sql.DB) ([]*models.Project, error) {
-	query := `SELECT id, name, directory_path, created_at FROM projects ORDER BY name`
+func ListProjects(ctx context.Context, db Querier) ([]*models.Project, error) {
+	query := `SELECT id, name, directory_path, remote_url, created_at FROM projects ORDER BY name`
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list projects: %w", err)
 	}
@@ -93,7 +166,7 @@ func ListProjects(db *sql.DB) ([]*models.Project, error) {
 		var project models.Project
 		var idStr string
 
-		if err := rows.Scan(&idStr, &project.Name, &project.DirectoryPath, &project.CreatedAt); err != nil {
+		if err := rows.Scan(&idStr, &project.Name, &project.DirectoryPath, &project.RemoteURL, &project.CreatedAt); err != nil {
 			return nil, fmt.Errorf("failed to scan project: %w", err)
 		}
 
@@ -104,22 +177,110 @@ func ListProjects(db *sql.DB) ([]*models.Project, error) {
 	return projects, nil
 }
 
+// GetProjectMap returns every project keyed by ID, for looking up the projects of many
+// todos without a query each.
+func GetProjectMap(ctx context.Context, db Querier) (map[uuid.UUID]*models.Project, error) {
+	projects, err := ListProjects(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	projectMap := make(map[uuid.UUID]*models.Project, len(projects))
+	for _, project := range projects {
+		projectMap[project.ID] = project
+	}
+	return projectMap, nil
+}
+
 // UpdateProjectPath updates the directory path for a project.
-func UpdateProjectPath(db *sql.DB, id uuid.UUID, path *string) error {
+func UpdateProjectPath(ctx context.Context, db Querier, id uuid.UUID, path *string) error {
 	query := `UPDATE projects SET directory_path = ? WHERE id = ?`
-	_, err := db.Exec(query, path, id.String())
+	_, err := exec(ctx, db, query, path, id.String())
 	if err != nil {
 		return fmt.Errorf("failed to update project path: %w", err)
 	}
 	return nil
 }
 
-// DeleteProject deletes a project (cascades to todos).
-func DeleteProject(db *sql.DB, id uuid.UUID) error {
-	query := `DELETE FROM projects WHERE id = ?`
-	_, err := db.Exec(query, id.String())
+// UpdateProjectRemote links a project to a normalized remote URL (nil clears it).
+func UpdateProjectRemote(ctx context.Context, db Querier, id uuid.UUID, remoteURL *string) error {
+	query := `UPDATE projects SET remote_url = ? WHERE id = ?`
+	_, err := exec(ctx, db, query, remoteURL, id.String())
+	if err != nil {
+		return fmt.Errorf("failed to update project remote: %w", err)
+	}
+	return nil
+}
+
+// AddProjectPath records an additional directory for a project. Paths equal to the
+// project's primary directory are ignored, and a path already recorded for another
+// project is moved to this one.
+func AddProjectPath(ctx context.Context, db Querier, id uuid.UUID, path string) error {
+	query := `INSERT INTO project_paths (path, project_id)
+	          SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM projects WHERE id = ? AND directory_path = ?)
+	          ON CONFLICT(path) DO UPDATE SET project_id = excluded.project_id`
+	_, err := exec(ctx, db, query, path, id.String(), id.String(), path)
+	if err != nil {
+		return fmt.Errorf("failed to add project path: %w", err)
+	}
+	return nil
+}
+
+// RemoveProjectPath forgets an additional directory for a project.
+func RemoveProjectPath(ctx context.Context, db Querier, id uuid.UUID, path string) error {
+	query := `DELETE FROM project_paths WHERE project_id = ? AND path = ?`
+	_, err := exec(ctx, db, query, id.String(), path)
 	if err != nil {
-		return fmt.Errorf("failed to delete project: %w", err)
+		return fmt.Errorf("failed to remove project path: %w", err)
 	}
 	return nil
 }
+
+// ListProjectPaths returns the additional directories recorded for a project.
+func ListProjectPaths(ctx context.Context, db Querier, id uuid.UUID) ([]string, error) {
+	query := `SELECT path FROM project_paths WHERE project_id = ? ORDER BY path`
+
+	rows, err := db.QueryContext(ctx, query, id.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project paths: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan project path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// deleteProjectStatements remove a project and everything that belongs to it, children
+// first. They do not rely on ON DELETE CASCADE, which only fires on connections that
+// have foreign keys enabled.
+var deleteProjectStatements = []string{
+	`DELETE FROM todo_tags WHERE todo_id IN (SELECT id FROM todos WHERE project_id = ?1)`,
+	`DELETE FROM todo_commits WHERE todo_id IN (SELECT id FROM todos WHERE project_id = ?1)`,
+	`DELETE FROM time_entries WHERE todo_id IN (SELECT id FROM todos WHERE project_id = ?1)`,
+	`DELETE FROM code_comments
+	 WHERE project_id = ?1 OR todo_id IN (SELECT id FROM todos WHERE project_id = ?1)`,
+	`DELETE FROM todos WHERE project_id = ?1`,
+	`DELETE FROM project_paths WHERE project_id = ?1`,
+	`DELETE FROM projects WHERE id = ?1`,
+}
+
+// DeleteProject deletes a project with its paths, todos, and their tags, commits,
+// comments, and time entries, in one transaction.
+func DeleteProject(ctx context.Context, db Querier, id uuid.UUID) error {
+	return inTx(ctx, db, func(q Querier) error {
+		for _, statement := range deleteProjectStatements {
+			if _, err := q.ExecContext(ctx, statement, id.String()); err != nil {
+				return fmt.Errorf("failed to delete project: %w", err)
+			}
+		}
+		return nil
+	})
+}
@@ -1,20 +1,24 @@
 // ABOUTME: Tests for project database operations
-// ABOUTME: Covers CRUD operations and path-based lookups
+// ABOUTME: Covers CRUD operations, path-based lookups, and remote matching
 
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/harper/toki/internal/git"
 	"github.com/harper/toki/internal/models"
 )
 
-func setupTestDB(t *testing.T) *sql.DB {
+func setupTestDB(t testing.TB) *sql.DB {
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
-	db, err := InitDB(dbPath)
+	db, err := InitDB(t.Context(), dbPath)
 	if err != nil {
 		t.Fatalf("Failed to init DB: %v", err)
 	}
@@ -28,12 +32,12 @@ func TestCreateProject(t *testing.T) {
 	path := "/home/user/project"
 	project := models.NewProject("test-project", &path)
 
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	// Verify it was created
-	retrieved, err := GetProjectByID(db, project.ID)
+	retrieved, err := GetProjectByID(t.Context(), db, project.ID)
 	if err != nil {
 		t.Fatalf("Failed to retrieve project: %v", err)
 	}
@@ -48,11 +52,11 @@ func TestGetProjectByName(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("findme", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
-	found, err := GetProjectByName(db, "findme")
+	found, err := GetProjectByName(t.Context(), db, "findme")
 	if err != nil {
 		t.Fatalf("Failed to get project: %v", err)
 	}
@@ -68,11 +72,11 @@ func TestGetProjectByPath(t *testing.T) {
 
 	path := "/home/user/myproject"
 	project := models.NewProject("myproject", &path)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
-	found, err := GetProjectByPath(db, path)
+	found, err := GetProjectByPath(t.Context(), db, path)
 	if err != nil {
 		t.Fatalf("Failed to get project by path: %v", err)
 	}
@@ -86,14 +90,14 @@ func TestListProjects(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() { _ = db.Close() }()
 
-	if err := CreateProject(db, models.NewProject("project1", nil)); err != nil {
+	if err := CreateProject(t.Context(), db, models.NewProject("project1", nil)); err != nil {
 		t.Fatal(err)
 	}
-	if err := CreateProject(db, models.NewProject("project2", nil)); err != nil {
+	if err := CreateProject(t.Context(), db, models.NewProject("project2", nil)); err != nil {
 		t.Fatal(err)
 	}
 
-	projects, err := ListProjects(db)
+	projects, err := ListProjects(t.Context(), db)
 	if err != nil {
 		t.Fatalf("Failed to list projects: %v", err)
 	}
@@ -108,17 +112,169 @@ func TestDeleteProject(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("todelete", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
-	err := DeleteProject(db, project.ID)
+	err := DeleteProject(t.Context(), db, project.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete project: %v", err)
 	}
 
-	_, err = GetProjectByID(db, project.ID)
+	_, err = GetProjectByID(t.Context(), db, project.ID)
 	if err == nil {
 		t.Error("Project should not exist after deletion")
 	}
 }
+
+func TestProjectAdditionalPaths(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	path := "/home/user/api"
+	project := models.NewProject("api", &path)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	other := "/home/user/api-copy"
+	if err := AddProjectPath(t.Context(), db, project.ID, other); err != nil {
+		t.Fatalf("Failed to add path: %v", err)
+	}
+	// The primary path is never recorded as an additional path
+	if err := AddProjectPath(t.Context(), db, project.ID, path); err != nil {
+		t.Fatalf("Failed to add primary path: %v", err)
+	}
+
+	paths, err := ListProjectPaths(t.Context(), db, project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || paths[0] != other {
+		t.Errorf("Expected [%s], got %v", other, paths)
+	}
+
+	found, err := GetProjectByPath(t.Context(), db, other)
+	if err != nil {
+		t.Fatalf("Failed to find project by additional path: %v", err)
+	}
+	if found.ID != project.ID {
+		t.Error("Found wrong project")
+	}
+
+	if err := RemoveProjectPath(t.Context(), db, project.ID, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetProjectByPath(t.Context(), db, other); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after removal, got %v", err)
+	}
+}
+
+func TestFindProjectForDirByRemote(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	remote := "github.com/acme/api"
+	project := models.NewProject("api", nil)
+	project.RemoteURL = &remote
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	clone := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(clone, ".git"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	config := "[remote \"origin\"]\n\turl = git@github.com:acme/api.git\n"
+	if err := os.WriteFile(filepath.Join(clone, ".git", "config"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	found, repo, err := FindProjectForDir(t.Context(), db, clone, git.Options{})
+	if err != nil {
+		t.Fatalf("Failed to find project by remote: %v", err)
+	}
+	if found.ID != project.ID {
+		t.Error("Found wrong project")
+	}
+
+	// The clone's location is remembered for path-based lookups
+	found, err = GetProjectByPath(t.Context(), db, repo.Root)
+	if err != nil {
+		t.Fatalf("Expected clone path to be recorded: %v", err)
+	}
+	if found.ID != project.ID {
+		t.Error("Recorded path points at wrong project")
+	}
+
+	// Once the location is known, lookups only read
+	if _, _, err := FindProjectForDir(t.Context(), readOnly{db}, clone, git.Options{}); err != nil {
+		t.Errorf("Expected a repeated lookup not to write: %v", err)
+	}
+}
+
+// readOnly is a Querier that refuses writes.
+type readOnly struct{ Querier }
+
+func (readOnly) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return nil, errors.New("unexpected write: " + query)
+}
+
+func TestFindProjectForDirKeepsOtherProjectsPaths(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	clone := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(clone, ".git"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	config := "[remote \"origin\"]\n\turl = git@github.com:acme/api.git\n"
+	if err := os.WriteFile(filepath.Join(clone, ".git", "config"), []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root, err := git.NormalizePath(clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote := "github.com/acme/api"
+	api := models.NewProject("api", nil)
+	api.RemoteURL = &remote
+	fork := models.NewProject("fork", nil)
+	for _, project := range []*models.Project{api, fork} {
+		if err := CreateProject(t.Context(), db, project); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddProjectPath(t.Context(), db, fork.ID, root); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, _, err := FindProjectForDir(t.Context(), db, clone, git.Options{}); err != nil || found.ID != api.ID {
+		t.Fatalf("Expected the remote's project, got %v (%v)", found, err)
+	}
+	if owner, err := GetProjectByPath(t.Context(), db, root); err != nil || owner.ID != fork.ID {
+		t.Errorf("Expected the path to stay with fork, got %v (%v)", owner, err)
+	}
+}
+
+func TestGetProjectMap(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	api := models.NewProject("api", nil)
+	web := models.NewProject("web", nil)
+	for _, project := range []*models.Project{api, web} {
+		if err := CreateProject(t.Context(), db, project); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projects, err := GetProjectMap(t.Context(), db)
+	if err != nil {
+		t.Fatalf("Failed to get project map: %v", err)
+	}
+	if len(projects) != 2 || projects[api.ID].Name != "api" || projects[web.ID].Name != "web" {
+		t.Errorf("Unexpected project map: %v", projects)
+	}
+}
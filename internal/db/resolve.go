@@ -0,0 +1,137 @@
+// ABOUTME: Reference resolution for todos and projects
+// ABOUTME: Accepts full UUIDs, unique UUID prefixes, and project names or paths
+
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+)
+
+// MinPrefixLength is the shortest UUID prefix accepted as a reference.
+const MinPrefixLength = 6
+
+// ErrNotFound is wrapped by lookups that match no record.
+var ErrNotFound = errors.New("not found")
+
+// RefCandidate describes one record matched by an ambiguous reference.
+type RefCandidate struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// AmbiguousRefError is returned when a reference matches more than one record.
+type AmbiguousRefError struct {
+	Kind       string
+	Ref        string
+	Candidates []RefCandidate
+}
+
+func (e *AmbiguousRefError) Error() string {
+	ids := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		ids[i] = c.ID[:8]
+	}
+	return fmt.Sprintf("ambiguous %s reference '%s', matches: %s", e.Kind, e.Ref, strings.Join(ids, ", "))
+}
+
+// ResolveTodoRef finds a todo by full UUID or unique UUID prefix.
+func ResolveTodoRef(ctx context.Context, db Querier, ref string) (*models.Todo, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		return GetTodoByID(ctx, db, id)
+	}
+	return GetTodoByPrefix(ctx, db, ref)
+}
+
+// ResolveProjectRef finds a project by full UUID, name, directory path, or unique UUID prefix.
+func ResolveProjectRef(ctx context.Context, db Querier, ref string) (*models.Project, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("project reference is required")
+	}
+
+	if id, err := uuid.Parse(ref); err == nil {
+		return GetProjectByID(ctx, db, id)
+	}
+
+	project, err := GetProjectByName(ctx, db, ref)
+	if err == nil || !errors.Is(err, ErrNotFound) {
+		return project, err
+	}
+
+	if LooksLikePath(ref) {
+		normalized, err := git.NormalizePath(ref)
+		if err != nil {
+			return nil, err
+		}
+		project, err := GetProjectByPath(ctx, db, normalized)
+		if err == nil || !errors.Is(err, ErrNotFound) {
+			return project, err
+		}
+	}
+
+	if len(ref) >= MinPrefixLength && IsUUIDPrefix(ref) {
+		return getProjectByPrefix(ctx, db, ref)
+	}
+
+	return nil, fmt.Errorf("project %w: %s", ErrNotFound, ref)
+}
+
+func getProjectByPrefix(ctx context.Context, db Querier, prefix string) (*models.Project, error) {
+	query := `SELECT id, name, directory_path, remote_url, created_at FROM projects WHERE id LIKE ? ORDER BY name`
+
+	rows, err := db.QueryContext(ctx, query, strings.ToLower(prefix)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query projects: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var matches []*models.Project
+	for rows.Next() {
+		var project models.Project
+		var idStr string
+		if err := rows.Scan(&idStr, &project.Name, &project.DirectoryPath, &project.RemoteURL, &project.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		project.ID, _ = uuid.Parse(idStr)
+		matches = append(matches, &project)
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("project %w with prefix: %s", ErrNotFound, prefix)
+	}
+
+	if len(matches) > 1 {
+		candidates := make([]RefCandidate, len(matches))
+		for i, p := range matches {
+			candidates[i] = RefCandidate{ID: p.ID.String(), Label: p.Name}
+		}
+		return nil, &AmbiguousRefError{Kind: "project", Ref: prefix, Candidates: candidates}
+	}
+
+	return matches[0], nil
+}
+
+// IsUUIDPrefix reports whether s could be the start of a UUID string.
+func IsUUIDPrefix(s string) bool {
+	for _, r := range s {
+		isHex := (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+		if !isHex && r != '-' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// LooksLikePath reports whether a project reference is meant as a directory.
+func LooksLikePath(s string) bool {
+	return filepath.IsAbs(s) || strings.HasPrefix(s, ".") || strings.ContainsRune(s, filepath.Separator)
+}
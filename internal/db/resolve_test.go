@@ -0,0 +1,135 @@
+// ABOUTME: Tests for todo and project reference resolution
+// ABOUTME: Covers UUIDs, prefixes, names, paths, and ambiguity errors
+
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+func TestResolveTodoRef(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	todo := models.NewTodo(project.ID, "resolve me")
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ref := range []string{todo.ID.String(), todo.ID.String()[:8]} {
+		found, err := ResolveTodoRef(t.Context(), db, ref)
+		if err != nil {
+			t.Fatalf("Failed to resolve %s: %v", ref, err)
+		}
+		if found.ID != todo.ID {
+			t.Errorf("Resolved %s to wrong todo", ref)
+		}
+	}
+
+	_, err := ResolveTodoRef(t.Context(), db, uuid.New().String())
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for unknown UUID, got %v", err)
+	}
+
+	_, err = ResolveTodoRef(t.Context(), db, "not-a-uuid")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for non-hex reference, got %v", err)
+	}
+}
+
+func TestResolveTodoRefAmbiguous(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	todo1 := models.NewTodo(project.ID, "first")
+	todo1.ID = uuid.MustParse("abcdef01-0000-0000-0000-000000000001")
+	todo2 := models.NewTodo(project.ID, "second")
+	todo2.ID = uuid.MustParse("abcdef02-0000-0000-0000-000000000002")
+	for _, todo := range []*models.Todo{todo1, todo2} {
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := ResolveTodoRef(t.Context(), db, "abcdef")
+	var ambiguous *AmbiguousRefError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("Expected AmbiguousRefError, got %v", err)
+	}
+	if len(ambiguous.Candidates) != 2 {
+		t.Errorf("Expected 2 candidates, got %d", len(ambiguous.Candidates))
+	}
+
+	found, err := ResolveTodoRef(t.Context(), db, "ABCDEF02")
+	if err != nil {
+		t.Fatalf("Failed to resolve longer prefix: %v", err)
+	}
+	if found.ID != todo2.ID {
+		t.Error("Resolved to wrong todo")
+	}
+}
+
+func TestResolveProjectRef(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	path := "/home/user/api"
+	project := models.NewProject("api", &path)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := []string{project.ID.String(), project.ID.String()[:8], "api", path}
+	for _, ref := range refs {
+		found, err := ResolveProjectRef(t.Context(), db, ref)
+		if err != nil {
+			t.Fatalf("Failed to resolve %s: %v", ref, err)
+		}
+		if found.ID != project.ID {
+			t.Errorf("Resolved %s to wrong project", ref)
+		}
+	}
+
+	_, err := ResolveProjectRef(t.Context(), db, "missing")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestResolveProjectRefAmbiguous(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project1 := models.NewProject("one", nil)
+	project1.ID = uuid.MustParse("12345601-0000-0000-0000-000000000001")
+	project2 := models.NewProject("two", nil)
+	project2.ID = uuid.MustParse("12345602-0000-0000-0000-000000000002")
+	for _, p := range []*models.Project{project1, project2} {
+		if err := CreateProject(t.Context(), db, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := ResolveProjectRef(t.Context(), db, "123456")
+	var ambiguous *AmbiguousRefError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("Expected AmbiguousRefError, got %v", err)
+	}
+	if ambiguous.Candidates[0].Label != "one" || ambiguous.Candidates[1].Label != "two" {
+		t.Errorf("Unexpected candidates: %+v", ambiguous.Candidates)
+	}
+}
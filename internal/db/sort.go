@@ -0,0 +1,150 @@
+// ABOUTME: SQL for todo listings sorted by due date, priority, creation, update, or urgency
+// ABOUTME: Mirrors package sorting so sorted pages can be limited and resumed in the query
+
+package db
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harper/toki/internal/sorting"
+)
+
+// Order is the order of a todo query. The zero Order lists todos newest first. Times
+// order to the millisecond, as SQLite's date functions have it.
+type Order struct {
+	Key     sorting.Key
+	Reverse bool
+	// Urgency weighs the score KeyUrgency sorts by.
+	Urgency sorting.Coefficients
+	// Now is when urgency is scored. Later pages score at the time of the first, which
+	// their cursor carries, so todos don't move between pages as they age.
+	Now time.Time
+}
+
+// sortColumns is how the todos subquery of a sorted query computes its order: missing
+// is true for todos lacking the value sorted on, which come last either way, and value
+// is the value, ascending unless desc.
+type sortColumns struct {
+	missing string
+	value   string
+	args    []any
+	desc    bool
+}
+
+// sortSQL returns the columns that put todos in order, as sorting.Sort would: most
+// pressing first, ties newest first.
+func (o Order) sortSQL() (sortColumns, error) {
+	var columns sortColumns
+	switch o.Key {
+	case sorting.KeyDue:
+		columns = sortColumns{missing: "t.due_date IS NULL", value: sqlJulianDay("t.due_date")}
+	case sorting.KeyPriority:
+		rank := "(CASE t.priority WHEN 'high' THEN 3 WHEN 'medium' THEN 2 WHEN 'low' THEN 1 ELSE 0 END)"
+		columns = sortColumns{missing: rank + " = 0", value: rank, desc: true}
+	case sorting.KeyCreated:
+		columns = sortColumns{missing: "0", value: sqlJulianDay("t.created_at"), desc: true}
+	case sorting.KeyUpdated:
+		columns = sortColumns{missing: "0", value: sqlJulianDay("t.updated_at"), desc: true}
+	case sorting.KeyUrgency:
+		value, args := sqlUrgency(o.Urgency, o.Now)
+		columns = sortColumns{missing: "0", value: value, args: args, desc: true}
+	default:
+		return sortColumns{}, fmt.Errorf("invalid sort '%s': must be one of %s", o.Key, strings.Join(sorting.KeyNames(), ", "))
+	}
+	columns.desc = columns.desc != o.Reverse
+	return columns, nil
+}
+
+// sqlUrgency returns an expression computing sorting.Urgency for the todo t at now.
+func sqlUrgency(c sorting.Coefficients, now time.Time) (string, []any) {
+	days := func(column string) string {
+		return "(? - " + sqlJulianDay(column) + ")"
+	}
+	tagCount := "(SELECT COUNT(*) FROM todo_tags WHERE todo_id = t.id)"
+
+	expr := `(CASE t.priority WHEN 'high' THEN ? WHEN 'medium' THEN ? WHEN 'low' THEN ? ELSE 0 END)
+	 + (CASE WHEN t.due_date IS NULL THEN 0
+	         WHEN ` + days("t.due_date") + ` >= 7 THEN ?
+	         WHEN ` + days("t.due_date") + ` >= -14 THEN ? * ((` + days("t.due_date") + ` + 14) * 0.8 / 21 + 0.2)
+	         ELSE ? * 0.2 END)
+	 + ? * min(1, max(0, ` + days("t.created_at") + ` / ?))
+	 + (CASE ` + tagCount + ` WHEN 0 THEN 0 WHEN 1 THEN ? * 0.8 WHEN 2 THEN ? * 0.9 ELSE ? END)`
+	jd := julianDay(now)
+	args := []any{
+		c.PriorityHigh, c.PriorityMedium, c.PriorityLow,
+		jd, c.Due, jd, c.Due, jd, c.Due,
+		c.Age, jd, c.AgeMax,
+		c.Tags, c.Tags, c.Tags,
+	}
+
+	if len(c.Tag) > 0 {
+		names := make([]string, 0, len(c.Tag))
+		for name := range c.Tag {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		expr += `
+	 + (SELECT COALESCE(SUM(CASE tg.name` + strings.Repeat(" WHEN ? THEN ?", len(names)) + ` ELSE 0 END), 0)
+	    FROM todo_tags tt INNER JOIN tags tg ON tg.id = tt.tag_id
+	    WHERE tt.todo_id = t.id)`
+		for _, name := range names {
+			args = append(args, name, c.Tag[name])
+		}
+	}
+	return "(" + expr + ")", args
+}
+
+// decodeSortedCursor reads a sorted query's cursor, returning the order with the time
+// urgency was scored at and the sort column values of the last todo.
+func decodeSortedCursor(cursor string, order Order) (Order, []any, error) {
+	fields, err := decodeCursor(cursor, sortedCursor, 7)
+	if err != nil {
+		return Order{}, nil, err
+	}
+	if fields[0] != string(order.Key) || fields[1] != strconv.FormatBool(order.Reverse) {
+		return Order{}, nil, fmt.Errorf("%w '%s': it belongs to a listing in a different order", ErrInvalidCursor, cursor)
+	}
+
+	now, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return Order{}, nil, fmt.Errorf("%w '%s'", ErrInvalidCursor, cursor)
+	}
+	missing, err := strconv.ParseBool(fields[3])
+	if err != nil {
+		return Order{}, nil, fmt.Errorf("%w '%s'", ErrInvalidCursor, cursor)
+	}
+	value, err := strconv.ParseFloat(fields[4], 64)
+	if err != nil {
+		return Order{}, nil, fmt.Errorf("%w '%s'", ErrInvalidCursor, cursor)
+	}
+	order.Now = time.UnixMilli(now)
+	return order, []any{missing, value, fields[5], fields[6]}, nil
+}
+
+// encodeSortedCursor returns the cursor of the page after the todo with the given sort
+// column values.
+func encodeSortedCursor(order Order, missing bool, value float64, created, id string) string {
+	return encodeCursor(sortedCursor, string(order.Key), strconv.FormatBool(order.Reverse),
+		strconv.FormatInt(order.Now.UnixMilli(), 10), strconv.FormatBool(missing),
+		strconv.FormatFloat(value, 'g', -1, 64), created, id)
+}
+
+// sqlAfter returns a condition matching the rows that come after values in the order of
+// columns, each descending where desc says.
+func sqlAfter(columns []string, desc []bool, values []any) (string, []any) {
+	op := ">"
+	if desc[0] {
+		op = "<"
+	}
+	if len(columns) == 1 {
+		return columns[0] + " " + op + " ?", values[:1]
+	}
+	rest, args := sqlAfter(columns[1:], desc[1:], values[1:])
+	cond := "(" + columns[0] + " " + op + " ? OR (" + columns[0] + " = ? AND " + rest + "))"
+	return cond, append([]any{values[0], values[0]}, args...)
+}
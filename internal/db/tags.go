@@ -4,64 +4,54 @@
 package db
 
 import (
-	"database/sql"
-	"errors"
+	"context"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/harper/toki/internal/models"
 )
 
-// GetOrCreateTag retrieves a tag by name or creates it if it doesn't exist.
-func GetOrCreateTag(db *sql.DB, name string) (*models.Tag, error) {
-	// Try to get existing tag
-	var tag models.Tag
-	err := db.QueryRow("SELECT id, name FROM tags WHERE name = ?", name).Scan(&tag.ID, &tag.Name)
-
-	if err == nil {
-		return &tag, nil
-	}
-
-	if !errors.Is(err, sql.ErrNoRows) {
-		return nil, fmt.Errorf("failed to query tag: %w", err)
-	}
-
-	// Create new tag
-	result, err := db.Exec("INSERT INTO tags (name) VALUES (?)", name)
+// GetOrCreateTag retrieves a tag by name or creates it if it doesn't exist. It is a
+// single upsert, so concurrent callers creating the same tag all get the one row.
+func GetOrCreateTag(ctx context.Context, db Querier, name string) (*models.Tag, error) {
+	query := `INSERT INTO tags (name) VALUES (?)
+	          ON CONFLICT (name) DO UPDATE SET name = excluded.name
+	          RETURNING id`
+
+	tag := models.Tag{Name: name}
+	err := write(ctx, db, func() error {
+		return db.QueryRowContext(ctx, query, name).Scan(&tag.ID)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create tag: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get tag ID: %w", err)
-	}
-
-	return &models.Tag{ID: id, Name: name}, nil
+	return &tag, nil
 }
 
-// AddTagToTodo associates a tag with a todo.
-func AddTagToTodo(db *sql.DB, todoID uuid.UUID, tagName string) error {
-	tag, err := GetOrCreateTag(db, tagName)
-	if err != nil {
-		return err
-	}
-
-	query := `INSERT OR IGNORE INTO todo_tags (todo_id, tag_id) VALUES (?, ?)`
-	_, err = db.Exec(query, todoID.String(), tag.ID)
-	if err != nil {
-		return fmt.Errorf("failed to add tag to todo: %w", err)
-	}
+// AddTagToTodo associates a tag with a todo, creating the tag in the same transaction.
+func AddTagToTodo(ctx context.Context, db Querier, todoID uuid.UUID, tagName string) error {
+	return inTx(ctx, db, func(q Querier) error {
+		tag, err := GetOrCreateTag(ctx, q, tagName)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		query := `INSERT OR IGNORE INTO todo_tags (todo_id, tag_id) VALUES (?, ?)`
+		if _, err := q.ExecContext(ctx, query, todoID.String(), tag.ID); err != nil {
+			return fmt.Errorf("failed to add tag to todo: %w", err)
+		}
+		return nil
+	})
 }
 
 // RemoveTagFromTodo removes a tag association from a todo.
-func RemoveTagFromTodo(db *sql.DB, todoID uuid.UUID, tagName string) error {
+func RemoveTagFromTodo(ctx context.Context, db Querier, todoID uuid.UUID, tagName string) error {
 	query := `DELETE FROM todo_tags
 	          WHERE todo_id = ? AND tag_id = (SELECT id FROM tags WHERE name = ?)`
 
-	_, err := db.Exec(query, todoID.String(), tagName)
+	_, err := exec(ctx, db, query, todoID.String(), tagName)
 	if err != nil {
 		return fmt.Errorf("failed to remove tag from todo: %w", err)
 	}
@@ -69,15 +59,30 @@ func RemoveTagFromTodo(db *sql.DB, todoID uuid.UUID, tagName string) error {
 	return nil
 }
 
+// SetTodoTags replaces the tags of a todo with tagNames, in one transaction.
+func SetTodoTags(ctx context.Context, db Querier, todoID uuid.UUID, tagNames []string) error {
+	return inTx(ctx, db, func(q Querier) error {
+		if _, err := q.ExecContext(ctx, `DELETE FROM todo_tags WHERE todo_id = ?`, todoID.String()); err != nil {
+			return fmt.Errorf("failed to clear todo tags: %w", err)
+		}
+		for _, name := range tagNames {
+			if err := AddTagToTodo(ctx, q, todoID, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 // GetTodoTags retrieves all tags associated with a todo.
-func GetTodoTags(db *sql.DB, todoID uuid.UUID) ([]*models.Tag, error) {
+func GetTodoTags(ctx context.Context, db Querier, todoID uuid.UUID) ([]*models.Tag, error) {
 	query := `SELECT t.id, t.name
 	          FROM tags t
 	          INNER JOIN todo_tags tt ON t.id = tt.tag_id
 	          WHERE tt.todo_id = ?
 	          ORDER BY t.name`
 
-	rows, err := db.Query(query, todoID.String())
+	rows, err := db.QueryContext(ctx, query, todoID.String())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get todo tags: %w", err)
 	}
@@ -95,11 +100,71 @@ func GetTodoTags(db *sql.DB, todoID uuid.UUID) ([]*models.Tag, error) {
 	return tags, nil
 }
 
+// maxBatchIDs is the most todo IDs GetTagsForTodos binds as parameters; larger batches
+// read every tag association instead.
+const maxBatchIDs = 500
+
+// GetTagsForTodos returns the tags of each todo, sorted by name, in one query. Todos
+// without tags are absent from the map.
+func GetTagsForTodos(ctx context.Context, db Querier, todos []*models.Todo) (map[uuid.UUID][]*models.Tag, error) {
+	todoTags := make(map[uuid.UUID][]*models.Tag, len(todos))
+	if len(todos) == 0 {
+		return todoTags, nil
+	}
+
+	wanted := make(map[string]uuid.UUID, len(todos))
+	for _, todo := range todos {
+		wanted[todo.ID.String()] = todo.ID
+	}
+
+	query := `SELECT tt.todo_id, t.id, t.name
+	          FROM todo_tags tt
+	          INNER JOIN tags t ON t.id = tt.tag_id`
+	var args []any
+	if len(wanted) <= maxBatchIDs {
+		query += ` WHERE tt.todo_id IN (?` + strings.Repeat(", ?", len(wanted)-1) + `)`
+		for id := range wanted {
+			args = append(args, id)
+		}
+	}
+	query += ` ORDER BY t.name`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get todo tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tags := make(map[int64]*models.Tag)
+	for rows.Next() {
+		var todoIDStr, name string
+		var tagID int64
+		if err := rows.Scan(&todoIDStr, &tagID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		todoID, ok := wanted[todoIDStr]
+		if !ok {
+			continue
+		}
+		tag := tags[tagID]
+		if tag == nil {
+			tag = &models.Tag{ID: tagID, Name: name}
+			tags[tagID] = tag
+		}
+		todoTags[todoID] = append(todoTags[todoID], tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get todo tags: %w", err)
+	}
+
+	return todoTags, nil
+}
+
 // ListAllTags retrieves all tags in the database.
-func ListAllTags(db *sql.DB) ([]*models.Tag, error) {
+func ListAllTags(ctx context.Context, db Querier) ([]*models.Tag, error) {
 	query := `SELECT id, name FROM tags ORDER BY name`
 
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tags: %w", err)
 	}
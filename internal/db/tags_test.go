@@ -13,7 +13,7 @@ func TestGetOrCreateTag(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() { _ = db.Close() }()
 
-	tag, err := GetOrCreateTag(db, "urgent")
+	tag, err := GetOrCreateTag(t.Context(), db, "urgent")
 	if err != nil {
 		t.Fatalf("Failed to create tag: %v", err)
 	}
@@ -23,7 +23,7 @@ func TestGetOrCreateTag(t *testing.T) {
 	}
 
 	// Getting same tag should return same ID
-	tag2, err := GetOrCreateTag(db, "urgent")
+	tag2, err := GetOrCreateTag(t.Context(), db, "urgent")
 	if err != nil {
 		t.Fatalf("Failed to get existing tag: %v", err)
 	}
@@ -38,21 +38,21 @@ func TestAddTagToTodo(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	todo := models.NewTodo(project.ID, "test")
-	if err := CreateTodo(db, todo); err != nil {
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
 		t.Fatal(err)
 	}
 
-	err := AddTagToTodo(db, todo.ID, "backend")
+	err := AddTagToTodo(t.Context(), db, todo.ID, "backend")
 	if err != nil {
 		t.Fatalf("Failed to add tag: %v", err)
 	}
 
-	tags, err := GetTodoTags(db, todo.ID)
+	tags, err := GetTodoTags(t.Context(), db, todo.ID)
 	if err != nil {
 		t.Fatalf("Failed to get tags: %v", err)
 	}
@@ -71,25 +71,25 @@ func TestRemoveTagFromTodo(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	todo := models.NewTodo(project.ID, "test")
-	if err := CreateTodo(db, todo); err != nil {
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
 		t.Fatal(err)
 	}
 
-	if err := AddTagToTodo(db, todo.ID, "frontend"); err != nil {
+	if err := AddTagToTodo(t.Context(), db, todo.ID, "frontend"); err != nil {
 		t.Fatal(err)
 	}
 
-	err := RemoveTagFromTodo(db, todo.ID, "frontend")
+	err := RemoveTagFromTodo(t.Context(), db, todo.ID, "frontend")
 	if err != nil {
 		t.Fatalf("Failed to remove tag: %v", err)
 	}
 
-	tags, err := GetTodoTags(db, todo.ID)
+	tags, err := GetTodoTags(t.Context(), db, todo.ID)
 	if err != nil {
 		t.Fatalf("Failed to get tags: %v", err)
 	}
@@ -103,17 +103,17 @@ func TestListAllTags(t *testing.T) {
 	db := setupTestDB(t)
 	defer func() { _ = db.Close() }()
 
-	if _, err := GetOrCreateTag(db, "tag1"); err != nil {
+	if _, err := GetOrCreateTag(t.Context(), db, "tag1"); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := GetOrCreateTag(db, "tag2"); err != nil {
+	if _, err := GetOrCreateTag(t.Context(), db, "tag2"); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := GetOrCreateTag(db, "tag3"); err != nil {
+	if _, err := GetOrCreateTag(t.Context(), db, "tag3"); err != nil {
 		t.Fatal(err)
 	}
 
-	tags, err := ListAllTags(db)
+	tags, err := ListAllTags(t.Context(), db)
 	if err != nil {
 		t.Fatalf("Failed to list tags: %v", err)
 	}
@@ -122,3 +122,71 @@ func TestListAllTags(t *testing.T) {
 		t.Errorf("Expected at least 3 tags, got %d", len(tags))
 	}
 }
+
+func TestGetTagsForTodos(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	tagged := models.NewTodo(project.ID, "tagged")
+	other := models.NewTodo(project.ID, "other")
+	untagged := models.NewTodo(project.ID, "untagged")
+	for _, todo := range []*models.Todo{tagged, other, untagged} {
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tag := range []string{"zeta", "alpha"} {
+		if err := AddTagToTodo(t.Context(), db, tagged.ID, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := AddTagToTodo(t.Context(), db, other.ID, "alpha"); err != nil {
+		t.Fatal(err)
+	}
+
+	todoTags, err := GetTagsForTodos(t.Context(), db, []*models.Todo{tagged, untagged})
+	if err != nil {
+		t.Fatalf("Failed to get tags: %v", err)
+	}
+	if len(todoTags) != 1 {
+		t.Fatalf("Expected only the tagged todo in the map, got %v", todoTags)
+	}
+	if tags := todoTags[tagged.ID]; len(tags) != 2 || tags[0].Name != "alpha" || tags[1].Name != "zeta" {
+		t.Errorf("Expected tags sorted by name, got %v", tags)
+	}
+
+	empty, err := GetTagsForTodos(t.Context(), db, nil)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("Expected an empty map for no todos, got %v, %v", empty, err)
+	}
+}
+
+func TestGetTagsForTodosLargeBatch(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	todos := seedTodos(t, db, maxBatchIDs+100)
+	batch := todos[:maxBatchIDs+1]
+
+	todoTags, err := GetTagsForTodos(t.Context(), db, batch)
+	if err != nil {
+		t.Fatalf("Failed to get tags: %v", err)
+	}
+
+	wanted := uuidSet(batch)
+	for id := range todoTags {
+		if !wanted[id] {
+			t.Fatalf("Got tags for todo %s outside the batch", id)
+		}
+	}
+	for i, todo := range batch {
+		if got := len(todoTags[todo.ID]); got != i%3 {
+			t.Fatalf("Todo %d: expected %d tags, got %d", i, i%3, got)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// ABOUTME: Time entry database operations
+// ABOUTME: Starts and stops the single active timer, logs durations, and totals tracked time
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+// ErrNoTimer is returned when no timer is running.
+var ErrNoTimer = errors.New("no timer is running")
+
+const timeEntryColumns = `id, todo_id, started_at, ended_at, seconds`
+
+// scanTimeEntry scans timeEntryColumns followed by any extra columns into extra.
+func scanTimeEntry(row interface{ Scan(...any) error }, extra ...any) (*models.TimeEntry, error) {
+	entry := &models.TimeEntry{}
+	var todoID string
+	var seconds int64
+	dest := append([]any{&entry.ID, &todoID, &entry.StartedAt, &entry.EndedAt, &seconds}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	id, err := uuid.Parse(todoID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid todo id '%s': %w", todoID, err)
+	}
+	entry.TodoID = id
+	entry.Duration = time.Duration(seconds) * time.Second
+	return entry, nil
+}
+
+// ActiveTimer returns the running timer, or ErrNoTimer.
+func ActiveTimer(ctx context.Context, db Querier) (*models.TimeEntry, error) {
+	row := db.QueryRowContext(ctx, `SELECT `+timeEntryColumns+` FROM time_entries WHERE ended_at IS NULL`)
+	entry, err := scanTimeEntry(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoTimer
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active timer: %w", err)
+	}
+	return entry, nil
+}
+
+// StartTimer starts a timer on a todo at now. Only one timer runs at a time, so a timer
+// running on another todo is stopped first and returned as stopped, in the same
+// transaction: either the old timer is stopped and the new one started, or neither.
+func StartTimer(ctx context.Context, db Querier, todoID uuid.UUID, now time.Time) (*models.TimeEntry, *models.TimeEntry, error) {
+	var started, stopped *models.TimeEntry
+	err := inTx(ctx, db, func(q Querier) error {
+		active, err := ActiveTimer(ctx, q)
+		if err != nil && !errors.Is(err, ErrNoTimer) {
+			return err
+		}
+
+		if active != nil {
+			if active.TodoID == todoID {
+				return fmt.Errorf("a timer is already running for this todo (started %s ago)", active.Elapsed(now).Round(time.Second))
+			}
+			if stopped, err = StopTimer(ctx, q, now); err != nil {
+				return err
+			}
+		}
+
+		result, err := q.ExecContext(ctx, `INSERT INTO time_entries (todo_id, started_at) VALUES (?, ?)`, todoID.String(), now)
+		if err != nil {
+			return fmt.Errorf("failed to start timer: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to start timer: %w", err)
+		}
+		started = &models.TimeEntry{ID: id, TodoID: todoID, StartedAt: now}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return started, stopped, nil
+}
+
+// StopTimer stops the running timer at now and returns it, or ErrNoTimer.
+func StopTimer(ctx context.Context, db Querier, now time.Time) (*models.TimeEntry, error) {
+	var entry *models.TimeEntry
+	err := inTx(ctx, db, func(q Querier) error {
+		var err error
+		if entry, err = ActiveTimer(ctx, q); err != nil {
+			return err
+		}
+
+		entry.Duration = max(now.Sub(entry.StartedAt).Round(time.Second), 0)
+		entry.EndedAt = &now
+		query := `UPDATE time_entries SET ended_at = ?, seconds = ? WHERE id = ?`
+		if _, err := q.ExecContext(ctx, query, now, int64(entry.Duration/time.Second), entry.ID); err != nil {
+			return fmt.Errorf("failed to stop timer: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// LogTime records a duration spent on a todo, ending at now.
+func LogTime(ctx context.Context, db Querier, todoID uuid.UUID, duration time.Duration, now time.Time) (*models.TimeEntry, error) {
+	duration = duration.Round(time.Second)
+	if duration <= 0 {
+		return nil, fmt.Errorf("duration must be at least one second")
+	}
+
+	started := now.Add(-duration)
+	query := `INSERT INTO time_entries (todo_id, started_at, ended_at, seconds) VALUES (?, ?, ?, ?)`
+	result, err := exec(ctx, db, query, todoID.String(), started, now, int64(duration/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("failed to log time: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to log time: %w", err)
+	}
+
+	return &models.TimeEntry{ID: id, TodoID: todoID, StartedAt: started, EndedAt: &now, Duration: duration}, nil
+}
+
+// TrackedTime returns the total time tracked per todo, counting the running timer up to
+// now. Todos without time entries are absent.
+func TrackedTime(ctx context.Context, db Querier, now time.Time) (map[uuid.UUID]time.Duration, error) {
+	rows, err := db.QueryContext(ctx, `SELECT todo_id, SUM(seconds) FROM time_entries WHERE ended_at IS NOT NULL GROUP BY todo_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to total tracked time: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	totals := make(map[uuid.UUID]time.Duration)
+	for rows.Next() {
+		var todoID string
+		var seconds int64
+		if err := rows.Scan(&todoID, &seconds); err != nil {
+			return nil, fmt.Errorf("failed to scan tracked time: %w", err)
+		}
+		if id, err := uuid.Parse(todoID); err == nil {
+			totals[id] = time.Duration(seconds) * time.Second
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to total tracked time: %w", err)
+	}
+
+	active, err := ActiveTimer(ctx, db)
+	if err != nil && !errors.Is(err, ErrNoTimer) {
+		return nil, err
+	}
+	if active != nil {
+		totals[active.TodoID] += active.Elapsed(now)
+	}
+
+	return totals, nil
+}
+
+// GetTimeReport totals the time tracked in [from, to) by project and by tag, each sorted
+// by most time first. Entries crossing the range are cut to the part inside it, and the
+// running timer counts up to now.
+func GetTimeReport(ctx context.Context, db Querier, from, to, now time.Time) (*models.TimeReport, error) {
+	query := `SELECT e.id, e.todo_id, e.started_at, e.ended_at, e.seconds, p.name
+	          FROM time_entries e
+	          INNER JOIN todos t ON t.id = e.todo_id
+	          INNER JOIN projects p ON p.id = t.project_id
+	          WHERE ` + sqlJulianDay("e.started_at") + ` < ?
+	            AND (e.ended_at IS NULL OR ` + sqlJulianDay("e.ended_at") + ` > ?)`
+
+	rows, err := db.QueryContext(ctx, query, julianDay(to), julianDay(from))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query time entries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	report := &models.TimeReport{From: from, To: to}
+	projectTotals := make(map[string]time.Duration)
+	todoTotals := make(map[uuid.UUID]time.Duration)
+	for rows.Next() {
+		var project string
+		entry, err := scanTimeEntry(rows, &project)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+
+		tracked := entry.TrackedIn(from, to, now)
+		if tracked <= 0 {
+			continue
+		}
+
+		report.Total += tracked
+		projectTotals[project] += tracked
+		todoTotals[entry.TodoID] += tracked
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query time entries: %w", err)
+	}
+
+	todos := make([]*models.Todo, 0, len(todoTotals))
+	for todoID := range todoTotals {
+		todos = append(todos, &models.Todo{ID: todoID})
+	}
+	todoTags, err := GetTagsForTodos(ctx, db, todos)
+	if err != nil {
+		return nil, err
+	}
+
+	tagTotals := make(map[string]time.Duration)
+	for todoID, tracked := range todoTotals {
+		tags := todoTags[todoID]
+		if len(tags) == 0 {
+			tagTotals[""] += tracked
+		}
+		for _, tag := range tags {
+			tagTotals[tag.Name] += tracked
+		}
+	}
+
+	report.Projects = models.SortTimeTotals(projectTotals)
+	report.Tags = models.SortTimeTotals(tagTotals)
+	return report, nil
+}
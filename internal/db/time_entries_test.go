@@ -0,0 +1,213 @@
+// ABOUTME: Tests for time entry database operations
+// ABOUTME: Covers the single active timer, logged durations, totals, and range reports
+
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+func TestTimerStartStop(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	first := models.NewTodo(project.ID, "first")
+	second := models.NewTodo(project.ID, "second")
+	for _, todo := range []*models.Todo{first, second} {
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := StopTimer(t.Context(), db, time.Now()); !errors.Is(err, ErrNoTimer) {
+		t.Fatalf("Expected ErrNoTimer, got %v", err)
+	}
+
+	start := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	entry, stopped, err := StartTimer(t.Context(), db, first.ID, start)
+	if err != nil {
+		t.Fatalf("Failed to start timer: %v", err)
+	}
+	if stopped != nil || !entry.Running() {
+		t.Errorf("Expected a fresh running timer, got %+v stopped %+v", entry, stopped)
+	}
+
+	if _, _, err := StartTimer(t.Context(), db, first.ID, start.Add(time.Minute)); err == nil {
+		t.Error("Expected an error starting a second timer on the same todo")
+	}
+
+	// Starting on another todo stops the first timer
+	_, stopped, err = StartTimer(t.Context(), db, second.ID, start.Add(25*time.Minute))
+	if err != nil {
+		t.Fatalf("Failed to switch timer: %v", err)
+	}
+	if stopped == nil || stopped.TodoID != first.ID || stopped.Duration != 25*time.Minute {
+		t.Errorf("Expected the first timer stopped after 25m, got %+v", stopped)
+	}
+
+	active, err := ActiveTimer(t.Context(), db)
+	if err != nil || active.TodoID != second.ID {
+		t.Fatalf("Expected the second todo's timer to run, got %+v, %v", active, err)
+	}
+
+	// A timer that can't start leaves the running one alone
+	if _, _, err := StartTimer(t.Context(), db, uuid.New(), start.Add(30*time.Minute)); err == nil {
+		t.Fatal("Expected starting a timer on a missing todo to fail")
+	}
+	if active, err := ActiveTimer(t.Context(), db); err != nil || active.TodoID != second.ID {
+		t.Errorf("Expected the second todo's timer still running, got %+v, %v", active, err)
+	}
+
+	stopped, err = StopTimer(t.Context(), db, start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to stop timer: %v", err)
+	}
+	if stopped.Duration != 35*time.Minute || stopped.Running() {
+		t.Errorf("Expected a stopped 35m entry, got %+v", stopped)
+	}
+	if _, err := ActiveTimer(t.Context(), db); !errors.Is(err, ErrNoTimer) {
+		t.Errorf("Expected no running timer, got %v", err)
+	}
+}
+
+func TestTrackedTime(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo(project.ID, "tracked")
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if _, err := LogTime(t.Context(), db, todo.ID, 90*time.Minute, now.Add(-time.Hour)); err != nil {
+		t.Fatalf("Failed to log time: %v", err)
+	}
+	if _, err := LogTime(t.Context(), db, todo.ID, 0, now); err == nil {
+		t.Error("Expected an error logging a zero duration")
+	}
+	if _, _, err := StartTimer(t.Context(), db, todo.ID, now.Add(-10*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	totals, err := TrackedTime(t.Context(), db, now)
+	if err != nil {
+		t.Fatalf("Failed to total tracked time: %v", err)
+	}
+	if got := totals[todo.ID]; got != 100*time.Minute {
+		t.Errorf("Expected 1h40m including the running timer, got %v", got)
+	}
+
+	// Deleting the todo removes its time
+	if err := DeleteTodo(t.Context(), db, todo.ID); err != nil {
+		t.Fatal(err)
+	}
+	if totals, _ := TrackedTime(t.Context(), db, now); len(totals) != 0 {
+		t.Errorf("Expected no tracked time after deleting the todo, got %v", totals)
+	}
+}
+
+func TestGetTimeReport(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	api := models.NewProject("api", nil)
+	web := models.NewProject("web", nil)
+	for _, project := range []*models.Project{api, web} {
+		if err := CreateProject(t.Context(), db, project); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bug := models.NewTodo(api.ID, "fix bug")
+	page := models.NewTodo(web.ID, "new page")
+	for _, todo := range []*models.Todo{bug, page} {
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tag := range []string{"bug", "backend"} {
+		if err := AddTagToTodo(t.Context(), db, bug.ID, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	monday := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	log := func(todo *models.Todo, d time.Duration, end time.Time) {
+		t.Helper()
+		if _, err := LogTime(t.Context(), db, todo.ID, d, end); err != nil {
+			t.Fatal(err)
+		}
+	}
+	log(bug, 2*time.Hour, monday.Add(11*time.Hour))
+	log(page, 30*time.Minute, monday.Add(14*time.Hour))
+	// Crosses into the week: only the hour after midnight counts
+	log(page, 2*time.Hour, monday.Add(time.Hour))
+	// Outside the week entirely
+	log(bug, 5*time.Hour, monday.Add(-48*time.Hour))
+
+	report, err := GetTimeReport(t.Context(), db, monday, monday.AddDate(0, 0, 7), monday.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatalf("Failed to build report: %v", err)
+	}
+
+	if report.Total != 3*time.Hour+30*time.Minute {
+		t.Errorf("Expected 3h30m total, got %v", report.Total)
+	}
+	wantProjects := []models.TimeTotal{{Name: "api", Duration: 2 * time.Hour}, {Name: "web", Duration: 90 * time.Minute}}
+	if len(report.Projects) != 2 || report.Projects[0] != wantProjects[0] || report.Projects[1] != wantProjects[1] {
+		t.Errorf("Unexpected project totals: %+v", report.Projects)
+	}
+	wantTags := []models.TimeTotal{
+		{Name: "backend", Duration: 2 * time.Hour},
+		{Name: "bug", Duration: 2 * time.Hour},
+		{Name: "", Duration: 90 * time.Minute},
+	}
+	if len(report.Tags) != 3 || report.Tags[0] != wantTags[0] || report.Tags[1] != wantTags[1] || report.Tags[2] != wantTags[2] {
+		t.Errorf("Unexpected tag totals: %+v", report.Tags)
+	}
+
+	// A running timer counts up to now
+	now := monday.AddDate(0, 0, 1)
+	if _, _, err := StartTimer(t.Context(), db, page.ID, now.Add(-15*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	report, err = GetTimeReport(t.Context(), db, monday, monday.AddDate(0, 0, 7), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 3*time.Hour+45*time.Minute {
+		t.Errorf("Expected the running timer counted, got %v", report.Total)
+	}
+
+	// Ranges are compared to the millisecond
+	if _, err := StopTimer(t.Context(), db, now); err != nil {
+		t.Fatal(err)
+	}
+	report, err = GetTimeReport(t.Context(), db, now.Add(-500*time.Millisecond), now.Add(time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Projects) != 1 || report.Projects[0].Name != "web" {
+		t.Errorf("Expected the timer ending inside the range, got %+v", report.Projects)
+	}
+	report, err = GetTimeReport(t.Context(), db, now.Add(500*time.Millisecond), now.Add(time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Projects) != 0 {
+		t.Errorf("Expected no entries after the timer ended, got %+v", report.Projects)
+	}
+}
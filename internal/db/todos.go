@@ -4,20 +4,24 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/harper/toki/internal/models"
 )
 
 // CreateTodo inserts a new todo into the database.
-func CreateTodo(db *sql.DB, todo *models.Todo) error {
-	query := `INSERT INTO todos (id, project_id, description, done, priority, notes, created_at, updated_at, completed_at, due_date)
-	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+func CreateTodo(ctx context.Context, db Querier, todo *models.Todo) error {
+	points, seconds := estimateColumns(todo.Estimate)
+	query := `INSERT INTO todos (id, project_id, description, done, priority, notes, created_at, updated_at, completed_at, due_date, branch, estimate_points, estimate_seconds)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
-	_, err := db.Exec(query,
+	_, err := exec(ctx, db, query,
 		todo.ID.String(),
 		todo.ProjectID.String(),
 		todo.Description,
@@ -28,6 +32,9 @@ func CreateTodo(db *sql.DB, todo *models.Todo) error {
 		todo.UpdatedAt,
 		todo.CompletedAt,
 		todo.DueDate,
+		todo.Branch,
+		points,
+		seconds,
 	)
 
 	if err != nil {
@@ -36,24 +43,43 @@ func CreateTodo(db *sql.DB, todo *models.Todo) error {
 	return nil
 }
 
+// CreateTodoWithTags inserts a todo along with its tags, in one transaction: if any tag
+// fails, the todo is not created either.
+func CreateTodoWithTags(ctx context.Context, db Querier, todo *models.Todo, tagNames []string) error {
+	return inTx(ctx, db, func(q Querier) error {
+		if err := CreateTodo(ctx, q, todo); err != nil {
+			return err
+		}
+		for _, name := range tagNames {
+			if err := AddTagToTodo(ctx, q, todo.ID, name); err != nil {
+				return fmt.Errorf("failed to add tag '%s': %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
 // GetTodoByID retrieves a todo by its UUID.
-func GetTodoByID(db *sql.DB, id uuid.UUID) (*models.Todo, error) {
-	query := `SELECT id, project_id, description, done, priority, notes, created_at, updated_at, completed_at, due_date
+func GetTodoByID(ctx context.Context, db Querier, id uuid.UUID) (*models.Todo, error) {
+	query := `SELECT id, project_id, description, done, priority, notes, created_at, updated_at, completed_at, due_date, branch, estimate_points, estimate_seconds
 	          FROM todos WHERE id = ?`
 
-	return scanTodo(db.QueryRow(query, id.String()))
+	return scanTodo(db.QueryRowContext(ctx, query, id.String()))
 }
 
 // GetTodoByPrefix retrieves a todo by UUID prefix (minimum 6 characters).
-func GetTodoByPrefix(db *sql.DB, prefix string) (*models.Todo, error) {
-	if len(prefix) < 6 {
-		return nil, fmt.Errorf("prefix must be at least 6 characters")
+func GetTodoByPrefix(ctx context.Context, db Querier, prefix string) (*models.Todo, error) {
+	if len(prefix) < MinPrefixLength {
+		return nil, fmt.Errorf("prefix must be at least %d characters", MinPrefixLength)
+	}
+	if !IsUUIDPrefix(prefix) {
+		return nil, fmt.Errorf("todo %w with prefix: %s", ErrNotFound, prefix)
 	}
 
-	query := `SELECT id, project_id, description, done, priority, notes, created_at, updated_at, completed_at, due_date
+	query := `SELECT id, project_id, description, done, priority, notes, created_at, updated_at, completed_at, due_date, branch, estimate_points, estimate_seconds
 	          FROM todos WHERE id LIKE ?`
 
-	rows, err := db.Query(query, prefix+"%")
+	rows, err := db.QueryContext(ctx, query, strings.ToLower(prefix)+"%")
 	if err != nil {
 		return nil, fmt.Errorf("failed to query todos: %w", err)
 	}
@@ -69,23 +95,26 @@ func GetTodoByPrefix(db *sql.DB, prefix string) (*models.Todo, error) {
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no todo found with prefix: %s", prefix)
+		return nil, fmt.Errorf("todo %w with prefix: %s", ErrNotFound, prefix)
 	}
 
 	if len(matches) > 1 {
-		ids := make([]string, len(matches))
+		candidates := make([]RefCandidate, len(matches))
 		for i, t := range matches {
-			ids[i] = t.ID.String()[:8]
+			candidates[i] = RefCandidate{ID: t.ID.String(), Label: t.Description}
 		}
-		return nil, fmt.Errorf("ambiguous prefix '%s', matches: %s", prefix, strings.Join(ids, ", "))
+		return nil, &AmbiguousRefError{Kind: "todo", Ref: prefix, Candidates: candidates}
 	}
 
 	return matches[0], nil
 }
 
-// ListTodos returns todos filtered by project, done status, priority, and/or tag.
-func ListTodos(db *sql.DB, projectID *uuid.UUID, done *bool, priority *string, tag *string) ([]*models.Todo, error) {
-	query := `SELECT DISTINCT t.id, t.project_id, t.description, t.done, t.priority, t.notes, t.created_at, t.updated_at, t.completed_at, t.due_date
+// todoColumns are the columns scanTodoFromRows reads, from todos aliased as t.
+const todoColumns = `t.id, t.project_id, t.description, t.done, t.priority, t.notes, t.created_at, t.updated_at, t.completed_at, t.due_date, t.branch, t.estimate_points, t.estimate_seconds`
+
+// ListTodos returns todos filtered by project, done status, priority, tag, and/or branch.
+func ListTodos(ctx context.Context, db Querier, projectID *uuid.UUID, done *bool, priority *string, tag *string, branch *string) ([]*models.Todo, error) {
+	query := `SELECT DISTINCT ` + todoColumns + `
 	          FROM todos t`
 
 	var args []interface{}
@@ -119,9 +148,14 @@ func ListTodos(db *sql.DB, projectID *uuid.UUID, done *bool, priority *string, t
 		args = append(args, *tag)
 	}
 
+	if branch != nil {
+		query += " AND t.branch = ?"
+		args = append(args, *branch)
+	}
+
 	query += " ORDER BY t.created_at DESC"
 
-	rows, err := db.Query(query, args...)
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list todos: %w", err)
 	}
@@ -140,12 +174,14 @@ func ListTodos(db *sql.DB, projectID *uuid.UUID, done *bool, priority *string, t
 }
 
 // UpdateTodo updates an existing todo.
-func UpdateTodo(db *sql.DB, todo *models.Todo) error {
+func UpdateTodo(ctx context.Context, db Querier, todo *models.Todo) error {
+	points, seconds := estimateColumns(todo.Estimate)
 	query := `UPDATE todos
-	          SET description = ?, done = ?, priority = ?, notes = ?, updated_at = ?, completed_at = ?, due_date = ?
+	          SET description = ?, done = ?, priority = ?, notes = ?, updated_at = ?, completed_at = ?, due_date = ?, branch = ?,
+	              estimate_points = ?, estimate_seconds = ?
 	          WHERE id = ?`
 
-	_, err := db.Exec(query,
+	_, err := exec(ctx, db, query,
 		todo.Description,
 		todo.Done,
 		todo.Priority,
@@ -153,6 +189,9 @@ func UpdateTodo(db *sql.DB, todo *models.Todo) error {
 		todo.UpdatedAt,
 		todo.CompletedAt,
 		todo.DueDate,
+		todo.Branch,
+		points,
+		seconds,
 		todo.ID.String(),
 	)
 
@@ -162,10 +201,61 @@ func UpdateTodo(db *sql.DB, todo *models.Todo) error {
 	return nil
 }
 
+// ListTodoBranches returns the distinct branches of a project's pending todos.
+func ListTodoBranches(ctx context.Context, db Querier, projectID uuid.UUID) ([]string, error) {
+	query := `SELECT DISTINCT branch FROM todos
+	          WHERE project_id = ? AND done = 0 AND branch IS NOT NULL
+	          ORDER BY branch`
+
+	rows, err := db.QueryContext(ctx, query, projectID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todo branches: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var branches []string
+	for rows.Next() {
+		var branch string
+		if err := rows.Scan(&branch); err != nil {
+			return nil, fmt.Errorf("failed to scan branch: %w", err)
+		}
+		branches = append(branches, branch)
+	}
+
+	return branches, nil
+}
+
+// CompleteBranchTodos marks every pending todo on a project branch as done.
+// Returns the number of todos completed.
+func CompleteBranchTodos(ctx context.Context, db Querier, projectID uuid.UUID, branch string) (int64, error) {
+	now := time.Now()
+	query := `UPDATE todos SET done = 1, completed_at = ?, updated_at = ?
+	          WHERE project_id = ? AND branch = ? AND done = 0`
+
+	result, err := exec(ctx, db, query, now, now, projectID.String(), branch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to complete branch todos: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// MoveBranchTodos reassigns a project branch's pending todos to another branch
+// (nil makes them project-wide). Returns the number of todos moved.
+func MoveBranchTodos(ctx context.Context, db Querier, projectID uuid.UUID, from string, to *string) (int64, error) {
+	query := `UPDATE todos SET branch = ?, updated_at = ?
+	          WHERE project_id = ? AND branch = ? AND done = 0`
+
+	result, err := exec(ctx, db, query, to, time.Now(), projectID.String(), from)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move branch todos: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // DeleteTodo deletes a todo.
-func DeleteTodo(db *sql.DB, id uuid.UUID) error {
+func DeleteTodo(ctx context.Context, db Querier, id uuid.UUID) error {
 	query := `DELETE FROM todos WHERE id = ?`
-	_, err := db.Exec(query, id.String())
+	_, err := exec(ctx, db, query, id.String())
 	if err != nil {
 		return fmt.Errorf("failed to delete todo: %w", err)
 	}
@@ -176,6 +266,8 @@ func DeleteTodo(db *sql.DB, id uuid.UUID) error {
 func scanTodo(row *sql.Row) (*models.Todo, error) {
 	var todo models.Todo
 	var idStr, projectIDStr string
+	var points sql.NullFloat64
+	var seconds sql.NullInt64
 
 	err := row.Scan(
 		&idStr,
@@ -188,27 +280,34 @@ func scanTodo(row *sql.Row) (*models.Todo, error) {
 		&todo.UpdatedAt,
 		&todo.CompletedAt,
 		&todo.DueDate,
+		&todo.Branch,
+		&points,
+		&seconds,
 	)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("todo not found")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("todo %w", ErrNotFound)
 		}
 		return nil, fmt.Errorf("failed to scan todo: %w", err)
 	}
 
 	todo.ID, _ = uuid.Parse(idStr)
 	todo.ProjectID, _ = uuid.Parse(projectIDStr)
+	todo.Estimate = estimateFromColumns(points, seconds)
 
 	return &todo, nil
 }
 
-// Helper function to scan a todo from multiple rows.
-func scanTodoFromRows(rows *sql.Rows) (*models.Todo, error) {
+// Helper function to scan a todo from multiple rows, followed by any extra columns into
+// extra.
+func scanTodoFromRows(rows *sql.Rows, extra ...any) (*models.Todo, error) {
 	var todo models.Todo
 	var idStr, projectIDStr string
+	var points sql.NullFloat64
+	var seconds sql.NullInt64
 
-	err := rows.Scan(
+	dest := []any{
 		&idStr,
 		&projectIDStr,
 		&todo.Description,
@@ -219,7 +318,11 @@ func scanTodoFromRows(rows *sql.Rows) (*models.Todo, error) {
 		&todo.UpdatedAt,
 		&todo.CompletedAt,
 		&todo.DueDate,
-	)
+		&todo.Branch,
+		&points,
+		&seconds,
+	}
+	err := rows.Scan(append(dest, extra...)...)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan todo: %w", err)
@@ -227,6 +330,31 @@ func scanTodoFromRows(rows *sql.Rows) (*models.Todo, error) {
 
 	todo.ID, _ = uuid.Parse(idStr)
 	todo.ProjectID, _ = uuid.Parse(projectIDStr)
+	todo.Estimate = estimateFromColumns(points, seconds)
 
 	return &todo, nil
 }
+
+// estimateColumns splits an estimate into its estimate_points and estimate_seconds values.
+func estimateColumns(estimate *models.Estimate) (*float64, *int64) {
+	switch {
+	case estimate == nil:
+		return nil, nil
+	case estimate.Duration > 0:
+		seconds := int64(estimate.Duration / time.Second)
+		return nil, &seconds
+	default:
+		return &estimate.Points, nil
+	}
+}
+
+func estimateFromColumns(points sql.NullFloat64, seconds sql.NullInt64) *models.Estimate {
+	switch {
+	case seconds.Valid:
+		return &models.Estimate{Duration: time.Duration(seconds.Int64) * time.Second}
+	case points.Valid:
+		return &models.Estimate{Points: points.Float64}
+	default:
+		return nil
+	}
+}
@@ -5,6 +5,7 @@ package db
 
 import (
 	"testing"
+	"time"
 
 	"github.com/harper/toki/internal/models"
 )
@@ -14,7 +15,7 @@ func TestCreateTodo(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
@@ -22,12 +23,12 @@ func TestCreateTodo(t *testing.T) {
 	priority := "high"
 	todo.Priority = &priority
 
-	err := CreateTodo(db, todo)
+	err := CreateTodo(t.Context(), db, todo)
 	if err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
-	retrieved, err := GetTodoByID(db, todo.ID)
+	retrieved, err := GetTodoByID(t.Context(), db, todo.ID)
 	if err != nil {
 		t.Fatalf("Failed to retrieve todo: %v", err)
 	}
@@ -45,19 +46,19 @@ func TestGetTodoByPrefix(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	todo := models.NewTodo(project.ID, "find me")
-	if err := CreateTodo(db, todo); err != nil {
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
 		t.Fatal(err)
 	}
 
 	// Use first 6 characters as prefix
 	prefix := todo.ID.String()[:6]
 
-	found, err := GetTodoByPrefix(db, prefix)
+	found, err := GetTodoByPrefix(t.Context(), db, prefix)
 	if err != nil {
 		t.Fatalf("Failed to find todo by prefix: %v", err)
 	}
@@ -72,19 +73,19 @@ func TestGetTodoByPrefixAmbiguous(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	// Create two todos - we can't guarantee prefix collision
 	// but we can test the error path
 	todo1 := models.NewTodo(project.ID, "todo1")
-	if err := CreateTodo(db, todo1); err != nil {
+	if err := CreateTodo(t.Context(), db, todo1); err != nil {
 		t.Fatal(err)
 	}
 
 	// Test with empty prefix should be ambiguous
-	_, err := GetTodoByPrefix(db, "")
+	_, err := GetTodoByPrefix(t.Context(), db, "")
 	if err == nil {
 		t.Error("Empty prefix should return error")
 	}
@@ -95,20 +96,20 @@ func TestListTodos(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	todo1 := models.NewTodo(project.ID, "todo1")
 	todo2 := models.NewTodo(project.ID, "todo2")
-	if err := CreateTodo(db, todo1); err != nil {
+	if err := CreateTodo(t.Context(), db, todo1); err != nil {
 		t.Fatal(err)
 	}
-	if err := CreateTodo(db, todo2); err != nil {
+	if err := CreateTodo(t.Context(), db, todo2); err != nil {
 		t.Fatal(err)
 	}
 
-	todos, err := ListTodos(db, &project.ID, nil, nil, nil)
+	todos, err := ListTodos(t.Context(), db, &project.ID, nil, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to list todos: %v", err)
 	}
@@ -123,7 +124,7 @@ func TestListTodosFilterDone(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
@@ -131,15 +132,15 @@ func TestListTodosFilterDone(t *testing.T) {
 	todo2 := models.NewTodo(project.ID, "done")
 	todo2.MarkDone()
 
-	if err := CreateTodo(db, todo1); err != nil {
+	if err := CreateTodo(t.Context(), db, todo1); err != nil {
 		t.Fatal(err)
 	}
-	if err := CreateTodo(db, todo2); err != nil {
+	if err := CreateTodo(t.Context(), db, todo2); err != nil {
 		t.Fatal(err)
 	}
 
 	doneFilter := false
-	todos, err := ListTodos(db, nil, &doneFilter, nil, nil)
+	todos, err := ListTodos(t.Context(), db, nil, &doneFilter, nil, nil, nil)
 	if err != nil {
 		t.Fatalf("Failed to list todos: %v", err)
 	}
@@ -157,30 +158,30 @@ func TestListTodosFilterByTag(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	todo1 := models.NewTodo(project.ID, "backend todo")
 	todo2 := models.NewTodo(project.ID, "frontend todo")
-	if err := CreateTodo(db, todo1); err != nil {
+	if err := CreateTodo(t.Context(), db, todo1); err != nil {
 		t.Fatal(err)
 	}
-	if err := CreateTodo(db, todo2); err != nil {
+	if err := CreateTodo(t.Context(), db, todo2); err != nil {
 		t.Fatal(err)
 	}
 
 	// Tag only todo1 with "backend"
-	if err := AddTagToTodo(db, todo1.ID, "backend"); err != nil {
+	if err := AddTagToTodo(t.Context(), db, todo1.ID, "backend"); err != nil {
 		t.Fatal(err)
 	}
-	if err := AddTagToTodo(db, todo2.ID, "frontend"); err != nil {
+	if err := AddTagToTodo(t.Context(), db, todo2.ID, "frontend"); err != nil {
 		t.Fatal(err)
 	}
 
 	// Filter by backend tag
 	tagFilter := "backend"
-	todos, err := ListTodos(db, nil, nil, nil, &tagFilter)
+	todos, err := ListTodos(t.Context(), db, nil, nil, nil, &tagFilter, nil)
 	if err != nil {
 		t.Fatalf("Failed to list todos by tag: %v", err)
 	}
@@ -199,24 +200,24 @@ func TestUpdateTodo(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	todo := models.NewTodo(project.ID, "original")
-	if err := CreateTodo(db, todo); err != nil {
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
 		t.Fatal(err)
 	}
 
 	todo.Description = "updated"
 	todo.MarkDone()
 
-	err := UpdateTodo(db, todo)
+	err := UpdateTodo(t.Context(), db, todo)
 	if err != nil {
 		t.Fatalf("Failed to update todo: %v", err)
 	}
 
-	retrieved, err := GetTodoByID(db, todo.ID)
+	retrieved, err := GetTodoByID(t.Context(), db, todo.ID)
 	if err != nil {
 		t.Fatalf("Failed to retrieve todo: %v", err)
 	}
@@ -234,22 +235,127 @@ func TestDeleteTodo(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	project := models.NewProject("test", nil)
-	if err := CreateProject(db, project); err != nil {
+	if err := CreateProject(t.Context(), db, project); err != nil {
 		t.Fatal(err)
 	}
 
 	todo := models.NewTodo(project.ID, "to delete")
-	if err := CreateTodo(db, todo); err != nil {
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
 		t.Fatal(err)
 	}
 
-	err := DeleteTodo(db, todo.ID)
+	err := DeleteTodo(t.Context(), db, todo.ID)
 	if err != nil {
 		t.Fatalf("Failed to delete todo: %v", err)
 	}
 
-	_, err = GetTodoByID(db, todo.ID)
+	_, err = GetTodoByID(t.Context(), db, todo.ID)
 	if err == nil {
 		t.Error("Todo should not exist after deletion")
 	}
 }
+
+func TestBranchScopedTodos(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	feature := "feature"
+	scoped := models.NewTodo(project.ID, "on feature")
+	scoped.Branch = &feature
+	unscoped := models.NewTodo(project.ID, "project-wide")
+	for _, todo := range []*models.Todo{scoped, unscoped} {
+		if err := CreateTodo(t.Context(), db, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	todos, err := ListTodos(t.Context(), db, &project.ID, nil, nil, nil, &feature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 1 || todos[0].ID != scoped.ID || *todos[0].Branch != feature {
+		t.Fatalf("Expected only the feature todo, got %d todos", len(todos))
+	}
+
+	branches, err := ListTodoBranches(t.Context(), db, project.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(branches) != 1 || branches[0] != feature {
+		t.Errorf("Expected [feature], got %v", branches)
+	}
+
+	other := "other"
+	moved, err := MoveBranchTodos(t.Context(), db, project.ID, feature, &other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 1 {
+		t.Errorf("Expected 1 todo moved, got %d", moved)
+	}
+
+	completed, err := CompleteBranchTodos(t.Context(), db, project.ID, other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if completed != 1 {
+		t.Errorf("Expected 1 todo completed, got %d", completed)
+	}
+
+	retrieved, err := GetTodoByID(t.Context(), db, scoped.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !retrieved.Done || retrieved.CompletedAt == nil || *retrieved.Branch != other {
+		t.Error("Expected the moved todo to be completed on its new branch")
+	}
+}
+
+func TestTodoEstimate(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	todo := models.NewTodo(project.ID, "estimated")
+	todo.Estimate = &models.Estimate{Points: 3}
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
+		t.Fatal(err)
+	}
+
+	retrieved, err := GetTodoByID(t.Context(), db, todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retrieved.Estimate == nil || retrieved.Estimate.String() != "3pt" {
+		t.Errorf("Expected a 3pt estimate, got %v", retrieved.Estimate)
+	}
+
+	retrieved.Estimate = &models.Estimate{Duration: 90 * time.Minute}
+	if err := UpdateTodo(t.Context(), db, retrieved); err != nil {
+		t.Fatal(err)
+	}
+	todos, err := QueryTodos(t.Context(), db, nil)
+	if err != nil || len(todos) != 1 {
+		t.Fatalf("Failed to query todos: %v", err)
+	}
+	if todos[0].Estimate == nil || todos[0].Estimate.String() != "1h30m" {
+		t.Errorf("Expected a 1h30m estimate, got %v", todos[0].Estimate)
+	}
+
+	todos[0].Estimate = nil
+	if err := UpdateTodo(t.Context(), db, todos[0]); err != nil {
+		t.Fatal(err)
+	}
+	if cleared, _ := GetTodoByID(t.Context(), db, todo.ID); cleared.Estimate != nil {
+		t.Errorf("Expected the estimate cleared, got %v", cleared.Estimate)
+	}
+}
@@ -0,0 +1,56 @@
+// ABOUTME: Transaction helpers for operations that take several statements
+// ABOUTME: WithTx commits or rolls back as a unit; inTx joins a transaction already open
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithTx runs fn in a transaction on database. The transaction is committed if fn
+// returns nil and rolled back if it returns an error, which WithTx then returns, or
+// panics. The transaction is this process's writer until it ends, so fn must make its
+// changes through tx: writing through database would wait for fn forever.
+func WithTx(ctx context.Context, database *sql.DB, fn func(tx *sql.Tx) error) error {
+	writeMu.Lock()
+	defer writeMu.Unlock()
+
+	var tx *sql.Tx
+	err := retryBusy(ctx, func() error {
+		var err error
+		tx, err = database.BeginTx(ctx, nil)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	committed = true
+	return nil
+}
+
+// inTx runs fn in a new transaction on db, or directly when db is a transaction already.
+func inTx(ctx context.Context, db Querier, fn func(q Querier) error) error {
+	database, ok := db.(*sql.DB)
+	if !ok {
+		return fn(db)
+	}
+	return WithTx(ctx, database, func(tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
@@ -0,0 +1,206 @@
+// ABOUTME: Tests for the transaction helpers
+// ABOUTME: Checks commit, rollback on error and panic, and atomic multi-step operations
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func TestWithTx(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	kept := models.NewProject("kept", nil)
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		return CreateProject(t.Context(), tx, kept)
+	})
+	if err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	if _, err := GetProjectByID(t.Context(), db, kept.ID); err != nil {
+		t.Errorf("Committed project should exist: %v", err)
+	}
+
+	failure := errors.New("stop")
+	discarded := models.NewProject("discarded", nil)
+	err = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		if err := CreateProject(t.Context(), tx, discarded); err != nil {
+			return err
+		}
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the error from fn, got %v", err)
+	}
+	if _, err := GetProjectByID(t.Context(), db, discarded.ID); err == nil {
+		t.Error("Rolled back project should not exist")
+	}
+
+	panicked := models.NewProject("panicked", nil)
+	func() {
+		defer func() { _ = recover() }()
+		_ = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+			if err := CreateProject(t.Context(), tx, panicked); err != nil {
+				return err
+			}
+			panic("boom")
+		})
+	}()
+	if _, err := GetProjectByID(t.Context(), db, panicked.ID); err == nil {
+		t.Error("Project created before a panic should not exist")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WithTx(ctx, db, func(*sql.Tx) error { return nil }); err == nil {
+		t.Error("Expected an error beginning a transaction with a cancelled context")
+	}
+}
+
+// failTag makes inserting the tag named name fail.
+func failTag(t *testing.T, db *sql.DB, name string) {
+	t.Helper()
+	_, err := db.Exec(`CREATE TRIGGER fail_tag BEFORE INSERT ON tags
+	                   WHEN NEW.name = '` + name + `'
+	                   BEGIN SELECT RAISE(ABORT, 'tag refused'); END`)
+	if err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+}
+
+func TestCreateTodoWithTags(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	todo := models.NewTodo(project.ID, "tagged")
+	if err := CreateTodoWithTags(t.Context(), db, todo, []string{"backend", "api"}); err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	tags, err := GetTodoTags(t.Context(), db, todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags[0].Name != "api" || tags[1].Name != "backend" {
+		t.Errorf("Expected tags api and backend, got %v", tags)
+	}
+
+	failTag(t, db, "broken")
+	orphan := models.NewTodo(project.ID, "half done")
+	if err := CreateTodoWithTags(t.Context(), db, orphan, []string{"fine", "broken"}); err == nil {
+		t.Fatal("Expected the failing tag to fail the create")
+	}
+	if _, err := GetTodoByID(t.Context(), db, orphan.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Todo should not exist when a tag fails, got %v", err)
+	}
+	if all, _ := ListAllTags(t.Context(), db); len(all) != 2 {
+		t.Errorf("Tags from the failed create should be rolled back, got %v", all)
+	}
+}
+
+func TestSetTodoTags(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo(project.ID, "retag")
+	if err := CreateTodoWithTags(t.Context(), db, todo, []string{"old", "kept"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetTodoTags(t.Context(), db, todo.ID, []string{"kept", "new"}); err != nil {
+		t.Fatalf("Failed to set tags: %v", err)
+	}
+	tags, _ := GetTodoTags(t.Context(), db, todo.ID)
+	if len(tags) != 2 || tags[0].Name != "kept" || tags[1].Name != "new" {
+		t.Errorf("Expected tags kept and new, got %v", tags)
+	}
+
+	failTag(t, db, "broken")
+	if err := SetTodoTags(t.Context(), db, todo.ID, []string{"broken"}); err == nil {
+		t.Fatal("Expected the failing tag to fail the replace")
+	}
+	tags, _ = GetTodoTags(t.Context(), db, todo.ID)
+	if len(tags) != 2 {
+		t.Errorf("A failed replace should keep the old tags, got %v", tags)
+	}
+}
+
+func TestDeleteProjectRemovesDependents(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("doomed", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddProjectPath(t.Context(), db, project.ID, t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo(project.ID, "goes too")
+	if err := CreateTodoWithTags(t.Context(), db, todo, []string{"gone"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LogTime(t.Context(), db, todo.ID, time.Hour, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without foreign keys nothing cascades, so DeleteProject must remove every row.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteProject(t.Context(), db, project.ID); err != nil {
+		t.Fatalf("Failed to delete project: %v", err)
+	}
+
+	for _, table := range []string{"projects", "project_paths", "todos", "todo_tags", "time_entries"} {
+		var count int
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("Expected %s to be empty, got %d rows", table, count)
+		}
+	}
+}
+
+func TestDeleteProjectIsAtomic(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("protected", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo(project.ID, "survives")
+	if err := CreateTodo(t.Context(), db, todo); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := db.Exec(`CREATE TRIGGER keep_project BEFORE DELETE ON projects
+	                   BEGIN SELECT RAISE(ABORT, 'project is protected'); END`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteProject(t.Context(), db, project.ID); err == nil {
+		t.Fatal("Expected the delete to fail")
+	}
+	if _, err := GetTodoByID(t.Context(), db, todo.ID); err != nil {
+		t.Errorf("Todo should survive a failed project delete: %v", err)
+	}
+}
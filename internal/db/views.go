@@ -0,0 +1,144 @@
+// ABOUTME: Saved view database operations
+// ABOUTME: Stores named filters and provides the built-in pending, overdue, and high-priority views
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/harper/toki/internal/models"
+)
+
+// builtinViews mirror the pre-built MCP todo resources.
+var builtinViews = []*models.View{
+	{Name: "high-priority", Filter: "priority:high", Builtin: true},
+	{Name: "overdue", Filter: "overdue", Sort: "due", Builtin: true},
+	{Name: "pending", Filter: "pending", Builtin: true},
+}
+
+// viewName restricts names to what works in toki://views/<name> and @name.
+var viewName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidateViewName reports whether name can be used for a saved view.
+func ValidateViewName(name string) error {
+	if !viewName.MatchString(name) {
+		return fmt.Errorf("invalid view name '%s' (use lowercase letters, digits, '-' and '_')", name)
+	}
+	if BuiltinView(name) != nil {
+		return fmt.Errorf("'%s' is a built-in view", name)
+	}
+	return nil
+}
+
+// BuiltinView returns a copy of the built-in view named name, or nil.
+func BuiltinView(name string) *models.View {
+	for _, view := range builtinViews {
+		if view.Name == name {
+			copied := *view
+			return &copied
+		}
+	}
+	return nil
+}
+
+// BuiltinViews returns copies of the built-in views, sorted by name.
+func BuiltinViews() []*models.View {
+	views := make([]*models.View, 0, len(builtinViews))
+	for _, view := range builtinViews {
+		copied := *view
+		views = append(views, &copied)
+	}
+	return views
+}
+
+// SaveView stores a view, replacing any saved view with the same name. It reports
+// whether an existing view was replaced.
+func SaveView(ctx context.Context, db Querier, view *models.View) (bool, error) {
+	if err := ValidateViewName(view.Name); err != nil {
+		return false, err
+	}
+
+	if view.CreatedAt.IsZero() {
+		view.CreatedAt = time.Now()
+	}
+
+	// Checked in the same transaction, so a view saved meanwhile is reported as replaced
+	var existing int
+	err := inTx(ctx, db, func(q Querier) error {
+		if err := q.QueryRowContext(ctx, `SELECT COUNT(*) FROM views WHERE name = ?`, view.Name).Scan(&existing); err != nil {
+			return fmt.Errorf("failed to check view: %w", err)
+		}
+
+		query := `INSERT INTO views (name, filter, sort, reverse, created_at) VALUES (?, ?, ?, ?, ?)
+		          ON CONFLICT(name) DO UPDATE SET filter = excluded.filter, sort = excluded.sort, reverse = excluded.reverse`
+		if _, err := q.ExecContext(ctx, query, view.Name, view.Filter, view.Sort, view.Reverse, view.CreatedAt); err != nil {
+			return fmt.Errorf("failed to save view: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return existing > 0, nil
+}
+
+// GetView returns a built-in or saved view by name.
+func GetView(ctx context.Context, db Querier, name string) (*models.View, error) {
+	if view := BuiltinView(name); view != nil {
+		return view, nil
+	}
+
+	view := &models.View{}
+	query := `SELECT name, filter, sort, reverse, created_at FROM views WHERE name = ?`
+	err := db.QueryRowContext(ctx, query, name).Scan(&view.Name, &view.Filter, &view.Sort, &view.Reverse, &view.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("no view named '%s' (see 'toki view list')", name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get view: %w", err)
+	}
+
+	return view, nil
+}
+
+// ListViews returns the built-in views followed by saved views, each sorted by name.
+func ListViews(ctx context.Context, db Querier) ([]*models.View, error) {
+	views := BuiltinViews()
+	rows, err := db.QueryContext(ctx, `SELECT name, filter, sort, reverse, created_at FROM views ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		view := &models.View{}
+		if err := rows.Scan(&view.Name, &view.Filter, &view.Sort, &view.Reverse, &view.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		views = append(views, view)
+	}
+
+	return views, rows.Err()
+}
+
+// DeleteView removes a saved view. Built-in views cannot be deleted.
+func DeleteView(ctx context.Context, db Querier, name string) error {
+	if BuiltinView(name) != nil {
+		return fmt.Errorf("'%s' is a built-in view and cannot be deleted", name)
+	}
+
+	result, err := exec(ctx, db, `DELETE FROM views WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete view: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("no view named '%s'", name)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+// ABOUTME: Tests for saved view operations
+// ABOUTME: Covers saving, replacing, listing, deleting, and built-in views
+
+package db
+
+import (
+	"testing"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func TestViews(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	replaced, err := SaveView(t.Context(), db, &models.View{Name: "today", Filter: "due<=today"})
+	if err != nil || replaced {
+		t.Fatalf("Expected new view to be saved, got %v, %v", replaced, err)
+	}
+	replaced, err = SaveView(t.Context(), db, &models.View{Name: "today", Filter: "due<=today and pending", Sort: "urgency"})
+	if err != nil || !replaced {
+		t.Fatalf("Expected view to be replaced, got %v, %v", replaced, err)
+	}
+
+	view, err := GetView(t.Context(), db, "today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if view.Filter != "due<=today and pending" || view.Sort != "urgency" || view.Builtin {
+		t.Errorf("Unexpected view: %+v", view)
+	}
+
+	views, err := ListViews(t.Context(), db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, v := range views {
+		names = append(names, v.Name)
+	}
+	want := []string{"high-priority", "overdue", "pending", "today"}
+	if len(names) != len(want) {
+		t.Fatalf("Expected views %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("Expected views %v, got %v", want, names)
+		}
+	}
+
+	if err := DeleteView(t.Context(), db, "today"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetView(t.Context(), db, "today"); err == nil {
+		t.Error("Expected deleted view to be gone")
+	}
+	if err := DeleteView(t.Context(), db, "today"); err == nil {
+		t.Error("Expected deleting a missing view to fail")
+	}
+}
+
+func TestBuiltinViews(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	view, err := GetView(t.Context(), db, "overdue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !view.Builtin || view.Filter != "overdue" {
+		t.Errorf("Unexpected built-in view: %+v", view)
+	}
+
+	// Callers get copies, so changes don't leak into the built-ins
+	view.Filter = "changed"
+	if again, _ := GetView(t.Context(), db, "overdue"); again.Filter != "overdue" {
+		t.Error("Expected built-in view to be unchanged")
+	}
+
+	if _, err := SaveView(t.Context(), db, &models.View{Name: "pending", Filter: "done"}); err == nil {
+		t.Error("Expected saving over a built-in view to fail")
+	}
+	if err := DeleteView(t.Context(), db, "pending"); err == nil {
+		t.Error("Expected deleting a built-in view to fail")
+	}
+	for _, name := range []string{"", "Today", "my view", "@today", "-x"} {
+		if err := ValidateViewName(name); err == nil {
+			t.Errorf("Expected name %q to be rejected", name)
+		}
+	}
+}
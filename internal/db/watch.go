@@ -0,0 +1,59 @@
+// ABOUTME: Database change detection for live views
+// ABOUTME: Polls SQLite's data_version to notice commits made by other connections
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// ChangeWatcher reports when the database has been modified by another connection,
+// such as an MCP server or a second toki process writing to the same file.
+type ChangeWatcher struct {
+	conn    *sql.Conn
+	version int64
+}
+
+// NewChangeWatcher pins a connection so that data_version is read consistently.
+// Close must be called to release it.
+func NewChangeWatcher(ctx context.Context, db *sql.DB) (*ChangeWatcher, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open watcher connection: %w", err)
+	}
+
+	watcher := &ChangeWatcher{conn: conn}
+	if watcher.version, err = watcher.dataVersion(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// Changed reports whether any other connection has committed since the last call.
+func (w *ChangeWatcher) Changed(ctx context.Context) (bool, error) {
+	version, err := w.dataVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	if version == w.version {
+		return false, nil
+	}
+	w.version = version
+	return true, nil
+}
+
+// Close releases the watcher's connection.
+func (w *ChangeWatcher) Close() error {
+	return w.conn.Close()
+}
+
+func (w *ChangeWatcher) dataVersion(ctx context.Context) (int64, error) {
+	var version int64
+	if err := w.conn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read data version: %w", err)
+	}
+	return version, nil
+}
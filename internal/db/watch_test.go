@@ -0,0 +1,48 @@
+// ABOUTME: Tests for database change detection
+// ABOUTME: Verifies writes from another connection are noticed exactly once
+
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func TestChangeWatcher(t *testing.T) {
+	dbPath := t.TempDir() + "/watch.db"
+	db, err := InitDB(t.Context(), dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ctx := context.Background()
+	watcher, err := NewChangeWatcher(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = watcher.Close() }()
+
+	if changed, err := watcher.Changed(ctx); err != nil || changed {
+		t.Fatalf("Expected no change yet, got %v, %v", changed, err)
+	}
+
+	// A separate handle stands in for an MCP server writing to the same file
+	other, err := InitDB(t.Context(), dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = other.Close() }()
+	if err := CreateProject(t.Context(), other, models.NewProject("external", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if changed, err := watcher.Changed(ctx); err != nil || !changed {
+		t.Fatalf("Expected external write to be detected, got %v, %v", changed, err)
+	}
+	if changed, err := watcher.Changed(ctx); err != nil || changed {
+		t.Fatalf("Expected change to be reported once, got %v, %v", changed, err)
+	}
+}
@@ -0,0 +1,88 @@
+// ABOUTME: Write path for the record functions: one writer at a time, retried while busy
+// ABOUTME: Lets several toki processes share a database without "database is locked" errors
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// writeMu makes this process's writes take turns, so that its pooled connections act
+// as a single writer and never contend with each other for SQLite's write lock.
+var writeMu sync.Mutex
+
+// A write still busy after busy_timeout is retried busyRetries times, waiting
+// busyBackoff before the first retry and twice as long before each next one, up to
+// maxBusyBackoff: about ten seconds in all, on top of busy_timeout per attempt.
+// That is longer than the five-second busy_timeout this replaced, so a writer
+// holding the lock for a while is still waited out, but here a cancelled context
+// ends the wait.
+const (
+	busyRetries    = 10
+	busyBackoff    = 50 * time.Millisecond
+	maxBusyBackoff = time.Second
+)
+
+// IsBusy reports whether err is SQLite refusing a statement because another connection
+// holds a conflicting lock.
+func IsBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// The low byte is the primary result code of extended codes like SQLITE_BUSY_SNAPSHOT
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED:
+		return true
+	}
+	return false
+}
+
+// retryBusy calls fn again while it fails with a busy error, up to busyRetries times,
+// unless ctx is done first.
+func retryBusy(ctx context.Context, fn func() error) error {
+	wait := busyBackoff
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == busyRetries || !IsBusy(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait = min(2*wait, maxBusyBackoff)
+	}
+}
+
+// write runs fn, which makes one change through db, as this process's writer, retrying
+// it while the database is busy. In a transaction, which WithTx already made the writer,
+// fn just runs: a busy statement there is retried with the whole transaction or not
+// at all.
+func write(ctx context.Context, db Querier, fn func() error) error {
+	if _, ok := db.(*sql.Tx); ok {
+		return fn()
+	}
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	return retryBusy(ctx, fn)
+}
+
+// exec runs a statement that changes the database, through write.
+func exec(ctx context.Context, db Querier, query string, args ...any) (sql.Result, error) {
+	var result sql.Result
+	err := write(ctx, db, func() error {
+		var err error
+		result, err = db.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
@@ -0,0 +1,210 @@
+// ABOUTME: Tests for the concurrency settings and the serialized, retried write path
+// ABOUTME: Holds SQLite's write lock from another handle and hammers one file from many goroutines
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func TestInitDBConnectionSettings(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	// Every pooled connection gets the settings, not just the first
+	ctx := context.Background()
+	for i := range 3 {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = conn.Close() }()
+
+		var journalMode string
+		var busyTimeout, foreignKeys int
+		if err := conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+			t.Fatal(err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatal(err)
+		}
+		if journalMode != "wal" || busyTimeout != 250 || foreignKeys != 1 {
+			t.Errorf("Connection %d: got journal_mode=%s busy_timeout=%d foreign_keys=%d",
+				i, journalMode, busyTimeout, foreignKeys)
+		}
+	}
+}
+
+// lockDatabase takes SQLite's write lock on path from a handle of its own, as another
+// process would, until the returned function is called.
+func lockDatabase(t *testing.T, path string) func() {
+	t.Helper()
+	other, err := sql.Open("sqlite", path+"?_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := other.Begin()
+	if err != nil {
+		t.Fatalf("Failed to take the write lock: %v", err)
+	}
+	return func() {
+		_ = tx.Rollback()
+		_ = other.Close()
+	}
+}
+
+func TestRetryBusy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := InitDB(t.Context(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+
+	// A handle without busy_timeout fails at once while another one writes
+	impatient, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = impatient.Close() }()
+
+	unlock := lockDatabase(t, path)
+	_, err = impatient.Exec(`INSERT INTO tags (name) VALUES ('refused')`)
+	if !IsBusy(err) {
+		t.Fatalf("Expected a busy error, got %v", err)
+	}
+
+	// Retrying outlasts the other writer
+	time.AfterFunc(120*time.Millisecond, unlock)
+	if _, err := exec(t.Context(), impatient, `INSERT INTO tags (name) VALUES ('retried')`); err != nil {
+		t.Fatalf("Expected the retried write to succeed, got %v", err)
+	}
+
+	if IsBusy(fmt.Errorf("failed: %w", sql.ErrNoRows)) {
+		t.Error("Only SQLite busy errors should count as busy")
+	}
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	// Two handles on one file stand in for two toki processes
+	var handles []*sql.DB
+	for range 2 {
+		db, err := InitDB(t.Context(), path)
+		if err != nil {
+			t.Fatalf("Failed to init DB: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+		handles = append(handles, db)
+	}
+
+	project := models.NewProject("busy", nil)
+	if err := CreateProject(t.Context(), handles[0], project); err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, perWorker = 8, 15
+	errs := make(chan error, workers*perWorker)
+	var wg sync.WaitGroup
+	for w := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db := handles[w%len(handles)]
+			for i := range perWorker {
+				todo := models.NewTodo(project.ID, fmt.Sprintf("worker %d todo %d", w, i))
+				if err := CreateTodoWithTags(t.Context(), db, todo, []string{"shared", fmt.Sprintf("w%d", w)}); err != nil {
+					errs <- err
+					continue
+				}
+				todo.MarkDone()
+				if err := UpdateTodo(t.Context(), db, todo); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("Write failed: %v", err)
+	}
+
+	shared := "shared"
+	todos, err := ListTodos(t.Context(), handles[1], &project.ID, nil, nil, &shared, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != workers*perWorker {
+		t.Errorf("Expected %d todos, got %d", workers*perWorker, len(todos))
+	}
+	for _, todo := range todos {
+		if !todo.Done {
+			t.Errorf("Update to %q was lost", todo.Description)
+		}
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	db := setupTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	project := models.NewProject("test", nil)
+	if err := CreateProject(t.Context(), db, project); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if _, err := ListTodos(ctx, db, nil, nil, nil, nil, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a read to fail with context.Canceled, got %v", err)
+	}
+	if err := CreateTodo(ctx, db, models.NewTodo(project.ID, "never")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a write to fail with context.Canceled, got %v", err)
+	}
+	if err := CreateTodoWithTags(ctx, db, models.NewTodo(project.ID, "never"), []string{"x"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a transaction to fail with context.Canceled, got %v", err)
+	}
+	if todos, _ := ListTodos(t.Context(), db, nil, nil, nil, nil, nil); len(todos) != 0 {
+		t.Errorf("Expected no todos written, got %d", len(todos))
+	}
+}
+
+func TestCancelWhileBusy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := InitDB(t.Context(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	unlock := lockDatabase(t, path)
+	defer unlock()
+
+	// A write waiting for another process's lock gives up once its context ends
+	ctx, cancel := context.WithTimeout(t.Context(), 300*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = CreateProject(ctx, db, models.NewProject("waiting", nil))
+	if !IsBusy(err) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the write to fail, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the cancelled write to stop waiting, took %v", elapsed)
+	}
+}
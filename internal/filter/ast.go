@@ -0,0 +1,216 @@
+// ABOUTME: Abstract syntax tree for todo filter expressions
+// ABOUTME: Defines fields, operators, and the and/or/not/term nodes produced by Parse
+
+package filter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Field is a todo attribute a term can test.
+type Field string
+
+// Fields understood by the filter language.
+const (
+	FieldProject   Field = "project"
+	FieldTag       Field = "tag"
+	FieldPriority  Field = "priority"
+	FieldDue       Field = "due"
+	FieldCreated   Field = "created"
+	FieldUpdated   Field = "updated"
+	FieldCompleted Field = "completed"
+	FieldDone      Field = "done"
+	FieldOverdue   Field = "overdue"
+	FieldBranch    Field = "branch"
+	FieldText      Field = "text"
+	FieldID        Field = "id"
+)
+
+// fieldAliases maps every accepted spelling to its field.
+var fieldAliases = map[string]Field{
+	"project":     FieldProject,
+	"tag":         FieldTag,
+	"priority":    FieldPriority,
+	"pri":         FieldPriority,
+	"due":         FieldDue,
+	"created":     FieldCreated,
+	"updated":     FieldUpdated,
+	"completed":   FieldCompleted,
+	"done":        FieldDone,
+	"overdue":     FieldOverdue,
+	"branch":      FieldBranch,
+	"text":        FieldText,
+	"description": FieldText,
+	"desc":        FieldText,
+	"id":          FieldID,
+}
+
+// IsDate reports whether the field holds a timestamp.
+func (f Field) IsDate() bool {
+	return f == FieldDue || f == FieldCreated || f == FieldUpdated || f == FieldCompleted
+}
+
+// IsBool reports whether the field is true or false.
+func (f Field) IsBool() bool {
+	return f == FieldDone || f == FieldOverdue
+}
+
+// Ordered reports whether the field supports <, <=, >, and >=.
+func (f Field) Ordered() bool {
+	return f.IsDate() || f == FieldPriority
+}
+
+// Op is a comparison operator.
+type Op string
+
+// Operators. OpMatch (":") means equality, except for text where it means "contains".
+const (
+	OpMatch Op = ":"
+	OpEq    Op = "="
+	OpNe    Op = "!="
+	OpLt    Op = "<"
+	OpLe    Op = "<="
+	OpGt    Op = ">"
+	OpGe    Op = ">="
+)
+
+// Special values accepted by optional fields.
+const (
+	ValueNone = "none"
+	ValueAny  = "any"
+)
+
+// Node is a filter expression.
+type Node interface {
+	// String renders the node in filter syntax; parsing the result yields an equal tree.
+	String() string
+	node()
+}
+
+// And matches todos matching both sides.
+type And struct{ Left, Right Node }
+
+// Or matches todos matching either side.
+type Or struct{ Left, Right Node }
+
+// Not matches todos not matching X.
+type Not struct{ X Node }
+
+// Term compares one field against a value. Pos is the byte offset of the term in the
+// parsed input, or -1 for terms built in code.
+type Term struct {
+	Field Field
+	Op    Op
+	Value string
+	Pos   int
+}
+
+func (And) node()  {}
+func (Or) node()   {}
+func (Not) node()  {}
+func (Term) node() {}
+
+func (n And) String() string { return group(n.Left, false) + " and " + group(n.Right, false) }
+func (n Or) String() string  { return n.Left.String() + " or " + n.Right.String() }
+func (n Not) String() string { return "not " + group(n.X, true) }
+
+func (n Term) String() string {
+	if n.Field.IsBool() && n.Op == OpMatch && n.Value == "true" {
+		return string(n.Field)
+	}
+	return string(n.Field) + string(n.Op) + quote(n.Value)
+}
+
+// group parenthesizes or-expressions, and with strict also and-expressions, so the
+// rendered string keeps the tree's precedence.
+func group(n Node, strict bool) string {
+	switch n.(type) {
+	case Or:
+		return "(" + n.String() + ")"
+	case And:
+		if strict {
+			return "(" + n.String() + ")"
+		}
+	}
+	return n.String()
+}
+
+// quote returns value as a bare word when the lexer would read it back unchanged.
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n()\":=<>!") || isKeyword(value) {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// NewTerm builds a term outside the parser, for combining flags with a parsed filter.
+func NewTerm(field Field, op Op, value string) Term {
+	return Term{Field: field, Op: op, Value: value, Pos: -1}
+}
+
+// AndAll joins nodes with and, skipping nils. It returns nil when every node is nil.
+func AndAll(nodes ...Node) Node {
+	var result Node
+	for _, n := range nodes {
+		switch {
+		case n == nil:
+		case result == nil:
+			result = n
+		default:
+			result = And{Left: result, Right: n}
+		}
+	}
+	return result
+}
+
+// Criteria are the fixed filters offered as command flags and tool arguments.
+type Criteria struct {
+	// Project is a project name or UUID.
+	Project  string
+	Done     *bool
+	Priority *string
+	Tag      *string
+	Branch   *string
+	Overdue  *bool
+}
+
+// Node returns the set criteria and-ed together, or nil when none are set.
+func (c Criteria) Node() Node {
+	var terms []Node
+	if c.Project != "" {
+		terms = append(terms, NewTerm(FieldProject, OpMatch, c.Project))
+	}
+	if c.Done != nil {
+		terms = append(terms, NewTerm(FieldDone, OpMatch, strconv.FormatBool(*c.Done)))
+	}
+	if c.Priority != nil {
+		terms = append(terms, NewTerm(FieldPriority, OpMatch, *c.Priority))
+	}
+	if c.Tag != nil {
+		terms = append(terms, NewTerm(FieldTag, OpMatch, *c.Tag))
+	}
+	if c.Branch != nil {
+		terms = append(terms, NewTerm(FieldBranch, OpMatch, *c.Branch))
+	}
+	if c.Overdue != nil {
+		terms = append(terms, NewTerm(FieldOverdue, OpMatch, strconv.FormatBool(*c.Overdue)))
+	}
+	return AndAll(terms...)
+}
+
+// References reports whether any term in n tests field.
+func References(n Node, field Field) bool {
+	switch n := n.(type) {
+	case And:
+		return References(n.Left, field) || References(n.Right, field)
+	case Or:
+		return References(n.Left, field) || References(n.Right, field)
+	case Not:
+		return References(n.X, field)
+	case Term:
+		return n.Field == field
+	default:
+		return false
+	}
+}
@@ -0,0 +1,124 @@
+// ABOUTME: Date values for filter expressions
+// ABOUTME: Resolves dates, relative offsets like +7d, and periods like this-week to time ranges
+
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResolveDate turns a date value into the half-open range [start, end) it covers. Day
+// values span a whole UTC day, matching how due dates are stored; "now" is an instant
+// with start equal to end. Relative values like +7d, -2w, 1m, or 1y count calendar days,
+// weeks, months, or years from today.
+func ResolveDate(value string, now time.Time) (time.Time, time.Time, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var day time.Time
+	switch value {
+	case "now":
+		return now, now, nil
+	case "today":
+		day = today
+	case "tomorrow":
+		day = today.AddDate(0, 0, 1)
+	case "yesterday":
+		day = today.AddDate(0, 0, -1)
+	default:
+		if parsed, err := time.Parse("2006-01-02", value); err == nil {
+			day = parsed
+			break
+		}
+		offset, err := relativeDate(today, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		day = offset
+	}
+
+	return day, day.AddDate(0, 0, 1), nil
+}
+
+func relativeDate(today time.Time, value string) (time.Time, error) {
+	invalid := fmt.Errorf("invalid date '%s' (expected YYYY-MM-DD, today, tomorrow, yesterday, now, or an offset like +7d, -2w, +1m)", value)
+	if len(value) < 2 {
+		return time.Time{}, invalid
+	}
+
+	digits := value[:len(value)-1]
+	if digits[0] == '+' {
+		digits = digits[1:]
+	}
+	n, err := strconv.Atoi(digits)
+	if err != nil || digits == "" || digits[0] == '+' {
+		return time.Time{}, invalid
+	}
+
+	switch value[len(value)-1] {
+	case 'd':
+		return today.AddDate(0, 0, n), nil
+	case 'w':
+		return today.AddDate(0, 0, 7*n), nil
+	case 'm':
+		return today.AddDate(0, n, 0), nil
+	case 'y':
+		return today.AddDate(n, 0, 0), nil
+	default:
+		return time.Time{}, invalid
+	}
+}
+
+// Periods are the named ranges accepted by ResolvePeriod.
+var Periods = []string{"today", "yesterday", "this-week", "last-week", "this-month", "last-month", "this-year", "all"}
+
+// ResolvePeriod turns a period into the half-open range [start, end) it covers in now's
+// time zone: one of Periods (weeks start on Monday), a day like 2025-01-31, or two days
+// joined by "..", both included. "all" starts at the zero time and ends at now.
+func ResolvePeriod(value string, now time.Time) (time.Time, time.Time, error) {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	weekStart := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+
+	switch value {
+	case "today":
+		return today, today.AddDate(0, 0, 1), nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), today, nil
+	case "this-week":
+		return weekStart, weekStart.AddDate(0, 0, 7), nil
+	case "last-week":
+		return weekStart.AddDate(0, 0, -7), weekStart, nil
+	case "this-month":
+		return monthStart, monthStart.AddDate(0, 1, 0), nil
+	case "last-month":
+		return monthStart.AddDate(0, -1, 0), monthStart, nil
+	case "this-year":
+		yearStart := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, loc)
+		return yearStart, yearStart.AddDate(1, 0, 0), nil
+	case "all":
+		return time.Time{}, now, nil
+	}
+
+	first, last, found := strings.Cut(value, "..")
+	if !found {
+		last = first
+	}
+	start, err := time.ParseInLocation("2006-01-02", first, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, invalidPeriod(value)
+	}
+	end, err := time.ParseInLocation("2006-01-02", last, loc)
+	if err != nil || end.Before(start) {
+		return time.Time{}, time.Time{}, invalidPeriod(value)
+	}
+	return start, end.AddDate(0, 0, 1), nil
+}
+
+func invalidPeriod(value string) error {
+	return fmt.Errorf("invalid range '%s' (expected %s, YYYY-MM-DD, or YYYY-MM-DD..YYYY-MM-DD)", value, strings.Join(Periods, ", "))
+}
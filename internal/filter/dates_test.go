@@ -0,0 +1,96 @@
+// ABOUTME: Tests for filter date values
+// ABOUTME: Checks named days, ISO dates, relative offsets, and periods resolve to ranges
+
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveDate(t *testing.T) {
+	now := time.Date(2025, 1, 31, 15, 30, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		value string
+		start time.Time
+	}{
+		{"today", day(2025, 1, 31)},
+		{"tomorrow", day(2025, 2, 1)},
+		{"yesterday", day(2025, 1, 30)},
+		{"2025-03-04", day(2025, 3, 4)},
+		{"+7d", day(2025, 2, 7)},
+		{"7d", day(2025, 2, 7)},
+		{"-2w", day(2025, 1, 17)},
+		{"+1m", day(2025, 3, 3)},
+		{"1y", day(2026, 1, 31)},
+	}
+
+	for _, tt := range tests {
+		start, end, err := ResolveDate(tt.value, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.value, err)
+			continue
+		}
+		if !start.Equal(tt.start) || !end.Equal(tt.start.AddDate(0, 0, 1)) {
+			t.Errorf("%s: expected [%v, +1d), got [%v, %v)", tt.value, tt.start, start, end)
+		}
+	}
+
+	start, end, err := ResolveDate("now", now)
+	if err != nil || !start.Equal(now) || !end.Equal(now) {
+		t.Errorf("now: expected instant %v, got [%v, %v) %v", now, start, end, err)
+	}
+
+	for _, value := range []string{"soon", "+d", "++1d", "1h", "2025-13-01"} {
+		if _, _, err := ResolveDate(value, now); err == nil {
+			t.Errorf("%s: expected error", value)
+		}
+	}
+}
+
+func TestResolvePeriod(t *testing.T) {
+	// Friday
+	now := time.Date(2025, 1, 31, 15, 30, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		value      string
+		start, end time.Time
+	}{
+		{"today", day(2025, 1, 31), day(2025, 2, 1)},
+		{"yesterday", day(2025, 1, 30), day(2025, 1, 31)},
+		{"this-week", day(2025, 1, 27), day(2025, 2, 3)},
+		{"last-week", day(2025, 1, 20), day(2025, 1, 27)},
+		{"this-month", day(2025, 1, 1), day(2025, 2, 1)},
+		{"last-month", day(2024, 12, 1), day(2025, 1, 1)},
+		{"this-year", day(2025, 1, 1), day(2026, 1, 1)},
+		{"all", time.Time{}, now},
+		{"2025-01-15", day(2025, 1, 15), day(2025, 1, 16)},
+		{"2025-01-01..2025-01-07", day(2025, 1, 1), day(2025, 1, 8)},
+	}
+
+	for _, tt := range tests {
+		start, end, err := ResolvePeriod(tt.value, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.value, err)
+			continue
+		}
+		if !start.Equal(tt.start) || !end.Equal(tt.end) {
+			t.Errorf("%s: expected [%v, %v), got [%v, %v)", tt.value, tt.start, tt.end, start, end)
+		}
+	}
+
+	// Sunday still belongs to the week that started on Monday
+	sunday := time.Date(2025, 2, 2, 23, 0, 0, 0, time.UTC)
+	if start, _, _ := ResolvePeriod("this-week", sunday); !start.Equal(day(2025, 1, 27)) {
+		t.Errorf("this-week on a Sunday: got start %v", start)
+	}
+
+	for _, value := range []string{"", "next-week", "2025-13-01", "2025-01-07..2025-01-01", "2025-01-01.."} {
+		if _, _, err := ResolvePeriod(value, now); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
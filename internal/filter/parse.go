@@ -0,0 +1,366 @@
+// ABOUTME: Lexer and recursive-descent parser for todo filter expressions
+// ABOUTME: Turns input like "tag:bug or priority:high" into an AST with positioned errors
+
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SyntaxError reports an invalid expression and where in the input it went wrong.
+type SyntaxError struct {
+	Input string
+	// Pos is the byte offset of the offending token.
+	Pos int
+	Msg string
+}
+
+// Column returns the 1-based character column of the error.
+func (e *SyntaxError) Column() int {
+	return utf8.RuneCountInString(e.Input[:min(e.Pos, len(e.Input))]) + 1
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter at column %d: %s", e.Column(), e.Msg)
+}
+
+// Context returns the input with a caret under the error position, for terminal output.
+func (e *SyntaxError) Context() string {
+	return "  " + e.Input + "\n  " + strings.Repeat(" ", e.Column()-1) + "^"
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of filter"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// lex splits input into tokens. Words run until whitespace, a parenthesis, a quote, or
+// an operator character; quoted strings may contain anything and use \" for quotes.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokenLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokenRParen, ")", i})
+			i++
+		case c == '"':
+			text, end, err := lexString(input, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokenString, text, i})
+			i = end
+		case strings.IndexByte(":=<>!", c) >= 0:
+			op := string(c)
+			if i+1 < len(input) && input[i+1] == '=' && c != ':' && c != '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, &SyntaxError{Input: input, Pos: i, Msg: "expected '!=' but found '!'"}
+			}
+			tokens = append(tokens, token{tokenOp, op, i})
+			i += len(op)
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\n\r()\":=<>!", rune(input[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, input[start:i], start})
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(input)}), nil
+}
+
+func lexString(input string, start int) (string, int, error) {
+	var builder strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			if i+1 < len(input) {
+				i++
+				builder.WriteByte(input[i])
+			}
+		case '"':
+			return builder.String(), i + 1, nil
+		default:
+			builder.WriteByte(input[i])
+		}
+	}
+	return "", 0, &SyntaxError{Input: input, Pos: start, Msg: "unterminated quoted string"}
+}
+
+func isKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not":
+		return true
+	}
+	return false
+}
+
+type parser struct {
+	input  string
+	tokens []token
+	pos    int
+}
+
+// Parse parses a filter expression. Terms are field:value or field<op>value, combined with
+// and, or, not, and parentheses; adjacent terms are and-ed. Bare words are keywords (done,
+// pending, overdue) or text searches.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 1 {
+		return nil, &SyntaxError{Input: input, Pos: 0, Msg: "empty filter"}
+	}
+
+	p := &parser{input: input, tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		if tok.kind == tokenRParen {
+			return nil, p.errorf(tok, "unmatched ')'")
+		}
+		return nil, p.errorf(tok, "unexpected %s", tok.describe())
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...any) error {
+	return &SyntaxError{Input: p.input, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) isWord(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenWord && strings.EqualFold(tok.text, word)
+}
+
+func (p *parser) parseOr() (Node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		switch {
+		case p.isWord("and"):
+			p.next()
+		case tok.kind == tokenEOF || tok.kind == tokenRParen || p.isWord("or"):
+			return left, nil
+		}
+		// Anything else starts another and-ed term
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.isWord("not") {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			if closing.kind == tokenEOF {
+				return nil, p.errorf(tok, "missing ')' for this '('")
+			}
+			return nil, p.errorf(closing, "expected ')' but found %s", closing.describe())
+		}
+		return node, nil
+	case tokenString:
+		return Term{Field: FieldText, Op: OpMatch, Value: tok.text, Pos: tok.pos}, nil
+	case tokenWord:
+		if isKeyword(tok.text) {
+			return nil, p.errorf(tok, "expected a term but found %s", tok.describe())
+		}
+		if p.peek().kind == tokenOp {
+			return p.parseComparison(tok)
+		}
+		return p.bareWord(tok), nil
+	case tokenOp:
+		return nil, p.errorf(tok, "expected a field name before %s", tok.describe())
+	default:
+		return nil, p.errorf(tok, "expected a term but found %s", tok.describe())
+	}
+}
+
+// bareWord turns a word without an operator into a keyword test or a text search.
+func (p *parser) bareWord(tok token) Node {
+	switch strings.ToLower(tok.text) {
+	case "done":
+		return Term{Field: FieldDone, Op: OpMatch, Value: "true", Pos: tok.pos}
+	case "pending":
+		return Term{Field: FieldDone, Op: OpMatch, Value: "false", Pos: tok.pos}
+	case "overdue":
+		return Term{Field: FieldOverdue, Op: OpMatch, Value: "true", Pos: tok.pos}
+	default:
+		return Term{Field: FieldText, Op: OpMatch, Value: tok.text, Pos: tok.pos}
+	}
+}
+
+func (p *parser) parseComparison(name token) (Node, error) {
+	field, ok := fieldAliases[strings.ToLower(name.text)]
+	if !ok {
+		return nil, p.errorf(name, "unknown field '%s' (expected one of %s)", name.text, fieldList())
+	}
+
+	opTok := p.next()
+	op := Op(opTok.text)
+	if !field.Ordered() && op != OpMatch && op != OpEq && op != OpNe {
+		return nil, p.errorf(opTok, "operator '%s' does not apply to %s", op, field)
+	}
+
+	valueTok := p.next()
+	if valueTok.kind != tokenWord && valueTok.kind != tokenString {
+		return nil, p.errorf(valueTok, "expected a value after '%s' but found %s", op, valueTok.describe())
+	}
+
+	value, err := normalizeValue(field, op, valueTok.text)
+	if err != nil {
+		return nil, p.errorf(valueTok, "%s", err)
+	}
+	return Term{Field: field, Op: op, Value: value, Pos: name.pos}, nil
+}
+
+// normalizeValue validates a value for its field and returns its canonical spelling.
+func normalizeValue(field Field, op Op, value string) (string, error) {
+	lower := strings.ToLower(value)
+	ordered := op != OpMatch && op != OpEq && op != OpNe
+
+	switch {
+	case field.IsBool():
+		b, err := parseBool(lower)
+		if err != nil {
+			return "", fmt.Errorf("%s expects true or false, got '%s'", field, value)
+		}
+		return strconv.FormatBool(b), nil
+	case field == FieldPriority:
+		if _, ok := priorityRanks[lower]; !ok && !(lower == ValueNone && !ordered) {
+			return "", fmt.Errorf("priority expects low, medium, high, or none, got '%s'", value)
+		}
+		return lower, nil
+	case field.IsDate():
+		if (lower == ValueNone || lower == ValueAny) && !ordered {
+			return lower, nil
+		}
+		if _, _, err := ResolveDate(lower, time.Now()); err != nil {
+			return "", err
+		}
+		return lower, nil
+	case field == FieldText:
+		if value == "" {
+			return "", fmt.Errorf("text search needs a non-empty value")
+		}
+		return value, nil
+	case field == FieldID:
+		return lower, nil
+	default:
+		return value, nil
+	}
+}
+
+// priorityRanks orders priorities for <, <=, >, and >=.
+var priorityRanks = map[string]int{"low": 1, "medium": 2, "high": 3}
+
+// PriorityRank returns a priority's position in low < medium < high, or 0 if unknown.
+func PriorityRank(priority string) int {
+	return priorityRanks[priority]
+}
+
+func parseBool(value string) (bool, error) {
+	switch value {
+	case "yes", "y":
+		return true, nil
+	case "no", "n":
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+func fieldList() string {
+	return strings.Join([]string{
+		string(FieldProject), string(FieldTag), string(FieldPriority), string(FieldDue),
+		string(FieldCreated), string(FieldUpdated), string(FieldCompleted), string(FieldDone),
+		string(FieldOverdue), string(FieldBranch), string(FieldText), string(FieldID),
+	}, ", ")
+}
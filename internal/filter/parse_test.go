@@ -0,0 +1,149 @@
+// ABOUTME: Tests for the filter expression parser
+// ABOUTME: Covers precedence, keywords, round-tripping, and error positions
+
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"project:api", "project:api"},
+		{"project:api and (tag:bug or priority:high) and due<+7d and not done", "project:api and (tag:bug or priority:high) and due<+7d and not done"},
+		{"tag:bug or tag:ui and priority:high", "tag:bug or tag:ui and priority:high"},
+		{"(tag:bug or tag:ui) priority:high", "(tag:bug or tag:ui) and priority:high"},
+		{"not (done or overdue)", "not (done or overdue)"},
+		{"pending", "done:false"},
+		{"PRI>=Medium", "priority>=medium"},
+		{"done:no", "done:false"},
+		{"login", "text:login"},
+		{`"fix login" desc:"a:b"`, `text:"fix login" and text:"a:b"`},
+		{`text:"and"`, `text:"and"`},
+		{"branch!=feature/login", "branch!=feature/login"},
+		{"due:none or due>2025-01-31", "due:none or due>2025-01-31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			node, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			if got := node.String(); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+
+			// Rendering must parse back to the same tree
+			again, err := Parse(node.String())
+			if err != nil {
+				t.Fatalf("Failed to reparse %q: %v", node.String(), err)
+			}
+			if again.String() != node.String() {
+				t.Errorf("Round trip changed %q to %q", node.String(), again.String())
+			}
+		})
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	node, err := Parse("a or b and c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	or, ok := node.(Or)
+	if !ok {
+		t.Fatalf("Expected or at the root, got %T", node)
+	}
+	if _, ok := or.Right.(And); !ok {
+		t.Errorf("Expected and to bind tighter than or, got %T", or.Right)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		column int
+		msg    string
+	}{
+		{"", 1, "empty filter"},
+		{"tag:bug and", 12, "expected a term but found end of filter"},
+		{"(tag:bug or done", 1, "missing ')'"},
+		{"tag:bug)", 8, "unmatched ')'"},
+		{"colour:red", 1, "unknown field 'colour'"},
+		{"tag<bug", 4, "operator '<' does not apply to tag"},
+		{"priority:urgent", 10, "priority expects"},
+		{"due<+7x", 5, "invalid date '+7x'"},
+		{"done:maybe", 6, "done expects true or false"},
+		{"tag:", 5, "expected a value after ':'"},
+		{`text:"open`, 6, "unterminated quoted string"},
+		{"tag:bug ! done", 9, "expected '!='"},
+		{"and done", 1, "expected a term but found 'and'"},
+		{":bug", 1, "expected a field name"},
+		{"día:1", 1, "unknown field 'día'"},
+		{"tag:x día:1", 7, "unknown field 'día'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected SyntaxError, got %v", err)
+			}
+			if syntaxErr.Column() != tt.column {
+				t.Errorf("Expected column %d, got %d (%v)", tt.column, syntaxErr.Column(), err)
+			}
+			if !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Expected error to contain %q, got %q", tt.msg, err.Error())
+			}
+		})
+	}
+}
+
+func TestSyntaxErrorContext(t *testing.T) {
+	_, err := Parse("tag:bug and colour:red")
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("Expected SyntaxError, got %v", err)
+	}
+	want := "  tag:bug and colour:red\n              ^"
+	if syntaxErr.Context() != want {
+		t.Errorf("Expected context:\n%s\ngot:\n%s", want, syntaxErr.Context())
+	}
+}
+
+func TestReferencesAndAndAll(t *testing.T) {
+	node, err := Parse("tag:bug or not done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !References(node, FieldDone) || References(node, FieldProject) {
+		t.Error("Unexpected References result")
+	}
+
+	combined := AndAll(nil, node, NewTerm(FieldProject, OpMatch, "api"), nil)
+	if got := combined.String(); got != "(tag:bug or not done) and project:api" {
+		t.Errorf("Unexpected combined filter %q", got)
+	}
+	if AndAll(nil, nil) != nil {
+		t.Error("Expected nil when every node is nil")
+	}
+}
+
+func TestCriteria(t *testing.T) {
+	done, overdue := false, true
+	tag := "bug"
+	node := Criteria{Project: "api", Done: &done, Tag: &tag, Overdue: &overdue}.Node()
+	if got := node.String(); got != "project:api and done:false and tag:bug and overdue" {
+		t.Errorf("Unexpected criteria filter %q", got)
+	}
+	if (Criteria{}).Node() != nil {
+		t.Error("Expected nil for empty criteria")
+	}
+}
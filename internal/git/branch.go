@@ -0,0 +1,200 @@
+// ABOUTME: Git branch detection from HEAD and local refs
+// ABOUTME: Reports the current branch, whether a branch exists, and whether it is merged
+
+package git
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrDetachedHead is returned when HEAD does not point at a branch.
+var ErrDetachedHead = errors.New("HEAD is detached")
+
+// CurrentBranch returns the branch checked out in the worktree containing start.
+// Each linked worktree has its own HEAD, so worktrees report their own branch.
+func CurrentBranch(start string) (string, error) {
+	gitDir, err := findGitDir(start)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD")) //nolint:gosec // Reading HEAD of the repository being detected
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref:")
+	if !ok {
+		return "", ErrDetachedHead
+	}
+
+	branch, ok := strings.CutPrefix(strings.TrimSpace(ref), "refs/heads/")
+	if !ok {
+		return "", ErrDetachedHead
+	}
+	return branch, nil
+}
+
+// TrackBranches reports whether the repository containing start opts in to recording
+// the current branch on every new todo, via `git config toki.trackBranches true`.
+func TrackBranches(start string) bool {
+	enabled, err := strconv.ParseBool(ConfigValue(start, "toki", "trackbranches"))
+	return err == nil && enabled
+}
+
+// ConfigValue returns a setting from the config of the repository containing start, or
+// an empty string when it is unset. Linked worktrees read the shared repository config.
+func ConfigValue(start, section, key string) string {
+	gitDir, err := findGitDir(start)
+	if err != nil {
+		return ""
+	}
+	if common, ok := readCommonDir(gitDir); ok {
+		gitDir = common
+	}
+	return readConfigValue(filepath.Join(gitDir, "config"), section, key)
+}
+
+// BranchExists reports whether refs/heads/<branch> exists as a loose or packed ref.
+func BranchExists(root, branch string) bool {
+	return resolveRef(gitDirForRoot(root), "refs/heads/"+branch) != ""
+}
+
+// DefaultBranch returns the branch other branches merge into: origin's HEAD when known,
+// otherwise "main" or "master" if present locally.
+func DefaultBranch(root string) (string, error) {
+	gitDir := gitDirForRoot(root)
+
+	data, err := os.ReadFile(filepath.Join(gitDir, "refs", "remotes", "origin", "HEAD")) //nolint:gosec // Reading refs of the repository being detected
+	if err == nil {
+		ref, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: refs/remotes/origin/")
+		if ok && resolveRef(gitDir, "refs/heads/"+ref) != "" {
+			return ref, nil
+		}
+	}
+
+	for _, name := range []string{"main", "master"} {
+		if resolveRef(gitDir, "refs/heads/"+name) != "" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("could not determine default branch for %s", root)
+}
+
+// IsMerged reports whether branch has been merged into target. A branch pointing at
+// the same commit as target is merged only if commits were made on it, as when it was
+// fast-forwarded into target; a branch just created from target is not. Otherwise
+// ancestry is checked with the git binary.
+func IsMerged(root, branch, target string) (bool, error) {
+	gitDir := gitDirForRoot(root)
+
+	branchSHA := resolveRef(gitDir, "refs/heads/"+branch)
+	if branchSHA == "" {
+		return false, fmt.Errorf("branch '%s' does not exist", branch)
+	}
+	targetSHA := resolveRef(gitDir, "refs/heads/"+target)
+	if targetSHA == "" {
+		return false, fmt.Errorf("branch '%s' does not exist", target)
+	}
+	if branchSHA == targetSHA {
+		return hasOwnCommits(gitDir, branch), nil
+	}
+
+	cmd := exec.Command("git", "-C", root, "merge-base", "--is-ancestor", branchSHA, targetSHA) //nolint:gosec // Arguments are ref hashes read from the repository
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check if '%s' is merged: %w", branch, err)
+}
+
+// hasOwnCommits reports whether the branch's reflog shows it moving after it was
+// created, other than by a reset or a rename. The reflog is the only record of this, so
+// a branch without one, as when core.logAllRefUpdates is off or the reflog has been
+// deleted, is taken to have no commits of its own and so not to be merged. A branch
+// checked out from a fresh clone's remote branch likewise has only its creation logged.
+func hasOwnCommits(gitDir, branch string) bool {
+	data, err := os.ReadFile(filepath.Join(gitDir, "logs", "refs", "heads", filepath.FromSlash(branch))) //nolint:gosec // Reading the reflog of the repository being checked
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		entry, message, _ := strings.Cut(line, "\t")
+		fields := strings.Fields(entry)
+		if len(fields) < 2 || fields[0] == fields[1] || strings.Trim(fields[0], "0") == "" {
+			// Malformed, a rename, or the branch's creation
+			continue
+		}
+		if !strings.HasPrefix(message, "reset:") && !strings.HasPrefix(message, "branch:") {
+			return true
+		}
+	}
+	return false
+}
+
+// findGitDir walks up from start to the git dir of the enclosing worktree, following
+// "gitdir:" files but not commondir, so linked worktrees keep their own HEAD.
+func findGitDir(start string) (string, error) {
+	absPath, err := filepath.Abs(start)
+	if err != nil {
+		return "", fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	currentPath := absPath
+	for {
+		gitPath := filepath.Join(currentPath, ".git")
+		if info, err := os.Stat(gitPath); err == nil {
+			if info.IsDir() {
+				return gitPath, nil
+			}
+			return readGitDirFile(gitPath)
+		}
+
+		if isBareRepo(currentPath) {
+			return currentPath, nil
+		}
+
+		parent := filepath.Dir(currentPath)
+		if parent == currentPath {
+			return "", fmt.Errorf("not in a git repository")
+		}
+		currentPath = parent
+	}
+}
+
+// resolveRef returns the commit hash for a full ref name, checking loose refs before
+// packed-refs. Symbolic refs are not followed. Returns "" when the ref does not exist.
+func resolveRef(gitDir, ref string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, filepath.FromSlash(ref))) //nolint:gosec // Reading refs of the repository being detected
+	if err == nil {
+		return strings.TrimSpace(string(data))
+	}
+
+	file, err := os.Open(filepath.Join(gitDir, "packed-refs")) //nolint:gosec // Reading refs of the repository being detected
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		sha, name, ok := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		if ok && name == ref {
+			return sha
+		}
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+// ABOUTME: Tests for git branch detection
+// ABOUTME: Covers HEAD parsing, worktree branches, ref lookup, and merge checks
+
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCurrentBranch(t *testing.T) {
+	repo := resolvedTempDir(t)
+	makeGitDir(t, filepath.Join(repo, ".git"))
+	writeFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/feature/login\n")
+
+	branch, err := CurrentBranch(repo)
+	if err != nil {
+		t.Fatalf("Failed to read branch: %v", err)
+	}
+	if branch != "feature/login" {
+		t.Errorf("Expected feature/login, got %s", branch)
+	}
+
+	writeFile(t, filepath.Join(repo, ".git", "HEAD"), "0123456789abcdef0123456789abcdef01234567\n")
+	if _, err := CurrentBranch(repo); !errors.Is(err, ErrDetachedHead) {
+		t.Errorf("Expected ErrDetachedHead, got %v", err)
+	}
+}
+
+func TestCurrentBranchLinkedWorktree(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "initial")
+	worktree := filepath.Join(t.TempDir(), "wt")
+	runGit(t, repoDir, "worktree", "add", "-q", "-b", "topic", worktree)
+
+	branch, err := CurrentBranch(worktree)
+	if err != nil {
+		t.Fatalf("Failed to read worktree branch: %v", err)
+	}
+	if branch != "topic" {
+		t.Errorf("Expected topic, got %s", branch)
+	}
+}
+
+func TestBranchExistsPackedRefs(t *testing.T) {
+	repo := resolvedTempDir(t)
+	makeGitDir(t, filepath.Join(repo, ".git"))
+	writeFile(t, filepath.Join(repo, ".git", "refs", "heads", "loose"), "1111111111111111111111111111111111111111\n")
+	writeFile(t, filepath.Join(repo, ".git", "packed-refs"),
+		"# pack-refs with: peeled fully-peeled sorted\n2222222222222222222222222222222222222222 refs/heads/packed\n")
+
+	for branch, expected := range map[string]bool{"loose": true, "packed": true, "gone": false} {
+		if got := BranchExists(repo, branch); got != expected {
+			t.Errorf("BranchExists(%s) = %v, want %v", branch, got, expected)
+		}
+	}
+}
+
+func TestIsMerged(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	runGit(t, repoDir, "checkout", "-q", "-b", "main")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "initial")
+	runGit(t, repoDir, "branch", "merged")
+	runGit(t, repoDir, "checkout", "-q", "-b", "unmerged")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "work")
+	runGit(t, repoDir, "checkout", "-q", "main")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "more")
+	runGit(t, repoDir, "checkout", "-q", "-b", "fast-forwarded")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "feature")
+	runGit(t, repoDir, "checkout", "-q", "main")
+	runGit(t, repoDir, "merge", "-q", "--ff-only", "fast-forwarded")
+	// Just created: at main's commit, but with no commits of its own
+	runGit(t, repoDir, "branch", "fresh")
+	runGit(t, repoDir, "branch", "to-rename")
+	runGit(t, repoDir, "branch", "-m", "to-rename", "renamed")
+
+	target, err := DefaultBranch(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "main" {
+		t.Fatalf("Expected default branch main, got %s", target)
+	}
+
+	for branch, expected := range map[string]bool{"merged": true, "unmerged": false, "fast-forwarded": true, "fresh": false, "renamed": false} {
+		merged, err := IsMerged(repoDir, branch, target)
+		if err != nil {
+			t.Fatalf("IsMerged(%s) failed: %v", branch, err)
+		}
+		if merged != expected {
+			t.Errorf("IsMerged(%s) = %v, want %v", branch, merged, expected)
+		}
+	}
+}
+
+func TestIsMergedWithoutReflog(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	runGit(t, repoDir, "checkout", "-q", "-b", "main")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "initial")
+	runGit(t, repoDir, "branch", "behind")
+	runGit(t, repoDir, "checkout", "-q", "-b", "fast-forwarded")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "feature")
+	runGit(t, repoDir, "checkout", "-q", "main")
+	runGit(t, repoDir, "merge", "-q", "--ff-only", "fast-forwarded")
+
+	for _, branch := range []string{"behind", "fast-forwarded"} {
+		if err := os.Remove(filepath.Join(repoDir, ".git", "logs", "refs", "heads", branch)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Ancestry still shows a branch behind main merged, but at main's commit nothing
+	// tells a fast-forwarded branch from a new one, so it isn't taken as merged
+	for branch, expected := range map[string]bool{"behind": true, "fast-forwarded": false} {
+		merged, err := IsMerged(repoDir, branch, "main")
+		if err != nil {
+			t.Fatalf("IsMerged(%s) failed: %v", branch, err)
+		}
+		if merged != expected {
+			t.Errorf("IsMerged(%s) = %v, want %v", branch, merged, expected)
+		}
+	}
+}
+
+func TestIsMergedFreshClone(t *testing.T) {
+	origin := setupGitRepo(t)
+	runGit(t, origin, "checkout", "-q", "-b", "main")
+	runGit(t, origin, "commit", "--allow-empty", "-m", "initial")
+	runGit(t, origin, "branch", "behind")
+	runGit(t, origin, "checkout", "-q", "-b", "fast-forwarded")
+	runGit(t, origin, "commit", "--allow-empty", "-m", "feature")
+	runGit(t, origin, "checkout", "-q", "main")
+	runGit(t, origin, "merge", "-q", "--ff-only", "fast-forwarded")
+
+	clone := filepath.Join(resolvedTempDir(t), "clone")
+	runGit(t, origin, "clone", "-q", origin, clone)
+	runGit(t, clone, "branch", "behind", "origin/behind")
+	runGit(t, clone, "branch", "fast-forwarded", "origin/fast-forwarded")
+
+	target, err := DefaultBranch(clone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "main" {
+		t.Fatalf("Expected default branch main, got %s", target)
+	}
+
+	// The clone's reflogs only record the branches being created
+	for branch, expected := range map[string]bool{"behind": true, "fast-forwarded": false} {
+		merged, err := IsMerged(clone, branch, target)
+		if err != nil {
+			t.Fatalf("IsMerged(%s) failed: %v", branch, err)
+		}
+		if merged != expected {
+			t.Errorf("IsMerged(%s) = %v, want %v", branch, merged, expected)
+		}
+	}
+}
+
+func TestTrackBranches(t *testing.T) {
+	repo := resolvedTempDir(t)
+	makeGitDir(t, filepath.Join(repo, ".git"))
+	if TrackBranches(repo) {
+		t.Error("Expected branch tracking to be off by default")
+	}
+
+	writeFile(t, filepath.Join(repo, ".git", "config"), "[toki]\n\ttrackBranches = true\n")
+	if !TrackBranches(repo) {
+		t.Error("Expected branch tracking to be enabled by config")
+	}
+}
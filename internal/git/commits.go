@@ -0,0 +1,113 @@
+// ABOUTME: Commit history reading and todo reference parsing
+// ABOUTME: Finds "closes toki:<id>" and "refs toki:<id>" in commit messages
+
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Todo reference actions found in commit messages.
+const (
+	ActionCloses = "closes"
+	ActionRefs   = "refs"
+)
+
+// Commit is a commit's hash and full message.
+type Commit struct {
+	SHA     string
+	Message string
+}
+
+// TodoRef is a todo referenced from a commit message.
+type TodoRef struct {
+	Action string
+	Ref    string
+}
+
+var todoRefPattern = regexp.MustCompile(`(?i)\b(close[sd]?|fix(?:e[sd])?|resolve[sd]?|refs?|references)\s+toki:([0-9a-f][0-9a-f-]{5,35})\b`)
+
+// ParseTodoRefs returns the todo references in a commit message, in order of appearance.
+// "closes", "fixes", and "resolves" (and their tenses) close a todo; "refs" only records it.
+func ParseTodoRefs(message string) []TodoRef {
+	var refs []TodoRef
+	for _, match := range todoRefPattern.FindAllStringSubmatch(message, -1) {
+		action := ActionCloses
+		if strings.HasPrefix(strings.ToLower(match[1]), "ref") {
+			action = ActionRefs
+		}
+		refs = append(refs, TodoRef{Action: action, Ref: strings.ToLower(match[2])})
+	}
+	return refs
+}
+
+// Log returns the commits in revRange (any range accepted by git log), oldest first.
+func Log(root, revRange string) ([]Commit, error) {
+	if strings.HasPrefix(revRange, "-") {
+		return nil, fmt.Errorf("invalid revision range '%s'", revRange)
+	}
+	return readLog(root, "--reverse", revRange)
+}
+
+// HeadCommit returns the commit HEAD points at.
+func HeadCommit(root string) (*Commit, error) {
+	commits, err := readLog(root, "-1", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits in %s", root)
+	}
+	return &commits[0], nil
+}
+
+func readLog(root string, args ...string) ([]Commit, error) {
+	args = append([]string{"-C", root, "log", "--format=%H%x1f%B%x1e"}, args...)
+	args = append(args, "--")
+	cmd := exec.Command("git", args...) //nolint:gosec // Arguments are fixed flags and a validated revision range
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("failed to read git log: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("failed to read git log: %w", err)
+	}
+
+	var commits []Commit
+	for _, record := range strings.Split(string(output), "\x1e") {
+		sha, message, ok := strings.Cut(strings.TrimLeft(record, "\n"), "\x1f")
+		if !ok {
+			continue
+		}
+		commits = append(commits, Commit{SHA: sha, Message: strings.TrimSpace(message)})
+	}
+	return commits, nil
+}
+
+// HooksDir returns the directory git runs hooks from for the repository at root,
+// honoring core.hooksPath.
+func HooksDir(root string) string {
+	gitDir := gitDirForRoot(root)
+
+	if hooksPath := readConfigValue(filepath.Join(gitDir, "config"), "core", "hookspath"); hooksPath != "" {
+		if strings.HasPrefix(hooksPath, "~/") {
+			if home, err := os.UserHomeDir(); err == nil {
+				hooksPath = filepath.Join(home, hooksPath[2:])
+			}
+		}
+		if !filepath.IsAbs(hooksPath) {
+			// Relative hooksPath is resolved against the worktree root
+			hooksPath = filepath.Join(root, hooksPath)
+		}
+		return filepath.Clean(hooksPath)
+	}
+
+	return filepath.Join(gitDir, "hooks")
+}
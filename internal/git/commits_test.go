@@ -0,0 +1,72 @@
+// ABOUTME: Tests for commit history reading and todo reference parsing
+// ABOUTME: Covers closes/refs keywords, git log ranges, and hooks directory lookup
+
+package git
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestParseTodoRefs(t *testing.T) {
+	message := "Fix login redirect\n\nCloses toki:A3F2B9, refs toki:c0ffee12 and fixes toki:bad\nResolved toki:123456"
+	refs := ParseTodoRefs(message)
+
+	expected := []TodoRef{
+		{Action: ActionCloses, Ref: "a3f2b9"},
+		{Action: ActionRefs, Ref: "c0ffee12"},
+		{Action: ActionCloses, Ref: "123456"},
+	}
+	if len(refs) != len(expected) {
+		t.Fatalf("Expected %d refs, got %+v", len(expected), refs)
+	}
+	for i := range expected {
+		if refs[i] != expected[i] {
+			t.Errorf("Ref %d: expected %+v, got %+v", i, expected[i], refs[i])
+		}
+	}
+
+	if refs := ParseTodoRefs("mention toki:a3f2b9 without a keyword"); len(refs) != 0 {
+		t.Errorf("Expected no refs without a keyword, got %+v", refs)
+	}
+}
+
+func TestLogAndHeadCommit(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "first")
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "second\n\nrefs toki:abcdef")
+
+	commits, err := Log(repoDir, "HEAD")
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Message != "first" {
+		t.Fatalf("Expected 2 commits oldest first, got %+v", commits)
+	}
+
+	head, err := HeadCommit(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if head.SHA != commits[1].SHA || head.Message != "second\n\nrefs toki:abcdef" {
+		t.Errorf("Unexpected head commit: %+v", head)
+	}
+
+	if _, err := Log(repoDir, "--output=/tmp/x"); err == nil {
+		t.Error("Expected option-like revision range to be rejected")
+	}
+}
+
+func TestHooksDir(t *testing.T) {
+	repo := resolvedTempDir(t)
+	makeGitDir(t, filepath.Join(repo, ".git"))
+
+	if dir := HooksDir(repo); dir != filepath.Join(repo, ".git", "hooks") {
+		t.Errorf("Expected default hooks dir, got %s", dir)
+	}
+
+	writeFile(t, filepath.Join(repo, ".git", "config"), "[core]\n\thooksPath = .githooks\n")
+	if dir := HooksDir(repo); dir != filepath.Join(repo, ".githooks") {
+		t.Errorf("Expected hooksPath dir, got %s", dir)
+	}
+}
@@ -1,17 +1,54 @@
 // ABOUTME: Git repository detection and path normalization
-// ABOUTME: Walks directory tree to find .git and resolves symlinks
+// ABOUTME: Resolves worktrees, submodules, and bare repos to a stable repository root
 
 package git
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// SubmoduleMode selects how a submodule checkout maps to a repository root.
+type SubmoduleMode string
+
+const (
+	// SubmodulesSeparate treats each submodule as its own repository root.
+	SubmodulesSeparate SubmoduleMode = "separate"
+	// SubmodulesSuperproject resolves submodules to the top-level superproject root.
+	SubmodulesSuperproject SubmoduleMode = "superproject"
+)
+
+// ParseSubmoduleMode validates a submodule mode name. An empty name means SubmodulesSeparate.
+func ParseSubmoduleMode(name string) (SubmoduleMode, error) {
+	switch SubmoduleMode(name) {
+	case "", SubmodulesSeparate:
+		return SubmodulesSeparate, nil
+	case SubmodulesSuperproject:
+		return SubmodulesSuperproject, nil
+	default:
+		return "", fmt.Errorf("invalid submodule mode '%s': must be 'separate' or 'superproject'", name)
+	}
+}
+
+// Options controls how repository roots are resolved.
+type Options struct {
+	Submodules SubmoduleMode
+}
+
 // FindGitRoot walks up the directory tree looking for .git
 // Returns the absolute path to the repository root.
 func FindGitRoot(startPath string) (string, error) {
+	return FindRepoRoot(startPath, Options{})
+}
+
+// FindRepoRoot walks up the directory tree looking for a repository.
+// Linked worktrees resolve to the main repository root (or the bare repository
+// directory), so every worktree of a repo shares one root. Submodules resolve
+// according to opts.Submodules.
+func FindRepoRoot(startPath string, opts Options) (string, error) {
 	absPath, err := filepath.Abs(startPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to get absolute path: %w", err)
@@ -20,13 +57,23 @@ func FindGitRoot(startPath string) (string, error) {
 	currentPath := absPath
 	for {
 		gitPath := filepath.Join(currentPath, ".git")
-		if _, err := os.Stat(gitPath); err == nil {
-			// Resolve symlinks
-			resolved, err := filepath.EvalSymlinks(currentPath)
-			if err != nil {
-				return currentPath, nil //nolint:nilerr // Intentional: symlink resolution failure is not critical, return unresolved path
+		if info, err := os.Stat(gitPath); err == nil {
+			root := currentPath
+			if !info.IsDir() {
+				root, err = resolveGitFile(currentPath, gitPath, opts)
+				if err != nil {
+					return "", err
+				}
 			}
-			return resolved, nil
+			return resolveSymlinks(root), nil
+		}
+
+		if isBareRepo(currentPath) {
+			if filepath.Base(currentPath) == ".git" {
+				// Inside a regular repository's .git directory
+				return resolveSymlinks(filepath.Dir(currentPath)), nil
+			}
+			return resolveSymlinks(currentPath), nil
 		}
 
 		parent := filepath.Dir(currentPath)
@@ -38,6 +85,161 @@ func FindGitRoot(startPath string) (string, error) {
 	}
 }
 
+// resolveGitFile maps a checkout whose .git is a "gitdir:" file (a linked worktree,
+// a submodule, or a separate git dir) to its repository root.
+func resolveGitFile(checkoutDir, gitFile string, opts Options) (string, error) {
+	gitDir, err := readGitDirFile(gitFile)
+	if err != nil {
+		return "", err
+	}
+
+	// Linked worktrees record the shared repository in commondir
+	commonDir := gitDir
+	if common, ok := readCommonDir(gitDir); ok {
+		commonDir = common
+	}
+
+	if super, ok := superprojectRoot(commonDir); ok {
+		if opts.Submodules == SubmodulesSuperproject {
+			return super, nil
+		}
+		if commonDir == gitDir {
+			return checkoutDir, nil
+		}
+		// Worktree of a submodule: its main checkout is recorded in core.worktree
+		if worktree := readCoreWorktree(commonDir); worktree != "" {
+			return worktree, nil
+		}
+		return checkoutDir, nil
+	}
+
+	if commonDir == gitDir {
+		// Separate git dir (git init --separate-git-dir): the checkout is the root
+		return checkoutDir, nil
+	}
+
+	return repoRootForGitDir(commonDir), nil
+}
+
+// readGitDirFile parses a "gitdir: <path>" file and returns the absolute git dir.
+func readGitDirFile(gitFile string) (string, error) {
+	data, err := os.ReadFile(gitFile) //nolint:gosec // Reading .git file inside the repository being detected
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", gitFile, err)
+	}
+
+	line := strings.TrimSpace(string(data))
+	path, ok := strings.CutPrefix(line, "gitdir:")
+	if !ok {
+		return "", fmt.Errorf("invalid git file %s: missing gitdir", gitFile)
+	}
+
+	path = strings.TrimSpace(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(gitFile), path)
+	}
+	return filepath.Clean(path), nil
+}
+
+// readCommonDir returns the shared git dir recorded in gitDir/commondir, if any.
+func readCommonDir(gitDir string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir")) //nolint:gosec // Path derived from the repository's own gitdir
+	if err != nil {
+		return "", false
+	}
+
+	path := strings.TrimSpace(string(data))
+	if path == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(gitDir, path)
+	}
+	return filepath.Clean(path), true
+}
+
+// readCoreWorktree returns the absolute core.worktree setting from gitDir/config, if any.
+func readCoreWorktree(gitDir string) string {
+	value := readConfigValue(filepath.Join(gitDir, "config"), "core", "worktree")
+	if value == "" {
+		return ""
+	}
+	if !filepath.IsAbs(value) {
+		value = filepath.Join(gitDir, value)
+	}
+	return filepath.Clean(value)
+}
+
+// readConfigValue returns the first value of key in a [section] of a git config file.
+// Subsections are written as they appear in the header, e.g. `remote "origin"`.
+func readConfigValue(configPath, section, key string) string {
+	file, err := os.Open(configPath) //nolint:gosec // Reading the repository's own config file
+	if err != nil {
+		return ""
+	}
+	defer func() { _ = file.Close() }()
+
+	inSection := false
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			inSection = strings.EqualFold(strings.Trim(line, "[] \t"), section)
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if ok && strings.EqualFold(strings.TrimSpace(name), key) {
+			return strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return ""
+}
+
+// superprojectRoot reports whether gitDir is a submodule's git dir stored under a
+// superproject's .git/modules, returning the top-level superproject root.
+func superprojectRoot(gitDir string) (string, bool) {
+	marker := string(filepath.Separator) + filepath.Join(".git", "modules") + string(filepath.Separator)
+	idx := strings.Index(gitDir, marker)
+	if idx < 0 {
+		return "", false
+	}
+	return gitDir[:idx], true
+}
+
+// repoRootForGitDir maps a repository's git dir to its root: the parent of a
+// ".git" directory, or the directory itself for a bare repository.
+func repoRootForGitDir(gitDir string) string {
+	if filepath.Base(gitDir) == ".git" {
+		return filepath.Dir(gitDir)
+	}
+	return gitDir
+}
+
+// isBareRepo reports whether dir has the HEAD, objects, and refs of a git dir.
+func isBareRepo(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, "HEAD")); err != nil || info.IsDir() {
+		return false
+	}
+	for _, sub := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	return true
+}
+
+func resolveSymlinks(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		// Symlink resolution failure is not critical, return unresolved path
+		return path
+	}
+	return resolved
+}
+
 // NormalizePath converts a path to absolute and resolves symlinks.
 func NormalizePath(path string) (string, error) {
 	absPath, err := filepath.Abs(path)
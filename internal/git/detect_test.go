@@ -75,3 +75,164 @@ func TestNormalizePath(t *testing.T) {
 		}
 	}
 }
+
+// writeFile creates parent directories and writes content to path.
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// makeGitDir creates the minimal HEAD/objects/refs layout of a git directory.
+func makeGitDir(t *testing.T, dir string) {
+	t.Helper()
+	writeFile(t, filepath.Join(dir, "HEAD"), "ref: refs/heads/main\n")
+	for _, sub := range []string{"objects", "refs"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0750); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func resolvedTempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// runGit runs a git command in dir with a fixed identity so commits succeed anywhere.
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=toki", "-c", "user.email=toki@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func assertRoot(t *testing.T, start string, opts Options, expected string) {
+	t.Helper()
+	root, err := FindRepoRoot(start, opts)
+	if err != nil {
+		t.Fatalf("Failed to find root from %s: %v", start, err)
+	}
+	if root != expected {
+		t.Errorf("From %s: expected root %s, got %s", start, expected, root)
+	}
+}
+
+func TestFindRepoRootLinkedWorktree(t *testing.T) {
+	base := resolvedTempDir(t)
+	mainRepo := filepath.Join(base, "main")
+	worktree := filepath.Join(base, "feature")
+
+	makeGitDir(t, filepath.Join(mainRepo, ".git"))
+	wtGitDir := filepath.Join(mainRepo, ".git", "worktrees", "feature")
+	writeFile(t, filepath.Join(wtGitDir, "HEAD"), "ref: refs/heads/feature\n")
+	writeFile(t, filepath.Join(wtGitDir, "commondir"), "../..\n")
+	writeFile(t, filepath.Join(worktree, ".git"), "gitdir: "+wtGitDir+"\n")
+
+	nested := filepath.Join(worktree, "src", "pkg")
+	if err := os.MkdirAll(nested, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	assertRoot(t, nested, Options{}, mainRepo)
+	assertRoot(t, mainRepo, Options{}, mainRepo)
+}
+
+func TestFindRepoRootBareRepoWorktree(t *testing.T) {
+	base := resolvedTempDir(t)
+	bare := filepath.Join(base, "repo.git")
+	worktree := filepath.Join(base, "main")
+
+	makeGitDir(t, bare)
+	wtGitDir := filepath.Join(bare, "worktrees", "main")
+	writeFile(t, filepath.Join(wtGitDir, "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(wtGitDir, "commondir"), "../..\n")
+	// Relative gitdir paths are resolved from the worktree
+	writeFile(t, filepath.Join(worktree, ".git"), "gitdir: ../repo.git/worktrees/main\n")
+
+	assertRoot(t, worktree, Options{}, bare)
+	assertRoot(t, filepath.Join(bare, "refs"), Options{}, bare)
+}
+
+func TestFindRepoRootSubmodule(t *testing.T) {
+	base := resolvedTempDir(t)
+	super := filepath.Join(base, "super")
+	submodule := filepath.Join(super, "libs", "dep")
+
+	makeGitDir(t, filepath.Join(super, ".git"))
+	makeGitDir(t, filepath.Join(super, ".git", "modules", "libs", "dep"))
+	writeFile(t, filepath.Join(submodule, ".git"), "gitdir: ../../.git/modules/libs/dep\n")
+
+	assertRoot(t, submodule, Options{}, submodule)
+	assertRoot(t, submodule, Options{Submodules: SubmodulesSeparate}, submodule)
+	assertRoot(t, submodule, Options{Submodules: SubmodulesSuperproject}, super)
+}
+
+func TestFindRepoRootSubmoduleWorktree(t *testing.T) {
+	base := resolvedTempDir(t)
+	super := filepath.Join(base, "super")
+	submodule := filepath.Join(super, "dep")
+	subGitDir := filepath.Join(super, ".git", "modules", "dep")
+	worktree := filepath.Join(base, "dep-feature")
+
+	makeGitDir(t, filepath.Join(super, ".git"))
+	makeGitDir(t, subGitDir)
+	writeFile(t, filepath.Join(subGitDir, "config"), "[core]\n\tworktree = ../../../dep\n")
+	writeFile(t, filepath.Join(submodule, ".git"), "gitdir: ../.git/modules/dep\n")
+
+	wtGitDir := filepath.Join(subGitDir, "worktrees", "feature")
+	writeFile(t, filepath.Join(wtGitDir, "commondir"), "../..\n")
+	writeFile(t, filepath.Join(worktree, ".git"), "gitdir: "+wtGitDir+"\n")
+
+	assertRoot(t, worktree, Options{}, submodule)
+	assertRoot(t, worktree, Options{Submodules: SubmodulesSuperproject}, super)
+}
+
+func TestFindRepoRootInsideGitDir(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	expected, _ := filepath.EvalSymlinks(repoDir)
+
+	assertRoot(t, filepath.Join(repoDir, ".git", "refs"), Options{}, expected)
+}
+
+func TestFindRepoRootRealWorktree(t *testing.T) {
+	repoDir := setupGitRepo(t)
+	expected, _ := filepath.EvalSymlinks(repoDir)
+
+	runGit(t, repoDir, "commit", "--allow-empty", "-m", "initial")
+	worktree := filepath.Join(t.TempDir(), "wt")
+	runGit(t, repoDir, "worktree", "add", "-q", worktree)
+
+	assertRoot(t, worktree, Options{}, expected)
+}
+
+func TestParseSubmoduleMode(t *testing.T) {
+	for name, expected := range map[string]SubmoduleMode{
+		"":             SubmodulesSeparate,
+		"separate":     SubmodulesSeparate,
+		"superproject": SubmodulesSuperproject,
+	} {
+		mode, err := ParseSubmoduleMode(name)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %v", name, err)
+		}
+		if mode != expected {
+			t.Errorf("Expected %s for %q, got %s", expected, name, mode)
+		}
+	}
+
+	if _, err := ParseSubmoduleMode("nested"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
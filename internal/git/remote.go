@@ -0,0 +1,90 @@
+// ABOUTME: Git remote URL discovery and normalization
+// ABOUTME: Reads origin from the repository config and unifies ssh and https forms
+
+package git
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Repo describes the git repository containing a directory.
+type Repo struct {
+	// Root is the repository root as resolved by FindRepoRoot.
+	Root string
+	// RemoteURL is the normalized origin URL, or empty when there is no origin.
+	RemoteURL string
+}
+
+// DetectRepo finds the repository containing startPath and its normalized origin URL.
+func DetectRepo(startPath string, opts Options) (*Repo, error) {
+	root, err := FindRepoRoot(startPath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Repo{
+		Root:      root,
+		RemoteURL: NormalizeRemoteURL(RemoteURL(root, "origin")),
+	}, nil
+}
+
+// RemoteURL returns the raw URL configured for remote in the repository at root,
+// or an empty string if it is not set.
+func RemoteURL(root, remote string) string {
+	configPath := filepath.Join(gitDirForRoot(root), "config")
+	return readConfigValue(configPath, `remote "`+remote+`"`, "url")
+}
+
+// gitDirForRoot returns the directory holding the shared config for a repository root.
+func gitDirForRoot(root string) string {
+	gitPath := filepath.Join(root, ".git")
+	info, err := os.Stat(gitPath)
+	if err != nil {
+		// Bare repository: the root is the git dir
+		return root
+	}
+	if info.IsDir() {
+		return gitPath
+	}
+
+	gitDir, err := readGitDirFile(gitPath)
+	if err != nil {
+		return gitPath
+	}
+	if common, ok := readCommonDir(gitDir); ok {
+		return common
+	}
+	return gitDir
+}
+
+// NormalizeRemoteURL reduces a remote URL to "host/path" so that the ssh, scp-like,
+// and https forms of the same repository compare equal. Credentials, ports, a
+// trailing ".git", and letter case of the host are dropped. Local paths are
+// returned cleaned.
+func NormalizeRemoteURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+
+	var host, path string
+	if u, err := url.Parse(raw); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if before, after, ok := strings.Cut(raw, ":"); ok && !strings.Contains(before, "/") && len(before) > 1 {
+		// scp-like syntax: [user@]host:path
+		host = before
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+		path = after
+	} else {
+		return filepath.Clean(strings.TrimPrefix(raw, "file://"))
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	return strings.ToLower(host) + "/" + path
+}
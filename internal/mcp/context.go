@@ -0,0 +1,106 @@
+// ABOUTME: Working-directory project detection for MCP tools
+// ABOUTME: Maps a cwd argument, the --cwd flag, or client roots to a git-backed project
+
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// detectProject finds the project for the caller's working directory. An explicit cwd
+// wins over the server's configured Cwd, which wins over the client's roots. Returns nil
+// when no directory maps to a registered project and create is false.
+func (s *Server) detectProject(ctx context.Context, session *mcp.ServerSession, cwd *string, create bool) (*models.Project, error) {
+	var unregistered *git.Repo
+	for _, dir := range s.candidateDirs(ctx, session, cwd) {
+		project, repo, err := s.store.FindProjectForDir(ctx, dir, s.opts.Git)
+		if err == nil {
+			return project, nil
+		}
+		if repo == nil {
+			continue
+		}
+		if !errors.Is(err, db.ErrNotFound) {
+			return nil, err
+		}
+		if unregistered == nil {
+			unregistered = repo
+		}
+	}
+
+	if unregistered == nil || !create {
+		return nil, nil //nolint:nilnil // nil means no project context
+	}
+
+	return s.createProjectForRepo(ctx, unregistered)
+}
+
+func (s *Server) candidateDirs(ctx context.Context, session *mcp.ServerSession, cwd *string) []string {
+	if cwd != nil && *cwd != "" {
+		return []string{*cwd}
+	}
+	if s.opts.Cwd != "" {
+		return []string{s.opts.Cwd}
+	}
+	return listRootDirs(ctx, session)
+}
+
+// listRootDirs returns the local directories the client exposes as roots.
+// Clients without roots support simply yield no directories.
+func listRootDirs(ctx context.Context, session *mcp.ServerSession) []string {
+	if session == nil {
+		return nil
+	}
+
+	result, err := session.ListRoots(ctx, nil)
+	if err != nil {
+		return nil
+	}
+
+	dirs := make([]string, 0, len(result.Roots))
+	for _, root := range result.Roots {
+		u, err := url.Parse(root.URI)
+		if err != nil || u.Scheme != "file" || u.Path == "" {
+			continue
+		}
+		dirs = append(dirs, filepath.FromSlash(u.Path))
+	}
+	return dirs
+}
+
+// createProjectForRepo registers a project named after the repository directory,
+// adding a numeric suffix if that name is already taken.
+func (s *Server) createProjectForRepo(ctx context.Context, repo *git.Repo) (*models.Project, error) {
+	base := filepath.Base(repo.Root)
+	name := base
+	for i := 2; ; i++ {
+		_, err := s.store.GetProjectByName(ctx, name)
+		if errors.Is(err, db.ErrNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	root := repo.Root
+	project := models.NewProject(name, &root)
+	if repo.RemoteURL != "" {
+		remote := repo.RemoteURL
+		project.RemoteURL = &remote
+	}
+	if err := s.store.CreateProject(ctx, project); err != nil {
+		return nil, fmt.Errorf("failed to create project for %s: %w", repo.Root, err)
+	}
+	return project, nil
+}
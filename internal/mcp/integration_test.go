@@ -14,6 +14,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/harper/toki/internal/db"
 	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -22,7 +23,7 @@ func TestServerInitialization(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
 
-	server, err := NewServer(database)
+	server, err := NewServer(store.NewSQLite(database))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
@@ -35,16 +36,16 @@ func TestServerInitialization(t *testing.T) {
 		t.Fatal("Expected MCP server to be initialized")
 	}
 
-	if server.db == nil {
-		t.Fatal("Expected database to be set")
+	if server.store == nil {
+		t.Fatal("Expected store to be set")
 	}
 }
 
-// TestServerInitializationWithNilDB verifies that server rejects nil database.
-func TestServerInitializationWithNilDB(t *testing.T) {
+// TestServerInitializationWithNilStore verifies that server rejects a nil store.
+func TestServerInitializationWithNilStore(t *testing.T) {
 	_, err := NewServer(nil)
 	if err == nil {
-		t.Error("Expected error when creating server with nil database")
+		t.Error("Expected error when creating server without a store")
 	}
 }
 
@@ -458,7 +459,7 @@ func TestDatabaseIntegrationToolsPersistData(t *testing.T) {
 	}
 
 	// Verify project exists in database directly
-	dbProject, err := db.GetProjectByID(database, projectID)
+	dbProject, err := db.GetProjectByID(t.Context(), database, projectID)
 	if err != nil {
 		t.Fatalf("Project should exist in database: %v", err)
 	}
@@ -487,7 +488,7 @@ func TestDatabaseIntegrationToolsPersistData(t *testing.T) {
 	}
 
 	// Verify todo exists in database directly
-	dbTodo, err := db.GetTodoByID(database, todoID)
+	dbTodo, err := db.GetTodoByID(t.Context(), database, todoID)
 	if err != nil {
 		t.Fatalf("Todo should exist in database: %v", err)
 	}
@@ -503,14 +504,14 @@ func TestConcurrentAccessCLIAndMCP(t *testing.T) {
 	dbPath := filepath.Join(tmpDir, "shared.db")
 
 	// Initialize database as CLI would
-	cliDB, err := db.InitDB(dbPath)
+	cliDB, err := db.InitDB(t.Context(), dbPath)
 	if err != nil {
 		t.Fatalf("Failed to init CLI database: %v", err)
 	}
 
 	// Create project via CLI database connection
 	cliProject := models.NewProject("cli-created-project", nil)
-	if err := db.CreateProject(cliDB, cliProject); err != nil {
+	if err := db.CreateProject(t.Context(), cliDB, cliProject); err != nil {
 		t.Fatalf("Failed to create project via CLI: %v", err)
 	}
 
@@ -578,13 +579,13 @@ func TestConcurrentAccessCLIAndMCP(t *testing.T) {
 	}
 
 	// Reopen database as CLI and verify todo exists
-	cliDB2, err := db.InitDB(dbPath)
+	cliDB2, err := db.InitDB(t.Context(), dbPath)
 	if err != nil {
 		t.Fatalf("Failed to reopen CLI database: %v", err)
 	}
 	defer func() { _ = cliDB2.Close() }()
 
-	dbTodo, err := db.GetTodoByID(cliDB2, todoID)
+	dbTodo, err := db.GetTodoByID(t.Context(), cliDB2, todoID)
 	if err != nil {
 		t.Fatalf("CLI should see todo created by MCP: %v", err)
 	}
@@ -615,13 +616,13 @@ func TestStatsResource(t *testing.T) {
 	// Create 2 done todos
 	doneTodo1 := createTestTodoInDB(t, database, project.ID, "done 1", nil, nil)
 	doneTodo1.MarkDone()
-	if err := db.UpdateTodo(database, doneTodo1); err != nil {
+	if err := db.UpdateTodo(t.Context(), database, doneTodo1); err != nil {
 		t.Fatalf("Failed to mark todo done: %v", err)
 	}
 
 	doneTodo2 := createTestTodoInDB(t, database, project.ID, "done 2", nil, nil)
 	doneTodo2.MarkDone()
-	if err := db.UpdateTodo(database, doneTodo2); err != nil {
+	if err := db.UpdateTodo(t.Context(), database, doneTodo2); err != nil {
 		t.Fatalf("Failed to mark todo done: %v", err)
 	}
 
@@ -0,0 +1,82 @@
+// ABOUTME: Pagination of todo lists in tools and resources
+// ABOUTME: Reads limit and cursor arguments and builds next-page cursors and URIs
+
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/sorting"
+)
+
+// Lists are paged by default so a large database doesn't flood the client's context.
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// parsePage returns the page asked for, defaulting to the first defaultPageLimit todos.
+// Limits above maxPageLimit are lowered to it.
+func parsePage(limit *int, cursor *string) (db.Page, error) {
+	page := db.Page{Limit: defaultPageLimit}
+	if limit != nil {
+		if *limit < 1 {
+			return db.Page{}, fmt.Errorf("invalid limit %d: must be at least 1", *limit)
+		}
+		page.Limit = min(*limit, maxPageLimit)
+	}
+	if cursor != nil {
+		page.Cursor = *cursor
+	}
+	return page, nil
+}
+
+// parsePageQuery reads the limit and cursor query parameters of a resource URI.
+func parsePageQuery(query url.Values) (db.Page, error) {
+	var limit *int
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return db.Page{}, fmt.Errorf("invalid limit '%s': must be a number", value)
+		}
+		limit = &n
+	}
+	cursor := query.Get("cursor")
+	return parsePage(limit, &cursor)
+}
+
+// nextPageURI is uri with its cursor query parameter set to cursor.
+func nextPageURI(uri *url.URL, cursor string) string {
+	next := *uri
+	query := next.Query()
+	query.Set("cursor", cursor)
+	// Resource templates only match spaces escaped as %20, and Encode escapes a literal + as %2B
+	next.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+	return next.String()
+}
+
+// queryTodoPage reads one page of the todos matching expr, newest first or in the order
+// of sortKey. Only the page is read either way; the cursor of a sorted page carries its
+// sort key, so the next page picks up after it.
+func (s *Server) queryTodoPage(
+	ctx context.Context,
+	expr filter.Node,
+	sortKey sorting.Key,
+	reverse bool,
+	page db.Page,
+) ([]*models.Todo, map[uuid.UUID][]*models.Tag, string, error) {
+	order := db.Order{Key: sortKey, Reverse: reverse}
+	if sortKey != "" {
+		order.Urgency, order.Now = s.urgencyCoefficients(), time.Now()
+	}
+	return s.store.QueryTodosPage(ctx, expr, order, page)
+}
@@ -0,0 +1,70 @@
+// ABOUTME: Todo and project reference resolution for MCP tools
+// ABOUTME: Turns db lookup failures into agent-friendly not-found and ambiguity errors
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/models"
+)
+
+// resolveTodo looks up a todo by full UUID or unique UUID prefix.
+func (s *Server) resolveTodo(ctx context.Context, ref string) (*models.Todo, error) {
+	todo, err := s.store.ResolveTodoRef(ctx, ref)
+	if err != nil {
+		return nil, referenceError("todo", ref, err)
+	}
+	return todo, nil
+}
+
+// resolveProject looks up a project by UUID, UUID prefix, name, or directory path.
+func (s *Server) resolveProject(ctx context.Context, ref string) (*models.Project, error) {
+	project, err := s.store.ResolveProjectRef(ctx, ref)
+	if err != nil {
+		return nil, referenceError("project", ref, err)
+	}
+	return project, nil
+}
+
+func referenceError(kind, ref string, err error) error {
+	var ambiguous *db.AmbiguousRefError
+	if errors.As(err, &ambiguous) {
+		return &ambiguityError{ref: ambiguous}
+	}
+
+	if errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("%s not found: no %s matches '%s'. Use list_%ss to see available %ss", kind, kind, ref, kind, kind)
+	}
+
+	return err
+}
+
+// ambiguityError renders an ambiguous reference as JSON so agents can retry with a candidate ID.
+type ambiguityError struct {
+	ref *db.AmbiguousRefError
+}
+
+func (e *ambiguityError) Error() string {
+	payload := map[string]any{
+		"error":      "ambiguous_reference",
+		"message":    fmt.Sprintf("%s. Retry with one of the candidate IDs", e.ref.Error()),
+		"kind":       e.ref.Kind,
+		"reference":  e.ref.Ref,
+		"candidates": e.ref.Candidates,
+	}
+
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		return e.ref.Error()
+	}
+	return string(jsonBytes)
+}
+
+func (e *ambiguityError) Unwrap() error {
+	return e.ref
+}
@@ -1,5 +1,5 @@
 // ABOUTME: MCP resource providers
-// ABOUTME: Exposes read-only views of projects, todos, and stats
+// ABOUTME: Exposes read-only views of projects, todos, saved views, and stats
 
 package mcp
 
@@ -7,12 +7,15 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
 	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/sorting"
+	"github.com/harper/toki/internal/stats"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -29,6 +32,8 @@ type ResourceMetadata struct {
 	Count       int            `json:"count"`
 	ResourceURI string         `json:"resource_uri"`
 	Filters     map[string]any `json:"filters,omitempty"`
+	// NextCursor continues a paged todo list; links["next"] is the URI of that page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func (s *Server) registerResources() {
@@ -42,6 +47,9 @@ func (s *Server) registerResources() {
 	// Query resource (custom filters)
 	s.registerQueryResource()
 
+	// Built-in and saved views (named filters)
+	s.registerViewResources()
+
 	// Statistics and analytics
 	s.registerStatsResource()
 
@@ -59,7 +67,7 @@ func (s *Server) registerProjectsResource() {
 		Description: "List all projects with metadata including name, directory path, and creation time",
 		MIMEType:    "application/json",
 	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
-		projects, err := db.ListProjects(s.db)
+		projects, err := s.store.ListProjects(ctx)
 		if err != nil {
 			return nil, fmt.Errorf("failed to list projects: %w", err)
 		}
@@ -75,6 +83,9 @@ func (s *Server) registerProjectsResource() {
 			if proj.DirectoryPath != nil {
 				output["directory_path"] = *proj.DirectoryPath
 			}
+			if proj.RemoteURL != nil {
+				output["remote_url"] = *proj.RemoteURL
+			}
 			projectOutputs = append(projectOutputs, output)
 		}
 
@@ -107,8 +118,20 @@ func (s *Server) registerProjectsResource() {
 	})
 }
 
+// addTodoResource adds a todo list resource along with a template for its pages, which
+// take limit and cursor query parameters.
+func (s *Server) addTodoResource(resource *mcp.Resource, handler mcp.ResourceHandler) {
+	s.mcp.AddResource(resource, handler)
+	s.mcp.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: resource.URI + "{?limit,cursor}",
+		Name:        resource.Name + " (Paged)",
+		Description: resource.Description + " Returns 100 todos per page by default; follow links.next for the next page.",
+		MIMEType:    resource.MIMEType,
+	}, handler)
+}
+
 func (s *Server) registerTodosResource() {
-	s.mcp.AddResource(&mcp.Resource{
+	s.addTodoResource(&mcp.Resource{
 		URI:         "toki://todos",
 		Name:        "All Todos",
 		Description: "List all todos across all projects, both pending and completed. Use filtered views like toki://todos/pending for specific subsets.",
@@ -119,7 +142,7 @@ func (s *Server) registerTodosResource() {
 }
 
 func (s *Server) registerTodosPendingResource() {
-	s.mcp.AddResource(&mcp.Resource{
+	s.addTodoResource(&mcp.Resource{
 		URI:         "toki://todos/pending",
 		Name:        "Pending Todos",
 		Description: "List all incomplete (not done) todos across all projects. Useful for seeing active work items.",
@@ -131,7 +154,7 @@ func (s *Server) registerTodosPendingResource() {
 }
 
 func (s *Server) registerTodosOverdueResource() {
-	s.mcp.AddResource(&mcp.Resource{
+	s.addTodoResource(&mcp.Resource{
 		URI:         "toki://todos/overdue",
 		Name:        "Overdue Todos",
 		Description: "List all todos that are past their due date and not yet completed. Critical items requiring immediate attention.",
@@ -142,7 +165,7 @@ func (s *Server) registerTodosOverdueResource() {
 }
 
 func (s *Server) registerTodosHighPriorityResource() {
-	s.mcp.AddResource(&mcp.Resource{
+	s.addTodoResource(&mcp.Resource{
 		URI:         "toki://todos/high-priority",
 		Name:        "High Priority Todos",
 		Description: "List all todos marked with high priority, regardless of completion status. Important work items that need focus.",
@@ -154,67 +177,260 @@ func (s *Server) registerTodosHighPriorityResource() {
 }
 
 func (s *Server) registerQueryResource() {
-	// TODO(v2): The MCP Go SDK v1.1.0 doesn't support URI templates for resources.
-	// This means we can't register `toki://query{?params}` as a single resource
-	// that matches query://query?foo=bar URLs.
-	//
-	// For v1, we register the base toki://query resource which returns all todos.
-	// Future versions could:
-	// 1. Use a custom resource matcher (if SDK adds support)
-	// 2. Register multiple specific query combinations
-	// 3. Move complex queries to Tools instead of Resources
-	//
-	// For now, use pre-built resources (pending, overdue, high-priority) for
-	// common filtered views, and use the list_todos Tool for custom queries.
+	// The bare URI is listed as a resource and returns all todos; the template matches
+	// toki://query?filter=<expression> with the expression URL-encoded.
 	s.mcp.AddResource(&mcp.Resource{
 		URI:         "toki://query",
 		Name:        "All Todos (Query Base)",
-		Description: "Returns all todos. In v1, use pre-built resources (toki://todos/pending, toki://todos/overdue, toki://todos/high-priority) for filtered views, or use the list_todos tool for custom filtering.",
+		Description: "Returns all todos. Append ?filter=<expression> (URL-encoded) to filter with the same syntax as the list_todos filter argument, and limit and cursor to page through the results.",
 		MIMEType:    "application/json",
-	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
-		// v1: Just return all todos
-		// For filtered queries, clients should use pre-built resources or the list_todos tool
-		return s.handleTodoResource(ctx, req, nil, nil, nil, nil, false)
+	}, s.handleQueryResource)
+
+	s.mcp.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "toki://query{?filter,limit,cursor}",
+		Name:        "Todo Query",
+		Description: "Todos matching a filter expression, e.g. toki://query?filter=tag%3Abug%20and%20not%20done. Terms like project:<name>, tag:<name>, priority:<level>, due<+7d, and overdue combine with and, or, not, and parentheses.",
+		MIMEType:    "application/json",
+	}, s.handleQueryResource)
+}
+
+// handleQueryResource serves toki://query, applying the filter query parameter if present.
+func (s *Server) handleQueryResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri, err := url.Parse(req.Params.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resource URI: %w", err)
+	}
+
+	var expr filter.Node
+	filters := map[string]any{}
+	if value := uri.Query().Get("filter"); value != "" {
+		expr, err = parseFilter(value)
+		if err != nil {
+			return nil, err
+		}
+		filters["filter"] = value
+	}
+
+	return s.filteredTodoResource(ctx, req, expr, "", false, filters, map[string]string{
+		"all_todos": "toki://todos",
+		"pending":   "toki://todos/pending",
 	})
 }
 
-//nolint:funlen // Resource handler combines data fetching, transformation, and formatting
-func (s *Server) handleTodoResource(
-	_ context.Context, //nolint:unparam // ctx reserved for future use
+func (s *Server) registerViewResources() {
+	s.mcp.AddResource(&mcp.Resource{
+		URI:         "toki://views",
+		Name:        "Saved Views",
+		Description: "List built-in and saved views (named filters created with 'toki view save') with their filter expressions and resource URIs.",
+		MIMEType:    "application/json",
+	}, s.handleViewsResource)
+
+	// Views saved after startup are still readable through the template
+	s.mcp.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: "toki://views/{name}{?limit,cursor}",
+		Name:        "Saved View",
+		Description: "Todos matching a built-in or saved view, e.g. toki://views/overdue. See toki://views for the available names.",
+		MIMEType:    "application/json",
+	}, s.handleViewResource)
+
+	// Each view is listed as of the resources/list request asking for it
+	s.mcp.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method == "resources/list" {
+				s.listViewResources(ctx)
+			}
+			return next(ctx, method, req)
+		}
+	})
+}
+
+// listViewResources adds a resource for each view and removes those of deleted views.
+// Only changes are made, since each one notifies clients that the list changed. Listing
+// each view is best-effort; the template serves them either way.
+func (s *Server) listViewResources(ctx context.Context) {
+	views, err := s.store.ListViews(ctx)
+	if err != nil {
+		return
+	}
+
+	s.viewsMu.Lock()
+	defer s.viewsMu.Unlock()
+
+	listed := make(map[string]string, len(views))
+	for _, view := range views {
+		description := viewDescription(view)
+		listed[view.Name] = description
+		if s.views[view.Name] == description {
+			continue
+		}
+		s.mcp.AddResource(&mcp.Resource{
+			URI:         viewURI(view.Name),
+			Name:        "View: " + view.Name,
+			Description: description,
+			MIMEType:    "application/json",
+		}, s.handleViewResource)
+	}
+	for name := range s.views {
+		if _, ok := listed[name]; !ok {
+			s.mcp.RemoveResources(viewURI(name))
+		}
+	}
+	s.views = listed
+}
+
+func viewURI(name string) string {
+	return "toki://views/" + name
+}
+
+func viewDescription(view *models.View) string {
+	kind := "Saved view"
+	if view.Builtin {
+		kind = "Built-in view"
+	}
+	filterText := view.Filter
+	if filterText == "" {
+		filterText = "all todos"
+	}
+	return fmt.Sprintf("%s matching: %s", kind, filterText)
+}
+
+func (s *Server) handleViewsResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	views, err := s.store.ListViews(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]map[string]any, 0, len(views))
+	for _, view := range views {
+		entry := map[string]any{
+			"name":    view.Name,
+			"filter":  view.Filter,
+			"builtin": view.Builtin,
+			"uri":     viewURI(view.Name),
+		}
+		if view.Sort != "" {
+			entry["sort"] = view.Sort
+			entry["reverse"] = view.Reverse
+		}
+		data = append(data, entry)
+	}
+
+	return marshalResource(req, ResourceData{
+		Metadata: ResourceMetadata{
+			Timestamp:   time.Now(),
+			Count:       len(data),
+			ResourceURI: req.Params.URI,
+		},
+		Data:  data,
+		Links: map[string]string{"query": "toki://query"},
+	})
+}
+
+// handleViewResource serves toki://views/<name> with the view's filter and sort order.
+func (s *Server) handleViewResource(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	path, _, _ := strings.Cut(req.Params.URI, "?")
+	name := strings.TrimPrefix(path, "toki://views/")
+	view, err := s.store.GetView(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var expr filter.Node
+	if view.Filter != "" {
+		expr, err = parseFilter(view.Filter)
+		if err != nil {
+			return nil, fmt.Errorf("view '%s' has an invalid filter: %w", view.Name, err)
+		}
+	}
+	var sortKey sorting.Key
+	if view.Sort != "" {
+		sortKey, err = sorting.ParseKey(view.Sort)
+		if err != nil {
+			return nil, fmt.Errorf("view '%s' has an invalid sort: %w", view.Name, err)
+		}
+	}
+
+	filters := map[string]any{"view": view.Name}
+	links := map[string]string{"views": "toki://views"}
+	if view.Filter != "" {
+		filters["filter"] = view.Filter
+		links["query"] = "toki://query?filter=" + url.QueryEscape(view.Filter)
+	}
+	return s.filteredTodoResource(ctx, req, expr, sortKey, view.Reverse, filters, links)
+}
+
+// filteredTodoResource reads the page of the todos matching expr, optionally sorted,
+// given by the limit and cursor query parameters of the resource URI.
+func (s *Server) filteredTodoResource(
+	ctx context.Context,
 	req *mcp.ReadResourceRequest,
-	projectID *uuid.UUID, //nolint:unparam // v1: always nil, v2 will use for project filtering
-	done *bool,
-	priority *string,
-	tag *string, //nolint:unparam // v1: always nil, v2 will use for tag filtering
-	overdue bool,
+	expr filter.Node,
+	sortKey sorting.Key,
+	reverse bool,
+	filters map[string]any,
+	links map[string]string,
 ) (*mcp.ReadResourceResult, error) {
-	// Fetch and filter todos
-	todos, err := s.fetchAndFilterTodos(projectID, done, priority, tag, overdue)
+	uri, err := url.Parse(req.Params.URI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse resource URI: %w", err)
+	}
+	page, err := parsePageQuery(uri.Query())
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to output format
-	todoOutputs, err := s.buildTodoOutputs(todos)
+	todos, todoTags, next, err := s.queryTodoPage(ctx, expr, sortKey, reverse, page)
 	if err != nil {
 		return nil, err
 	}
+	if next != "" {
+		links["next"] = nextPageURI(uri, next)
+	}
 
-	// Build response metadata
-	filters := buildFiltersMetadata(projectID, done, priority, tag, overdue)
-	links := s.buildTodoResourceLinks(projectID, done, priority, tag, overdue)
+	todoOutputs := buildTodoOutputs(todos, todoTags)
 
-	resourceData := ResourceData{
+	return marshalResource(req, ResourceData{
 		Metadata: ResourceMetadata{
 			Timestamp:   time.Now(),
 			Count:       len(todoOutputs),
 			ResourceURI: req.Params.URI,
 			Filters:     filters,
+			NextCursor:  next,
 		},
 		Data:  todoOutputs,
 		Links: links,
+	})
+}
+
+func (s *Server) handleTodoResource(
+	ctx context.Context, //nolint:unparam // ctx reserved for future use
+	req *mcp.ReadResourceRequest,
+	projectID *uuid.UUID, //nolint:unparam // v1: always nil, v2 will use for project filtering
+	done *bool,
+	priority *string,
+	tag *string, //nolint:unparam // v1: always nil, v2 will use for tag filtering
+	overdue bool,
+) (*mcp.ReadResourceResult, error) {
+	expr := todoResourceFilter(projectID, done, priority, tag, overdue)
+	filters := buildFiltersMetadata(projectID, done, priority, tag, overdue)
+	links := s.buildTodoResourceLinks(expr, done, priority, overdue)
+	return s.filteredTodoResource(ctx, req, expr, "", false, filters, links)
+}
+
+// todoResourceFilter combines the fixed filters of a todo resource into one expression.
+func todoResourceFilter(projectID *uuid.UUID, done *bool, priority *string, tag *string, overdue bool) filter.Node {
+	criteria := filter.Criteria{Done: done, Priority: priority, Tag: tag}
+	if projectID != nil {
+		criteria.Project = projectID.String()
 	}
+	if overdue {
+		criteria.Overdue = &overdue
+	}
+	return criteria.Node()
+}
 
+// marshalResource encodes resource data as the JSON contents of a read result.
+func marshalResource(req *mcp.ReadResourceRequest, resourceData ResourceData) (*mcp.ReadResourceResult, error) {
 	jsonBytes, err := json.MarshalIndent(resourceData, "", "  ")
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal resource data: %w", err)
@@ -231,36 +447,12 @@ func (s *Server) handleTodoResource(
 	}, nil
 }
 
-// fetchAndFilterTodos retrieves todos from database and applies filters.
-func (s *Server) fetchAndFilterTodos(
-	projectID *uuid.UUID,
-	done *bool,
-	priority *string,
-	tag *string,
-	overdue bool,
-) ([]*models.Todo, error) {
-	todos, err := db.ListTodos(s.db, projectID, done, priority, tag)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list todos: %w", err)
-	}
-
-	if overdue {
-		todos = filterOverdueTodos(todos)
-	}
-
-	return todos, nil
-}
-
-// buildTodoOutputs converts todos to JSON-serializable format.
-func (s *Server) buildTodoOutputs(todos []*models.Todo) ([]map[string]interface{}, error) {
+// buildTodoOutputs converts todos with their tags to JSON-serializable format.
+func buildTodoOutputs(todos []*models.Todo, todoTags map[uuid.UUID][]*models.Tag) []map[string]interface{} {
 	todoOutputs := make([]map[string]interface{}, 0, len(todos))
 
 	for _, todo := range todos {
-		tags, err := db.GetTodoTags(s.db, todo.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get tags: %w", err)
-		}
-
+		tags := todoTags[todo.ID]
 		tagNames := make([]string, 0, len(tags))
 		for _, t := range tags {
 			tagNames = append(tagNames, t.Name)
@@ -287,11 +479,14 @@ func (s *Server) buildTodoOutputs(todos []*models.Todo) ([]map[string]interface{
 		if todo.DueDate != nil {
 			output["due_date"] = *todo.DueDate
 		}
+		if todo.Branch != nil {
+			output["branch"] = *todo.Branch
+		}
 
 		todoOutputs = append(todoOutputs, output)
 	}
 
-	return todoOutputs, nil
+	return todoOutputs
 }
 
 // buildFiltersMetadata creates the filters map for resource metadata.
@@ -325,36 +520,18 @@ func buildFiltersMetadata(
 
 // buildTodoResourceLinks constructs relevant links for todo resources.
 func (s *Server) buildTodoResourceLinks(
-	projectID *uuid.UUID,
+	expr filter.Node,
 	done *bool,
 	priority *string,
-	tag *string,
 	overdue bool,
 ) map[string]string {
 	links := map[string]string{
 		"all_todos": "toki://todos",
 	}
 
-	// Build query URL with same filters
-	var queryParts []string
-	if projectID != nil {
-		queryParts = append(queryParts, fmt.Sprintf("project_id=%s", projectID.String()))
-	}
-	if done != nil {
-		queryParts = append(queryParts, fmt.Sprintf("done=%t", *done))
-	}
-	if priority != nil {
-		queryParts = append(queryParts, fmt.Sprintf("priority=%s", *priority))
-	}
-	if tag != nil {
-		queryParts = append(queryParts, fmt.Sprintf("tag=%s", *tag))
-	}
-	if overdue {
-		queryParts = append(queryParts, "overdue=true")
-	}
-
-	if len(queryParts) > 0 {
-		links["query"] = "toki://query?" + strings.Join(queryParts, "&")
+	// Link to the equivalent filter query
+	if expr != nil {
+		links["query"] = "toki://query?filter=" + url.QueryEscape(expr.String())
 	}
 
 	// Add links to related pre-built views
@@ -375,10 +552,10 @@ func (s *Server) registerStatsResource() {
 	s.mcp.AddResource(&mcp.Resource{
 		URI:         "toki://stats",
 		Name:        "Summary Statistics",
-		Description: "Overview of todo statistics including totals, pending/completed counts, overdue items, breakdown by priority and project, and oldest pending todo",
+		Description: "Overview of todo statistics including totals, pending/completed counts, overdue items, breakdown by priority, project (with overdue counts), and tag, age distribution of pending todos, oldest pending todo, and trends: weekly throughput and completion rate over the last 8 weeks and average cycle time from creation to completion",
 		MIMEType:    "application/json",
 	}, func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
-		stats, err := s.calculateStats()
+		statsData, err := stats.Load(ctx, s.store, time.Now())
 		if err != nil {
 			return nil, fmt.Errorf("failed to calculate stats: %w", err)
 		}
@@ -389,7 +566,7 @@ func (s *Server) registerStatsResource() {
 				Count:       0, // Stats don't have a count
 				ResourceURI: "toki://stats",
 			},
-			Data: stats,
+			Data: statsData,
 			Links: map[string]string{
 				"all_todos": "toki://todos",
 				"pending":   "toki://todos/pending",
@@ -414,138 +591,3 @@ func (s *Server) registerStatsResource() {
 		}, nil
 	})
 }
-
-// StatsData represents the statistics summary.
-type StatsData struct {
-	Summary       StatsSummary       `json:"summary"`
-	ByPriority    map[string]int     `json:"by_priority"`
-	ByProject     []ProjectStats     `json:"by_project"`
-	OldestPending *OldestPendingTodo `json:"oldest_pending,omitempty"`
-}
-
-// StatsSummary contains overall todo counts.
-type StatsSummary struct {
-	TotalTodos int `json:"total_todos"`
-	Pending    int `json:"pending"`
-	Completed  int `json:"completed"`
-	Overdue    int `json:"overdue"`
-}
-
-// ProjectStats contains per-project todo counts.
-type ProjectStats struct {
-	ProjectID   string `json:"project_id"`
-	ProjectName string `json:"project_name"`
-	TodoCount   int    `json:"todo_count"`
-}
-
-// OldestPendingTodo represents the oldest incomplete todo.
-type OldestPendingTodo struct {
-	ID          string `json:"id"`
-	Description string `json:"description"`
-	AgeDays     int    `json:"age_days"`
-}
-
-//nolint:funlen // Stats calculation aggregates multiple data sources in a single pass
-func (s *Server) calculateStats() (*StatsData, error) {
-	// Fetch all todos
-	allTodos, err := db.ListTodos(s.db, nil, nil, nil, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list todos: %w", err)
-	}
-
-	// Fetch all projects
-	projects, err := db.ListProjects(s.db)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list projects: %w", err)
-	}
-
-	// Calculate summary stats
-	summary := StatsSummary{
-		TotalTodos: len(allTodos),
-	}
-
-	priorityCounts := make(map[string]int)
-	projectCounts := make(map[uuid.UUID]int)
-	var oldestPending *models.Todo
-	now := time.Now()
-
-	for _, todo := range allTodos {
-		// Count by completion status
-		if todo.Done {
-			summary.Completed++
-		} else {
-			summary.Pending++
-
-			// Track oldest pending
-			if oldestPending == nil || todo.CreatedAt.Before(oldestPending.CreatedAt) {
-				oldestPending = todo
-			}
-		}
-
-		// Count overdue (not done and past due date)
-		if !todo.Done && todo.DueDate != nil && todo.DueDate.Before(now) {
-			summary.Overdue++
-		}
-
-		// Count by priority
-		priority := "none"
-		if todo.Priority != nil {
-			priority = *todo.Priority
-		}
-		priorityCounts[priority]++
-
-		// Count by project
-		projectCounts[todo.ProjectID]++
-	}
-
-	// Build by_priority map (only include priorities that exist)
-	byPriority := make(map[string]int)
-	for priority, count := range priorityCounts {
-		byPriority[priority] = count
-	}
-
-	// Build by_project array (sorted by count, descending)
-	projectStatsMap := make(map[uuid.UUID]*ProjectStats)
-	for _, proj := range projects {
-		if count, ok := projectCounts[proj.ID]; ok {
-			projectStatsMap[proj.ID] = &ProjectStats{
-				ProjectID:   proj.ID.String(),
-				ProjectName: proj.Name,
-				TodoCount:   count,
-			}
-		}
-	}
-
-	// Convert to sorted slice
-	byProject := make([]ProjectStats, 0, len(projectStatsMap))
-	for _, stats := range projectStatsMap {
-		byProject = append(byProject, *stats)
-	}
-
-	// Sort by todo count (descending)
-	for i := 0; i < len(byProject)-1; i++ {
-		for j := i + 1; j < len(byProject); j++ {
-			if byProject[j].TodoCount > byProject[i].TodoCount {
-				byProject[i], byProject[j] = byProject[j], byProject[i]
-			}
-		}
-	}
-
-	// Build oldest pending info
-	var oldestPendingData *OldestPendingTodo
-	if oldestPending != nil {
-		ageDays := int(now.Sub(oldestPending.CreatedAt).Hours() / 24)
-		oldestPendingData = &OldestPendingTodo{
-			ID:          oldestPending.ID.String(),
-			Description: oldestPending.Description,
-			AgeDays:     ageDays,
-		}
-	}
-
-	return &StatsData{
-		Summary:       summary,
-		ByPriority:    byPriority,
-		ByProject:     byProject,
-		OldestPending: oldestPendingData,
-	}, nil
-}
@@ -6,11 +6,14 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/harper/toki/internal/db"
 	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -48,13 +51,13 @@ func TestResourceProjects(t *testing.T) {
 
 	// Create test projects
 	proj1 := models.NewProject("project-alpha", nil)
-	if err := db.CreateProject(database, proj1); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj1); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	path := "/tmp/test"
 	proj2 := models.NewProject("project-beta", &path)
-	if err := db.CreateProject(database, proj2); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj2); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
@@ -92,18 +95,18 @@ func TestResourceTodosAll(t *testing.T) {
 
 	// Create test project and todos
 	proj := models.NewProject("test-project", nil)
-	if err := db.CreateProject(database, proj); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	todo1 := models.NewTodo(proj.ID, "task one")
-	if err := db.CreateTodo(database, todo1); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo1); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	todo2 := models.NewTodo(proj.ID, "task two")
 	todo2.MarkDone()
-	if err := db.CreateTodo(database, todo2); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo2); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -136,18 +139,18 @@ func TestResourceTodosPending(t *testing.T) {
 
 	// Create test project and todos
 	proj := models.NewProject("test-project", nil)
-	if err := db.CreateProject(database, proj); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	todo1 := models.NewTodo(proj.ID, "pending task")
-	if err := db.CreateTodo(database, todo1); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo1); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	todo2 := models.NewTodo(proj.ID, "completed task")
 	todo2.MarkDone()
-	if err := db.CreateTodo(database, todo2); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo2); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -186,7 +189,7 @@ func TestResourceTodosOverdue(t *testing.T) {
 
 	// Create test project and todos
 	proj := models.NewProject("test-project", nil)
-	if err := db.CreateProject(database, proj); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
@@ -194,7 +197,7 @@ func TestResourceTodosOverdue(t *testing.T) {
 	yesterday := time.Now().Add(-24 * time.Hour)
 	todo1 := models.NewTodo(proj.ID, "overdue task")
 	todo1.DueDate = &yesterday
-	if err := db.CreateTodo(database, todo1); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo1); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -202,13 +205,13 @@ func TestResourceTodosOverdue(t *testing.T) {
 	tomorrow := time.Now().Add(24 * time.Hour)
 	todo2 := models.NewTodo(proj.ID, "future task")
 	todo2.DueDate = &tomorrow
-	if err := db.CreateTodo(database, todo2); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo2); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	// No due date todo
 	todo3 := models.NewTodo(proj.ID, "no due date")
-	if err := db.CreateTodo(database, todo3); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo3); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -236,6 +239,47 @@ func TestResourceTodosOverdue(t *testing.T) {
 	}
 }
 
+func TestResourceTodosPagination(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+	session := setupTestSession(t, database)
+	defer session.cleanup()
+
+	proj := models.NewProject("test-project", nil)
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	for range 3 {
+		if err := db.CreateTodo(t.Context(), database, models.NewTodo(proj.ID, "pending task")); err != nil {
+			t.Fatalf("Failed to create todo: %v", err)
+		}
+	}
+
+	for _, uri := range []string{"toki://todos/pending?limit=2", "toki://query?filter=not%20done&limit=2", "toki://views/pending?limit=2"} {
+		resp := readResource(t, session, uri)
+		if resp.Metadata.Count != 2 || resp.Metadata.NextCursor == "" {
+			t.Fatalf("%s: expected 2 todos and a next cursor, got %d and %q", uri, resp.Metadata.Count, resp.Metadata.NextCursor)
+		}
+		next := resp.Links["next"]
+		if !strings.Contains(next, "cursor="+resp.Metadata.NextCursor) || !strings.Contains(next, "limit=2") {
+			t.Fatalf("%s: expected a next link with the cursor and limit, got %q", uri, next)
+		}
+
+		resp = readResource(t, session, next)
+		if resp.Metadata.Count != 1 || resp.Metadata.NextCursor != "" {
+			t.Errorf("%s: expected 1 todo on the last page, got %d and cursor %q", next, resp.Metadata.Count, resp.Metadata.NextCursor)
+		}
+		if _, ok := resp.Links["next"]; ok {
+			t.Errorf("%s: expected no next link on the last page", next)
+		}
+	}
+
+	// Without a limit everything fits on the default page
+	if resp := readResource(t, session, "toki://todos"); resp.Metadata.Count != 3 || resp.Metadata.NextCursor != "" {
+		t.Errorf("Expected 3 todos on one page, got %d and cursor %q", resp.Metadata.Count, resp.Metadata.NextCursor)
+	}
+}
+
 func TestResourceTodosHighPriority(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
@@ -244,26 +288,26 @@ func TestResourceTodosHighPriority(t *testing.T) {
 
 	// Create test project and todos
 	proj := models.NewProject("test-project", nil)
-	if err := db.CreateProject(database, proj); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	high := "high"
 	todo1 := models.NewTodo(proj.ID, "high priority task")
 	todo1.Priority = &high
-	if err := db.CreateTodo(database, todo1); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo1); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	medium := "medium"
 	todo2 := models.NewTodo(proj.ID, "medium priority task")
 	todo2.Priority = &medium
-	if err := db.CreateTodo(database, todo2); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo2); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	todo3 := models.NewTodo(proj.ID, "no priority task")
-	if err := db.CreateTodo(database, todo3); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo3); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -295,9 +339,6 @@ func TestResourceTodosHighPriority(t *testing.T) {
 }
 
 func TestResourceQuery(t *testing.T) {
-	// NOTE: In v1, the query resource doesn't support URL parameters due to MCP SDK limitations.
-	// It returns all todos, same as toki://todos.
-	// For filtered views, use pre-built resources like toki://todos/pending or toki://todos/high-priority.
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
 	session := setupTestSession(t, database)
@@ -305,25 +346,25 @@ func TestResourceQuery(t *testing.T) {
 
 	// Create test project and todos
 	proj := models.NewProject("test-project", nil)
-	if err := db.CreateProject(database, proj); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	high := "high"
 	todo1 := models.NewTodo(proj.ID, "high priority task")
 	todo1.Priority = &high
-	if err := db.CreateTodo(database, todo1); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo1); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	low := "low"
 	todo2 := models.NewTodo(proj.ID, "low priority task")
 	todo2.Priority = &low
-	if err := db.CreateTodo(database, todo2); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo2); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
-	// Query base resource (returns all todos in v1)
+	// Query base resource returns all todos
 	resp := readResource(t, session, "toki://query")
 
 	// Verify it returns all todos
@@ -338,6 +379,135 @@ func TestResourceQuery(t *testing.T) {
 	if len(todos) != 2 {
 		t.Errorf("Expected 2 todos, got %d", len(todos))
 	}
+
+	// The filter parameter narrows the results
+	resp = readResource(t, session, "toki://query?filter=priority%3Ahigh%20and%20not%20done")
+	if resp.Metadata.Count != 1 {
+		t.Errorf("Expected count 1, got %d", resp.Metadata.Count)
+	}
+	if resp.Metadata.Filters["filter"] != "priority:high and not done" {
+		t.Errorf("Expected filter in metadata, got %v", resp.Metadata.Filters["filter"])
+	}
+	todos = nil
+	if err := json.Unmarshal(resp.Data, &todos); err != nil {
+		t.Fatalf("Failed to parse data: %v", err)
+	}
+	if len(todos) != 1 || todos[0]["description"] != "high priority task" {
+		t.Errorf("Expected only the high priority task, got %v", todos)
+	}
+}
+
+func TestResourceQueryInvalidFilter(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+	session := setupTestSession(t, database)
+	defer session.cleanup()
+
+	_, err := session.session.ReadResource(context.Background(), &mcp.ReadResourceParams{
+		URI: "toki://query?filter=priority%3Aurgent",
+	})
+	if err == nil {
+		t.Fatal("Expected error for invalid filter")
+	}
+	if !strings.Contains(err.Error(), "column 10") {
+		t.Errorf("Expected error to point at column 10, got %v", err)
+	}
+}
+
+func TestResourceViews(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	proj := models.NewProject("test-project", nil)
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	high := "high"
+	urgent := models.NewTodo(proj.ID, "urgent bug")
+	urgent.Priority = &high
+	if err := db.CreateTodo(t.Context(), database, urgent); err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	if err := db.AddTagToTodo(t.Context(), database, urgent.ID, "bug"); err != nil {
+		t.Fatalf("Failed to add tag: %v", err)
+	}
+	if err := db.CreateTodo(t.Context(), database, models.NewTodo(proj.ID, "chore")); err != nil {
+		t.Fatalf("Failed to create todo: %v", err)
+	}
+	if _, err := db.SaveView(t.Context(), database, &models.View{Name: "bugs", Filter: "tag:bug"}); err != nil {
+		t.Fatalf("Failed to save view: %v", err)
+	}
+
+	session := setupTestSession(t, database)
+	defer session.cleanup()
+
+	// Views are listed as resources
+	resources, err := session.session.ListResources(context.Background(), &mcp.ListResourcesParams{})
+	if err != nil {
+		t.Fatalf("Failed to list resources: %v", err)
+	}
+	listed := map[string]bool{}
+	for _, resource := range resources.Resources {
+		listed[resource.URI] = true
+	}
+	for _, uri := range []string{"toki://views", "toki://views/bugs", "toki://views/pending", "toki://views/overdue", "toki://views/high-priority"} {
+		if !listed[uri] {
+			t.Errorf("Expected resource %s to be listed", uri)
+		}
+	}
+
+	// Including those saved after startup, while deleted ones are not
+	if _, err := db.SaveView(t.Context(), database, &models.View{Name: "later", Filter: "priority:high"}); err != nil {
+		t.Fatalf("Failed to save view: %v", err)
+	}
+	if err := db.DeleteView(t.Context(), database, "bugs"); err != nil {
+		t.Fatalf("Failed to delete view: %v", err)
+	}
+	resources, err = session.session.ListResources(context.Background(), &mcp.ListResourcesParams{})
+	if err != nil {
+		t.Fatalf("Failed to list resources: %v", err)
+	}
+	listed = map[string]bool{}
+	for _, resource := range resources.Resources {
+		listed[resource.URI] = true
+	}
+	if !listed["toki://views/later"] || listed["toki://views/bugs"] {
+		t.Errorf("Expected the later view listed and the deleted one gone, got %v", listed)
+	}
+	if err := db.DeleteView(t.Context(), database, "later"); err != nil {
+		t.Fatalf("Failed to delete view: %v", err)
+	}
+	if _, err := db.SaveView(t.Context(), database, &models.View{Name: "bugs", Filter: "tag:bug"}); err != nil {
+		t.Fatalf("Failed to save view: %v", err)
+	}
+
+	resp := readResource(t, session, "toki://views/bugs")
+	if resp.Metadata.Count != 1 || resp.Metadata.Filters["view"] != "bugs" {
+		t.Errorf("Expected 1 todo for view 'bugs', got %d with filters %v", resp.Metadata.Count, resp.Metadata.Filters)
+	}
+
+	resp = readResource(t, session, "toki://views/pending")
+	if resp.Metadata.Count != 2 {
+		t.Errorf("Expected 2 pending todos, got %d", resp.Metadata.Count)
+	}
+
+	// Views saved later are served by the template
+	if _, err := db.SaveView(t.Context(), database, &models.View{Name: "chores", Filter: "text:chore"}); err != nil {
+		t.Fatalf("Failed to save view: %v", err)
+	}
+	resp = readResource(t, session, "toki://views/chores")
+	if resp.Metadata.Count != 1 {
+		t.Errorf("Expected 1 todo for view 'chores', got %d", resp.Metadata.Count)
+	}
+
+	resp = readResource(t, session, "toki://views")
+	if resp.Metadata.Count != 5 {
+		t.Errorf("Expected 5 views, got %d", resp.Metadata.Count)
+	}
+
+	if _, err := session.session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "toki://views/missing"}); err == nil {
+		t.Error("Expected error for unknown view")
+	}
 }
 
 func TestResourceEmptyResults(t *testing.T) {
@@ -372,14 +542,14 @@ func TestResourceLinksPresent(t *testing.T) {
 
 	// Create test data
 	proj := models.NewProject("test-project", nil)
-	if err := db.CreateProject(database, proj); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	high := "high"
 	todo1 := models.NewTodo(proj.ID, "high priority task")
 	todo1.Priority = &high
-	if err := db.CreateTodo(database, todo1); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo1); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -393,14 +563,17 @@ func TestResourceLinksPresent(t *testing.T) {
 	if _, ok := resp.Links["all_todos"]; !ok {
 		t.Error("Expected 'all_todos' link")
 	}
-	if _, ok := resp.Links["query"]; !ok {
-		t.Error("Expected 'query' link")
+	query, ok := resp.Links["query"]
+	if !ok {
+		t.Fatal("Expected 'query' link")
 	}
-}
 
-// Note: TestResourceInvalidQueryParameter and TestResourceInvalidPriority removed
-// because query parameters aren't supported in v1 due to MCP SDK limitations.
-// For custom filtering, use the list_todos tool instead of resources.
+	// The query link reproduces the same view
+	linked := readResource(t, session, query)
+	if linked.Metadata.Count != resp.Metadata.Count {
+		t.Errorf("Expected query link %s to return %d todos, got %d", query, resp.Metadata.Count, linked.Metadata.Count)
+	}
+}
 
 //nolint:gocyclo,funlen // Comprehensive stats test verifies multiple data points and edge cases
 func TestResourceStats(t *testing.T) {
@@ -411,12 +584,12 @@ func TestResourceStats(t *testing.T) {
 
 	// Create test projects
 	proj1 := models.NewProject("backend-api", nil)
-	if err := db.CreateProject(database, proj1); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj1); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
 	proj2 := models.NewProject("frontend", nil)
-	if err := db.CreateProject(database, proj2); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj2); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
@@ -425,7 +598,7 @@ func TestResourceStats(t *testing.T) {
 	high := "high"
 	todo1 := models.NewTodo(proj1.ID, "implement auth")
 	todo1.Priority = &high
-	if err := db.CreateTodo(database, todo1); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo1); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -434,13 +607,13 @@ func TestResourceStats(t *testing.T) {
 	todo2 := models.NewTodo(proj1.ID, "add tests")
 	todo2.Priority = &medium
 	todo2.DueDate = &yesterday // overdue
-	if err := db.CreateTodo(database, todo2); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo2); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	todo3 := models.NewTodo(proj1.ID, "write docs")
 	todo3.MarkDone()
-	if err := db.CreateTodo(database, todo3); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo3); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -448,7 +621,7 @@ func TestResourceStats(t *testing.T) {
 	low := "low"
 	todo4 := models.NewTodo(proj2.ID, "update styles")
 	todo4.Priority = &low
-	if err := db.CreateTodo(database, todo4); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo4); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -522,6 +695,19 @@ func TestResourceStats(t *testing.T) {
 	if int(firstProj["todo_count"].(float64)) != 3 {
 		t.Errorf("Expected backend-api todo_count=3, got %v", firstProj["todo_count"])
 	}
+	if int(firstProj["overdue"].(float64)) != 1 {
+		t.Errorf("Expected backend-api overdue=1, got %v", firstProj["overdue"])
+	}
+
+	// Verify the age distribution counts all 3 pending todos
+	ages := statsData["age_distribution"].([]interface{})
+	pendingByAge := 0
+	for _, bucket := range ages {
+		pendingByAge += int(bucket.(map[string]interface{})["count"].(float64))
+	}
+	if pendingByAge != 3 {
+		t.Errorf("Expected 3 pending todos across age buckets, got %d", pendingByAge)
+	}
 
 	// Verify oldest_pending exists
 	oldestPending, ok := statsData["oldest_pending"].(map[string]interface{})
@@ -536,6 +722,29 @@ func TestResourceStats(t *testing.T) {
 		t.Error("Expected oldest_pending to have age_days")
 	}
 
+	// Verify trends: write docs was created and completed this week
+	trends, ok := statsData["trends"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected trends to be present")
+	}
+	weeks := trends["weeks"].([]interface{})
+	if len(weeks) != 8 {
+		t.Fatalf("Expected 8 weeks of trends, got %d", len(weeks))
+	}
+	thisWeek := weeks[len(weeks)-1].(map[string]interface{})
+	if int(thisWeek["created"].(float64)) != 4 {
+		t.Errorf("Expected 4 todos created this week, got %v", thisWeek["created"])
+	}
+	if int(thisWeek["completed"].(float64)) != 1 {
+		t.Errorf("Expected 1 todo completed this week, got %v", thisWeek["completed"])
+	}
+	if thisWeek["completion_rate"].(float64) != 0.25 {
+		t.Errorf("Expected completion_rate=0.25, got %v", thisWeek["completion_rate"])
+	}
+	if int(trends["cycle_time_samples"].(float64)) != 1 {
+		t.Errorf("Expected 1 cycle time sample, got %v", trends["cycle_time_samples"])
+	}
+
 	// Verify links
 	if len(resp.Links) == 0 {
 		t.Error("Expected links to be present")
@@ -612,7 +821,7 @@ func TestResourceStatsOldestPending(t *testing.T) {
 
 	// Create project
 	proj := models.NewProject("test-project", nil)
-	if err := db.CreateProject(database, proj); err != nil {
+	if err := db.CreateProject(t.Context(), database, proj); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 
@@ -620,14 +829,14 @@ func TestResourceStatsOldestPending(t *testing.T) {
 	// Simulate oldest todo (created 45 days ago)
 	oldestTodo := models.NewTodo(proj.ID, "very old task")
 	oldestTodo.CreatedAt = time.Now().Add(-45 * 24 * time.Hour)
-	if err := db.CreateTodo(database, oldestTodo); err != nil {
+	if err := db.CreateTodo(t.Context(), database, oldestTodo); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
 	// Create a newer pending todo
 	newerTodo := models.NewTodo(proj.ID, "newer task")
 	newerTodo.CreatedAt = time.Now().Add(-5 * 24 * time.Hour)
-	if err := db.CreateTodo(database, newerTodo); err != nil {
+	if err := db.CreateTodo(t.Context(), database, newerTodo); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -635,7 +844,7 @@ func TestResourceStatsOldestPending(t *testing.T) {
 	completedTodo := models.NewTodo(proj.ID, "completed task")
 	completedTodo.CreatedAt = time.Now().Add(-60 * 24 * time.Hour)
 	completedTodo.MarkDone()
-	if err := db.CreateTodo(database, completedTodo); err != nil {
+	if err := db.CreateTodo(t.Context(), database, completedTodo); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 
@@ -664,3 +873,24 @@ func TestResourceStatsOldestPending(t *testing.T) {
 		t.Errorf("Expected age_days around 45, got %d", ageDays)
 	}
 }
+
+func TestQueryResourceStopsOnCancelledContext(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	createTestTodoInDB(t, database, project.ID, "pending", nil, nil)
+
+	server, err := NewServer(store.NewSQLite(database))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	req := &mcp.ReadResourceRequest{Params: &mcp.ReadResourceParams{URI: "toki://query?filter=done:false"}}
+	if _, err := server.handleQueryResource(ctx, req); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -5,22 +5,68 @@ package mcp
 
 import (
 	"context"
-	"database/sql"
 	"fmt"
+	"sync"
 
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/sorting"
+	"github.com/harper/toki/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
-// Server wraps MCP server with database connection.
+// Server wraps MCP server with the store it serves.
 type Server struct {
-	mcp *mcp.Server
-	db  *sql.DB
+	mcp   *mcp.Server
+	store store.Store
+	opts  Options
+
+	// viewsMu guards views, the description of each view listed as a resource.
+	viewsMu sync.Mutex
+	views   map[string]string
+}
+
+// Options configures how the server picks a project when tools omit project_id.
+type Options struct {
+	// Cwd is the directory used to detect the project, taking precedence over client roots.
+	Cwd string
+	// AutoCreateProjects registers a project for an unknown git repository instead of
+	// falling back to the default project.
+	AutoCreateProjects bool
+	// Git controls how worktrees and submodules map to repository roots.
+	Git git.Options
+	// Urgency overrides the coefficients used to score todos; nil uses the defaults.
+	Urgency *sorting.Coefficients
+	// DefaultProject names the fallback project; empty means "default".
+	DefaultProject string
+}
+
+// defaultProjectName returns the project used when no project is given or detected.
+func (s *Server) defaultProjectName() string {
+	if s.opts.DefaultProject != "" {
+		return s.opts.DefaultProject
+	}
+	return "default"
+}
+
+// urgencyCoefficients returns the configured urgency coefficients or the defaults.
+func (s *Server) urgencyCoefficients() sorting.Coefficients {
+	if s.opts.Urgency != nil {
+		return *s.opts.Urgency
+	}
+	return sorting.DefaultCoefficients()
+}
+
+// NewServer creates MCP server with all capabilities, serving the todos in st.
+func NewServer(st store.Store) (*Server, error) {
+	return NewServerWithOptions(st, Options{})
 }
 
-// NewServer creates MCP server with all capabilities.
-func NewServer(db *sql.DB) (*Server, error) {
-	if db == nil {
-		return nil, fmt.Errorf("database connection is required")
+// NewServerWithOptions creates MCP server with all capabilities and the given options.
+// Everything the server reads and writes goes through st, which may be SQLite or a
+// caching or in-memory store.
+func NewServerWithOptions(st store.Store, opts Options) (*Server, error) {
+	if st == nil {
+		return nil, fmt.Errorf("store is required")
 	}
 
 	mcpServer := mcp.NewServer(
@@ -32,8 +78,9 @@ func NewServer(db *sql.DB) (*Server, error) {
 	)
 
 	s := &Server{
-		mcp: mcpServer,
-		db:  db,
+		mcp:   mcpServer,
+		store: st,
+		opts:  opts,
 	}
 
 	// Register tools, resources, prompts
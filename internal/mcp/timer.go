@@ -0,0 +1,243 @@
+// ABOUTME: MCP tools for time tracking on todos
+// ABOUTME: Starts and stops the timer, logs durations, and reports tracked time by project and tag
+
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/ui"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// TimeEntryOutput represents a timer or logged duration in tool output.
+type TimeEntryOutput struct {
+	ID              int64      `json:"id"`
+	TodoID          string     `json:"todo_id"`
+	StartedAt       time.Time  `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at,omitempty"`
+	DurationSeconds int64      `json:"duration_seconds"`
+	Duration        string     `json:"duration"`
+	Running         bool       `json:"running"`
+}
+
+// StartTimerInput defines the input parameters for the start_timer tool.
+type StartTimerInput struct {
+	TodoID string `json:"todo_id"`
+}
+
+// StartTimerOutput defines the output structure for the start_timer tool.
+type StartTimerOutput struct {
+	Timer TimeEntryOutput `json:"timer"`
+	// Stopped is the timer that was running on another todo, if any.
+	Stopped *TimeEntryOutput `json:"stopped,omitempty"`
+}
+
+// StopTimerInput defines the input parameters for the stop_timer tool (empty).
+type StopTimerInput struct{}
+
+// LogTimeInput defines the input parameters for the log_time tool.
+type LogTimeInput struct {
+	TodoID   string `json:"todo_id"`
+	Duration string `json:"duration"`
+}
+
+// TimeReportInput defines the input parameters for the time_report tool.
+type TimeReportInput struct {
+	Range *string `json:"range,omitempty"`
+}
+
+// TimeTotalOutput is the time tracked for one project or tag.
+type TimeTotalOutput struct {
+	Name     string `json:"name"`
+	Seconds  int64  `json:"seconds"`
+	Duration string `json:"duration"`
+}
+
+// TimeReportOutput defines the output structure for the time_report tool.
+type TimeReportOutput struct {
+	Range        string            `json:"range"`
+	From         time.Time         `json:"from"`
+	To           time.Time         `json:"to"`
+	TotalSeconds int64             `json:"total_seconds"`
+	Total        string            `json:"total"`
+	Projects     []TimeTotalOutput `json:"projects"`
+	Tags         []TimeTotalOutput `json:"tags"`
+}
+
+func (s *Server) registerTimerTools() {
+	mcp.AddTool(s.mcp, &mcp.Tool{
+		Name:        "start_timer",
+		Description: `Start tracking time on a todo by its UUID or a unique UUID prefix. Use this when you begin working on a task. Only one timer runs at a time: a timer running on another todo is stopped first and returned as "stopped". Call stop_timer when you finish, or use log_time to record effort after the fact.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"todo_id": map[string]interface{}{
+					"type":        "string",
+					"description": "UUID of the todo to work on, or a unique prefix of at least 6 characters. Example: 'abc12345'",
+				},
+			},
+			"required": []string{"todo_id"},
+		},
+	}, s.handleStartTimer)
+
+	mcp.AddTool(s.mcp, &mcp.Tool{
+		Name:        "stop_timer",
+		Description: `Stop the running timer and record the time spent. Returns the finished time entry with its duration. Fails if no timer is running.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+		},
+	}, s.handleStopTimer)
+
+	mcp.AddTool(s.mcp, &mcp.Tool{
+		Name:        "log_time",
+		Description: `Record time spent on a todo without running a timer, for example after completing a task. The entry ends now. Returns the logged time entry.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"todo_id": map[string]interface{}{
+					"type":        "string",
+					"description": "UUID of the todo the time was spent on, or a unique prefix of at least 6 characters. Example: 'abc12345'",
+				},
+				"duration": map[string]interface{}{
+					"type":        "string",
+					"description": "Time spent as hours, minutes, and seconds. Examples: '1h30m', '45m', '2h'",
+				},
+			},
+			"required": []string{"todo_id", "duration"},
+		},
+	}, s.handleLogTime)
+
+	mcp.AddTool(s.mcp, &mcp.Tool{
+		Name:        "time_report",
+		Description: `Report the time tracked in a range, totaled by project and by tag, most time first. A todo with several tags counts toward each of them; untagged time has an empty tag name. Use this to summarize where effort went.`,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"range": map[string]interface{}{
+					"type":        "string",
+					"description": "Period to report on: " + strings.Join(filter.Periods, ", ") + ", a day like '2025-01-31', or '2025-01-01..2025-01-15'. Weeks start on Monday. Defaults to 'this-week'.",
+				},
+			},
+		},
+	}, s.handleTimeReport)
+}
+
+func (s *Server) handleStartTimer(ctx context.Context, req *mcp.CallToolRequest, input StartTimerInput) (*mcp.CallToolResult, StartTimerOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
+	if err != nil {
+		return nil, StartTimerOutput{}, err
+	}
+
+	now := time.Now()
+	entry, stopped, err := s.store.StartTimer(ctx, todo.ID, now)
+	if err != nil {
+		return nil, StartTimerOutput{}, err
+	}
+
+	output := StartTimerOutput{Timer: timeEntryOutput(entry, now)}
+	if stopped != nil {
+		stoppedOutput := timeEntryOutput(stopped, now)
+		output.Stopped = &stoppedOutput
+	}
+	return jsonResult(output)
+}
+
+func (s *Server) handleStopTimer(ctx context.Context, req *mcp.CallToolRequest, input StopTimerInput) (*mcp.CallToolResult, TimeEntryOutput, error) {
+	now := time.Now()
+	entry, err := s.store.StopTimer(ctx, now)
+	if err != nil {
+		return nil, TimeEntryOutput{}, err
+	}
+	return jsonResult(timeEntryOutput(entry, now))
+}
+
+func (s *Server) handleLogTime(ctx context.Context, req *mcp.CallToolRequest, input LogTimeInput) (*mcp.CallToolResult, TimeEntryOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
+	if err != nil {
+		return nil, TimeEntryOutput{}, err
+	}
+
+	duration, err := time.ParseDuration(input.Duration)
+	if err != nil {
+		return nil, TimeEntryOutput{}, fmt.Errorf("invalid duration '%s' (expected something like 1h30m or 45m)", input.Duration)
+	}
+
+	now := time.Now()
+	entry, err := s.store.LogTime(ctx, todo.ID, duration, now)
+	if err != nil {
+		return nil, TimeEntryOutput{}, err
+	}
+	return jsonResult(timeEntryOutput(entry, now))
+}
+
+func (s *Server) handleTimeReport(ctx context.Context, req *mcp.CallToolRequest, input TimeReportInput) (*mcp.CallToolResult, TimeReportOutput, error) {
+	period := "this-week"
+	if input.Range != nil && *input.Range != "" {
+		period = *input.Range
+	}
+
+	now := time.Now()
+	from, to, err := filter.ResolvePeriod(period, now)
+	if err != nil {
+		return nil, TimeReportOutput{}, err
+	}
+
+	report, err := s.store.GetTimeReport(ctx, from, to, now)
+	if err != nil {
+		return nil, TimeReportOutput{}, err
+	}
+
+	return jsonResult(TimeReportOutput{
+		Range:        period,
+		From:         report.From,
+		To:           report.To,
+		TotalSeconds: int64(report.Total / time.Second),
+		Total:        ui.FormatDuration(report.Total),
+		Projects:     timeTotalOutputs(report.Projects),
+		Tags:         timeTotalOutputs(report.Tags),
+	})
+}
+
+func timeEntryOutput(entry *models.TimeEntry, now time.Time) TimeEntryOutput {
+	elapsed := entry.Elapsed(now)
+	return TimeEntryOutput{
+		ID:              entry.ID,
+		TodoID:          entry.TodoID.String(),
+		StartedAt:       entry.StartedAt,
+		EndedAt:         entry.EndedAt,
+		DurationSeconds: int64(elapsed / time.Second),
+		Duration:        ui.FormatDuration(elapsed),
+		Running:         entry.Running(),
+	}
+}
+
+func timeTotalOutputs(totals []models.TimeTotal) []TimeTotalOutput {
+	outputs := make([]TimeTotalOutput, 0, len(totals))
+	for _, total := range totals {
+		outputs = append(outputs, TimeTotalOutput{
+			Name:     total.Name,
+			Seconds:  int64(total.Duration / time.Second),
+			Duration: ui.FormatDuration(total.Duration),
+		})
+	}
+	return outputs
+}
+
+// jsonResult returns output as indented JSON text alongside the structured output.
+func jsonResult[T any](output T) (*mcp.CallToolResult, T, error) {
+	jsonBytes, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return nil, output, fmt.Errorf("failed to marshal output: %w", err)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: string(jsonBytes)}},
+	}, output, nil
+}
@@ -0,0 +1,85 @@
+// ABOUTME: Tests for the time tracking MCP tools
+// ABOUTME: Covers starting, switching, and stopping timers, logging time, and reports
+
+package mcp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestTimerTools(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	first := createTestTodoInDB(t, database, project.ID, "first task", nil, nil)
+	second := createTestTodoInDB(t, database, project.ID, "second task", nil, nil)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	call := func(name string, args map[string]any) *mcp.CallToolResult {
+		t.Helper()
+		result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatalf("Failed to call %s: %v", name, err)
+		}
+		return result
+	}
+
+	if result := call("stop_timer", map[string]any{}); !result.IsError {
+		t.Error("Expected stop_timer to fail without a running timer")
+	}
+
+	started := parseToolResult(t, call("start_timer", map[string]any{"todo_id": first.ID.String()[:8]}))
+	timer := started["timer"].(map[string]any)
+	if timer["todo_id"] != first.ID.String() || timer["running"] != true {
+		t.Errorf("Unexpected timer: %v", timer)
+	}
+	if _, ok := started["stopped"]; ok {
+		t.Error("Expected no stopped timer on the first start")
+	}
+
+	switched := parseToolResult(t, call("start_timer", map[string]any{"todo_id": second.ID.String()}))
+	stopped, ok := switched["stopped"].(map[string]any)
+	if !ok || stopped["todo_id"] != first.ID.String() || stopped["running"] != false {
+		t.Errorf("Expected the first timer to be stopped, got %v", switched)
+	}
+
+	stoppedEntry := parseToolResult(t, call("stop_timer", map[string]any{}))
+	if stoppedEntry["todo_id"] != second.ID.String() || stoppedEntry["ended_at"] == nil {
+		t.Errorf("Unexpected stopped entry: %v", stoppedEntry)
+	}
+
+	logged := parseToolResult(t, call("log_time", map[string]any{"todo_id": first.ID.String(), "duration": "1h30m"}))
+	if logged["duration_seconds"] != float64(5400) || logged["duration"] != "1h30m" {
+		t.Errorf("Unexpected logged entry: %v", logged)
+	}
+	if result := call("log_time", map[string]any{"todo_id": first.ID.String(), "duration": "soon"}); !result.IsError {
+		t.Error("Expected log_time to reject an invalid duration")
+	}
+
+	report := parseToolResult(t, call("time_report", map[string]any{"range": "today"}))
+	if report["total_seconds"].(float64) < 5400 {
+		t.Errorf("Expected the logged time in today's report: %v", report)
+	}
+	projects := report["projects"].([]any)
+	if len(projects) != 1 || projects[0].(map[string]any)["name"] != project.Name {
+		t.Errorf("Unexpected project totals: %v", projects)
+	}
+	if result := call("time_report", map[string]any{"range": "fortnight"}); !result.IsError {
+		t.Error("Expected time_report to reject an unknown range")
+	}
+
+	list := parseListTodosResult(t, call("list_todos", map[string]any{"project_id": project.ID.String()}))
+	for _, item := range list["todos"].([]any) {
+		todo := item.(map[string]any)
+		if todo["id"] == first.ID.String() && todo["tracked_seconds"].(float64) < 5400 {
+			t.Errorf("Expected tracked time on the listed todo: %v", todo)
+		}
+	}
+}
@@ -5,14 +5,19 @@ package mcp
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/git"
 	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/sorting"
+	"github.com/harper/toki/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -24,6 +29,9 @@ type AddTodoInput struct {
 	Tags        []string `json:"tags,omitempty"`
 	Notes       *string  `json:"notes,omitempty"`
 	DueDate     *string  `json:"due_date,omitempty"`
+	Branch      *string  `json:"branch,omitempty"`
+	Estimate    *string  `json:"estimate,omitempty"`
+	Cwd         *string  `json:"cwd,omitempty"`
 }
 
 // AddTodoOutput defines the output structure for the add_todo tool.
@@ -37,6 +45,8 @@ type AddTodoOutput struct {
 	Tags        []string   `json:"tags,omitempty"`
 	CreatedAt   time.Time  `json:"created_at"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
+	Branch      *string    `json:"branch,omitempty"`
+	Estimate    string     `json:"estimate,omitempty"`
 }
 
 // ListTodosInput defines the input parameters for the list_todos tool.
@@ -46,6 +56,13 @@ type ListTodosInput struct {
 	Priority  *string `json:"priority,omitempty"`
 	Tag       *string `json:"tag,omitempty"`
 	Overdue   *bool   `json:"overdue,omitempty"`
+	Branch    *string `json:"branch,omitempty"`
+	Cwd       *string `json:"cwd,omitempty"`
+	Sort      *string `json:"sort,omitempty"`
+	Reverse   *bool   `json:"reverse,omitempty"`
+	Filter    *string `json:"filter,omitempty"`
+	Limit     *int    `json:"limit,omitempty"`
+	Cursor    *string `json:"cursor,omitempty"`
 }
 
 // TodoOutput represents a single todo in list output.
@@ -60,6 +77,11 @@ type TodoOutput struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DueDate     *time.Time `json:"due_date,omitempty"`
+	Branch      *string    `json:"branch,omitempty"`
+	Estimate    string     `json:"estimate,omitempty"`
+	Urgency     *float64   `json:"urgency,omitempty"`
+	// TrackedSeconds is the time tracked on the todo, including a running timer.
+	TrackedSeconds int64 `json:"tracked_seconds,omitempty"`
 }
 
 // ListTodosOutput defines the output structure for the list_todos tool.
@@ -67,6 +89,8 @@ type ListTodosOutput struct {
 	Todos   []TodoOutput   `json:"todos"`
 	Count   int            `json:"count"`
 	Filters map[string]any `json:"filters"`
+	// NextCursor is passed as cursor to get the next page. It is empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func (s *Server) registerTools() {
@@ -81,6 +105,7 @@ func (s *Server) registerTools() {
 	s.registerAddProjectTool()
 	s.registerListProjectsTool()
 	s.registerDeleteProjectTool()
+	s.registerTimerTools()
 }
 
 func (s *Server) registerAddTodoTool() {
@@ -96,7 +121,7 @@ func (s *Server) registerAddTodoTool() {
 				},
 				"project_id": map[string]interface{}{
 					"type":        "string",
-					"description": "UUID of the project this todo belongs to. If not provided, uses the default project. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "Project this todo belongs to: UUID, unique UUID prefix, project name, or directory path. If not provided, the project is detected from cwd or the client's roots, falling back to the default project. Example: 'backend-api'",
 				},
 				"priority": map[string]interface{}{
 					"type":        "string",
@@ -117,6 +142,18 @@ func (s *Server) registerAddTodoTool() {
 					"format":      "date-time",
 					"description": "Due date in ISO 8601 format. Example: '2025-12-01T15:04:05Z'",
 				},
+				"branch": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional git branch this todo belongs to, for work scoped to a feature branch. Example: 'feature/login'",
+				},
+				"estimate": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional estimate in story points or as a duration, used for velocity and burndown. Examples: '3', '5pt', '2h', '1h30m'",
+				},
+				"cwd": map[string]interface{}{
+					"type":        "string",
+					"description": "Working directory used to pick the project from its git repository when project_id is omitted. Example: '/home/user/projects/backend-api'",
+				},
 			},
 			"required": []string{"description"},
 		},
@@ -126,13 +163,13 @@ func (s *Server) registerAddTodoTool() {
 func (s *Server) registerListTodosTool() {
 	mcp.AddTool(s.mcp, &mcp.Tool{
 		Name:        "list_todos",
-		Description: `Retrieve todos with powerful filtering capabilities. Filter by project, completion status, priority, tags, or due date. All filters are optional and can be combined for precise queries. Use this to view your task list, find specific todos, or generate reports. Returns an array of todos with full metadata, count, and applied filters for context.`,
+		Description: `Retrieve todos with powerful filtering capabilities. Filter by project, completion status, priority, tags, or due date. All filters are optional and can be combined for precise queries. Use this to view your task list, find specific todos, or generate reports. Returns an array of todos with full metadata, count, and applied filters for context. Results are paged, 100 todos at a time by default: when more match, next_cursor is set, and passing it as cursor with the same filters and sort returns the next page.`,
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"project_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Filter by project UUID. Only todos from this project will be returned. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "Filter by project: UUID, unique UUID prefix, project name, or directory path. Only todos from this project will be returned. Example: 'backend-api'",
 				},
 				"done": map[string]interface{}{
 					"type":        "boolean",
@@ -151,13 +188,43 @@ func (s *Server) registerListTodosTool() {
 					"type":        "boolean",
 					"description": "Filter by overdue status. true = only overdue todos (due date in the past), false = only non-overdue todos. Example: true",
 				},
+				"branch": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter to todos scoped to this git branch. Example: 'feature/login'",
+				},
+				"cwd": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter to the project of the git repository containing this directory. Ignored when project_id is set. Example: '/home/user/projects/backend-api'",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter expression combined with the other filters. Terms like project:<name>, tag:<name>, priority:<level>, due<date, branch:<name>, text:<words>, done, pending, and overdue are joined with and, or, not, and parentheses. Dates are YYYY-MM-DD, today, tomorrow, or offsets like +7d and -2w; priority and dates also take <, <=, >, >=. Example: 'project:api and (tag:bug or priority:high) and due<+7d and not done'",
+				},
+				"sort": map[string]interface{}{
+					"type":        "string",
+					"enum":        sorting.KeyNames(),
+					"description": "Sort order. due = soonest first, priority = highest first, created/updated = newest first, urgency = most urgent first (a score weighing priority, due date, age, and tags). Todos without a due date or priority sort last. Defaults to newest created first. Example: 'urgency'",
+				},
+				"reverse": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Reverse the sort order. Example: true",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"minimum":     1,
+					"description": "Maximum number of todos to return, at most 1000. Defaults to 100. Example: 20",
+				},
+				"cursor": map[string]interface{}{
+					"type":        "string",
+					"description": "The next_cursor of the previous page, to continue listing where it stopped. Use the same filters and sort as that call.",
+				},
 			},
 		},
 	}, s.handleListTodos)
 }
 
-func (s *Server) handleAddTodo(_ context.Context, req *mcp.CallToolRequest, input AddTodoInput) (*mcp.CallToolResult, AddTodoOutput, error) {
-	projectID, err := s.resolveProjectID(input.ProjectID)
+func (s *Server) handleAddTodo(ctx context.Context, req *mcp.CallToolRequest, input AddTodoInput) (*mcp.CallToolResult, AddTodoOutput, error) {
+	projectID, err := s.resolveProjectID(ctx, req.Session, input.ProjectID, input.Cwd)
 	if err != nil {
 		return nil, AddTodoOutput{}, err
 	}
@@ -171,7 +238,12 @@ func (s *Server) handleAddTodo(_ context.Context, req *mcp.CallToolRequest, inpu
 		return nil, AddTodoOutput{}, err
 	}
 
-	todo, err := s.createTodoWithTags(projectID, input, dueDate)
+	estimate, err := parseEstimate(input.Estimate)
+	if err != nil {
+		return nil, AddTodoOutput{}, err
+	}
+
+	todo, err := s.createTodoWithTags(ctx, projectID, input, dueDate, estimate)
 	if err != nil {
 		return nil, AddTodoOutput{}, err
 	}
@@ -179,36 +251,46 @@ func (s *Server) handleAddTodo(_ context.Context, req *mcp.CallToolRequest, inpu
 	return buildAddTodoResult(todo, input.Tags, dueDate)
 }
 
-func (s *Server) resolveProjectID(projectIDStr *string) (uuid.UUID, error) {
-	if projectIDStr != nil && *projectIDStr != "" {
-		return s.parseAndVerifyProjectID(*projectIDStr)
+func (s *Server) resolveProjectID(ctx context.Context, session *mcp.ServerSession, projectRef, cwd *string) (uuid.UUID, error) {
+	if projectRef != nil && *projectRef != "" {
+		project, err := s.resolveProject(ctx, *projectRef)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		return project.ID, nil
 	}
-	return s.getOrCreateDefaultProject()
-}
 
-func (s *Server) parseAndVerifyProjectID(projectIDStr string) (uuid.UUID, error) {
-	projectID, err := uuid.Parse(projectIDStr)
+	project, err := s.detectProject(ctx, session, cwd, s.opts.AutoCreateProjects)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid project_id: must be a valid UUID. Use the project's full UUID. Error: %w", err)
+		return uuid.Nil, fmt.Errorf("failed to detect project: %w", err)
 	}
-
-	_, err = db.GetProjectByID(s.db, projectID)
-	if err != nil {
-		return uuid.Nil, fmt.Errorf("project not found: no project exists with ID '%s'. Create the project first or omit project_id to use default", projectID)
+	if project != nil {
+		return project.ID, nil
 	}
 
-	return projectID, nil
+	return s.getOrCreateDefaultProject(ctx)
 }
 
-func (s *Server) getOrCreateDefaultProject() (uuid.UUID, error) {
-	project, err := db.GetProjectByName(s.db, "default")
-	if err == nil {
-		return project.ID, nil
-	}
+// getOrCreateDefaultProject runs in a transaction so that concurrent servers create the
+// default project once. Only a missing project is created; other lookup errors are
+// returned.
+func (s *Server) getOrCreateDefaultProject(ctx context.Context) (uuid.UUID, error) {
+	var project *models.Project
+	err := s.store.Tx(ctx, func(tx store.Store) error {
+		var err error
+		project, err = tx.GetProjectByName(ctx, s.defaultProjectName())
+		if !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
 
-	project = models.NewProject("default", nil)
-	if err := db.CreateProject(s.db, project); err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create default project: %w", err)
+		project = models.NewProject(s.defaultProjectName(), nil)
+		if err := tx.CreateProject(ctx, project); err != nil {
+			return fmt.Errorf("failed to create default project: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return uuid.Nil, err
 	}
 	return project.ID, nil
 }
@@ -235,20 +317,33 @@ func parseDueDate(dueDateStr *string) (*time.Time, error) {
 	return &parsed, nil
 }
 
-func (s *Server) createTodoWithTags(projectID uuid.UUID, input AddTodoInput, dueDate *time.Time) (*models.Todo, error) {
+func parseEstimate(estimateStr *string) (*models.Estimate, error) {
+	if estimateStr == nil || *estimateStr == "" {
+		return nil, nil //nolint:nilnil // nil pointer is valid for optional estimate
+	}
+	return models.ParseEstimate(*estimateStr)
+}
+
+// estimateString formats an optional estimate for output, empty when there is none.
+func estimateString(estimate *models.Estimate) string {
+	if estimate == nil {
+		return ""
+	}
+	return estimate.String()
+}
+
+func (s *Server) createTodoWithTags(ctx context.Context, projectID uuid.UUID, input AddTodoInput, dueDate *time.Time, estimate *models.Estimate) (*models.Todo, error) {
 	todo := models.NewTodo(projectID, input.Description)
 	todo.Priority = input.Priority
 	todo.Notes = input.Notes
 	todo.DueDate = dueDate
-
-	if err := db.CreateTodo(s.db, todo); err != nil {
-		return nil, fmt.Errorf("failed to create todo: %w", err)
+	todo.Estimate = estimate
+	if input.Branch != nil && *input.Branch != "" {
+		todo.Branch = input.Branch
 	}
 
-	for _, tag := range input.Tags {
-		if err := db.AddTagToTodo(s.db, todo.ID, tag); err != nil {
-			return nil, fmt.Errorf("failed to add tag '%s': %w", tag, err)
-		}
+	if err := s.store.CreateTodoWithTags(ctx, todo, input.Tags); err != nil {
+		return nil, fmt.Errorf("failed to create todo: %w", err)
 	}
 
 	return todo, nil
@@ -265,6 +360,8 @@ func buildAddTodoResult(todo *models.Todo, tags []string, dueDate *time.Time) (*
 		Tags:        tags,
 		CreatedAt:   todo.CreatedAt,
 		DueDate:     dueDate,
+		Branch:      todo.Branch,
+		Estimate:    estimateString(todo.Estimate),
 	}
 
 	jsonBytes, err := json.MarshalIndent(output, "", "  ")
@@ -277,8 +374,8 @@ func buildAddTodoResult(todo *models.Todo, tags []string, dueDate *time.Time) (*
 	}, output, nil
 }
 
-func (s *Server) handleListTodos(_ context.Context, req *mcp.CallToolRequest, input ListTodosInput) (*mcp.CallToolResult, ListTodosOutput, error) {
-	projectID, err := s.resolveOptionalProjectID(input.ProjectID)
+func (s *Server) handleListTodos(ctx context.Context, req *mcp.CallToolRequest, input ListTodosInput) (*mcp.CallToolResult, ListTodosOutput, error) {
+	projectID, err := s.resolveOptionalProjectID(ctx, req.Session, input.ProjectID, input.Cwd)
 	if err != nil {
 		return nil, ListTodosOutput{}, err
 	}
@@ -287,98 +384,131 @@ func (s *Server) handleListTodos(_ context.Context, req *mcp.CallToolRequest, in
 		return nil, ListTodosOutput{}, err
 	}
 
-	todos, err := s.fetchFilteredTodos(projectID, input)
+	var sortKey sorting.Key
+	if input.Sort != nil && *input.Sort != "" {
+		sortKey, err = sorting.ParseKey(*input.Sort)
+		if err != nil {
+			return nil, ListTodosOutput{}, err
+		}
+	} else if input.Reverse != nil && *input.Reverse {
+		sortKey = sorting.KeyCreated
+	}
+
+	page, err := parsePage(input.Limit, input.Cursor)
 	if err != nil {
 		return nil, ListTodosOutput{}, err
 	}
 
-	return buildListTodosResult(s.db, todos, input)
+	expr, err := listTodosFilter(projectID, input)
+	if err != nil {
+		return nil, ListTodosOutput{}, err
+	}
+
+	todos, todoTags, next, err := s.queryTodoPage(ctx, expr, sortKey, input.Reverse != nil && *input.Reverse, page)
+	if err != nil {
+		return nil, ListTodosOutput{}, err
+	}
+
+	return buildListTodosResult(ctx, s.store, todos, todoTags, input, next, s.urgencyCoefficients())
 }
 
-func (s *Server) resolveOptionalProjectID(projectIDStr *string) (*uuid.UUID, error) {
-	if projectIDStr == nil || *projectIDStr == "" {
+func (s *Server) resolveOptionalProjectID(ctx context.Context, session *mcp.ServerSession, projectRef, cwd *string) (*uuid.UUID, error) {
+	if projectRef != nil && *projectRef != "" {
+		project, err := s.resolveProject(ctx, *projectRef)
+		if err != nil {
+			return nil, err
+		}
+		return &project.ID, nil
+	}
+
+	if cwd == nil || *cwd == "" {
 		return nil, nil //nolint:nilnil // nil means no filter
 	}
 
-	projectID, err := uuid.Parse(*projectIDStr)
+	project, err := s.detectProject(ctx, session, cwd, false)
 	if err != nil {
-		return nil, fmt.Errorf("invalid project_id: must be a valid UUID. Error: %w", err)
+		return nil, fmt.Errorf("failed to detect project: %w", err)
+	}
+	if project == nil {
+		return nil, fmt.Errorf("project not found: no project is registered for the git repository containing '%s'. Use add_project with its path first", *cwd)
 	}
 
-	return &projectID, nil
+	return &project.ID, nil
 }
 
-func (s *Server) fetchFilteredTodos(projectID *uuid.UUID, input ListTodosInput) ([]*models.Todo, error) {
-	todos, err := db.ListTodos(s.db, projectID, input.Done, input.Priority, input.Tag)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list todos: %w", err)
+// listTodosFilter combines the list_todos arguments into one filter expression.
+func listTodosFilter(projectID *uuid.UUID, input ListTodosInput) (filter.Node, error) {
+	var expr filter.Node
+	if input.Filter != nil && *input.Filter != "" {
+		var err error
+		expr, err = parseFilter(*input.Filter)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	if input.Overdue != nil && *input.Overdue {
-		todos = filterOverdueTodos(todos)
-	} else if input.Overdue != nil && !*input.Overdue {
-		todos = filterNonOverdueTodos(todos)
+	criteria := filter.Criteria{Done: input.Done, Priority: input.Priority, Tag: input.Tag, Branch: input.Branch, Overdue: input.Overdue}
+	if projectID != nil {
+		criteria.Project = projectID.String()
 	}
-
-	return todos, nil
+	return filter.AndAll(criteria.Node(), expr), nil
 }
 
-func filterOverdueTodos(todos []*models.Todo) []*models.Todo {
-	now := time.Now()
-	var filtered []*models.Todo
-	for _, todo := range todos {
-		if todo.DueDate != nil && todo.DueDate.Before(now) && !todo.Done {
-			filtered = append(filtered, todo)
+// parseFilter parses a filter expression, pointing at the error position on failure.
+func parseFilter(value string) (filter.Node, error) {
+	expr, err := filter.Parse(value)
+	if err != nil {
+		var syntaxErr *filter.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, fmt.Errorf("%w\n%s", err, syntaxErr.Context())
 		}
+		return nil, err
 	}
-	return filtered
+	return expr, nil
 }
 
-func filterNonOverdueTodos(todos []*models.Todo) []*models.Todo {
+func buildListTodosResult(ctx context.Context, timers store.Timers, todos []*models.Todo, todoTags map[uuid.UUID][]*models.Tag, input ListTodosInput, next string, coefficients sorting.Coefficients) (*mcp.CallToolResult, ListTodosOutput, error) {
 	now := time.Now()
-	var filtered []*models.Todo
-	for _, todo := range todos {
-		if todo.DueDate == nil || !todo.DueDate.Before(now) || todo.Done {
-			filtered = append(filtered, todo)
-		}
+
+	tracked, err := timers.TrackedTime(ctx, now)
+	if err != nil {
+		return nil, ListTodosOutput{}, err
 	}
-	return filtered
-}
 
-func buildListTodosResult(database *sql.DB, todos []*models.Todo, input ListTodosInput) (*mcp.CallToolResult, ListTodosOutput, error) {
 	todoOutputs := make([]TodoOutput, 0, len(todos))
-
 	for _, todo := range todos {
-		tags, err := db.GetTodoTags(database, todo.ID)
-		if err != nil {
-			return nil, ListTodosOutput{}, fmt.Errorf("failed to get tags for todo %s: %w", todo.ID, err)
-		}
-
+		tags := todoTags[todo.ID]
 		tagNames := make([]string, len(tags))
 		for i, tag := range tags {
 			tagNames[i] = tag.Name
 		}
 
+		score := math.Round(sorting.Urgency(todo, tags, coefficients, now)*100) / 100
 		todoOutputs = append(todoOutputs, TodoOutput{
-			ID:          todo.ID.String(),
-			ProjectID:   todo.ProjectID.String(),
-			Description: todo.Description,
-			Done:        todo.Done,
-			Priority:    todo.Priority,
-			Notes:       todo.Notes,
-			Tags:        tagNames,
-			CreatedAt:   todo.CreatedAt,
-			UpdatedAt:   todo.UpdatedAt,
-			DueDate:     todo.DueDate,
+			ID:             todo.ID.String(),
+			ProjectID:      todo.ProjectID.String(),
+			Description:    todo.Description,
+			Done:           todo.Done,
+			Priority:       todo.Priority,
+			Notes:          todo.Notes,
+			Tags:           tagNames,
+			CreatedAt:      todo.CreatedAt,
+			UpdatedAt:      todo.UpdatedAt,
+			DueDate:        todo.DueDate,
+			Branch:         todo.Branch,
+			Estimate:       estimateString(todo.Estimate),
+			Urgency:        &score,
+			TrackedSeconds: int64(tracked[todo.ID] / time.Second),
 		})
 	}
 
 	appliedFilters := buildAppliedFilters(input)
 
 	output := ListTodosOutput{
-		Todos:   todoOutputs,
-		Count:   len(todoOutputs),
-		Filters: appliedFilters,
+		Todos:      todoOutputs,
+		Count:      len(todoOutputs),
+		Filters:    appliedFilters,
+		NextCursor: next,
 	}
 
 	jsonBytes, err := json.MarshalIndent(output, "", "  ")
@@ -409,6 +539,21 @@ func buildAppliedFilters(input ListTodosInput) map[string]any {
 	if input.Overdue != nil {
 		filters["overdue"] = *input.Overdue
 	}
+	if input.Branch != nil && *input.Branch != "" {
+		filters["branch"] = *input.Branch
+	}
+	if input.Cwd != nil && *input.Cwd != "" {
+		filters["cwd"] = *input.Cwd
+	}
+	if input.Filter != nil && *input.Filter != "" {
+		filters["filter"] = *input.Filter
+	}
+	if input.Sort != nil && *input.Sort != "" {
+		filters["sort"] = *input.Sort
+	}
+	if input.Reverse != nil {
+		filters["reverse"] = *input.Reverse
+	}
 
 	return filters
 }
@@ -421,13 +566,13 @@ type MarkDoneInput struct {
 func (s *Server) registerMarkDoneTool() {
 	mcp.AddTool(s.mcp, &mcp.Tool{
 		Name:        "mark_done",
-		Description: `Mark a todo as complete by its UUID. Use this when a task is finished to track completion and update status. The todo will be marked as done with a completion timestamp. Returns the updated todo with all metadata so you can verify the change. To find the UUID of a todo, use list_todos first.`,
+		Description: `Mark a todo as complete by its UUID or a unique UUID prefix. Use this when a task is finished to track completion and update status. The todo will be marked as done with a completion timestamp. Returns the updated todo with all metadata so you can verify the change. Short UUID prefixes are accepted; ambiguous prefixes return the matching candidates.`,
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"todo_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Full UUID of the todo to mark as complete. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "UUID of the todo to mark as complete, or a unique prefix of at least 6 characters. Example: 'abc12345'",
 				},
 			},
 			"required": []string{"todo_id"},
@@ -435,23 +580,18 @@ func (s *Server) registerMarkDoneTool() {
 	}, s.handleMarkDone)
 }
 
-func (s *Server) handleMarkDone(_ context.Context, req *mcp.CallToolRequest, input MarkDoneInput) (*mcp.CallToolResult, TodoOutput, error) {
-	todoID, err := uuid.Parse(input.TodoID)
+func (s *Server) handleMarkDone(ctx context.Context, req *mcp.CallToolRequest, input MarkDoneInput) (*mcp.CallToolResult, TodoOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
 	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("invalid todo_id: must be a valid UUID. Error: %w", err)
-	}
-
-	todo, err := db.GetTodoByID(s.db, todoID)
-	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("todo not found: no todo exists with ID '%s'. Use list_todos to see available todos", input.TodoID)
+		return nil, TodoOutput{}, err
 	}
 
 	todo.MarkDone()
-	if err := db.UpdateTodo(s.db, todo); err != nil {
+	if err := s.store.UpdateTodo(ctx, todo); err != nil {
 		return nil, TodoOutput{}, fmt.Errorf("failed to update todo: %w", err)
 	}
 
-	return buildTodoResult(s.db, todo)
+	return buildTodoResult(ctx, s.store, todo)
 }
 
 // MarkUndoneInput defines the input parameters for the mark_undone tool.
@@ -468,7 +608,7 @@ func (s *Server) registerMarkUndoneTool() {
 			"properties": map[string]interface{}{
 				"todo_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Full UUID of the todo to reopen. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "UUID of the todo to reopen, or a unique prefix of at least 6 characters. Example: 'abc12345'",
 				},
 			},
 			"required": []string{"todo_id"},
@@ -476,34 +616,29 @@ func (s *Server) registerMarkUndoneTool() {
 	}, s.handleMarkUndone)
 }
 
-func (s *Server) handleMarkUndone(_ context.Context, req *mcp.CallToolRequest, input MarkUndoneInput) (*mcp.CallToolResult, TodoOutput, error) {
-	todoID, err := uuid.Parse(input.TodoID)
-	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("invalid todo_id: must be a valid UUID. Error: %w", err)
-	}
-
-	todo, err := db.GetTodoByID(s.db, todoID)
+func (s *Server) handleMarkUndone(ctx context.Context, req *mcp.CallToolRequest, input MarkUndoneInput) (*mcp.CallToolResult, TodoOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
 	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("todo not found: no todo exists with ID '%s'. Use list_todos to see available todos", input.TodoID)
+		return nil, TodoOutput{}, err
 	}
 
 	todo.MarkUndone()
-	if err := db.UpdateTodo(s.db, todo); err != nil {
+	if err := s.store.UpdateTodo(ctx, todo); err != nil {
 		return nil, TodoOutput{}, fmt.Errorf("failed to update todo: %w", err)
 	}
 
-	return buildTodoResult(s.db, todo)
+	return buildTodoResult(ctx, s.store, todo)
 }
 
 // buildTodoResult builds a TodoOutput from a todo model.
-func buildTodoResult(database *sql.DB, todo *models.Todo) (*mcp.CallToolResult, TodoOutput, error) {
-	tags, err := db.GetTodoTags(database, todo.ID)
+func buildTodoResult(ctx context.Context, tags store.Tags, todo *models.Todo) (*mcp.CallToolResult, TodoOutput, error) {
+	todoTags, err := tags.GetTodoTags(ctx, todo.ID)
 	if err != nil {
 		return nil, TodoOutput{}, fmt.Errorf("failed to get tags: %w", err)
 	}
 
-	tagNames := make([]string, len(tags))
-	for i, tag := range tags {
+	tagNames := make([]string, len(todoTags))
+	for i, tag := range todoTags {
 		tagNames[i] = tag.Name
 	}
 
@@ -518,6 +653,8 @@ func buildTodoResult(database *sql.DB, todo *models.Todo) (*mcp.CallToolResult,
 		CreatedAt:   todo.CreatedAt,
 		UpdatedAt:   todo.UpdatedAt,
 		DueDate:     todo.DueDate,
+		Branch:      todo.Branch,
+		Estimate:    estimateString(todo.Estimate),
 	}
 
 	jsonBytes, err := json.MarshalIndent(output, "", "  ")
@@ -545,13 +682,13 @@ type DeleteTodoOutput struct {
 func (s *Server) registerDeleteTodoTool() {
 	mcp.AddTool(s.mcp, &mcp.Tool{
 		Name:        "delete_todo",
-		Description: `Permanently delete a todo by its UUID. Use this when a task is no longer relevant or was created by mistake. This action cannot be undone. The todo and all its associations (tags, etc.) will be removed from the database. Returns success confirmation with the deleted todo's ID. To find the UUID of a todo, use list_todos first.`,
+		Description: `Permanently delete a todo by its UUID or a unique UUID prefix. Use this when a task is no longer relevant or was created by mistake. This action cannot be undone. The todo and all its associations (tags, etc.) will be removed from the database. Returns success confirmation with the deleted todo's ID. Short UUID prefixes are accepted; ambiguous prefixes return the matching candidates.`,
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"todo_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Full UUID of the todo to delete permanently. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "UUID of the todo to delete permanently, or a unique prefix of at least 6 characters. Example: 'abc12345'",
 				},
 			},
 			"required": []string{"todo_id"},
@@ -559,26 +696,20 @@ func (s *Server) registerDeleteTodoTool() {
 	}, s.handleDeleteTodo)
 }
 
-func (s *Server) handleDeleteTodo(_ context.Context, req *mcp.CallToolRequest, input DeleteTodoInput) (*mcp.CallToolResult, DeleteTodoOutput, error) {
-	todoID, err := uuid.Parse(input.TodoID)
-	if err != nil {
-		return nil, DeleteTodoOutput{}, fmt.Errorf("invalid todo_id: must be a valid UUID. Error: %w", err)
-	}
-
-	// Check if todo exists first
-	_, err = db.GetTodoByID(s.db, todoID)
+func (s *Server) handleDeleteTodo(ctx context.Context, req *mcp.CallToolRequest, input DeleteTodoInput) (*mcp.CallToolResult, DeleteTodoOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
 	if err != nil {
-		return nil, DeleteTodoOutput{}, fmt.Errorf("todo not found: no todo exists with ID '%s'. Use list_todos to see available todos", input.TodoID)
+		return nil, DeleteTodoOutput{}, err
 	}
 
-	if err := db.DeleteTodo(s.db, todoID); err != nil {
+	if err := s.store.DeleteTodo(ctx, todo.ID); err != nil {
 		return nil, DeleteTodoOutput{}, fmt.Errorf("failed to delete todo: %w", err)
 	}
 
 	output := DeleteTodoOutput{
 		Success: true,
-		Message: fmt.Sprintf("Todo '%s' successfully deleted", input.TodoID),
-		TodoID:  input.TodoID,
+		Message: fmt.Sprintf("Todo '%s' successfully deleted", todo.ID),
+		TodoID:  todo.ID.String(),
 	}
 
 	jsonBytes, err := json.MarshalIndent(output, "", "  ")
@@ -598,18 +729,19 @@ type UpdateTodoInput struct {
 	Priority    *string `json:"priority,omitempty"`
 	Notes       *string `json:"notes,omitempty"`
 	DueDate     *string `json:"due_date,omitempty"`
+	Estimate    *string `json:"estimate,omitempty"`
 }
 
 func (s *Server) registerUpdateTodoTool() {
 	mcp.AddTool(s.mcp, &mcp.Tool{
 		Name:        "update_todo",
-		Description: `Update a todo's metadata including description, priority, notes, and due date. All update fields are optional - only provide the fields you want to change. Use this for modifying existing todos without recreating them. Returns the updated todo with all metadata. To find the UUID of a todo, use list_todos first.`,
+		Description: `Update a todo's metadata including description, priority, notes, due date, and estimate. All update fields are optional - only provide the fields you want to change. Use this for modifying existing todos without recreating them. Returns the updated todo with all metadata. Short UUID prefixes are accepted; ambiguous prefixes return the matching candidates.`,
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"todo_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Full UUID of the todo to update. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "UUID of the todo to update, or a unique prefix of at least 6 characters. Example: 'abc12345'",
 				},
 				"description": map[string]interface{}{
 					"type":        "string",
@@ -629,21 +761,20 @@ func (s *Server) registerUpdateTodoTool() {
 					"format":      "date-time",
 					"description": "New due date in ISO 8601 format. Example: '2025-12-15T15:04:05Z'",
 				},
+				"estimate": map[string]interface{}{
+					"type":        "string",
+					"description": "New estimate in story points or as a duration, or an empty string to clear it. Examples: '3', '5pt', '2h'",
+				},
 			},
 			"required": []string{"todo_id"},
 		},
 	}, s.handleUpdateTodo)
 }
 
-func (s *Server) handleUpdateTodo(_ context.Context, req *mcp.CallToolRequest, input UpdateTodoInput) (*mcp.CallToolResult, TodoOutput, error) {
-	todoID, err := uuid.Parse(input.TodoID)
+func (s *Server) handleUpdateTodo(ctx context.Context, req *mcp.CallToolRequest, input UpdateTodoInput) (*mcp.CallToolResult, TodoOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
 	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("invalid todo_id: must be a valid UUID. Error: %w", err)
-	}
-
-	todo, err := db.GetTodoByID(s.db, todoID)
-	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("todo not found: no todo exists with ID '%s'. Use list_todos to see available todos", input.TodoID)
+		return nil, TodoOutput{}, err
 	}
 
 	// Validate priority if provided
@@ -660,6 +791,11 @@ func (s *Server) handleUpdateTodo(_ context.Context, req *mcp.CallToolRequest, i
 		}
 	}
 
+	estimate, err := parseEstimate(input.Estimate)
+	if err != nil {
+		return nil, TodoOutput{}, err
+	}
+
 	// Update fields if provided
 	if input.Description != nil {
 		todo.Description = *input.Description
@@ -673,15 +809,18 @@ func (s *Server) handleUpdateTodo(_ context.Context, req *mcp.CallToolRequest, i
 	if input.DueDate != nil {
 		todo.DueDate = dueDate
 	}
+	if input.Estimate != nil {
+		todo.Estimate = estimate
+	}
 
 	// Update the timestamp
 	todo.UpdatedAt = time.Now()
 
-	if err := db.UpdateTodo(s.db, todo); err != nil {
+	if err := s.store.UpdateTodo(ctx, todo); err != nil {
 		return nil, TodoOutput{}, fmt.Errorf("failed to update todo: %w", err)
 	}
 
-	return buildTodoResult(s.db, todo)
+	return buildTodoResult(ctx, s.store, todo)
 }
 
 // AddTagToTodoInput defines the input parameters for the add_tag_to_todo tool.
@@ -699,7 +838,7 @@ func (s *Server) registerAddTagToTodoTool() {
 			"properties": map[string]interface{}{
 				"todo_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Full UUID of the todo to tag. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "UUID of the todo to tag, or a unique prefix of at least 6 characters. Example: 'abc12345'",
 				},
 				"tag_name": map[string]interface{}{
 					"type":        "string",
@@ -711,23 +850,17 @@ func (s *Server) registerAddTagToTodoTool() {
 	}, s.handleAddTagToTodo)
 }
 
-func (s *Server) handleAddTagToTodo(_ context.Context, req *mcp.CallToolRequest, input AddTagToTodoInput) (*mcp.CallToolResult, TodoOutput, error) {
-	todoID, err := uuid.Parse(input.TodoID)
-	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("invalid todo_id: must be a valid UUID. Error: %w", err)
-	}
-
-	// Check if todo exists
-	todo, err := db.GetTodoByID(s.db, todoID)
+func (s *Server) handleAddTagToTodo(ctx context.Context, req *mcp.CallToolRequest, input AddTagToTodoInput) (*mcp.CallToolResult, TodoOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
 	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("todo not found: no todo exists with ID '%s'. Use list_todos to see available todos", input.TodoID)
+		return nil, TodoOutput{}, err
 	}
 
-	if err := db.AddTagToTodo(s.db, todoID, input.TagName); err != nil {
+	if err := s.store.AddTagToTodo(ctx, todo.ID, input.TagName); err != nil {
 		return nil, TodoOutput{}, fmt.Errorf("failed to add tag: %w", err)
 	}
 
-	return buildTodoResult(s.db, todo)
+	return buildTodoResult(ctx, s.store, todo)
 }
 
 // RemoveTagFromTodoInput defines the input parameters for the remove_tag_from_todo tool.
@@ -745,7 +878,7 @@ func (s *Server) registerRemoveTagFromTodoTool() {
 			"properties": map[string]interface{}{
 				"todo_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Full UUID of the todo to untag. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "UUID of the todo to untag, or a unique prefix of at least 6 characters. Example: 'abc12345'",
 				},
 				"tag_name": map[string]interface{}{
 					"type":        "string",
@@ -757,23 +890,17 @@ func (s *Server) registerRemoveTagFromTodoTool() {
 	}, s.handleRemoveTagFromTodo)
 }
 
-func (s *Server) handleRemoveTagFromTodo(_ context.Context, req *mcp.CallToolRequest, input RemoveTagFromTodoInput) (*mcp.CallToolResult, TodoOutput, error) {
-	todoID, err := uuid.Parse(input.TodoID)
+func (s *Server) handleRemoveTagFromTodo(ctx context.Context, req *mcp.CallToolRequest, input RemoveTagFromTodoInput) (*mcp.CallToolResult, TodoOutput, error) {
+	todo, err := s.resolveTodo(ctx, input.TodoID)
 	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("invalid todo_id: must be a valid UUID. Error: %w", err)
-	}
-
-	// Check if todo exists
-	todo, err := db.GetTodoByID(s.db, todoID)
-	if err != nil {
-		return nil, TodoOutput{}, fmt.Errorf("todo not found: no todo exists with ID '%s'. Use list_todos to see available todos", input.TodoID)
+		return nil, TodoOutput{}, err
 	}
 
-	if err := db.RemoveTagFromTodo(s.db, todoID, input.TagName); err != nil {
+	if err := s.store.RemoveTagFromTodo(ctx, todo.ID, input.TagName); err != nil {
 		return nil, TodoOutput{}, fmt.Errorf("failed to remove tag: %w", err)
 	}
 
-	return buildTodoResult(s.db, todo)
+	return buildTodoResult(ctx, s.store, todo)
 }
 
 // AddProjectInput defines the input parameters for the add_project tool.
@@ -787,13 +914,14 @@ type ProjectOutput struct {
 	ID        string    `json:"id"`
 	Name      string    `json:"name"`
 	Path      *string   `json:"path,omitempty"`
+	RemoteURL *string   `json:"remote_url,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
 func (s *Server) registerAddProjectTool() {
 	mcp.AddTool(s.mcp, &mcp.Tool{
 		Name:        "add_project",
-		Description: `Create a new project to organize todos. Projects are containers for related tasks and can optionally be associated with a git repository path. Use this to separate work for different codebases, clients, or areas of focus. Returns the created project with a UUID that you can use when adding todos. All projects can be listed with list_projects.`,
+		Description: `Create a new project to organize todos. Projects are containers for related tasks and can optionally be associated with a git repository path. When the path is a git repository with an origin remote, the project is linked to that remote so any clone of the repository resolves to it. Use this to separate work for different codebases, clients, or areas of focus. Returns the created project with a UUID that you can use when adding todos. All projects can be listed with list_projects.`,
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -811,9 +939,14 @@ func (s *Server) registerAddProjectTool() {
 	}, s.handleAddProject)
 }
 
-func (s *Server) handleAddProject(_ context.Context, req *mcp.CallToolRequest, input AddProjectInput) (*mcp.CallToolResult, ProjectOutput, error) {
+func (s *Server) handleAddProject(ctx context.Context, req *mcp.CallToolRequest, input AddProjectInput) (*mcp.CallToolResult, ProjectOutput, error) {
 	project := models.NewProject(input.Name, input.Path)
-	if err := db.CreateProject(s.db, project); err != nil {
+	if input.Path != nil {
+		if repo, err := git.DetectRepo(*input.Path, s.opts.Git); err == nil && repo.RemoteURL != "" {
+			project.RemoteURL = &repo.RemoteURL
+		}
+	}
+	if err := s.store.CreateProject(ctx, project); err != nil {
 		return nil, ProjectOutput{}, fmt.Errorf("failed to create project: %w", err)
 	}
 
@@ -821,6 +954,7 @@ func (s *Server) handleAddProject(_ context.Context, req *mcp.CallToolRequest, i
 		ID:        project.ID.String(),
 		Name:      project.Name,
 		Path:      project.DirectoryPath,
+		RemoteURL: project.RemoteURL,
 		CreatedAt: project.CreatedAt,
 	}
 
@@ -853,8 +987,8 @@ func (s *Server) registerListProjectsTool() {
 	}, s.handleListProjects)
 }
 
-func (s *Server) handleListProjects(_ context.Context, req *mcp.CallToolRequest, input ListProjectsInput) (*mcp.CallToolResult, ListProjectsOutput, error) {
-	projects, err := db.ListProjects(s.db)
+func (s *Server) handleListProjects(ctx context.Context, req *mcp.CallToolRequest, input ListProjectsInput) (*mcp.CallToolResult, ListProjectsOutput, error) {
+	projects, err := s.store.ListProjects(ctx)
 	if err != nil {
 		return nil, ListProjectsOutput{}, fmt.Errorf("failed to list projects: %w", err)
 	}
@@ -865,6 +999,7 @@ func (s *Server) handleListProjects(_ context.Context, req *mcp.CallToolRequest,
 			ID:        project.ID.String(),
 			Name:      project.Name,
 			Path:      project.DirectoryPath,
+			RemoteURL: project.RemoteURL,
 			CreatedAt: project.CreatedAt,
 		})
 	}
@@ -899,13 +1034,13 @@ type DeleteProjectOutput struct {
 func (s *Server) registerDeleteProjectTool() {
 	mcp.AddTool(s.mcp, &mcp.Tool{
 		Name:        "delete_project",
-		Description: `Permanently delete a project by its UUID. This action cannot be undone. WARNING: Deleting a project will also delete all associated todos due to database CASCADE constraints. Use this when a project is no longer needed and you want to clean up all related tasks. Returns success confirmation. To find the UUID of a project, use list_projects first.`,
+		Description: `Permanently delete a project by its UUID, name, or path. This action cannot be undone. WARNING: Deleting a project will also delete all associated todos due to database CASCADE constraints. Use this when a project is no longer needed and you want to clean up all related tasks. Returns success confirmation. Ambiguous references return the matching candidates.`,
 		InputSchema: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
 				"project_id": map[string]interface{}{
 					"type":        "string",
-					"description": "Full UUID of the project to delete. Example: 'abc12345-1234-1234-1234-123456789abc'",
+					"description": "Project to delete: UUID, unique UUID prefix, project name, or directory path. Example: 'backend-api'",
 				},
 			},
 			"required": []string{"project_id"},
@@ -913,26 +1048,20 @@ func (s *Server) registerDeleteProjectTool() {
 	}, s.handleDeleteProject)
 }
 
-func (s *Server) handleDeleteProject(_ context.Context, req *mcp.CallToolRequest, input DeleteProjectInput) (*mcp.CallToolResult, DeleteProjectOutput, error) {
-	projectID, err := uuid.Parse(input.ProjectID)
-	if err != nil {
-		return nil, DeleteProjectOutput{}, fmt.Errorf("invalid project_id: must be a valid UUID. Error: %w", err)
-	}
-
-	// Check if project exists first
-	_, err = db.GetProjectByID(s.db, projectID)
+func (s *Server) handleDeleteProject(ctx context.Context, req *mcp.CallToolRequest, input DeleteProjectInput) (*mcp.CallToolResult, DeleteProjectOutput, error) {
+	project, err := s.resolveProject(ctx, input.ProjectID)
 	if err != nil {
-		return nil, DeleteProjectOutput{}, fmt.Errorf("project not found: no project exists with ID '%s'. Use list_projects to see available projects", input.ProjectID)
+		return nil, DeleteProjectOutput{}, err
 	}
 
-	if err := db.DeleteProject(s.db, projectID); err != nil {
+	if err := s.store.DeleteProject(ctx, project.ID); err != nil {
 		return nil, DeleteProjectOutput{}, fmt.Errorf("failed to delete project: %w", err)
 	}
 
 	output := DeleteProjectOutput{
 		Success:   true,
-		Message:   fmt.Sprintf("Project '%s' and all associated todos successfully deleted", input.ProjectID),
-		ProjectID: input.ProjectID,
+		Message:   fmt.Sprintf("Project '%s' and all associated todos successfully deleted", project.Name),
+		ProjectID: project.ID.String(),
 	}
 
 	jsonBytes, err := json.MarshalIndent(output, "", "  ")
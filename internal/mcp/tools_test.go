@@ -8,13 +8,19 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/harper/toki/internal/db"
 	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/store"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -22,7 +28,7 @@ func setupTestDB(t *testing.T) *sql.DB {
 	t.Helper()
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
-	database, err := db.InitDB(dbPath)
+	database, err := db.InitDB(t.Context(), dbPath)
 	if err != nil {
 		t.Fatalf("Failed to init DB: %v", err)
 	}
@@ -37,7 +43,7 @@ type testSession struct {
 func setupTestSession(t *testing.T, database *sql.DB) *testSession {
 	t.Helper()
 
-	server, err := NewServer(database)
+	server, err := NewServer(store.NewSQLite(database))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
@@ -89,7 +95,7 @@ func TestRegisterTools(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
 
-	server, err := NewServer(database)
+	server, err := NewServer(store.NewSQLite(database))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
@@ -198,7 +204,7 @@ func createTestProject(t *testing.T, database *sql.DB) *models.Project {
 		Name:      "test-project-" + uuid.New().String()[:8],
 		CreatedAt: time.Now(),
 	}
-	if err := db.CreateProject(database, project); err != nil {
+	if err := db.CreateProject(t.Context(), database, project); err != nil {
 		t.Fatalf("Failed to create project: %v", err)
 	}
 	return project
@@ -238,7 +244,7 @@ func verifyTodoTags(t *testing.T, database *sql.DB, todoIDStr string) {
 		t.Fatalf("Failed to parse todo ID: %v", err)
 	}
 
-	tags, err := db.GetTodoTags(database, todoID)
+	tags, err := db.GetTodoTags(t.Context(), database, todoID)
 	if err != nil {
 		t.Fatalf("Failed to get todo tags: %v", err)
 	}
@@ -261,7 +267,7 @@ func TestAddTodoMissingDescription(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
 
-	server, err := NewServer(database)
+	server, err := NewServer(store.NewSQLite(database))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
@@ -295,7 +301,7 @@ func TestAddTodoInvalidPriority(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
 
-	server, err := NewServer(database)
+	server, err := NewServer(store.NewSQLite(database))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
@@ -332,7 +338,7 @@ func TestAddTodoInvalidProjectID(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
 
-	server, err := NewServer(database)
+	server, err := NewServer(store.NewSQLite(database))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
@@ -370,6 +376,40 @@ func TestAddTodoInvalidProjectID(t *testing.T) {
 	}
 }
 
+func TestAddTodoWithFailingTagCreatesNothing(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	_, err := database.Exec(`CREATE TRIGGER fail_tag BEFORE INSERT ON tags
+	                         WHEN NEW.name = 'broken'
+	                         BEGIN SELECT RAISE(ABORT, 'tag refused'); END`)
+	if err != nil {
+		t.Fatalf("Failed to create trigger: %v", err)
+	}
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	result, err := ts.session.CallTool(context.Background(), &mcp.CallToolParams{
+		Name:      "add_todo",
+		Arguments: map[string]any{"description": "half made", "tags": []string{"fine", "broken"}},
+	})
+	if err != nil {
+		t.Fatalf("Tool call failed: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("Expected an error result when a tag cannot be added")
+	}
+
+	todos, err := db.ListTodos(t.Context(), database, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to list todos: %v", err)
+	}
+	if len(todos) != 0 {
+		t.Errorf("Expected no todo without its tags, got %d", len(todos))
+	}
+}
+
 // Helper function to parse list_todos result.
 func parseListTodosResult(t *testing.T, result *mcp.CallToolResult) map[string]interface{} {
 	t.Helper()
@@ -399,7 +439,7 @@ func TestListTodosRegistered(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
 
-	server, err := NewServer(database)
+	server, err := NewServer(store.NewSQLite(database))
 	if err != nil {
 		t.Fatalf("Failed to create server: %v", err)
 	}
@@ -525,7 +565,7 @@ func TestListTodosFilterByDone(t *testing.T) {
 	_ = createTestTodoInDB(t, database, project.ID, "not done", nil, nil)
 	todo2 := createTestTodoInDB(t, database, project.ID, "done", nil, nil)
 	todo2.MarkDone()
-	if err := db.UpdateTodo(database, todo2); err != nil {
+	if err := db.UpdateTodo(t.Context(), database, todo2); err != nil {
 		t.Fatalf("Failed to update todo: %v", err)
 	}
 
@@ -608,10 +648,10 @@ func TestListTodosFilterByTag(t *testing.T) {
 	todo1 := createTestTodoInDB(t, database, project.ID, "bug fix", nil, nil)
 	todo2 := createTestTodoInDB(t, database, project.ID, "feature", nil, nil)
 
-	if err := db.AddTagToTodo(database, todo1.ID, "bug"); err != nil {
+	if err := db.AddTagToTodo(t.Context(), database, todo1.ID, "bug"); err != nil {
 		t.Fatalf("Failed to add tag: %v", err)
 	}
-	if err := db.AddTagToTodo(database, todo2.ID, "feature"); err != nil {
+	if err := db.AddTagToTodo(t.Context(), database, todo2.ID, "feature"); err != nil {
 		t.Fatalf("Failed to add tag: %v", err)
 	}
 
@@ -692,17 +732,17 @@ func TestListTodosCombinedFilters(t *testing.T) {
 
 	// Create several todos with different attributes
 	todo1 := createTestTodoInDB(t, database, project.ID, "high priority bug", stringPtr("high"), nil)
-	if err := db.AddTagToTodo(database, todo1.ID, "bug"); err != nil {
+	if err := db.AddTagToTodo(t.Context(), database, todo1.ID, "bug"); err != nil {
 		t.Fatalf("Failed to add tag: %v", err)
 	}
 
 	todo2 := createTestTodoInDB(t, database, project.ID, "low priority bug", stringPtr("low"), nil)
-	if err := db.AddTagToTodo(database, todo2.ID, "bug"); err != nil {
+	if err := db.AddTagToTodo(t.Context(), database, todo2.ID, "bug"); err != nil {
 		t.Fatalf("Failed to add tag: %v", err)
 	}
 
 	todo3 := createTestTodoInDB(t, database, project.ID, "high priority feature", stringPtr("high"), nil)
-	if err := db.AddTagToTodo(database, todo3.ID, "feature"); err != nil {
+	if err := db.AddTagToTodo(t.Context(), database, todo3.ID, "feature"); err != nil {
 		t.Fatalf("Failed to add tag: %v", err)
 	}
 
@@ -734,6 +774,202 @@ func TestListTodosCombinedFilters(t *testing.T) {
 	}
 }
 
+func TestListTodosSortByUrgency(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	createTestTodoInDB(t, database, project.ID, "low priority", stringPtr("low"), nil)
+	overdue := time.Now().Add(-10 * 24 * time.Hour)
+	createTestTodoInDB(t, database, project.ID, "overdue", nil, &overdue)
+	createTestTodoInDB(t, database, project.ID, "high priority", stringPtr("high"), nil)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"sort": "urgency"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call list_todos: %v", err)
+	}
+
+	response := parseListTodosResult(t, result)
+	todos := response["todos"].([]interface{})
+	want := []string{"overdue", "high priority", "low priority"}
+	for i, description := range want {
+		todo := todos[i].(map[string]interface{})
+		if todo["description"] != description {
+			t.Errorf("Expected todo %d to be %q, got %v", i, description, todo["description"])
+		}
+		if _, ok := todo["urgency"].(float64); !ok {
+			t.Errorf("Expected urgency on %q, got %v", description, todo["urgency"])
+		}
+	}
+
+	result, err = ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"sort": "urgency", "reverse": true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call list_todos: %v", err)
+	}
+	todos = parseListTodosResult(t, result)["todos"].([]interface{})
+	if first := todos[0].(map[string]interface{}); first["description"] != "low priority" {
+		t.Errorf("Expected reversed order to start with 'low priority', got %v", first["description"])
+	}
+
+	result, err = ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"sort": "size"},
+	})
+	if err == nil && !result.IsError {
+		t.Error("Expected error for invalid sort")
+	}
+}
+
+func TestListTodosPagination(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	for i := range 5 {
+		priority := "low"
+		if i%2 == 0 {
+			priority = "high"
+		}
+		createTestTodoInDB(t, database, project.ID, fmt.Sprintf("task %d", i), &priority, nil)
+	}
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	for _, sortKey := range []string{"", "priority"} {
+		seen := make(map[string]bool)
+		args := map[string]any{"limit": 2}
+		if sortKey != "" {
+			args["sort"] = sortKey
+		}
+		for pages := 0; ; pages++ {
+			if pages > 3 {
+				t.Fatalf("Sort %q: paging did not stop", sortKey)
+			}
+			result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{Name: "list_todos", Arguments: args})
+			if err != nil {
+				t.Fatalf("Failed to call list_todos: %v", err)
+			}
+
+			response := parseListTodosResult(t, result)
+			todos := response["todos"].([]interface{})
+			if len(todos) > 2 {
+				t.Fatalf("Sort %q: expected at most 2 todos per page, got %d", sortKey, len(todos))
+			}
+			for _, todo := range todos {
+				description := todo.(map[string]interface{})["description"].(string)
+				if seen[description] {
+					t.Errorf("Sort %q: %q appeared on two pages", sortKey, description)
+				}
+				seen[description] = true
+			}
+
+			next, _ := response["next_cursor"].(string)
+			if next == "" {
+				break
+			}
+			args["cursor"] = next
+		}
+		if len(seen) != 5 {
+			t.Errorf("Sort %q: expected 5 todos across pages, got %d", sortKey, len(seen))
+		}
+	}
+
+	// Cursors are tied to the order they were made for
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"limit": 2, "sort": "priority"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call list_todos: %v", err)
+	}
+	cursor := parseListTodosResult(t, result)["next_cursor"]
+	result, err = ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"limit": 2, "cursor": cursor},
+	})
+	if err == nil && !result.IsError {
+		t.Error("Expected error for a cursor from a sorted listing")
+	}
+
+	result, err = ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"limit": 0},
+	})
+	if err == nil && !result.IsError {
+		t.Error("Expected error for limit 0")
+	}
+}
+
+func TestListTodosFilterExpression(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	bug := createTestTodoInDB(t, database, project.ID, "low priority bug", stringPtr("low"), nil)
+	if err := db.AddTagToTodo(t.Context(), database, bug.ID, "bug"); err != nil {
+		t.Fatalf("Failed to add tag: %v", err)
+	}
+	createTestTodoInDB(t, database, project.ID, "high priority feature", stringPtr("high"), nil)
+	createTestTodoInDB(t, database, project.ID, "low priority chore", stringPtr("low"), nil)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "list_todos",
+		Arguments: map[string]any{
+			"filter":   "tag:bug or priority:high",
+			"priority": "low",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call list_todos: %v", err)
+	}
+
+	response := parseListTodosResult(t, result)
+	todos := response["todos"].([]interface{})
+	if len(todos) != 1 {
+		t.Fatalf("Expected 1 todo matching filter and priority, got %d", len(todos))
+	}
+	if todo := todos[0].(map[string]interface{}); todo["description"] != "low priority bug" {
+		t.Errorf("Expected 'low priority bug', got %v", todo["description"])
+	}
+	filters := response["filters"].(map[string]interface{})
+	if filters["filter"] != "tag:bug or priority:high" {
+		t.Errorf("Expected filter in applied filters, got %v", filters["filter"])
+	}
+
+	result, err = ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"filter": "tag:bug and (priority:high"},
+	})
+	if err == nil && !result.IsError {
+		t.Fatal("Expected error for unbalanced filter")
+	}
+	var message string
+	if err != nil {
+		message = err.Error()
+	} else {
+		message = result.Content[0].(*mcp.TextContent).Text
+	}
+	if !strings.Contains(message, "column 13") {
+		t.Errorf("Expected error to point at column 13, got %v", message)
+	}
+}
+
 func TestListTodosEmptyResults(t *testing.T) {
 	database := setupTestDB(t)
 	defer func() { _ = database.Close() }()
@@ -795,7 +1031,7 @@ func createTestTodoInDB(t *testing.T, database *sql.DB, projectID uuid.UUID, des
 	todo := models.NewTodo(projectID, description)
 	todo.Priority = priority
 	todo.DueDate = dueDate
-	if err := db.CreateTodo(database, todo); err != nil {
+	if err := db.CreateTodo(t.Context(), database, todo); err != nil {
 		t.Fatalf("Failed to create todo: %v", err)
 	}
 	return todo
@@ -911,7 +1147,7 @@ func TestMarkUndoneSuccess(t *testing.T) {
 	project := createTestProject(t, database)
 	todo := createTestTodoInDB(t, database, project.ID, "test todo", nil, nil)
 	todo.MarkDone()
-	if err := db.UpdateTodo(database, todo); err != nil {
+	if err := db.UpdateTodo(t.Context(), database, todo); err != nil {
 		t.Fatalf("Failed to mark todo done: %v", err)
 	}
 
@@ -980,7 +1216,7 @@ func TestDeleteTodoSuccess(t *testing.T) {
 	}
 
 	// Verify todo is actually deleted
-	_, err = db.GetTodoByID(database, todo.ID)
+	_, err = db.GetTodoByID(t.Context(), database, todo.ID)
 	if err == nil {
 		t.Error("Todo should be deleted from database")
 	}
@@ -1150,6 +1386,59 @@ func TestUpdateTodoNotFound(t *testing.T) {
 	}
 }
 
+func TestTodoEstimate(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "add_todo",
+		Arguments: map[string]any{
+			"description": "estimated work",
+			"project_id":  project.ID.String(),
+			"estimate":    "5pt",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call add_todo: %v", err)
+	}
+	added := parseToolResult(t, result)
+	if added["estimate"] != "5pt" {
+		t.Errorf("Expected estimate '5pt', got %v", added["estimate"])
+	}
+	id := added["id"].(string)
+
+	update := func(estimate string) *mcp.CallToolResult {
+		result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+			Name:      "update_todo",
+			Arguments: map[string]any{"todo_id": id, "estimate": estimate},
+		})
+		if err != nil {
+			t.Fatalf("Failed to call update_todo: %v", err)
+		}
+		return result
+	}
+
+	updated := parseToolResult(t, update("1h30m"))
+	if updated["estimate"] != "1h30m" {
+		t.Errorf("Expected estimate '1h30m', got %v", updated["estimate"])
+	}
+
+	if result := update("soon"); !result.IsError {
+		t.Error("Expected error result for an invalid estimate")
+	}
+
+	cleared := parseToolResult(t, update(""))
+	if _, ok := cleared["estimate"]; ok {
+		t.Errorf("Expected estimate to be cleared, got %v", cleared["estimate"])
+	}
+}
+
 // TestAddTagToTodo tests the add_tag_to_todo tool.
 func TestAddTagToTodoSuccess(t *testing.T) {
 	database := setupTestDB(t)
@@ -1189,7 +1478,7 @@ func TestAddTagToTodoMultipleTags(t *testing.T) {
 
 	project := createTestProject(t, database)
 	todo := createTestTodoInDB(t, database, project.ID, "test todo", nil, nil)
-	if err := db.AddTagToTodo(database, todo.ID, "urgent"); err != nil {
+	if err := db.AddTagToTodo(t.Context(), database, todo.ID, "urgent"); err != nil {
 		t.Fatalf("Failed to add initial tag: %v", err)
 	}
 
@@ -1246,7 +1535,7 @@ func TestRemoveTagFromTodoSuccess(t *testing.T) {
 
 	project := createTestProject(t, database)
 	todo := createTestTodoInDB(t, database, project.ID, "test todo", nil, nil)
-	if err := db.AddTagToTodo(database, todo.ID, "bug"); err != nil {
+	if err := db.AddTagToTodo(t.Context(), database, todo.ID, "bug"); err != nil {
 		t.Fatalf("Failed to add tag: %v", err)
 	}
 
@@ -1431,7 +1720,7 @@ func TestDeleteProjectSuccess(t *testing.T) {
 	}
 
 	// Verify project is actually deleted
-	_, err = db.GetProjectByID(database, project.ID)
+	_, err = db.GetProjectByID(t.Context(), database, project.ID)
 	if err == nil {
 		t.Error("Project should be deleted from database")
 	}
@@ -1482,8 +1771,488 @@ func TestDeleteProjectCascadesTodos(t *testing.T) {
 	}
 
 	// Verify todos are also deleted
-	_, err = db.GetTodoByID(database, todo.ID)
+	_, err = db.GetTodoByID(t.Context(), database, todo.ID)
 	if err == nil {
 		t.Error("Todos should be deleted when project is deleted (cascade)")
 	}
 }
+
+func TestMarkDoneByPrefix(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	todo := createTestTodoInDB(t, database, project.ID, "test todo", nil, nil)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "mark_done",
+		Arguments: map[string]any{"todo_id": todo.ID.String()[:8]},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call mark_done: %v", err)
+	}
+
+	updatedTodo := parseToolResult(t, result)
+	if updatedTodo["id"] != todo.ID.String() {
+		t.Errorf("Expected todo ID %s, got %v", todo.ID, updatedTodo["id"])
+	}
+	if !updatedTodo["done"].(bool) {
+		t.Error("Todo should be marked as done")
+	}
+}
+
+func TestMarkDoneAmbiguousPrefix(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	for _, id := range []string{"abcdef01-0000-0000-0000-000000000001", "abcdef02-0000-0000-0000-000000000002"} {
+		todo := models.NewTodo(project.ID, "todo "+id[:8])
+		todo.ID = uuid.MustParse(id)
+		if err := db.CreateTodo(t.Context(), database, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "mark_done",
+		Arguments: map[string]any{"todo_id": "abcdef"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call mark_done: %v", err)
+	}
+
+	if !result.IsError {
+		t.Fatal("Expected error result for ambiguous prefix")
+	}
+
+	textContent, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		t.Fatal("Expected text content")
+	}
+
+	var payload struct {
+		Error      string            `json:"error"`
+		Kind       string            `json:"kind"`
+		Candidates []db.RefCandidate `json:"candidates"`
+	}
+	if err := json.Unmarshal([]byte(textContent.Text), &payload); err != nil {
+		t.Fatalf("Ambiguity error should be JSON: %v (%s)", err, textContent.Text)
+	}
+
+	if payload.Error != "ambiguous_reference" || payload.Kind != "todo" {
+		t.Errorf("Unexpected ambiguity payload: %+v", payload)
+	}
+	if len(payload.Candidates) != 2 {
+		t.Errorf("Expected 2 candidates, got %d", len(payload.Candidates))
+	}
+}
+
+func TestAddTodoByProjectName(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "add_todo",
+		Arguments: map[string]any{
+			"description": "named project todo",
+			"project_id":  project.Name,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call add_todo: %v", err)
+	}
+
+	todo := parseAddTodoResult(t, result)
+	if todo["project_id"] != project.ID.String() {
+		t.Errorf("Expected project_id %s, got %v", project.ID, todo["project_id"])
+	}
+}
+
+func TestDeleteProjectByPrefix(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "delete_project",
+		Arguments: map[string]any{
+			"project_id": project.ID.String()[:8],
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call delete_project: %v", err)
+	}
+
+	response := parseToolResult(t, result)
+	if response["project_id"] != project.ID.String() {
+		t.Errorf("Expected project_id %s, got %v", project.ID, response["project_id"])
+	}
+}
+
+// Helper function to create a git repository in a temp dir.
+func setupGitRepo(t *testing.T) string {
+	t.Helper()
+	repoDir := t.TempDir()
+	cmd := exec.Command("git", "init")
+	cmd.Dir = repoDir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+	resolved, err := filepath.EvalSymlinks(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolved
+}
+
+func TestAddTodoDetectsProjectFromCwd(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	repoDir := setupGitRepo(t)
+	project := models.NewProject("repo-project", &repoDir)
+	if err := db.CreateProject(t.Context(), database, project); err != nil {
+		t.Fatal(err)
+	}
+
+	subDir := filepath.Join(repoDir, "pkg")
+	if err := os.MkdirAll(subDir, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "add_todo",
+		Arguments: map[string]any{
+			"description": "detected todo",
+			"cwd":         subDir,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call add_todo: %v", err)
+	}
+
+	todo := parseAddTodoResult(t, result)
+	if todo["project_id"] != project.ID.String() {
+		t.Errorf("Expected project_id %s, got %v", project.ID, todo["project_id"])
+	}
+}
+
+func TestAddTodoUnregisteredCwdUsesDefault(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	repoDir := setupGitRepo(t)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "add_todo",
+		Arguments: map[string]any{
+			"description": "default todo",
+			"cwd":         repoDir,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call add_todo: %v", err)
+	}
+
+	todo := parseAddTodoResult(t, result)
+	defaultProject, err := db.GetProjectByName(t.Context(), database, "default")
+	if err != nil {
+		t.Fatalf("Default project should exist: %v", err)
+	}
+	if todo["project_id"] != defaultProject.ID.String() {
+		t.Errorf("Expected default project, got %v", todo["project_id"])
+	}
+}
+
+// failingProjects is a Store whose project lookups by name fail.
+type failingProjects struct {
+	store.Store
+	err error
+}
+
+func (f failingProjects) GetProjectByName(ctx context.Context, name string) (*models.Project, error) {
+	return nil, f.err
+}
+
+func (f failingProjects) Tx(ctx context.Context, fn func(store.Store) error) error {
+	return f.Store.Tx(ctx, func(tx store.Store) error {
+		return fn(failingProjects{Store: tx, err: f.err})
+	})
+}
+
+func TestDefaultProjectLookupError(t *testing.T) {
+	memory := store.NewMemory()
+	failure := errors.New("database is locked")
+	server, err := NewServer(failingProjects{Store: memory, err: failure})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	if _, err := server.getOrCreateDefaultProject(t.Context()); !errors.Is(err, failure) {
+		t.Errorf("Expected the lookup error, got %v", err)
+	}
+	if projects, _ := memory.ListProjects(t.Context()); len(projects) != 0 {
+		t.Errorf("Expected no default project created after a failed lookup, got %d", len(projects))
+	}
+}
+
+func TestAddTodoAutoCreatesProject(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	repoDir := setupGitRepo(t)
+	if err := db.CreateProject(t.Context(), database, models.NewProject(filepath.Base(repoDir), nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServerWithOptions(store.NewSQLite(database), Options{Cwd: repoDir, AutoCreateProjects: true})
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	ctx := context.Background()
+	t1, t2 := mcp.NewInMemoryTransports()
+	if _, err := server.mcp.Connect(ctx, t1, nil); err != nil {
+		t.Fatalf("Failed to connect server: %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "0.0.1"}, nil)
+	session, err := client.Connect(ctx, t2, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "add_todo",
+		Arguments: map[string]any{"description": "auto project todo"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call add_todo: %v", err)
+	}
+	todo := parseAddTodoResult(t, result)
+
+	project, err := db.GetProjectByPath(t.Context(), database, repoDir)
+	if err != nil {
+		t.Fatalf("Project should be created for repo: %v", err)
+	}
+	if project.Name != filepath.Base(repoDir)+"-2" {
+		t.Errorf("Expected suffixed project name, got %s", project.Name)
+	}
+	if todo["project_id"] != project.ID.String() {
+		t.Errorf("Expected project_id %s, got %v", project.ID, todo["project_id"])
+	}
+}
+
+func TestAddTodoDetectsProjectFromRoots(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	repoDir := setupGitRepo(t)
+	project := models.NewProject("rooted", &repoDir)
+	if err := db.CreateProject(t.Context(), database, project); err != nil {
+		t.Fatal(err)
+	}
+
+	server, err := NewServer(store.NewSQLite(database))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	ctx := context.Background()
+	t1, t2 := mcp.NewInMemoryTransports()
+	if _, err := server.mcp.Connect(ctx, t1, nil); err != nil {
+		t.Fatalf("Failed to connect server: %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "0.0.1"}, nil)
+	client.AddRoots(&mcp.Root{URI: "file://" + filepath.ToSlash(repoDir)})
+	session, err := client.Connect(ctx, t2, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "add_todo",
+		Arguments: map[string]any{"description": "rooted todo"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call add_todo: %v", err)
+	}
+
+	todo := parseAddTodoResult(t, result)
+	if todo["project_id"] != project.ID.String() {
+		t.Errorf("Expected project_id %s, got %v", project.ID, todo["project_id"])
+	}
+}
+
+func TestListTodosFilterByCwd(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	repoDir := setupGitRepo(t)
+	project := models.NewProject("repo-project", &repoDir)
+	if err := db.CreateProject(t.Context(), database, project); err != nil {
+		t.Fatal(err)
+	}
+	other := createTestProject(t, database)
+	createTestTodoInDB(t, database, project.ID, "in repo", nil, nil)
+	createTestTodoInDB(t, database, other.ID, "elsewhere", nil, nil)
+
+	ts := setupTestSession(t, database)
+	defer ts.cleanup()
+
+	ctx := context.Background()
+	result, err := ts.session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"cwd": repoDir},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call list_todos: %v", err)
+	}
+
+	response := parseListTodosResult(t, result)
+	todos := response["todos"].([]interface{})
+	if len(todos) != 1 {
+		t.Fatalf("Expected 1 todo, got %d", len(todos))
+	}
+	if todos[0].(map[string]interface{})["description"] != "in repo" {
+		t.Error("Wrong todo returned for cwd filter")
+	}
+}
+
+func TestServerWithMemoryStore(t *testing.T) {
+	memory := store.NewMemory()
+	project := models.NewProject("memory-project", nil)
+	if err := memory.CreateProject(t.Context(), project); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+
+	server, err := NewServer(memory)
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	ctx := context.Background()
+	t1, t2 := mcp.NewInMemoryTransports()
+	if _, err := server.mcp.Connect(ctx, t1, nil); err != nil {
+		t.Fatalf("Failed to connect server: %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test-client", Version: "0.0.1"}, nil)
+	session, err := client.Connect(ctx, t2, nil)
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	defer func() { _ = session.Close() }()
+
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name: "add_todo",
+		Arguments: map[string]any{
+			"description": "kept in memory",
+			"project_id":  project.ID.String(),
+			"tags":        []string{"ram"},
+		},
+	}); err != nil {
+		t.Fatalf("Failed to call add_todo: %v", err)
+	}
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "list_todos",
+		Arguments: map[string]any{"tag": "ram"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to call list_todos: %v", err)
+	}
+	todos := parseListTodosResult(t, result)["todos"].([]interface{})
+	if len(todos) != 1 || todos[0].(map[string]interface{})["description"] != "kept in memory" {
+		t.Fatalf("Expected the todo from the memory store, got %v", todos)
+	}
+
+	// Time entries and views are kept in the memory store too
+	todoID := todos[0].(map[string]interface{})["id"].(string)
+	for _, call := range []*mcp.CallToolParams{
+		{Name: "start_timer", Arguments: map[string]any{"todo_id": todoID}},
+		{Name: "log_time", Arguments: map[string]any{"todo_id": todoID, "duration": "30m"}},
+	} {
+		result, err := session.CallTool(ctx, call)
+		if err != nil || result.IsError {
+			t.Fatalf("Failed to call %s: %v %v", call.Name, err, result)
+		}
+	}
+	if _, err := memory.SaveView(t.Context(), &models.View{Name: "ram", Filter: "tag:ram"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "toki://views/ram"}); err != nil {
+		t.Errorf("Expected the view saved in the memory store, got %v", err)
+	}
+
+	if tracked, err := memory.TrackedTime(t.Context(), time.Now()); err != nil || len(tracked) != 1 {
+		t.Errorf("Expected the time tracked in the memory store, got %v (%v)", tracked, err)
+	}
+}
+
+func TestToolsStopOnCancelledContext(t *testing.T) {
+	database := setupTestDB(t)
+	defer func() { _ = database.Close() }()
+
+	project := createTestProject(t, database)
+	todo := createTestTodoInDB(t, database, project.ID, "untouched", nil, nil)
+
+	server, err := NewServer(store.NewSQLite(database))
+	if err != nil {
+		t.Fatalf("Failed to create server: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	projectID := project.ID.String()
+	req := &mcp.CallToolRequest{}
+	if _, _, err := server.handleAddTodo(ctx, req, AddTodoInput{Description: "never", ProjectID: &projectID}); !errors.Is(err, context.Canceled) {
+		t.Errorf("add_todo: expected context.Canceled, got %v", err)
+	}
+	if _, _, err := server.handleListTodos(ctx, req, ListTodosInput{ProjectID: &projectID}); !errors.Is(err, context.Canceled) {
+		t.Errorf("list_todos: expected context.Canceled, got %v", err)
+	}
+	if _, _, err := server.handleMarkDone(ctx, req, MarkDoneInput{TodoID: todo.ID.String()}); !errors.Is(err, context.Canceled) {
+		t.Errorf("mark_done: expected context.Canceled, got %v", err)
+	}
+
+	stored, err := db.GetTodoByID(t.Context(), database, todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Done {
+		t.Error("Expected the cancelled mark_done to change nothing")
+	}
+	if todos, _ := db.ListTodos(t.Context(), database, &project.ID, nil, nil, nil, nil); len(todos) != 1 {
+		t.Errorf("Expected the cancelled add_todo to add nothing, got %d todos", len(todos))
+	}
+}
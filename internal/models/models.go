@@ -1,9 +1,13 @@
-// ABOUTME: Core data models for projects, todos, and tags
+// ABOUTME: Core data models for projects, todos, tags, views, and time entries
 // ABOUTME: Provides constructor functions and business logic methods
 
 package models
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +18,7 @@ type Project struct {
 	ID            uuid.UUID
 	Name          string
 	DirectoryPath *string
+	RemoteURL     *string
 	CreatedAt     time.Time
 }
 
@@ -29,6 +34,171 @@ type Todo struct {
 	UpdatedAt   time.Time
 	CompletedAt *time.Time
 	DueDate     *time.Time
+	Branch      *string
+	Estimate    *Estimate
+}
+
+// Estimate is the expected size of a todo: story points or a duration, never both.
+type Estimate struct {
+	Points   float64
+	Duration time.Duration
+}
+
+// ParseEstimate parses story points like "3", "0.5", or "5pt", or a duration like "2h"
+// or "1h30m".
+func ParseEstimate(value string) (*Estimate, error) {
+	value = strings.TrimSpace(strings.ToLower(value))
+	number := strings.TrimSuffix(strings.TrimSuffix(value, "pt"), "p")
+	if points, err := strconv.ParseFloat(number, 64); err == nil {
+		if points <= 0 {
+			return nil, fmt.Errorf("estimate must be positive")
+		}
+		return &Estimate{Points: points}, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid estimate '%s' (expected points like 3 or 5pt, or a duration like 2h or 1h30m)", value)
+	}
+	if duration < time.Minute {
+		return nil, fmt.Errorf("estimate must be at least a minute")
+	}
+	return &Estimate{Duration: duration.Round(time.Minute)}, nil
+}
+
+// String formats the estimate as ParseEstimate accepts it, like "3pt" or "1h30m".
+func (e Estimate) String() string {
+	if e.Duration == 0 {
+		return strconv.FormatFloat(e.Points, 'f', -1, 64) + "pt"
+	}
+	text := strings.TrimSuffix(e.Duration.String(), "0s")
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+	return text
+}
+
+// TodoCommit links a todo to a git commit that closed or referenced it.
+type TodoCommit struct {
+	TodoID    uuid.UUID
+	SHA       string
+	Action    string
+	CreatedAt time.Time
+}
+
+// CodeComment links a todo to a TODO-style comment found in a project's source code.
+type CodeComment struct {
+	ProjectID   uuid.UUID
+	TodoID      uuid.UUID
+	Fingerprint string
+	File        string
+	Line        int
+	Marker      string
+	Text        string
+	// Missing is set once the comment is no longer found in the code.
+	Missing bool
+}
+
+// View is a named filter expression with a sort order, run with 'toki view run' or '@name'.
+type View struct {
+	Name string
+	// Filter is a filter expression; empty matches every todo.
+	Filter    string
+	Sort      string
+	Reverse   bool
+	CreatedAt time.Time
+	// Builtin marks views shipped with toki, which are not stored in the database.
+	Builtin bool
+}
+
+// TimeEntry is time spent on a todo: a timer that ran or is running, or a logged duration.
+type TimeEntry struct {
+	ID        int64
+	TodoID    uuid.UUID
+	StartedAt time.Time
+	// EndedAt is nil while the timer is running.
+	EndedAt  *time.Time
+	Duration time.Duration
+}
+
+// Running reports whether the entry is an active timer.
+func (e *TimeEntry) Running() bool {
+	return e.EndedAt == nil
+}
+
+// Elapsed returns the entry's duration, counting a running timer up to now.
+func (e *TimeEntry) Elapsed(now time.Time) time.Duration {
+	if e.Running() {
+		return now.Sub(e.StartedAt)
+	}
+	return e.Duration
+}
+
+// TrackedIn returns the part of the entry's time inside [from, to), counting a running
+// timer up to now. A logged duration covers its whole span evenly, so a cut entry counts
+// the same share of its duration as of its span.
+func (e *TimeEntry) TrackedIn(from, to, now time.Time) time.Duration {
+	end := now
+	if !e.Running() {
+		end = *e.EndedAt
+	}
+	span := end.Sub(e.StartedAt)
+	inside := minTime(end, to).Sub(maxTime(e.StartedAt, from))
+	if inside <= 0 || span <= 0 {
+		return 0
+	}
+	if e.Running() {
+		return inside
+	}
+	if inside < span {
+		return time.Duration(float64(e.Duration) * float64(inside) / float64(span)).Round(time.Second)
+	}
+	return e.Duration
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// TimeReport totals the time tracked in a range, by project and by tag.
+type TimeReport struct {
+	From     time.Time
+	To       time.Time
+	Total    time.Duration
+	Projects []TimeTotal
+	// Tags counts a todo's time toward each of its tags; Name is empty for untagged todos.
+	Tags []TimeTotal
+}
+
+// TimeTotal is the time tracked for one project or tag.
+type TimeTotal struct {
+	Name     string
+	Duration time.Duration
+}
+
+// SortTimeTotals returns totals by name as TimeTotals, most time first.
+func SortTimeTotals(totals map[string]time.Duration) []TimeTotal {
+	sorted := make([]TimeTotal, 0, len(totals))
+	for name, duration := range totals {
+		sorted = append(sorted, TimeTotal{Name: name, Duration: duration})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Duration != sorted[j].Duration {
+			return sorted[i].Duration > sorted[j].Duration
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
 }
 
 // Tag represents a label that can be applied to todos.
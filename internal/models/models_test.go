@@ -78,3 +78,34 @@ func TestTodoMarkUndone(t *testing.T) {
 		t.Error("CompletedAt should be nil")
 	}
 }
+
+func TestParseEstimate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"3", "3pt"},
+		{"0.5", "0.5pt"},
+		{"5pt", "5pt"},
+		{"8P", "8pt"},
+		{"2h", "2h"},
+		{"90m", "1h30m"},
+		{"45m", "45m"},
+	}
+	for _, tt := range tests {
+		estimate, err := ParseEstimate(tt.value)
+		if err != nil {
+			t.Errorf("ParseEstimate(%q) error: %v", tt.value, err)
+			continue
+		}
+		if got := estimate.String(); got != tt.want {
+			t.Errorf("ParseEstimate(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "0", "-2", "soon", "30s"} {
+		if _, err := ParseEstimate(value); err == nil {
+			t.Errorf("ParseEstimate(%q): expected an error", value)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+// ABOUTME: Prompter abstraction for every question the CLI asks the user
+// ABOUTME: Terminal, non-interactive, and scripted implementations plus TTY-based selection
+
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+)
+
+// NonInteractiveEnv disables prompts when set to a true value, like --no-input.
+const NonInteractiveEnv = "TOKI_NONINTERACTIVE"
+
+// Prompter asks the user questions. Implementations decide whether anyone is there to
+// answer them.
+type Prompter interface {
+	// Confirm asks a yes/no question; def is the answer when the user just presses enter.
+	Confirm(question string, def bool) (bool, error)
+	// Select asks for one of choices, matched by full name or first letter; def is
+	// returned for an empty answer.
+	Select(question string, choices []string, def string) (string, error)
+	// Input asks for free text; def is returned for an empty answer.
+	Input(question, def string) (string, error)
+	// Interactive reports whether answers come from a person.
+	Interactive() bool
+}
+
+// Options selects a prompter.
+type Options struct {
+	// Yes answers every confirmation with yes without prompting.
+	Yes bool
+	// NoInput never prompts: confirmations are declined and other questions take
+	// their defaults.
+	NoInput bool
+}
+
+// New returns a terminal prompter on stdin and stderr when stdin is a terminal and
+// neither the options nor TOKI_NONINTERACTIVE rule prompting out.
+func New(opts Options) Prompter {
+	if opts.Yes || opts.NoInput || EnvNonInteractive() || !isTerminal(os.Stdin) {
+		return &NonInteractive{Yes: opts.Yes}
+	}
+	return NewTerminal(os.Stdin, os.Stderr)
+}
+
+// EnvNonInteractive reports whether TOKI_NONINTERACTIVE is set to a true value.
+func EnvNonInteractive() bool {
+	switch strings.ToLower(os.Getenv(NonInteractiveEnv)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// Terminal prompts on out and reads answers line by line from in.
+type Terminal struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewTerminal creates a prompter reading from in and writing questions to out.
+func NewTerminal(in io.Reader, out io.Writer) *Terminal {
+	return &Terminal{in: bufio.NewReader(in), out: out}
+}
+
+// Interactive reports true.
+func (t *Terminal) Interactive() bool { return true }
+
+func (t *Terminal) ask(question string) (string, error) {
+	_, _ = fmt.Fprint(t.out, question)
+	line, err := t.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		if errors.Is(err, io.EOF) {
+			_, _ = fmt.Fprintln(t.out)
+			return "", ErrNoAnswer
+		}
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// ErrNoAnswer is returned when input ends before the question is answered.
+var ErrNoAnswer = errors.New("no answer: input ended")
+
+// Confirm asks until the answer is empty, yes, or no.
+func (t *Terminal) Confirm(question string, def bool) (bool, error) {
+	hint := " [y/N]: "
+	if def {
+		hint = " [Y/n]: "
+	}
+	for {
+		answer, err := t.ask(question + hint)
+		if err != nil {
+			return false, err
+		}
+		if value, ok := parseYesNo(answer, def); ok {
+			return value, nil
+		}
+		_, _ = fmt.Fprintln(t.out, "Please answer y or n.")
+	}
+}
+
+// Select asks until the answer names one of choices.
+func (t *Terminal) Select(question string, choices []string, def string) (string, error) {
+	labels := make([]string, len(choices))
+	for i, choice := range choices {
+		labels[i] = "[" + choice[:1] + "]" + choice[1:]
+	}
+	hint := " " + strings.Join(labels, ", ")
+	if def != "" {
+		hint += " [" + def[:1] + "]"
+	}
+	for {
+		answer, err := t.ask(question + hint + ": ")
+		if err != nil {
+			return "", err
+		}
+		if choice, ok := matchChoice(answer, choices, def); ok {
+			return choice, nil
+		}
+		_, _ = fmt.Fprintf(t.out, "Please answer one of: %s.\n", strings.Join(choices, ", "))
+	}
+}
+
+// Input asks once and returns the trimmed answer or def.
+func (t *Terminal) Input(question, def string) (string, error) {
+	answer, err := t.ask(question + ": ")
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+// NonInteractive answers without reading input.
+type NonInteractive struct {
+	// Yes accepts every confirmation; otherwise confirmations are declined.
+	Yes bool
+}
+
+// Interactive reports false.
+func (n *NonInteractive) Interactive() bool { return false }
+
+// Confirm returns Yes, never the question's default, so nothing happens unapproved.
+func (n *NonInteractive) Confirm(string, bool) (bool, error) { return n.Yes, nil }
+
+// Select returns the default choice.
+func (n *NonInteractive) Select(_ string, _ []string, def string) (string, error) { return def, nil }
+
+// Input returns the default.
+func (n *NonInteractive) Input(_, def string) (string, error) { return def, nil }
+
+// Scripted answers questions from a fixed list, for tests. Answers are parsed like
+// terminal input; running out of answers is an error.
+type Scripted struct {
+	Answers []string
+	// Questions records every question asked.
+	Questions []string
+}
+
+// Interactive reports true, since scripted answers stand in for a person.
+func (s *Scripted) Interactive() bool { return true }
+
+func (s *Scripted) next(question string) (string, error) {
+	s.Questions = append(s.Questions, question)
+	if len(s.Answers) == 0 {
+		return "", fmt.Errorf("%w: unexpected question %q", ErrNoAnswer, question)
+	}
+	answer := s.Answers[0]
+	s.Answers = s.Answers[1:]
+	return strings.TrimSpace(answer), nil
+}
+
+// Confirm returns the next scripted answer as yes or no.
+func (s *Scripted) Confirm(question string, def bool) (bool, error) {
+	answer, err := s.next(question)
+	if err != nil {
+		return false, err
+	}
+	value, ok := parseYesNo(answer, def)
+	if !ok {
+		return false, fmt.Errorf("scripted answer %q to %q is not yes or no", answer, question)
+	}
+	return value, nil
+}
+
+// Select returns the choice named by the next scripted answer.
+func (s *Scripted) Select(question string, choices []string, def string) (string, error) {
+	answer, err := s.next(question)
+	if err != nil {
+		return "", err
+	}
+	choice, ok := matchChoice(answer, choices, def)
+	if !ok {
+		return "", fmt.Errorf("scripted answer %q to %q is not one of %v", answer, question, choices)
+	}
+	return choice, nil
+}
+
+// Input returns the next scripted answer, or def when it is empty.
+func (s *Scripted) Input(question, def string) (string, error) {
+	answer, err := s.next(question)
+	if err != nil {
+		return "", err
+	}
+	if answer == "" {
+		return def, nil
+	}
+	return answer, nil
+}
+
+func parseYesNo(answer string, def bool) (bool, bool) {
+	switch strings.ToLower(answer) {
+	case "":
+		return def, true
+	case "y", "yes":
+		return true, true
+	case "n", "no":
+		return false, true
+	}
+	return false, false
+}
+
+func matchChoice(answer string, choices []string, def string) (string, bool) {
+	answer = strings.ToLower(answer)
+	if answer == "" {
+		return def, def != ""
+	}
+	if slices.Contains(choices, answer) {
+		return answer, true
+	}
+	for _, choice := range choices {
+		if answer == choice[:1] {
+			return choice, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,163 @@
+// ABOUTME: Tests for the prompter implementations
+// ABOUTME: Covers answer parsing, defaults, end of input, and non-interactive answers
+
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTerminalConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		def   bool
+		want  bool
+	}{
+		{"y\n", false, true},
+		{"YES\n", false, true},
+		{"n\n", true, false},
+		{"\n", true, true},
+		{"\n", false, false},
+		{"maybe\ny\n", false, true},
+		{"yes", false, true},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := NewTerminal(strings.NewReader(tt.input), &out).Confirm("Go?", tt.def)
+		if err != nil {
+			t.Fatalf("Confirm(%q) error: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("Confirm(%q, %v) = %v, want %v", tt.input, tt.def, got, tt.want)
+		}
+	}
+
+	var out bytes.Buffer
+	if _, err := NewTerminal(strings.NewReader("maybe\ny\n"), &out).Confirm("Go?", true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Go? [Y/n]: ") || !strings.Contains(out.String(), "Please answer y or n.") {
+		t.Errorf("Unexpected prompt output: %q", out.String())
+	}
+}
+
+func TestTerminalEndOfInput(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader(""), &out)
+
+	if _, err := term.Confirm("Go?", true); !errors.Is(err, ErrNoAnswer) {
+		t.Errorf("Confirm on empty input: got %v, want ErrNoAnswer", err)
+	}
+	if _, err := term.Input("Name", "x"); !errors.Is(err, ErrNoAnswer) {
+		t.Errorf("Input on empty input: got %v, want ErrNoAnswer", err)
+	}
+}
+
+func TestTerminalSelect(t *testing.T) {
+	choices := []string{"complete", "move", "skip"}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"c\n", "complete"},
+		{"move\n", "move"},
+		{"\n", "skip"},
+		{"x\nM\n", "move"},
+	}
+
+	for _, tt := range tests {
+		var out bytes.Buffer
+		got, err := NewTerminal(strings.NewReader(tt.input), &out).Select("Action?", choices, "skip")
+		if err != nil {
+			t.Fatalf("Select(%q) error: %v", tt.input, err)
+		}
+		if got != tt.want {
+			t.Errorf("Select(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "Action? [c]omplete, [m]ove, [s]kip [s]: ") {
+			t.Errorf("Unexpected prompt output: %q", out.String())
+		}
+	}
+}
+
+func TestTerminalInput(t *testing.T) {
+	var out bytes.Buffer
+	term := NewTerminal(strings.NewReader("  feature \n\n"), &out)
+
+	got, err := term.Input("Branch", "main")
+	if err != nil || got != "feature" {
+		t.Errorf("Input = %q, %v; want feature", got, err)
+	}
+	got, err = term.Input("Branch", "main")
+	if err != nil || got != "main" {
+		t.Errorf("Input = %q, %v; want the default", got, err)
+	}
+}
+
+func TestNonInteractive(t *testing.T) {
+	no := &NonInteractive{}
+	if got, _ := no.Confirm("Go?", true); got {
+		t.Error("Expected confirmations to be declined without --yes, even when the default is yes")
+	}
+	yes := &NonInteractive{Yes: true}
+	if got, _ := yes.Confirm("Go?", false); !got {
+		t.Error("Expected confirmations to be accepted with --yes")
+	}
+
+	if got, _ := yes.Select("Action?", []string{"complete", "skip"}, "skip"); got != "skip" {
+		t.Errorf("Select = %q, want the default", got)
+	}
+	if got, _ := no.Input("Branch", "main"); got != "main" {
+		t.Errorf("Input = %q, want the default", got)
+	}
+	if no.Interactive() || yes.Interactive() {
+		t.Error("Expected non-interactive prompters")
+	}
+}
+
+func TestScripted(t *testing.T) {
+	s := &Scripted{Answers: []string{"y", "m", "", "feature"}}
+
+	if got, err := s.Confirm("Go?", false); err != nil || !got {
+		t.Errorf("Confirm = %v, %v", got, err)
+	}
+	if got, err := s.Select("Action?", []string{"complete", "move"}, "complete"); err != nil || got != "move" {
+		t.Errorf("Select = %q, %v", got, err)
+	}
+	if got, err := s.Input("Branch", "main"); err != nil || got != "main" {
+		t.Errorf("Input = %q, %v", got, err)
+	}
+	if got, err := s.Input("Branch", "main"); err != nil || got != "feature" {
+		t.Errorf("Input = %q, %v", got, err)
+	}
+
+	if _, err := s.Confirm("Again?", true); !errors.Is(err, ErrNoAnswer) {
+		t.Errorf("Expected ErrNoAnswer when answers run out, got %v", err)
+	}
+	if len(s.Questions) != 5 || s.Questions[0] != "Go?" {
+		t.Errorf("Unexpected recorded questions: %q", s.Questions)
+	}
+
+	bad := &Scripted{Answers: []string{"perhaps"}}
+	if _, err := bad.Confirm("Go?", true); err == nil {
+		t.Error("Expected an error for an answer that is not yes or no")
+	}
+}
+
+func TestEnvNonInteractive(t *testing.T) {
+	for value, want := range map[string]bool{"1": true, "true": true, "YES": true, "0": false, "": false, "no": false} {
+		t.Setenv(NonInteractiveEnv, value)
+		if got := EnvNonInteractive(); got != want {
+			t.Errorf("EnvNonInteractive with %q = %v, want %v", value, got, want)
+		}
+	}
+
+	t.Setenv(NonInteractiveEnv, "1")
+	if New(Options{}).Interactive() {
+		t.Error("Expected TOKI_NONINTERACTIVE to disable prompts")
+	}
+}
@@ -0,0 +1,269 @@
+// ABOUTME: Source comment parsing for TODO-style markers
+// ABOUTME: Defines scanned languages and extracts marker comments with stable fingerprints
+
+package scan
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultMarkers are the comment markers scanned when none are configured.
+var DefaultMarkers = []string{"TODO", "FIXME", "HACK"}
+
+// Language describes how comments are written in a family of source files.
+type Language struct {
+	Name       string
+	Extensions []string
+	// LineComments are the tokens that start a comment running to the end of the line.
+	LineComments []string
+	// BlockComments reports whether /* ... */ comments (with " * " continuation lines) are used.
+	BlockComments bool
+}
+
+// Languages are the languages toki knows how to scan, keyed by name.
+var Languages = map[string]Language{
+	"go":         {Name: "go", Extensions: []string{".go"}, LineComments: []string{"//"}, BlockComments: true},
+	"javascript": {Name: "javascript", Extensions: []string{".js", ".jsx", ".mjs", ".cjs"}, LineComments: []string{"//"}, BlockComments: true},
+	"typescript": {Name: "typescript", Extensions: []string{".ts", ".tsx"}, LineComments: []string{"//"}, BlockComments: true},
+	"c":          {Name: "c", Extensions: []string{".c", ".h", ".cc", ".cpp", ".hpp", ".cxx"}, LineComments: []string{"//"}, BlockComments: true},
+	"java":       {Name: "java", Extensions: []string{".java", ".kt", ".kts", ".scala"}, LineComments: []string{"//"}, BlockComments: true},
+	"csharp":     {Name: "csharp", Extensions: []string{".cs"}, LineComments: []string{"//"}, BlockComments: true},
+	"rust":       {Name: "rust", Extensions: []string{".rs"}, LineComments: []string{"//"}, BlockComments: true},
+	"swift":      {Name: "swift", Extensions: []string{".swift"}, LineComments: []string{"//"}, BlockComments: true},
+	"php":        {Name: "php", Extensions: []string{".php"}, LineComments: []string{"//", "#"}, BlockComments: true},
+	"python":     {Name: "python", Extensions: []string{".py"}, LineComments: []string{"#"}},
+	"ruby":       {Name: "ruby", Extensions: []string{".rb"}, LineComments: []string{"#"}},
+	"shell":      {Name: "shell", Extensions: []string{".sh", ".bash", ".zsh"}, LineComments: []string{"#"}},
+	"yaml":       {Name: "yaml", Extensions: []string{".yml", ".yaml"}, LineComments: []string{"#"}},
+	"sql":        {Name: "sql", Extensions: []string{".sql"}, LineComments: []string{"--"}, BlockComments: true},
+	"lua":        {Name: "lua", Extensions: []string{".lua"}, LineComments: []string{"--"}},
+}
+
+// Config selects which markers and languages a scan looks for.
+type Config struct {
+	Markers   []string
+	Languages []Language
+}
+
+// NewConfig builds a Config from marker and language names. Empty lists select
+// DefaultMarkers and every known language.
+func NewConfig(markers, languages []string) (Config, error) {
+	config := Config{}
+
+	for _, marker := range markers {
+		marker = strings.TrimSpace(marker)
+		if marker != "" {
+			config.Markers = append(config.Markers, marker)
+		}
+	}
+	if len(config.Markers) == 0 {
+		config.Markers = DefaultMarkers
+	}
+
+	for _, name := range languages {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		lang, ok := Languages[name]
+		if !ok {
+			return Config{}, fmt.Errorf("unknown language '%s': must be one of %s", name, strings.Join(LanguageNames(), ", "))
+		}
+		config.Languages = append(config.Languages, lang)
+	}
+	if len(config.Languages) == 0 {
+		for _, name := range LanguageNames() {
+			config.Languages = append(config.Languages, Languages[name])
+		}
+	}
+
+	return config, nil
+}
+
+// LanguageNames returns the known language names in sorted order.
+func LanguageNames() []string {
+	names := make([]string, 0, len(Languages))
+	for name := range Languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// languageFor returns the configured language for a file, if any.
+func (c Config) languageFor(path string) (Language, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, lang := range c.Languages {
+		for _, e := range lang.Extensions {
+			if e == ext {
+				return lang, true
+			}
+		}
+	}
+	return Language{}, false
+}
+
+func (c Config) markerPattern() *regexp.Regexp {
+	quoted := make([]string, len(c.Markers))
+	for i, marker := range c.Markers {
+		quoted[i] = regexp.QuoteMeta(marker)
+	}
+	// MARKER, optional (owner), optional colon, then the text
+	return regexp.MustCompile(`^(` + strings.Join(quoted, "|") + `)\b(?:\([^)]*\))?:?\s*(.*)$`)
+}
+
+// Comment is a marker comment found in a source file.
+type Comment struct {
+	// File is the path relative to the scanned directory, with forward slashes.
+	File   string
+	Line   int
+	Marker string
+	Text   string
+	// Fingerprint identifies the comment across scans even when its line number changes.
+	Fingerprint string
+}
+
+// Location returns "file:line".
+func (c Comment) Location() string {
+	return fmt.Sprintf("%s:%d", c.File, c.Line)
+}
+
+// ParseComments extracts marker comments from source text in the given language.
+func ParseComments(r io.Reader, file string, lang Language, pattern *regexp.Regexp) ([]Comment, error) {
+	var comments []Comment
+	seen := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		text, ok := commentText(scanner.Text(), lang)
+		if !ok {
+			continue
+		}
+
+		match := pattern.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		comment := Comment{
+			File:   file,
+			Line:   lineNum,
+			Marker: match[1],
+			Text:   cleanCommentText(match[2]),
+		}
+
+		// Identical comments in one file are told apart by their order of appearance.
+		// The file is left out so renaming it keeps its todos; see Disambiguate.
+		key := comment.Marker + "\x00" + comment.Text
+		comment.Fingerprint = fingerprint("", key, seen[key])
+		seen[key]++
+
+		comments = append(comments, comment)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return comments, nil
+}
+
+// commentText returns the comment portion of a line, without its comment token.
+func commentText(line string, lang Language) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+
+	if lang.BlockComments && strings.HasPrefix(trimmed, "*") && !strings.HasPrefix(trimmed, "*/") {
+		return strings.TrimSpace(strings.TrimLeft(trimmed, "*")), true
+	}
+
+	tokens := lang.LineComments
+	if lang.BlockComments {
+		tokens = append(append([]string{}, tokens...), "/*")
+	}
+	best, bestLen := commentStart(line, tokens)
+	if best < 0 {
+		return "", false
+	}
+
+	rest := line[best+bestLen:]
+	// Doc comment styles such as "///", "//!", and "/**"
+	rest = strings.TrimLeft(rest, "/!*#")
+	return strings.TrimSpace(rest), true
+}
+
+// commentStart returns the index and length of the first comment token in line that
+// isn't inside a quoted string, or -1. A quote with no closing match on the line,
+// such as an apostrophe or a Rust lifetime, is treated as an ordinary character.
+func commentStart(line string, tokens []string) (int, int) {
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '"', '\'', '`':
+			if end := closingQuote(line, i+1, c); end >= 0 {
+				i = end
+				continue
+			}
+		}
+		for _, token := range tokens {
+			if strings.HasPrefix(line[i:], token) {
+				return i, len(token)
+			}
+		}
+	}
+	return -1, 0
+}
+
+// closingQuote returns the index of the quote closing a string opened just before
+// start, or -1. Backslashes escape the next character except in backquoted strings.
+func closingQuote(line string, start int, quote byte) int {
+	for i := start; i < len(line); i++ {
+		switch line[i] {
+		case quote:
+			return i
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		}
+	}
+	return -1
+}
+
+func cleanCommentText(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimSuffix(text, "*/")
+	text = strings.TrimSuffix(text, "-->")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// Disambiguate gives comments that share a fingerprint, because the same comment
+// appears in several files, fingerprints that include their file.
+func Disambiguate(comments []Comment) {
+	count := make(map[string]int, len(comments))
+	for _, c := range comments {
+		count[c.Fingerprint]++
+	}
+	seen := make(map[string]int)
+	for i, c := range comments {
+		if count[c.Fingerprint] < 2 {
+			continue
+		}
+		key := c.Marker + "\x00" + c.Text
+		comments[i].Fingerprint = fingerprint(c.File, key, seen[c.File+"\x00"+key])
+		seen[c.File+"\x00"+key]++
+	}
+}
+
+func fingerprint(file, key string, occurrence int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%s\x00%d", file, key, occurrence)))
+	return hex.EncodeToString(sum[:])[:16]
+}
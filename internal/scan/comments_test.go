@@ -0,0 +1,147 @@
+// ABOUTME: Tests for marker comment parsing
+// ABOUTME: Covers comment styles, custom markers, languages, and fingerprints
+
+package scan
+
+import (
+	"strings"
+	"testing"
+)
+
+func parse(t *testing.T, source, file, language string, markers ...string) []Comment {
+	t.Helper()
+	config, err := NewConfig(markers, []string{language})
+	if err != nil {
+		t.Fatal(err)
+	}
+	comments, err := ParseComments(strings.NewReader(source), file, config.Languages[0], config.markerPattern())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return comments
+}
+
+func TestParseCommentsGo(t *testing.T) {
+	source := `package main
+
+// TODO: handle retries
+func main() {
+	x := "TODO: not a comment"
+	/* FIXME(alice): leaks the file handle */
+	/*
+	 * HACK works around upstream bug
+	 */
+	// todo lowercase is ignored
+	// TODOS is not a marker
+}
+`
+	comments := parse(t, source, "main.go", "go")
+
+	expected := []struct {
+		line   int
+		marker string
+		text   string
+	}{
+		{3, "TODO", "handle retries"},
+		{6, "FIXME", "leaks the file handle"},
+		{8, "HACK", "works around upstream bug"},
+	}
+	if len(comments) != len(expected) {
+		t.Fatalf("Expected %d comments, got %+v", len(expected), comments)
+	}
+	for i, e := range expected {
+		c := comments[i]
+		if c.Line != e.line || c.Marker != e.marker || c.Text != e.text {
+			t.Errorf("Comment %d: expected %+v, got %+v", i, e, c)
+		}
+	}
+}
+
+func TestParseCommentsCustomMarkers(t *testing.T) {
+	source := "# XXX: tidy up\n# TODO: ignored here\nvalue = 1  # XXX trailing\n"
+	comments := parse(t, source, "app.py", "python", "XXX")
+
+	if len(comments) != 2 || comments[0].Text != "tidy up" || comments[1].Text != "trailing" {
+		t.Errorf("Unexpected comments: %+v", comments)
+	}
+}
+
+func TestFingerprintStableAcrossLineMoves(t *testing.T) {
+	before := parse(t, "// TODO: one\n// TODO: two\n// TODO: two\n", "a.go", "go")
+	after := parse(t, "\n\n// TODO: two\n// TODO: one\n// TODO: two\n", "a.go", "go")
+
+	fingerprints := func(comments []Comment) map[string]bool {
+		set := make(map[string]bool)
+		for _, c := range comments {
+			set[c.Fingerprint] = true
+		}
+		return set
+	}
+
+	b, a := fingerprints(before), fingerprints(after)
+	if len(b) != 3 {
+		t.Fatalf("Expected duplicate comments to get distinct fingerprints, got %d", len(b))
+	}
+	for fp := range b {
+		if !a[fp] {
+			t.Errorf("Fingerprint %s changed after moving lines", fp)
+		}
+	}
+
+	renamed := parse(t, "// TODO: one\n// TODO: two\n// TODO: two\n", "b.go", "go")
+	for _, c := range renamed {
+		if !b[c.Fingerprint] {
+			t.Errorf("Fingerprint of %q changed after renaming the file", c.Text)
+		}
+	}
+}
+
+func TestDisambiguateSharedComments(t *testing.T) {
+	comments := append(parse(t, "// TODO: one\n// TODO: two\n", "a.go", "go"), parse(t, "// TODO: one\n", "b.go", "go")...)
+	unique := comments[1].Fingerprint
+	Disambiguate(comments)
+
+	if comments[0].Fingerprint == comments[2].Fingerprint {
+		t.Error("Expected the same comment in two files to get distinct fingerprints")
+	}
+	if comments[1].Fingerprint != unique {
+		t.Error("Expected a comment found in one file to keep its fingerprint")
+	}
+}
+
+func TestParseCommentsSkipsStrings(t *testing.T) {
+	source := `package main
+
+func main() {
+	u := "http://x" // TODO fix
+	s := "// TODO not code"
+	r := '"' // FIXME after a rune
+	q := ` + "`/* HACK raw */`" + `
+	e := "escaped \" // TODO still a string"
+}
+`
+	comments := parse(t, source, "main.go", "go")
+	if len(comments) != 2 || comments[0].Text != "fix" || comments[1].Text != "after a rune" {
+		t.Errorf("Unexpected comments: %+v", comments)
+	}
+
+	// An apostrophe outside a string doesn't hide the comment
+	comments = parse(t, "echo don't # TODO quote\n", "run.sh", "shell")
+	if len(comments) != 1 || comments[0].Text != "quote" {
+		t.Errorf("Unexpected comments: %+v", comments)
+	}
+}
+
+func TestNewConfigUnknownLanguage(t *testing.T) {
+	if _, err := NewConfig(nil, []string{"cobol"}); err == nil {
+		t.Error("Expected error for unknown language")
+	}
+
+	config, err := NewConfig(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.Markers) != len(DefaultMarkers) || len(config.Languages) != len(Languages) {
+		t.Errorf("Expected defaults, got %d markers and %d languages", len(config.Markers), len(config.Languages))
+	}
+}
@@ -0,0 +1,247 @@
+// ABOUTME: Directory walking for source comment scans
+// ABOUTME: Lists source files while honoring .gitignore and collects marker comments
+
+package scan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// maxFileSize skips generated or vendored blobs that are unlikely to hold real TODOs.
+const maxFileSize = 1 << 20
+
+// Dir scans every source file under dir for marker comments, skipping files ignored
+// by .gitignore. Comments are returned in file and line order, with fingerprints that
+// don't depend on the file unless the same comment appears in more than one.
+func Dir(dir string, config Config) ([]Comment, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := config.markerPattern()
+	var comments []Comment
+	for _, file := range files {
+		lang, ok := config.languageFor(file)
+		if !ok {
+			continue
+		}
+
+		found, err := scanFile(filepath.Join(dir, filepath.FromSlash(file)), file, lang, pattern)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, found...)
+	}
+
+	Disambiguate(comments)
+	return comments, nil
+}
+
+func scanFile(fullPath, file string, lang Language, pattern *regexp.Regexp) ([]Comment, error) {
+	info, err := os.Stat(fullPath)
+	if errors.Is(err, os.ErrNotExist) {
+		// Tracked by git but deleted from the working tree
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", file, err)
+	}
+	if !info.Mode().IsRegular() || info.Size() > maxFileSize {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(fullPath) //nolint:gosec // Scanning files inside the project directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		// Binary file
+		return nil, nil
+	}
+
+	return ParseComments(bytes.NewReader(data), file, lang, pattern)
+}
+
+// listFiles returns the non-ignored files under dir as slash-separated relative paths.
+// Inside a git repository git itself decides what is ignored; elsewhere .gitignore
+// files are interpreted directly.
+func listFiles(dir string) ([]string, error) {
+	cmd := exec.Command("git", "-C", dir, "ls-files", "-z", "--cached", "--others", "--exclude-standard") //nolint:gosec // dir is the project directory
+	if output, err := cmd.Output(); err == nil {
+		var files []string
+		for _, file := range strings.Split(string(output), "\x00") {
+			if file != "" {
+				files = append(files, file)
+			}
+		}
+		return files, nil
+	}
+
+	return walkFiles(dir)
+}
+
+func walkFiles(dir string) ([]string, error) {
+	var files []string
+	var rules []ignoreRule
+
+	err := filepath.WalkDir(dir, func(fullPath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, fullPath)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if entry.IsDir() {
+			if rel != "." && (entry.Name() == ".git" || ignored(rules, rel, true)) {
+				return filepath.SkipDir
+			}
+			local, err := readIgnoreFile(fullPath, rel)
+			if err != nil {
+				return err
+			}
+			rules = append(rules, local...)
+			return nil
+		}
+
+		if !ignored(rules, rel, false) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+
+	return files, nil
+}
+
+// ignoreRule is one pattern from a .gitignore file.
+type ignoreRule struct {
+	// base is the slash-separated directory holding the .gitignore ("." for the root).
+	base    string
+	pattern *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// anchored patterns match the path relative to base rather than just the name.
+	anchored bool
+}
+
+func readIgnoreFile(dir, rel string) ([]ignoreRule, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore")) //nolint:gosec // Reading .gitignore inside the project directory
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+
+	var rules []ignoreRule
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, " \r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := ignoreRule{base: rel}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		pattern, err := regexp.Compile("^" + globToRegexp(line) + "$")
+		if err != nil {
+			// Patterns git would reject are skipped
+			continue
+		}
+		rule.pattern = pattern
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ignored applies rules in order; the last matching rule decides.
+func ignored(rules []ignoreRule, rel string, isDir bool) bool {
+	result := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+
+		target := rel
+		if rule.base != "." {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			target = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if !rule.anchored {
+			target = path.Base(target)
+		}
+
+		if rule.pattern.MatchString(target) {
+			result = !rule.negate
+		}
+	}
+	return result
+}
+
+// globToRegexp translates gitignore glob syntax, including "**", to a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**"):
+			b.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			class = strings.Replace(class, "!", "^", 1)
+			b.WriteString("[" + class + "]")
+			i += end
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
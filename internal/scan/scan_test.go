@@ -0,0 +1,102 @@
+// ABOUTME: Tests for directory scanning
+// ABOUTME: Verifies .gitignore handling with and without git, and language filtering
+
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func writeSource(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func setupSourceTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeSource(t, dir, ".gitignore", "vendor/\n*.gen.go\n!keep.gen.go\n/build\n")
+	writeSource(t, dir, "main.go", "// TODO: root\n")
+	writeSource(t, dir, "pkg/util.go", "// FIXME: nested\n")
+	writeSource(t, dir, "pkg/api.gen.go", "// TODO: generated\n")
+	writeSource(t, dir, "pkg/keep.gen.go", "// TODO: kept\n")
+	writeSource(t, dir, "vendor/lib/lib.go", "// TODO: vendored\n")
+	writeSource(t, dir, "build/out.go", "// TODO: build output\n")
+	writeSource(t, dir, "scripts/run.sh", "# HACK: shell\n")
+	writeSource(t, dir, "README.md", "TODO: not source\n")
+	return dir
+}
+
+func assertScanned(t *testing.T, comments []Comment, expected ...string) {
+	t.Helper()
+	found := make(map[string]bool)
+	for _, c := range comments {
+		found[c.File] = true
+	}
+	if len(found) != len(expected) {
+		t.Errorf("Expected files %v, got %+v", expected, comments)
+	}
+	for _, file := range expected {
+		if !found[file] {
+			t.Errorf("Expected %s to be scanned", file)
+		}
+	}
+}
+
+func TestDirHonorsGitignoreWithoutGit(t *testing.T) {
+	dir := setupSourceTree(t)
+
+	config, _ := NewConfig(nil, nil)
+	comments, err := Dir(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertScanned(t, comments, "main.go", "pkg/util.go", "pkg/keep.gen.go", "scripts/run.sh")
+}
+
+func TestDirHonorsGitignoreInRepo(t *testing.T) {
+	dir := setupSourceTree(t)
+	cmd := exec.Command("git", "init", "-q")
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to init git repo: %v", err)
+	}
+
+	config, _ := NewConfig(nil, []string{"go"})
+	comments, err := Dir(dir, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertScanned(t, comments, "main.go", "pkg/util.go", "pkg/keep.gen.go")
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	cases := map[string]map[string]bool{
+		"**/logs":   {"logs": true, "a/b/logs": true, "logsx": false},
+		"docs/**":   {"docs/a": true, "docs/a/b": true, "other/a": false},
+		"a/**/b":    {"a/b": true, "a/x/y/b": true, "a/x/c": false},
+		"*.[ch]":    {"x.c": true, "x.h": true, "x.go": false},
+		"file?.txt": {"file1.txt": true, "file12.txt": false},
+	}
+	for glob, paths := range cases {
+		rule, err := regexp.Compile("^" + globToRegexp(glob) + "$")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for p, expected := range paths {
+			if got := rule.MatchString(p); got != expected {
+				t.Errorf("%s matching %s = %v, want %v", glob, p, got, expected)
+			}
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// ABOUTME: Encryption keys and AES-GCM sealing for todo text and exported databases
+// ABOUTME: Loads the key from TOKI_KEY, a key file, or config and tells missing and wrong keys apart
+
+package secret
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Environment variables holding the key, or the path of a file holding it. They take
+// precedence over the encryption.key_file setting.
+const (
+	KeyEnv     = "TOKI_KEY"
+	KeyFileEnv = "TOKI_KEY_FILE"
+)
+
+// keySize is the length of a key: AES-256.
+const keySize = 32
+
+// keyHint tells how to set a key.
+const keyHint = "set " + KeyEnv + " or " + KeyFileEnv + ", or encryption.key_file in the config"
+
+// ErrNoKey is returned when reading encrypted todo text without a key.
+var ErrNoKey = errors.New("todo text is encrypted but no key is set; " + keyHint)
+
+// WrongKeyError is returned when data was encrypted with a different key than the one
+// set.
+type WrongKeyError struct {
+	// Want is the ID of the key the data was encrypted with; Have the ID of the key set.
+	Want, Have string
+}
+
+func (e *WrongKeyError) Error() string {
+	return fmt.Sprintf("data is encrypted with key %s, but the key set is %s", e.Want, e.Have)
+}
+
+// Key encrypts and decrypts todo text and exported databases.
+type Key struct {
+	id     string
+	fields cipher.AEAD
+	files  cipher.AEAD
+}
+
+// GenerateKey returns a new random key, encoded as ParseKey expects.
+func GenerateKey() string {
+	return base64.StdEncoding.EncodeToString(randomBytes(keySize))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	_, _ = rand.Read(b) // never fails
+	return b
+}
+
+// ParseKey decodes a base64 key, as GenerateKey makes.
+func ParseKey(text string) (*Key, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(text))
+	if err != nil || len(raw) != keySize {
+		return nil, fmt.Errorf("invalid key: expected %d bytes in base64, as 'toki key generate' prints", keySize)
+	}
+
+	// Separate subkeys for fields and files, so the same ciphertext can't serve as both
+	sum := sha256.Sum256(append([]byte("toki key id\x00"), raw...))
+	key := &Key{id: hex.EncodeToString(sum[:8])}
+	if key.fields, err = newAEAD(raw, "toki fields v1"); err != nil {
+		return nil, err
+	}
+	if key.files, err = newAEAD(raw, "toki files v1"); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newAEAD(raw []byte, info string) (cipher.AEAD, error) {
+	subkey, err := hkdf.Key(sha256.New, raw, nil, info, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(subkey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadKeyFile reads a key from a file.
+func ReadKeyFile(path string) (*Key, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	key, err := ParseKey(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return key, nil
+}
+
+// WriteKeyFile writes key text to a new file readable only by the user.
+func WriteKeyFile(path, text string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create key file: %w", err)
+	}
+	if _, err := f.WriteString(text + "\n"); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return f.Close()
+}
+
+// LoadKey returns the key set by TOKI_KEY, TOKI_KEY_FILE, or keyFile, in that order, and
+// where it came from. With none of them set it returns nil: todo text is then stored
+// as is.
+func LoadKey(keyFile string) (*Key, string, error) {
+	if text := os.Getenv(KeyEnv); text != "" {
+		key, err := ParseKey(text)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", KeyEnv, err)
+		}
+		return key, "$" + KeyEnv, nil
+	}
+
+	path := os.Getenv(KeyFileEnv)
+	if path == "" {
+		path = keyFile
+	}
+	if path == "" {
+		return nil, "", nil
+	}
+	key, err := ReadKeyFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	return key, path, nil
+}
+
+// ID identifies the key without revealing it.
+func (k *Key) ID() string {
+	return k.id
+}
+
+// fieldPrefix starts every sealed field, followed by the key ID, a colon, and the
+// base64 nonce and ciphertext.
+const fieldPrefix = "toki:enc:v1:"
+
+// Seal encrypts a field value. The value can only be opened with the same context,
+// such as the todo and field it belongs to, so sealed values can't be moved around.
+func (k *Key) Seal(context, value string) string {
+	nonce := randomBytes(k.fields.NonceSize())
+	sealed := k.fields.Seal(nonce, nonce, []byte(value), []byte(context))
+	return fieldPrefix + k.id + ":" + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// IsSealed reports whether a field value was encrypted by Seal.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, fieldPrefix)
+}
+
+// SealedKeyID returns the ID of the key a sealed value was encrypted with.
+func SealedKeyID(value string) string {
+	id, _, _ := strings.Cut(strings.TrimPrefix(value, fieldPrefix), ":")
+	return id
+}
+
+// Open decrypts a field value sealed with context, returning values that are not sealed
+// unchanged. A nil key opens only those.
+func Open(k *Key, context, value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+	if k == nil {
+		return "", ErrNoKey
+	}
+
+	id, encoded, _ := strings.Cut(strings.TrimPrefix(value, fieldPrefix), ":")
+	if id != k.id {
+		return "", &WrongKeyError{Want: id, Have: k.id}
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < k.fields.NonceSize() {
+		return "", errors.New("encrypted value is damaged")
+	}
+	nonce, ciphertext := sealed[:k.fields.NonceSize()], sealed[k.fields.NonceSize():]
+	plain, err := k.fields.Open(nil, nonce, ciphertext, []byte(context))
+	if err != nil {
+		return "", errors.New("encrypted value is damaged or belongs to another record")
+	}
+	return string(plain), nil
+}
+
+// fileMagic starts every sealed file, followed by the 16 hex digit key ID, the nonce,
+// and the ciphertext.
+var fileMagic = []byte("TOKIENC1")
+
+// SealFile encrypts the contents of a file.
+func (k *Key) SealFile(plain []byte) []byte {
+	header := append(append([]byte{}, fileMagic...), k.id...)
+	nonce := randomBytes(k.files.NonceSize())
+	return k.files.Seal(append(header, nonce...), nonce, plain, header)
+}
+
+// IsSealedFile reports whether data was encrypted by SealFile.
+func IsSealedFile(data []byte) bool {
+	return bytes.HasPrefix(data, fileMagic)
+}
+
+// OpenFile decrypts the contents of a file sealed by SealFile.
+func OpenFile(k *Key, data []byte) ([]byte, error) {
+	headerSize := len(fileMagic) + 16
+	if !IsSealedFile(data) || len(data) < headerSize {
+		return nil, errors.New("not an encrypted toki export")
+	}
+	if k == nil {
+		return nil, errors.New("the export is encrypted but no key is set; " + keyHint)
+	}
+
+	header, rest := data[:headerSize], data[headerSize:]
+	if id := string(header[len(fileMagic):]); id != k.id {
+		return nil, &WrongKeyError{Want: id, Have: k.id}
+	}
+	if len(rest) < k.files.NonceSize() {
+		return nil, errors.New("encrypted export is damaged")
+	}
+	nonce, ciphertext := rest[:k.files.NonceSize()], rest[k.files.NonceSize():]
+	plain, err := k.files.Open(nil, nonce, ciphertext, header)
+	if err != nil {
+		return nil, errors.New("encrypted export is damaged")
+	}
+	return plain, nil
+}
@@ -0,0 +1,132 @@
+// ABOUTME: Tests for encryption keys and sealing
+// ABOUTME: Covers field and file round trips, missing and wrong keys, and key loading
+
+package secret
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) *Key {
+	t.Helper()
+	key, err := ParseKey(GenerateKey())
+	if err != nil {
+		t.Fatalf("Failed to parse generated key: %v", err)
+	}
+	return key
+}
+
+func TestSealAndOpen(t *testing.T) {
+	key := newKey(t)
+
+	sealed := key.Seal("todo-1/description", "Call ACME about the outage")
+	if !IsSealed(sealed) || strings.Contains(sealed, "ACME") {
+		t.Fatalf("Expected an opaque sealed value, got %q", sealed)
+	}
+	if SealedKeyID(sealed) != key.ID() {
+		t.Errorf("Expected key ID %s, got %s", key.ID(), SealedKeyID(sealed))
+	}
+
+	plain, err := Open(key, "todo-1/description", sealed)
+	if err != nil || plain != "Call ACME about the outage" {
+		t.Errorf("Open() = %q, %v", plain, err)
+	}
+
+	// Plain values pass through, with or without a key
+	if plain, err := Open(nil, "todo-1/description", "plain"); err != nil || plain != "plain" {
+		t.Errorf("Expected plain value unchanged, got %q, %v", plain, err)
+	}
+
+	if _, err := Open(nil, "todo-1/description", sealed); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+
+	var wrongKey *WrongKeyError
+	if _, err := Open(newKey(t), "todo-1/description", sealed); !errors.As(err, &wrongKey) || wrongKey.Want != key.ID() {
+		t.Errorf("Expected WrongKeyError, got %v", err)
+	}
+
+	// A value moved to another todo does not open
+	if _, err := Open(key, "todo-2/description", sealed); err == nil {
+		t.Error("Expected a value sealed for another context to fail")
+	}
+}
+
+func TestSealFile(t *testing.T) {
+	key := newKey(t)
+	data := []byte("SQLite format 3\x00 and the rest")
+
+	sealed := key.SealFile(data)
+	if !IsSealedFile(sealed) {
+		t.Fatal("Expected sealed file to be recognized")
+	}
+
+	opened, err := OpenFile(key, sealed)
+	if err != nil || string(opened) != string(data) {
+		t.Errorf("OpenFile() = %q, %v", opened, err)
+	}
+
+	if _, err := OpenFile(nil, sealed); err == nil || !strings.Contains(err.Error(), KeyEnv) {
+		t.Errorf("Expected a missing key error naming %s, got %v", KeyEnv, err)
+	}
+	var wrongKey *WrongKeyError
+	if _, err := OpenFile(newKey(t), sealed); !errors.As(err, &wrongKey) {
+		t.Errorf("Expected WrongKeyError, got %v", err)
+	}
+
+	sealed[len(sealed)-1] ^= 1
+	if _, err := OpenFile(key, sealed); err == nil {
+		t.Error("Expected a damaged file to fail")
+	}
+}
+
+func TestParseKeyRejectsInvalidKeys(t *testing.T) {
+	for _, text := range []string{"", "not base64!", "c2hvcnQ="} {
+		if _, err := ParseKey(text); err == nil {
+			t.Errorf("ParseKey(%q) should fail", text)
+		}
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	t.Setenv(KeyEnv, "")
+	t.Setenv(KeyFileEnv, "")
+
+	if key, _, err := LoadKey(""); key != nil || err != nil {
+		t.Errorf("Expected no key, got %v, %v", key, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "toki.key")
+	text := GenerateKey()
+	if err := WriteKeyFile(path, text); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a private key file, got %v (%v)", info.Mode(), err)
+	}
+	if err := WriteKeyFile(path, text); err == nil {
+		t.Error("Expected WriteKeyFile not to overwrite a key")
+	}
+
+	fromFile, source, err := LoadKey(path)
+	if err != nil || source != path {
+		t.Fatalf("LoadKey(%s) = %s, %v", path, source, err)
+	}
+
+	// TOKI_KEY wins over key files
+	other := GenerateKey()
+	t.Setenv(KeyEnv, other)
+	fromEnv, source, err := LoadKey(path)
+	if err != nil || source != "$"+KeyEnv || fromEnv.ID() == fromFile.ID() {
+		t.Errorf("Expected the key from %s, got %s (%v)", KeyEnv, source, err)
+	}
+
+	t.Setenv(KeyEnv, "garbage")
+	if _, _, err := LoadKey(path); err == nil || !strings.Contains(err.Error(), KeyEnv) {
+		t.Errorf("Expected an invalid %s to fail, got %v", KeyEnv, err)
+	}
+}
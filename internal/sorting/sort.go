@@ -0,0 +1,112 @@
+// ABOUTME: Sort orders for todo listings
+// ABOUTME: Orders todos by due date, priority, creation, update, or urgency score
+
+package sorting
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/harper/toki/internal/models"
+)
+
+// Key names a sort order.
+type Key string
+
+// Sort keys accepted by --sort and the MCP list_todos tool.
+const (
+	KeyDue      Key = "due"
+	KeyPriority Key = "priority"
+	KeyCreated  Key = "created"
+	KeyUpdated  Key = "updated"
+	KeyUrgency  Key = "urgency"
+)
+
+// Keys lists every sort key in the order shown in help text.
+var Keys = []Key{KeyDue, KeyPriority, KeyCreated, KeyUpdated, KeyUrgency}
+
+// KeyNames returns the sort keys as strings.
+func KeyNames() []string {
+	names := make([]string, len(Keys))
+	for i, key := range Keys {
+		names[i] = string(key)
+	}
+	return names
+}
+
+// ParseKey validates a sort key name.
+func ParseKey(name string) (Key, error) {
+	for _, key := range Keys {
+		if string(key) == strings.ToLower(name) {
+			return key, nil
+		}
+	}
+	return "", fmt.Errorf("invalid sort '%s': must be one of %s", name, strings.Join(KeyNames(), ", "))
+}
+
+var priorityRank = map[string]int{"high": 3, "medium": 2, "low": 1}
+
+// Sort orders todos in place. By default the most pressing come first: soonest due,
+// highest priority, newest created or updated, or highest urgency. Reverse flips the
+// order, but todos without a due date or priority always sort last. Ties keep their
+// existing order. urgency is only called for KeyUrgency.
+func Sort(todos []*models.Todo, key Key, reverse bool, urgency func(*models.Todo) float64) {
+	compare := comparator(key, todos, urgency)
+	if compare == nil {
+		return
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := todos[i], todos[j]
+		if missingA, missingB := missing(key, a), missing(key, b); missingA || missingB {
+			return !missingA && missingB
+		}
+		if reverse {
+			return compare(a, b) > 0
+		}
+		return compare(a, b) < 0
+	})
+}
+
+// comparator returns a function that is negative when a should come before b.
+func comparator(key Key, todos []*models.Todo, urgency func(*models.Todo) float64) func(a, b *models.Todo) int {
+	switch key {
+	case KeyDue:
+		return func(a, b *models.Todo) int { return a.DueDate.Compare(*b.DueDate) }
+	case KeyPriority:
+		return func(a, b *models.Todo) int { return cmp.Compare(rank(b), rank(a)) }
+	case KeyCreated:
+		return func(a, b *models.Todo) int { return b.CreatedAt.Compare(a.CreatedAt) }
+	case KeyUpdated:
+		return func(a, b *models.Todo) int { return b.UpdatedAt.Compare(a.UpdatedAt) }
+	case KeyUrgency:
+		scores := make(map[*models.Todo]float64, len(todos))
+		for _, todo := range todos {
+			scores[todo] = urgency(todo)
+		}
+		return func(a, b *models.Todo) int { return cmp.Compare(scores[b], scores[a]) }
+	default:
+		return nil
+	}
+}
+
+func rank(todo *models.Todo) int {
+	if todo.Priority == nil {
+		return 0
+	}
+	return priorityRank[*todo.Priority]
+}
+
+// missing reports whether todo lacks the value sorted on, which keeps it last.
+func missing(key Key, todo *models.Todo) bool {
+	switch key {
+	case KeyDue:
+		return todo.DueDate == nil
+	case KeyPriority:
+		return rank(todo) == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,90 @@
+// ABOUTME: Tests for todo sort orders
+// ABOUTME: Covers each key, --reverse, and todos missing the sorted value
+
+package sorting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+func descriptions(todos []*models.Todo) []string {
+	names := make([]string, len(todos))
+	for i, todo := range todos {
+		names[i] = todo.Description
+	}
+	return names
+}
+
+func assertOrder(t *testing.T, todos []*models.Todo, want ...string) {
+	t.Helper()
+	got := descriptions(todos)
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] {
+			t.Fatalf("Expected order %v, got %v", want, got)
+		}
+	}
+}
+
+func fixtures() []*models.Todo {
+	base := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	high, low := "high", "low"
+	soon, later := base.Add(day), base.Add(5*day)
+
+	a := models.NewTodo(uuid.New(), "a")
+	a.CreatedAt, a.UpdatedAt = base, base.Add(3*time.Hour)
+	a.Priority = &low
+	a.DueDate = &later
+
+	b := models.NewTodo(uuid.New(), "b")
+	b.CreatedAt, b.UpdatedAt = base.Add(time.Hour), base.Add(time.Hour)
+	b.Priority = &high
+
+	c := models.NewTodo(uuid.New(), "c")
+	c.CreatedAt, c.UpdatedAt = base.Add(2*time.Hour), base.Add(2*time.Hour)
+	c.DueDate = &soon
+
+	return []*models.Todo{a, b, c}
+}
+
+func TestSort(t *testing.T) {
+	none := func(*models.Todo) float64 { return 0 }
+
+	todos := fixtures()
+	Sort(todos, KeyDue, false, none)
+	assertOrder(t, todos, "c", "a", "b")
+
+	Sort(todos, KeyDue, true, none)
+	assertOrder(t, todos, "a", "c", "b")
+
+	Sort(todos, KeyPriority, false, none)
+	assertOrder(t, todos, "b", "a", "c")
+
+	Sort(todos, KeyPriority, true, none)
+	assertOrder(t, todos, "a", "b", "c")
+
+	Sort(todos, KeyCreated, false, none)
+	assertOrder(t, todos, "c", "b", "a")
+
+	Sort(todos, KeyUpdated, false, none)
+	assertOrder(t, todos, "a", "c", "b")
+
+	scores := map[string]float64{"a": 1, "b": 3, "c": 2}
+	Sort(todos, KeyUrgency, false, func(todo *models.Todo) float64 { return scores[todo.Description] })
+	assertOrder(t, todos, "b", "c", "a")
+
+	Sort(todos, KeyUrgency, true, func(todo *models.Todo) float64 { return scores[todo.Description] })
+	assertOrder(t, todos, "a", "c", "b")
+}
+
+func TestParseKey(t *testing.T) {
+	if key, err := ParseKey("Urgency"); err != nil || key != KeyUrgency {
+		t.Errorf("Expected urgency, got %q, %v", key, err)
+	}
+	if _, err := ParseKey("size"); err == nil {
+		t.Error("Expected error for unknown sort key")
+	}
+}
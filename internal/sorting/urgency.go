@@ -0,0 +1,160 @@
+// ABOUTME: Urgency score combining priority, due date, age, and tags
+// ABOUTME: Coefficients follow Taskwarrior's defaults and can be overridden by the urgency setting or TOKI_URGENCY
+
+package sorting
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/harper/toki/internal/models"
+)
+
+// UrgencyEnv names the environment variable holding coefficient overrides, for example
+// TOKI_URGENCY="due=8,priority.high=10,tag.next=15", used when the urgency setting is
+// empty.
+const UrgencyEnv = "TOKI_URGENCY"
+
+const day = 24 * time.Hour
+
+// Coefficients weigh each factor of the urgency score.
+type Coefficients struct {
+	PriorityHigh   float64
+	PriorityMedium float64
+	PriorityLow    float64
+	// Due is applied in full once a todo is a week overdue, scaling down to a fifth
+	// for todos due two weeks or more from now.
+	Due float64
+	// Age is applied in full to todos at least AgeMax days old, proportionally before.
+	Age    float64
+	AgeMax float64
+	// Tags is applied in part to todos with one or two tags and in full from three.
+	Tags float64
+	// Tag adds a fixed amount for specific tags.
+	Tag map[string]float64
+}
+
+// DefaultCoefficients returns Taskwarrior's default urgency coefficients.
+func DefaultCoefficients() Coefficients {
+	return Coefficients{
+		PriorityHigh:   6.0,
+		PriorityMedium: 3.9,
+		PriorityLow:    1.8,
+		Due:            12.0,
+		Age:            2.0,
+		AgeMax:         365,
+		Tags:           1.0,
+		Tag:            map[string]float64{},
+	}
+}
+
+// ParseCoefficients applies comma-separated key=value overrides to base. Keys are
+// priority.high, priority.medium, priority.low, due, age, age.max, tags, and tag.<name>.
+func ParseCoefficients(spec string, base Coefficients) (Coefficients, error) {
+	result := base
+	result.Tag = make(map[string]float64, len(base.Tag))
+	for name, value := range base.Tag {
+		result.Tag[name] = value
+	}
+
+	fields := map[string]*float64{
+		"priority.high":   &result.PriorityHigh,
+		"priority.medium": &result.PriorityMedium,
+		"priority.low":    &result.PriorityLow,
+		"due":             &result.Due,
+		"age":             &result.Age,
+		"age.max":         &result.AgeMax,
+		"tags":            &result.Tags,
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		key, raw, ok := strings.Cut(entry, "=")
+		if !ok {
+			return base, fmt.Errorf("invalid urgency coefficient '%s': expected key=value", entry)
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return base, fmt.Errorf("invalid value for urgency coefficient '%s': %w", key, err)
+		}
+
+		if tag, ok := strings.CutPrefix(key, "tag."); ok && tag != "" {
+			result.Tag[tag] = value
+			continue
+		}
+		field, ok := fields[key]
+		if !ok {
+			return base, fmt.Errorf("unknown urgency coefficient '%s'", key)
+		}
+		*field = value
+	}
+
+	if result.AgeMax <= 0 {
+		return base, fmt.Errorf("urgency coefficient 'age.max' must be positive")
+	}
+	return result, nil
+}
+
+// CoefficientsFromEnv returns the default coefficients with overrides from TOKI_URGENCY.
+func CoefficientsFromEnv() (Coefficients, error) {
+	return ParseCoefficients(os.Getenv(UrgencyEnv), DefaultCoefficients())
+}
+
+// Urgency scores how pressing a todo is at now; higher is more urgent.
+func Urgency(todo *models.Todo, tags []*models.Tag, c Coefficients, now time.Time) float64 {
+	score := 0.0
+
+	if todo.Priority != nil {
+		switch *todo.Priority {
+		case "high":
+			score += c.PriorityHigh
+		case "medium":
+			score += c.PriorityMedium
+		case "low":
+			score += c.PriorityLow
+		}
+	}
+
+	if todo.DueDate != nil {
+		score += c.Due * dueFactor(now.Sub(*todo.DueDate))
+	}
+
+	age := now.Sub(todo.CreatedAt).Hours() / 24
+	score += c.Age * min(1, max(0, age/c.AgeMax))
+
+	switch {
+	case len(tags) >= 3:
+		score += c.Tags
+	case len(tags) == 2:
+		score += c.Tags * 0.9
+	case len(tags) == 1:
+		score += c.Tags * 0.8
+	}
+	for _, tag := range tags {
+		score += c.Tag[tag.Name]
+	}
+
+	return score
+}
+
+// dueFactor maps time past the due date to [0.2, 1]: 1 from a week overdue, 0.2 from two
+// weeks before the due date, linear in between.
+func dueFactor(overdue time.Duration) float64 {
+	days := overdue.Hours() / 24
+	switch {
+	case overdue >= 7*day:
+		return 1.0
+	case overdue >= -14*day:
+		return (days+14)*0.8/21 + 0.2
+	default:
+		return 0.2
+	}
+}
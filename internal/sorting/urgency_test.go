@@ -0,0 +1,90 @@
+// ABOUTME: Tests for the urgency score and coefficient parsing
+// ABOUTME: Checks each factor's contribution and TOKI_URGENCY-style overrides
+
+package sorting
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+func approx(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 0.001 {
+		t.Errorf("%s: expected %.3f, got %.3f", name, want, got)
+	}
+}
+
+func TestUrgency(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := DefaultCoefficients()
+	high := "high"
+
+	todo := models.NewTodo(uuid.New(), "fresh todo")
+	todo.CreatedAt = now
+	approx(t, "no factors", Urgency(todo, nil, c, now), 0)
+
+	todo.Priority = &high
+	approx(t, "high priority", Urgency(todo, nil, c, now), 6.0)
+
+	todo.Priority = nil
+	overdue := now.Add(-10 * day)
+	todo.DueDate = &overdue
+	approx(t, "week overdue", Urgency(todo, nil, c, now), 12.0)
+
+	dueNow := now
+	todo.DueDate = &dueNow
+	approx(t, "due now", Urgency(todo, nil, c, now), 12.0*(14*0.8/21+0.2))
+
+	farOff := now.Add(30 * day)
+	todo.DueDate = &farOff
+	approx(t, "due far off", Urgency(todo, nil, c, now), 12.0*0.2)
+
+	todo.DueDate = nil
+	todo.CreatedAt = now.Add(-730 * day)
+	approx(t, "old todo", Urgency(todo, nil, c, now), 2.0)
+
+	todo.CreatedAt = now
+	tags := []*models.Tag{{Name: "next"}, {Name: "work"}}
+	approx(t, "two tags", Urgency(todo, tags, c, now), 0.9)
+
+	c.Tag["next"] = 15
+	approx(t, "tag coefficient", Urgency(todo, tags, c, now), 15.9)
+}
+
+func TestParseCoefficients(t *testing.T) {
+	c, err := ParseCoefficients("due=8, priority.high=10,tag.next=15,age.max=30", DefaultCoefficients())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Due != 8 || c.PriorityHigh != 10 || c.Tag["next"] != 15 || c.AgeMax != 30 {
+		t.Errorf("Unexpected coefficients: %+v", c)
+	}
+	if c.PriorityMedium != 3.9 {
+		t.Errorf("Expected untouched coefficients to keep defaults, got %v", c.PriorityMedium)
+	}
+
+	if empty, err := ParseCoefficients("", DefaultCoefficients()); err != nil || empty.Due != 12 {
+		t.Errorf("Expected empty spec to return defaults, got %+v, %v", empty, err)
+	}
+
+	for _, spec := range []string{"due", "due=high", "speed=1", "age.max=0"} {
+		if _, err := ParseCoefficients(spec, DefaultCoefficients()); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestParseCoefficientsDoesNotShareTagMap(t *testing.T) {
+	base := DefaultCoefficients()
+	if _, err := ParseCoefficients("tag.next=15", base); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := base.Tag["next"]; ok {
+		t.Error("Expected base coefficients to be left unchanged")
+	}
+}
@@ -0,0 +1,259 @@
+// ABOUTME: Summary statistics over all todos: counts by status, priority, project, tag, and age
+// ABOUTME: Shared by the toki stats command and the toki://stats MCP resource
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/store"
+)
+
+// TrendWeeks is how many weeks of throughput a report covers.
+const TrendWeeks = 8
+
+// Report is the statistics summary, in the form it is output as JSON.
+type Report struct {
+	Summary       Summary        `json:"summary"`
+	ByPriority    map[string]int `json:"by_priority"`
+	ByProject     []ProjectCount `json:"by_project"`
+	ByTag         []TagCount     `json:"by_tag"`
+	Ages          []AgeBucket    `json:"age_distribution"`
+	OldestPending *OldestPending `json:"oldest_pending,omitempty"`
+	Trends        TrendReport    `json:"trends"`
+}
+
+// Summary contains overall todo counts.
+type Summary struct {
+	TotalTodos int `json:"total_todos"`
+	Pending    int `json:"pending"`
+	Completed  int `json:"completed"`
+	Overdue    int `json:"overdue"`
+}
+
+// ProjectCount contains per-project todo counts.
+type ProjectCount struct {
+	ProjectID   string `json:"project_id"`
+	ProjectName string `json:"project_name"`
+	TodoCount   int    `json:"todo_count"`
+	Pending     int    `json:"pending"`
+	Overdue     int    `json:"overdue"`
+}
+
+// TagCount contains per-tag todo counts.
+type TagCount struct {
+	Name      string `json:"name"`
+	TodoCount int    `json:"todo_count"`
+	Pending   int    `json:"pending"`
+}
+
+// AgeBucket counts the pending todos created between MinDays and MaxDays ago, inclusive.
+// The last bucket has no MaxDays.
+type AgeBucket struct {
+	Label   string `json:"label"`
+	MinDays int    `json:"min_days"`
+	MaxDays *int   `json:"max_days,omitempty"`
+	Count   int    `json:"count"`
+}
+
+// OldestPending represents the oldest incomplete todo.
+type OldestPending struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	AgeDays     int    `json:"age_days"`
+}
+
+// TrendReport contains recent throughput and cycle time.
+type TrendReport struct {
+	Weeks []WeekReport `json:"weeks"`
+	// AverageCycleTimeHours is the mean time from creation to completion of the todos
+	// completed in these weeks.
+	AverageCycleTimeHours float64 `json:"average_cycle_time_hours"`
+	CycleTimeSamples      int     `json:"cycle_time_samples"`
+}
+
+// WeekReport contains the todos created and completed in one week, starting on Monday.
+type WeekReport struct {
+	WeekStart        string  `json:"week_start"`
+	Created          int     `json:"created"`
+	Completed        int     `json:"completed"`
+	CompletedPoints  float64 `json:"completed_points"`
+	EstimatedSeconds int64   `json:"completed_estimated_seconds"`
+	// CompletionRate is the share of the todos created this week that are done now.
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+// ageBuckets are the upper bounds in days of each age bucket but the last.
+var ageBuckets = []struct {
+	label   string
+	maxDays int
+}{
+	{"under a week", 6},
+	{"1-4 weeks", 29},
+	{"1-3 months", 89},
+	{"3-12 months", 364},
+}
+
+// Load reads every todo, project, and tag and reports on them.
+func Load(ctx context.Context, st store.Store, now time.Time) (*Report, error) {
+	todos, err := st.ListTodos(ctx, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list todos: %w", err)
+	}
+
+	projects, err := st.ListProjects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	todoTags, err := st.GetTagsForTodos(ctx, todos)
+	if err != nil {
+		return nil, err
+	}
+
+	return Calculate(todos, projects, todoTags, now), nil
+}
+
+// Calculate reports on todos. Projects without todos are left out, and so are todos
+// whose project is not in projects.
+func Calculate(todos []*models.Todo, projects []*models.Project, todoTags map[uuid.UUID][]*models.Tag, now time.Time) *Report {
+	report := &Report{
+		Summary:    Summary{TotalTodos: len(todos)},
+		ByPriority: make(map[string]int),
+		Ages:       make([]AgeBucket, len(ageBuckets)+1),
+		Trends:     trendReport(GetTrends(todos, TrendWeeks, now)),
+	}
+
+	minDays := 0
+	for i, bucket := range ageBuckets {
+		maxDays := bucket.maxDays
+		report.Ages[i] = AgeBucket{Label: bucket.label, MinDays: minDays, MaxDays: &maxDays}
+		minDays = maxDays + 1
+	}
+	report.Ages[len(ageBuckets)] = AgeBucket{Label: "over a year", MinDays: minDays}
+
+	projectCounts := make(map[uuid.UUID]*ProjectCount)
+	tagCounts := make(map[string]*TagCount)
+	var oldestPending *models.Todo
+
+	for _, todo := range todos {
+		overdue := !todo.Done && todo.DueDate != nil && todo.DueDate.Before(now)
+
+		project := projectCounts[todo.ProjectID]
+		if project == nil {
+			project = &ProjectCount{}
+			projectCounts[todo.ProjectID] = project
+		}
+		project.TodoCount++
+
+		if todo.Done {
+			report.Summary.Completed++
+		} else {
+			report.Summary.Pending++
+			project.Pending++
+			report.Ages[ageBucket(ageDays(todo, now))].Count++
+			if oldestPending == nil || todo.CreatedAt.Before(oldestPending.CreatedAt) {
+				oldestPending = todo
+			}
+		}
+
+		if overdue {
+			report.Summary.Overdue++
+			project.Overdue++
+		}
+
+		priority := "none"
+		if todo.Priority != nil {
+			priority = *todo.Priority
+		}
+		report.ByPriority[priority]++
+
+		for _, tag := range todoTags[todo.ID] {
+			count := tagCounts[tag.Name]
+			if count == nil {
+				count = &TagCount{Name: tag.Name}
+				tagCounts[tag.Name] = count
+			}
+			count.TodoCount++
+			if !todo.Done {
+				count.Pending++
+			}
+		}
+	}
+
+	report.ByProject = make([]ProjectCount, 0, len(projectCounts))
+	for _, project := range projects {
+		if count, ok := projectCounts[project.ID]; ok {
+			count.ProjectID = project.ID.String()
+			count.ProjectName = project.Name
+			report.ByProject = append(report.ByProject, *count)
+		}
+	}
+	sort.Slice(report.ByProject, func(i, j int) bool {
+		a, b := report.ByProject[i], report.ByProject[j]
+		if a.TodoCount != b.TodoCount {
+			return a.TodoCount > b.TodoCount
+		}
+		return a.ProjectName < b.ProjectName
+	})
+
+	report.ByTag = make([]TagCount, 0, len(tagCounts))
+	for _, count := range tagCounts {
+		report.ByTag = append(report.ByTag, *count)
+	}
+	sort.Slice(report.ByTag, func(i, j int) bool {
+		a, b := report.ByTag[i], report.ByTag[j]
+		if a.TodoCount != b.TodoCount {
+			return a.TodoCount > b.TodoCount
+		}
+		return a.Name < b.Name
+	})
+
+	if oldestPending != nil {
+		report.OldestPending = &OldestPending{
+			ID:          oldestPending.ID.String(),
+			Description: oldestPending.Description,
+			AgeDays:     ageDays(oldestPending, now),
+		}
+	}
+
+	return report
+}
+
+func ageDays(todo *models.Todo, now time.Time) int {
+	return max(int(now.Sub(todo.CreatedAt).Hours()/24), 0)
+}
+
+func ageBucket(days int) int {
+	for i, bucket := range ageBuckets {
+		if days <= bucket.maxDays {
+			return i
+		}
+	}
+	return len(ageBuckets)
+}
+
+func trendReport(trends Trends) TrendReport {
+	report := TrendReport{
+		Weeks:                 make([]WeekReport, 0, len(trends.Weeks)),
+		AverageCycleTimeHours: math.Round(trends.AverageCycleTime.Hours()*10) / 10,
+		CycleTimeSamples:      trends.CycleTimeSamples,
+	}
+	for _, week := range trends.Weeks {
+		report.Weeks = append(report.Weeks, WeekReport{
+			WeekStart:        week.Start.Format("2006-01-02"),
+			Created:          week.Created,
+			Completed:        week.Completed.Todos,
+			CompletedPoints:  week.Completed.Points,
+			EstimatedSeconds: int64(week.Completed.Estimated / time.Second),
+			CompletionRate:   math.Round(week.CompletionRate*100) / 100,
+		})
+	}
+	return report
+}
@@ -0,0 +1,96 @@
+// ABOUTME: Tests for the summary statistics report
+// ABOUTME: Covers counts by status, priority, project, and tag, age buckets, and ordering
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+func TestCalculate(t *testing.T) {
+	now := at(31, 12)
+	api := models.NewProject("api", nil)
+	web := models.NewProject("web", nil)
+	empty := models.NewProject("empty", nil)
+
+	high := "high"
+	overdue := testTodo(at(30, 9), nil, nil)
+	overdue.ProjectID = api.ID
+	overdue.Priority = &high
+	overdue.DueDate = ptr(at(30, 0))
+
+	old := testTodo(now.AddDate(0, -2, 0), nil, nil)
+	old.ProjectID = web.ID
+
+	done := testTodo(at(20, 9), ptr(at(29, 9)), nil)
+	done.ProjectID = api.ID
+	done.DueDate = ptr(at(21, 0))
+
+	ancient := testTodo(now.AddDate(-2, 0, 0), nil, nil)
+	ancient.ProjectID = api.ID
+
+	bug := &models.Tag{ID: 1, Name: "bug"}
+	ui := &models.Tag{ID: 2, Name: "ui"}
+	tags := map[uuid.UUID][]*models.Tag{
+		overdue.ID: {bug, ui},
+		old.ID:     {ui},
+		done.ID:    {bug},
+	}
+
+	report := Calculate([]*models.Todo{overdue, old, done, ancient}, []*models.Project{web, empty, api}, tags, now)
+
+	if report.Summary != (Summary{TotalTodos: 4, Pending: 3, Completed: 1, Overdue: 1}) {
+		t.Errorf("Unexpected summary: %+v", report.Summary)
+	}
+	if report.ByPriority["high"] != 1 || report.ByPriority["none"] != 3 {
+		t.Errorf("Unexpected priorities: %v", report.ByPriority)
+	}
+
+	if len(report.ByProject) != 2 {
+		t.Fatalf("Expected projects without todos to be left out, got %+v", report.ByProject)
+	}
+	if got := report.ByProject[0]; got.ProjectName != "api" || got.TodoCount != 3 || got.Pending != 2 || got.Overdue != 1 {
+		t.Errorf("Unexpected first project: %+v", got)
+	}
+	if got := report.ByProject[1]; got.ProjectName != "web" || got.TodoCount != 1 || got.Overdue != 0 {
+		t.Errorf("Unexpected second project: %+v", got)
+	}
+
+	// Ties sort by name
+	if len(report.ByTag) != 2 || report.ByTag[0].Name != "bug" || report.ByTag[1].Name != "ui" {
+		t.Fatalf("Unexpected tags: %+v", report.ByTag)
+	}
+	if report.ByTag[0].TodoCount != 2 || report.ByTag[0].Pending != 1 || report.ByTag[1].Pending != 2 {
+		t.Errorf("Unexpected tag counts: %+v", report.ByTag)
+	}
+
+	wantAges := []int{1, 0, 1, 0, 1}
+	for i, want := range wantAges {
+		if report.Ages[i].Count != want {
+			t.Errorf("Age bucket %q: expected %d, got %d", report.Ages[i].Label, want, report.Ages[i].Count)
+		}
+	}
+	if report.Ages[len(report.Ages)-1].MaxDays != nil {
+		t.Error("Expected the last age bucket to be open-ended")
+	}
+
+	if report.OldestPending == nil || report.OldestPending.ID != ancient.ID.String() {
+		t.Errorf("Expected the ancient todo as oldest pending, got %+v", report.OldestPending)
+	}
+	if len(report.Trends.Weeks) != TrendWeeks || report.Trends.CycleTimeSamples != 1 {
+		t.Errorf("Unexpected trends: %+v", report.Trends)
+	}
+}
+
+func TestCalculateEmpty(t *testing.T) {
+	report := Calculate(nil, nil, nil, at(31, 12))
+	if report.Summary != (Summary{}) || report.OldestPending != nil {
+		t.Errorf("Expected an empty report, got %+v", report)
+	}
+	if report.ByProject == nil || report.ByTag == nil {
+		t.Error("Expected empty slices so JSON output has arrays, not null")
+	}
+}
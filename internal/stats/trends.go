@@ -0,0 +1,179 @@
+// ABOUTME: Completion analytics over todos: weekly velocity, daily burndown, and trends
+// ABOUTME: Works on loaded todos so the CLI and MCP server share the same numbers
+
+package stats
+
+import (
+	"time"
+
+	"github.com/harper/toki/internal/models"
+)
+
+// Work is an amount of work: todos, and the points and durations estimated for them.
+type Work struct {
+	Todos     int
+	Points    float64
+	Estimated time.Duration
+}
+
+func (w *Work) add(todo *models.Todo) {
+	w.Todos++
+	if todo.Estimate != nil {
+		w.Points += todo.Estimate.Points
+		w.Estimated += todo.Estimate.Duration
+	}
+}
+
+// Week is the work created and completed in one week, starting on Monday.
+type Week struct {
+	Start     time.Time
+	Created   int
+	Completed Work
+	// CompletionRate is the share of the todos created this week that are done now.
+	CompletionRate float64
+}
+
+// Day is the work remaining at the end of one day.
+type Day struct {
+	Date      time.Time
+	Remaining Work
+}
+
+// Trends summarize recent throughput and how long todos take to complete.
+type Trends struct {
+	Weeks []Week
+	// AverageCycleTime is the mean time from creation to completion of the todos
+	// completed in these weeks.
+	AverageCycleTime time.Duration
+	CycleTimeSamples int
+}
+
+// WeekStart returns midnight on the Monday of t's week, in t's location.
+func WeekStart(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// CompletedAt returns when a done todo was completed, falling back to its last update
+// for todos completed before completion times were recorded. It returns false for
+// pending todos.
+func CompletedAt(todo *models.Todo) (time.Time, bool) {
+	if !todo.Done {
+		return time.Time{}, false
+	}
+	if todo.CompletedAt != nil {
+		return *todo.CompletedAt, true
+	}
+	return todo.UpdatedAt, true
+}
+
+// Velocity returns the last n weeks up to and including the current one, oldest first.
+func Velocity(todos []*models.Todo, n int, now time.Time) []Week {
+	if n < 1 {
+		return nil
+	}
+
+	first := WeekStart(now).AddDate(0, 0, -7*(n-1))
+	weeks := make([]Week, n)
+	for i := range weeks {
+		weeks[i].Start = first.AddDate(0, 0, 7*i)
+	}
+	index := func(t time.Time) int {
+		t = t.In(now.Location())
+		if t.Before(first) {
+			return -1
+		}
+		i := int(WeekStart(t).Sub(first).Hours()/24+0.5) / 7
+		if i >= n {
+			return -1
+		}
+		return i
+	}
+
+	createdDone := make([]int, n)
+	for _, todo := range todos {
+		completed, done := CompletedAt(todo)
+		if i := index(todo.CreatedAt); i >= 0 {
+			weeks[i].Created++
+			if done {
+				createdDone[i]++
+			}
+		}
+		if done {
+			if i := index(completed); i >= 0 {
+				weeks[i].Completed.add(todo)
+			}
+		}
+	}
+
+	for i := range weeks {
+		if weeks[i].Created > 0 {
+			weeks[i].CompletionRate = float64(createdDone[i]) / float64(weeks[i].Created)
+		}
+	}
+	return weeks
+}
+
+// AverageCompleted returns the mean work completed per week.
+func AverageCompleted(weeks []Week) (todos, points float64, estimated time.Duration) {
+	if len(weeks) == 0 {
+		return 0, 0, 0
+	}
+	var total Work
+	for _, week := range weeks {
+		total.Todos += week.Completed.Todos
+		total.Points += week.Completed.Points
+		total.Estimated += week.Completed.Estimated
+	}
+	n := len(weeks)
+	return float64(total.Todos) / float64(n), total.Points / float64(n), total.Estimated / time.Duration(n)
+}
+
+// Burndown returns the work remaining at the end of each day from since through now:
+// todos created by then and not yet completed.
+func Burndown(todos []*models.Todo, since, now time.Time) []Day {
+	loc := now.Location()
+	day := time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, loc)
+
+	var days []Day
+	for !day.After(now) {
+		end := day.AddDate(0, 0, 1)
+		point := Day{Date: day}
+		for _, todo := range todos {
+			if !todo.CreatedAt.Before(end) {
+				continue
+			}
+			if completed, done := CompletedAt(todo); done && completed.Before(end) {
+				continue
+			}
+			point.Remaining.add(todo)
+		}
+		days = append(days, point)
+		day = end
+	}
+	return days
+}
+
+// GetTrends returns the velocity of the last n weeks with the average cycle time of the
+// todos completed in them.
+func GetTrends(todos []*models.Todo, n int, now time.Time) Trends {
+	trends := Trends{Weeks: Velocity(todos, n, now)}
+	if len(trends.Weeks) == 0 {
+		return trends
+	}
+
+	first := trends.Weeks[0].Start
+	var total time.Duration
+	for _, todo := range todos {
+		completed, done := CompletedAt(todo)
+		if !done || completed.Before(first) || completed.Before(todo.CreatedAt) {
+			continue
+		}
+		total += completed.Sub(todo.CreatedAt)
+		trends.CycleTimeSamples++
+	}
+	if trends.CycleTimeSamples > 0 {
+		trends.AverageCycleTime = total / time.Duration(trends.CycleTimeSamples)
+	}
+	return trends
+}
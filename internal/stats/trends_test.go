@@ -0,0 +1,119 @@
+// ABOUTME: Tests for completion analytics
+// ABOUTME: Covers week boundaries, velocity with estimates, burndown, and cycle time
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+func testTodo(created time.Time, completed *time.Time, estimate *models.Estimate) *models.Todo {
+	todo := models.NewTodo(uuid.New(), "todo")
+	todo.CreatedAt = created
+	todo.UpdatedAt = created
+	todo.Estimate = estimate
+	if completed != nil {
+		todo.Done = true
+		todo.CompletedAt = completed
+	}
+	return todo
+}
+
+func at(day int, hour int) time.Time {
+	return time.Date(2025, 1, day, hour, 0, 0, 0, time.UTC)
+}
+
+func ptr(t time.Time) *time.Time { return &t }
+
+func TestWeekStart(t *testing.T) {
+	for _, day := range []int{6, 8, 12} {
+		if got := WeekStart(at(day, 15)); !got.Equal(at(6, 0)) {
+			t.Errorf("WeekStart(Jan %d) = %v, want Monday Jan 6", day, got)
+		}
+	}
+	if got := WeekStart(at(13, 0)); !got.Equal(at(13, 0)) {
+		t.Errorf("WeekStart(Monday) = %v, want the same day", got)
+	}
+}
+
+func TestVelocity(t *testing.T) {
+	// Wednesday of the third week
+	now := at(22, 12)
+	todos := []*models.Todo{
+		testTodo(at(6, 9), ptr(at(7, 9)), &models.Estimate{Points: 3}),
+		testTodo(at(6, 9), ptr(at(8, 9)), &models.Estimate{Duration: 2 * time.Hour}),
+		testTodo(at(7, 9), nil, nil),
+		testTodo(at(13, 9), ptr(at(21, 9)), &models.Estimate{Points: 5}),
+		// Completed before the window
+		testTodo(at(1, 9), ptr(at(2, 9)), nil),
+	}
+
+	weeks := Velocity(todos, 3, now)
+	if len(weeks) != 3 || !weeks[0].Start.Equal(at(6, 0)) || !weeks[2].Start.Equal(at(20, 0)) {
+		t.Fatalf("Unexpected weeks: %+v", weeks)
+	}
+
+	first := weeks[0]
+	if first.Completed.Todos != 2 || first.Completed.Points != 3 || first.Completed.Estimated != 2*time.Hour {
+		t.Errorf("Unexpected first week: %+v", first.Completed)
+	}
+	if first.Created != 3 || first.CompletionRate < 0.66 || first.CompletionRate > 0.67 {
+		t.Errorf("Expected 2 of 3 created todos done, got %d created, rate %v", first.Created, first.CompletionRate)
+	}
+	if weeks[1].Completed.Todos != 0 || weeks[1].Created != 1 || weeks[1].CompletionRate != 1 {
+		t.Errorf("Unexpected second week: %+v", weeks[1])
+	}
+	if weeks[2].Completed.Todos != 1 || weeks[2].Completed.Points != 5 {
+		t.Errorf("Unexpected third week: %+v", weeks[2])
+	}
+
+	todosPerWeek, pointsPerWeek, _ := AverageCompleted(weeks)
+	if todosPerWeek != 1 || pointsPerWeek != 8.0/3 {
+		t.Errorf("Unexpected averages: %v todos, %v points", todosPerWeek, pointsPerWeek)
+	}
+}
+
+func TestBurndown(t *testing.T) {
+	now := at(9, 12)
+	todos := []*models.Todo{
+		testTodo(at(5, 9), ptr(at(7, 9)), &models.Estimate{Points: 2}),
+		testTodo(at(5, 9), nil, &models.Estimate{Points: 3}),
+		testTodo(at(8, 9), nil, nil),
+	}
+
+	days := Burndown(todos, at(6, 0), now)
+	if len(days) != 4 {
+		t.Fatalf("Expected 4 days, got %d", len(days))
+	}
+	want := []int{2, 1, 2, 2}
+	for i, day := range days {
+		if day.Remaining.Todos != want[i] {
+			t.Errorf("Day %v: expected %d remaining, got %d", day.Date, want[i], day.Remaining.Todos)
+		}
+	}
+	if days[0].Remaining.Points != 5 || days[1].Remaining.Points != 3 {
+		t.Errorf("Unexpected remaining points: %v, %v", days[0].Remaining.Points, days[1].Remaining.Points)
+	}
+}
+
+func TestGetTrends(t *testing.T) {
+	now := at(22, 12)
+	todos := []*models.Todo{
+		testTodo(at(13, 9), ptr(at(14, 9)), nil),
+		testTodo(at(13, 9), ptr(at(16, 9)), nil),
+		testTodo(at(1, 9), ptr(at(2, 9)), nil),
+		testTodo(at(20, 9), nil, nil),
+	}
+
+	trends := GetTrends(todos, 2, now)
+	if len(trends.Weeks) != 2 {
+		t.Fatalf("Expected 2 weeks, got %d", len(trends.Weeks))
+	}
+	if trends.CycleTimeSamples != 2 || trends.AverageCycleTime != 48*time.Hour {
+		t.Errorf("Expected a 48h average over 2 todos, got %v over %d", trends.AverageCycleTime, trends.CycleTimeSamples)
+	}
+}
@@ -0,0 +1,201 @@
+// ABOUTME: Syncs TODO-style source comments into todos tagged "code"
+// ABOUTME: Adds, moves, closes, and reopens code todos through any Store
+
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+// CodeTag is the tag applied to todos created from source comments.
+const CodeTag = "code"
+
+// SyncResult counts the changes made by SyncCodeComments.
+type SyncResult struct {
+	Added    int
+	Moved    int
+	Closed   int
+	Reopened int
+}
+
+// SyncCodeComments brings a project's code todos in line with the comments found by a
+// scan. New comments become todos tagged "code"; comments that moved update their
+// location; comments no longer found mark their todo done, and reappearing comments
+// reopen it. Todos go through s, so layers such as encryption apply to them as to
+// todos added any other way. The sync runs in one transaction, so a failed scan leaves
+// no todo without the comment that keeps the next scan from adding it again.
+func SyncCodeComments(ctx context.Context, s Store, projectID uuid.UUID, found []*models.CodeComment) (*SyncResult, error) {
+	var result *SyncResult
+	err := s.Tx(ctx, func(tx Store) error {
+		var err error
+		result, err = syncCodeComments(ctx, tx, projectID, found)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func syncCodeComments(ctx context.Context, s Store, projectID uuid.UUID, found []*models.CodeComment) (*SyncResult, error) {
+	existing, err := s.ListCodeComments(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]*models.CodeComment, len(existing))
+	for _, comment := range existing {
+		known[comment.Fingerprint] = comment
+	}
+
+	result := &SyncResult{}
+	seen := make(map[string]bool, len(found))
+	for _, comment := range found {
+		seen[comment.Fingerprint] = true
+		comment.ProjectID = projectID
+
+		previous, ok := known[comment.Fingerprint]
+		if !ok {
+			previous, err = adoptCodeComment(ctx, s, existing, seen, comment)
+			if err != nil {
+				return nil, err
+			}
+			ok = previous != nil
+		}
+		if !ok {
+			if err := addCodeTodo(ctx, s, comment); err != nil {
+				return nil, err
+			}
+			result.Added++
+			continue
+		}
+
+		comment.TodoID = previous.TodoID
+		if err := refreshCodeTodo(ctx, s, comment, previous, result); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, comment := range existing {
+		if seen[comment.Fingerprint] || comment.Missing {
+			continue
+		}
+		closed, err := closeMissingCodeTodo(ctx, s, comment)
+		if err != nil {
+			return nil, err
+		}
+		if closed {
+			result.Closed++
+		}
+	}
+
+	return result, nil
+}
+
+// adoptCodeComment finds the recorded comment at the same place as a comment with an
+// unknown fingerprint and gives it the new fingerprint, so todos recorded under an
+// older fingerprint scheme carry over. A comment edited in place isn't adopted: its
+// todo no longer has the comment's text. Adopted comments are marked in seen.
+func adoptCodeComment(ctx context.Context, s Store, existing []*models.CodeComment, seen map[string]bool, comment *models.CodeComment) (*models.CodeComment, error) {
+	for _, previous := range existing {
+		if seen[previous.Fingerprint] || previous.File != comment.File || previous.Line != comment.Line || previous.Marker != comment.Marker {
+			continue
+		}
+		todo, err := s.GetTodoByID(ctx, previous.TodoID)
+		if err != nil {
+			return nil, err
+		}
+		if todo.Description != codeTodoDescription(comment) {
+			continue
+		}
+
+		if err := s.RenameCodeComment(ctx, comment.ProjectID, previous.Fingerprint, comment.Fingerprint); err != nil {
+			return nil, err
+		}
+		seen[previous.Fingerprint] = true
+		return previous, nil
+	}
+	return nil, nil
+}
+
+func addCodeTodo(ctx context.Context, s Store, comment *models.CodeComment) error {
+	todo := models.NewTodo(comment.ProjectID, codeTodoDescription(comment))
+	notes := codeTodoNotes(comment)
+	todo.Notes = &notes
+
+	if err := s.CreateTodoWithTags(ctx, todo, []string{CodeTag}); err != nil {
+		return err
+	}
+
+	comment.TodoID = todo.ID
+	return s.AddCodeComment(ctx, comment)
+}
+
+// refreshCodeTodo updates a known comment's location and reopens its todo if the comment
+// had disappeared. Notes edited by the user are left alone.
+func refreshCodeTodo(ctx context.Context, s Store, comment, previous *models.CodeComment, result *SyncResult) error {
+	moved := previous.File != comment.File || previous.Line != comment.Line
+	wasMissing := previous.Missing
+
+	if moved || wasMissing {
+		todo, err := s.GetTodoByID(ctx, comment.TodoID)
+		if err != nil {
+			return err
+		}
+
+		// s returns decrypted notes, so the comparison holds with encryption on
+		if todo.Notes == nil || *todo.Notes == codeTodoNotes(previous) {
+			notes := codeTodoNotes(comment)
+			todo.Notes = &notes
+		}
+		if wasMissing && todo.Done {
+			todo.MarkUndone()
+			result.Reopened++
+		} else if moved {
+			result.Moved++
+		}
+		if err := s.UpdateTodo(ctx, todo); err != nil {
+			return err
+		}
+	}
+
+	comment.Missing = false
+	return s.UpdateCodeComment(ctx, comment)
+}
+
+// closeMissingCodeTodo marks a vanished comment's todo done. Reports whether the todo
+// was still open.
+func closeMissingCodeTodo(ctx context.Context, s Store, comment *models.CodeComment) (bool, error) {
+	comment.Missing = true
+	if err := s.UpdateCodeComment(ctx, comment); err != nil {
+		return false, err
+	}
+
+	todo, err := s.GetTodoByID(ctx, comment.TodoID)
+	if err != nil {
+		return false, err
+	}
+	if todo.Done {
+		return false, nil
+	}
+
+	todo.MarkDone()
+	if err := s.UpdateTodo(ctx, todo); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func codeTodoDescription(comment *models.CodeComment) string {
+	if comment.Text != "" {
+		return comment.Text
+	}
+	return fmt.Sprintf("%s in %s:%d", comment.Marker, comment.File, comment.Line)
+}
+
+func codeTodoNotes(comment *models.CodeComment) string {
+	return fmt.Sprintf("%s at %s:%d", comment.Marker, comment.File, comment.Line)
+}
@@ -0,0 +1,189 @@
+// ABOUTME: Tests for syncing source code comments into todos
+// ABOUTME: Covers adding, moving, closing, reopening, and deduplication across scans on every Store
+
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/harper/toki/internal/models"
+)
+
+func codeComment(fingerprint, file string, line int, text string) *models.CodeComment {
+	return &models.CodeComment{Fingerprint: fingerprint, File: file, Line: line, Marker: "TODO", Text: text}
+}
+
+func TestSyncCodeComments(t *testing.T) {
+	for name, s := range stores(t) {
+		project := models.NewProject("test", nil)
+		if err := s.CreateProject(t.Context(), project); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := SyncCodeComments(t.Context(), s, project.ID, []*models.CodeComment{
+			codeComment("fp1", "main.go", 3, "handle retries"),
+			codeComment("fp2", "util.go", 10, ""),
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if result.Added != 2 {
+			t.Fatalf("%s: expected 2 added, got %+v", name, result)
+		}
+
+		codeTag := CodeTag
+		todos, err := s.ListTodos(t.Context(), &project.ID, nil, nil, &codeTag, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 2 {
+			t.Fatalf("%s: expected 2 code todos, got %d", name, len(todos))
+		}
+
+		// Rescan: fp1 moved, fp2 removed
+		result, err = SyncCodeComments(t.Context(), s, project.ID, []*models.CodeComment{
+			codeComment("fp1", "main.go", 7, "handle retries"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Added != 0 || result.Moved != 1 || result.Closed != 1 {
+			t.Fatalf("%s: unexpected rescan result: %+v", name, result)
+		}
+
+		comments, err := s.ListCodeComments(t.Context(), project.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		byFingerprint := make(map[string]*models.CodeComment)
+		for _, c := range comments {
+			byFingerprint[c.Fingerprint] = c
+		}
+		if !byFingerprint["fp2"].Missing || byFingerprint["fp1"].Line != 7 {
+			t.Errorf("%s: expected fp1 moved and fp2 missing, got %+v and %+v", name, byFingerprint["fp1"], byFingerprint["fp2"])
+		}
+
+		moved, err := s.GetTodoByID(t.Context(), byFingerprint["fp1"].TodoID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if moved.Done || moved.Notes == nil || *moved.Notes != "TODO at main.go:7" {
+			t.Errorf("%s: expected moved todo to stay open with new location, got %+v", name, moved)
+		}
+
+		removed, err := s.GetTodoByID(t.Context(), byFingerprint["fp2"].TodoID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !removed.Done || removed.Description != "TODO in util.go:10" {
+			t.Errorf("%s: expected removed comment's todo to be done, got %+v", name, removed)
+		}
+
+		// The comment comes back
+		result, err = SyncCodeComments(t.Context(), s, project.ID, []*models.CodeComment{
+			codeComment("fp1", "main.go", 7, "handle retries"),
+			codeComment("fp2", "util.go", 12, ""),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Reopened != 1 || result.Added != 0 || result.Moved != 0 {
+			t.Fatalf("%s: unexpected result after comment returned: %+v", name, result)
+		}
+
+		todos, err = s.ListTodos(t.Context(), &project.ID, nil, nil, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(todos) != 2 {
+			t.Errorf("%s: expected rescans not to duplicate todos, got %d", name, len(todos))
+		}
+
+		// Comments go with their todo
+		if err := s.DeleteTodo(t.Context(), removed.ID); err != nil {
+			t.Fatal(err)
+		}
+		if comments, _ := s.ListCodeComments(t.Context(), project.ID); len(comments) != 1 {
+			t.Errorf("%s: expected the deleted todo's comment gone, got %d comments", name, len(comments))
+		}
+	}
+}
+
+func TestSyncCodeCommentsAdoptsNewFingerprints(t *testing.T) {
+	for name, s := range stores(t) {
+		project := models.NewProject("test", nil)
+		if err := s.CreateProject(t.Context(), project); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := SyncCodeComments(t.Context(), s, project.ID, []*models.CodeComment{
+			codeComment("old1", "main.go", 3, "handle retries"),
+			codeComment("old2", "main.go", 5, "add logging"),
+		}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		// Same places, new fingerprints; the second comment was edited
+		result, err := SyncCodeComments(t.Context(), s, project.ID, []*models.CodeComment{
+			codeComment("new1", "main.go", 3, "handle retries"),
+			codeComment("new2", "main.go", 5, "add structured logging"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Added != 1 || result.Closed != 1 {
+			t.Fatalf("%s: expected the unchanged comment to keep its todo, got %+v", name, result)
+		}
+
+		comments, err := s.ListCodeComments(t.Context(), project.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fingerprints := map[string]bool{}
+		for _, c := range comments {
+			fingerprints[c.Fingerprint] = true
+		}
+		if !fingerprints["new1"] || fingerprints["old1"] {
+			t.Errorf("%s: expected old1 to be recorded as new1, got %+v", name, fingerprints)
+		}
+	}
+}
+
+// failingComments is a Store that can't record code comments.
+type failingComments struct {
+	Store
+}
+
+func (f failingComments) Tx(ctx context.Context, fn func(Store) error) error {
+	return f.Store.Tx(ctx, func(s Store) error { return fn(failingComments{s}) })
+}
+
+func (f failingComments) AddCodeComment(ctx context.Context, comment *models.CodeComment) error {
+	return errors.New("disk full")
+}
+
+func TestSyncCodeCommentsIsAtomic(t *testing.T) {
+	for name, s := range stores(t) {
+		project := models.NewProject("test", nil)
+		if err := s.CreateProject(t.Context(), project); err != nil {
+			t.Fatal(err)
+		}
+
+		found := []*models.CodeComment{codeComment("fp1", "main.go", 3, "handle retries")}
+		if _, err := SyncCodeComments(t.Context(), failingComments{s}, project.ID, found); err == nil {
+			t.Fatalf("%s: expected the sync to fail", name)
+		}
+		if todos, _ := s.ListTodos(t.Context(), &project.ID, nil, nil, nil, nil); len(todos) != 0 {
+			t.Errorf("%s: expected no todo left without its comment, got %d", name, len(todos))
+		}
+
+		// The next scan adds the todo once
+		if _, err := SyncCodeComments(t.Context(), s, project.ID, found); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if todos, _ := s.ListTodos(t.Context(), &project.ID, nil, nil, nil, nil); len(todos) != 1 {
+			t.Errorf("%s: expected one todo after rescanning, got %d", name, len(todos))
+		}
+	}
+}
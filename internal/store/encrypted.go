@@ -0,0 +1,281 @@
+// ABOUTME: Store wrapper that encrypts todo descriptions and notes with a secret.Key
+// ABOUTME: Seals text on the way in, opens it on the way out, and evaluates text filters after decrypting
+
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/secret"
+)
+
+// Encrypted is a Store that keeps todo descriptions and notes encrypted in the Store it
+// wraps. Todos it returns are decrypted: text encrypted when no key or another key is
+// set fails with secret.ErrNoKey or *secret.WrongKeyError rather than showing up as
+// ciphertext. With a nil key, text is written as is.
+type Encrypted struct {
+	Store
+	key *secret.Key
+}
+
+var _ Store = (*Encrypted)(nil)
+
+// NewEncrypted returns inner with todo text encrypted by key.
+func NewEncrypted(inner Store, key *secret.Key) *Encrypted {
+	return &Encrypted{Store: inner, key: key}
+}
+
+// Tx implements Store.
+func (e *Encrypted) Tx(ctx context.Context, fn func(Store) error) error {
+	return e.Store.Tx(ctx, func(s Store) error {
+		return fn(NewEncrypted(s, e.key))
+	})
+}
+
+// textContext binds a sealed field to its todo, so it can't be copied to another one.
+func textContext(id uuid.UUID, field string) string {
+	return id.String() + "/" + field
+}
+
+// seal returns a copy of todo with its text encrypted.
+func seal(key *secret.Key, todo *models.Todo) *models.Todo {
+	if key == nil {
+		return todo
+	}
+	sealed := *todo
+	sealed.Description = key.Seal(textContext(todo.ID, "description"), todo.Description)
+	if todo.Notes != nil {
+		notes := key.Seal(textContext(todo.ID, "notes"), *todo.Notes)
+		sealed.Notes = &notes
+	}
+	return &sealed
+}
+
+// open decrypts the text of todo in place.
+func open(key *secret.Key, todo *models.Todo) error {
+	description, err := secret.Open(key, textContext(todo.ID, "description"), todo.Description)
+	if err != nil {
+		return err
+	}
+	todo.Description = description
+	if todo.Notes != nil {
+		notes, err := secret.Open(key, textContext(todo.ID, "notes"), *todo.Notes)
+		if err != nil {
+			return err
+		}
+		todo.Notes = &notes
+	}
+	return nil
+}
+
+func (e *Encrypted) openAll(todos []*models.Todo) error {
+	for _, todo := range todos {
+		if err := open(e.key, todo); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openOne decrypts the todo a lookup returned, or the labels of an ambiguous match.
+func (e *Encrypted) openOne(todo *models.Todo, err error) (*models.Todo, error) {
+	var ambiguous *db.AmbiguousRefError
+	if errors.As(err, &ambiguous) {
+		for i, candidate := range ambiguous.Candidates {
+			id, parseErr := uuid.Parse(candidate.ID)
+			if parseErr != nil {
+				continue
+			}
+			label, openErr := secret.Open(e.key, textContext(id, "description"), candidate.Label)
+			if openErr != nil {
+				return nil, openErr
+			}
+			ambiguous.Candidates[i].Label = label
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := open(e.key, todo); err != nil {
+		return nil, err
+	}
+	return todo, nil
+}
+
+// The methods below seal todo text before passing todos to the wrapped Store and open
+// the todos it returns.
+
+func (e *Encrypted) CreateTodo(ctx context.Context, todo *models.Todo) error {
+	return e.Store.CreateTodo(ctx, seal(e.key, todo))
+}
+
+func (e *Encrypted) CreateTodoWithTags(ctx context.Context, todo *models.Todo, tagNames []string) error {
+	return e.Store.CreateTodoWithTags(ctx, seal(e.key, todo), tagNames)
+}
+
+func (e *Encrypted) UpdateTodo(ctx context.Context, todo *models.Todo) error {
+	return e.Store.UpdateTodo(ctx, seal(e.key, todo))
+}
+
+func (e *Encrypted) GetTodoByID(ctx context.Context, id uuid.UUID) (*models.Todo, error) {
+	return e.openOne(e.Store.GetTodoByID(ctx, id))
+}
+
+func (e *Encrypted) GetTodoByPrefix(ctx context.Context, prefix string) (*models.Todo, error) {
+	return e.openOne(e.Store.GetTodoByPrefix(ctx, prefix))
+}
+
+func (e *Encrypted) ResolveTodoRef(ctx context.Context, ref string) (*models.Todo, error) {
+	return e.openOne(e.Store.ResolveTodoRef(ctx, ref))
+}
+
+func (e *Encrypted) ListTodos(ctx context.Context, projectID *uuid.UUID, done *bool, priority *string, tag *string, branch *string) ([]*models.Todo, error) {
+	todos, err := e.Store.ListTodos(ctx, projectID, done, priority, tag, branch)
+	if err != nil {
+		return nil, err
+	}
+	return todos, e.openAll(todos)
+}
+
+func (e *Encrypted) QueryTodos(ctx context.Context, expr filter.Node) ([]*models.Todo, error) {
+	if e.filtersText(expr) {
+		todos, _, err := e.queryText(ctx, expr)
+		return todos, err
+	}
+	todos, err := e.Store.QueryTodos(ctx, expr)
+	if err != nil {
+		return nil, err
+	}
+	return todos, e.openAll(todos)
+}
+
+func (e *Encrypted) QueryTodosWithTags(ctx context.Context, expr filter.Node) ([]*models.Todo, map[uuid.UUID][]*models.Tag, error) {
+	if e.filtersText(expr) {
+		return e.queryText(ctx, expr)
+	}
+	todos, todoTags, err := e.Store.QueryTodosWithTags(ctx, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return todos, todoTags, e.openAll(todos)
+}
+
+func (e *Encrypted) QueryTodosPage(ctx context.Context, expr filter.Node, order db.Order, page db.Page) ([]*models.Todo, map[uuid.UUID][]*models.Tag, string, error) {
+	if e.filtersText(expr) {
+		todos, todoTags, err := e.queryText(ctx, expr)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		todos, next, err := pageInOrder(todos, todoTags, order, page)
+		return todos, todoTags, next, err
+	}
+	todos, todoTags, next, err := e.Store.QueryTodosPage(ctx, expr, order, page)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return todos, todoTags, next, e.openAll(todos)
+}
+
+func (e *Encrypted) CountTodos(ctx context.Context, expr filter.Node) (int, error) {
+	if e.filtersText(expr) {
+		todos, _, err := e.queryText(ctx, expr)
+		return len(todos), err
+	}
+	return e.Store.CountTodos(ctx, expr)
+}
+
+// filtersText reports whether expr tests todo text, which the wrapped Store only has
+// encrypted.
+func (e *Encrypted) filtersText(expr filter.Node) bool {
+	return e.key != nil && expr != nil && filter.References(expr, filter.FieldText)
+}
+
+// queryText evaluates expr on every todo after decrypting it, newest first.
+func (e *Encrypted) queryText(ctx context.Context, expr filter.Node) ([]*models.Todo, map[uuid.UUID][]*models.Tag, error) {
+	todos, todoTags, err := e.Store.QueryTodosWithTags(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	projects, err := e.Store.GetProjectMap(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := make(map[string]string, len(projects))
+	for id, project := range projects {
+		names[id.String()] = project.Name
+	}
+	matcher := &matcher{
+		now:      time.Now(),
+		projects: names,
+		tags: func(todo *models.Todo) []string {
+			var tags []string
+			for _, tag := range todoTags[todo.ID] {
+				tags = append(tags, tag.Name)
+			}
+			return tags
+		},
+	}
+
+	var matched []*models.Todo
+	for _, todo := range todos {
+		if err := open(e.key, todo); err != nil {
+			return nil, nil, err
+		}
+		ok, err := matcher.match(expr, todo)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			matched = append(matched, todo)
+		}
+	}
+	return matched, todoTags, nil
+}
+
+// Rekey re-encrypts the text of every todo in s, which must not be an Encrypted store,
+// from the key from to the key to, in one transaction. A nil from reads only
+// unencrypted text, and a nil to decrypts. Returns the number of todos rewritten;
+// todos already encrypted with to are skipped.
+func Rekey(ctx context.Context, s Store, from, to *secret.Key) (int, error) {
+	rewritten := 0
+	err := s.Tx(ctx, func(tx Store) error {
+		todos, err := tx.QueryTodos(ctx, nil)
+		if err != nil {
+			return err
+		}
+		for _, todo := range todos {
+			// Text already as it should end up is left alone
+			if sealedWith(todo, to) {
+				continue
+			}
+			if err := open(from, todo); err != nil {
+				return err
+			}
+			if err := tx.UpdateTodo(ctx, seal(to, todo)); err != nil {
+				return err
+			}
+			rewritten++
+		}
+		return nil
+	})
+	return rewritten, err
+}
+
+// sealedWith reports whether the text of todo is encrypted with key, or with a nil key,
+// not encrypted at all.
+func sealedWith(todo *models.Todo, key *secret.Key) bool {
+	sealed := func(value string) bool {
+		if key == nil {
+			return !secret.IsSealed(value)
+		}
+		return secret.IsSealed(value) && secret.SealedKeyID(value) == key.ID()
+	}
+	return sealed(todo.Description) && (todo.Notes == nil || sealed(*todo.Notes))
+}
@@ -0,0 +1,166 @@
+// ABOUTME: Tests for the encrypting Store wrapper
+// ABOUTME: Checks text is unreadable underneath, missing keys fail clearly, and keys rotate
+
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/secret"
+)
+
+func newKey(t *testing.T) *secret.Key {
+	t.Helper()
+	key, err := secret.ParseKey(secret.GenerateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncryptedStoresCiphertext(t *testing.T) {
+	database, err := db.InitDB(t.Context(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+
+	plain := NewSQLite(database)
+	key := newKey(t)
+	encrypted := NewEncrypted(plain, key)
+
+	project := models.NewProject("clients", nil)
+	if err := encrypted.CreateProject(t.Context(), project); err != nil {
+		t.Fatal(err)
+	}
+	todo := models.NewTodo(project.ID, "Call ACME about the outage")
+	notes := "Incident 42: database down"
+	todo.Notes = &notes
+	if err := encrypted.CreateTodo(t.Context(), todo); err != nil {
+		t.Fatal(err)
+	}
+	if todo.Description != "Call ACME about the outage" {
+		t.Errorf("Expected the caller's todo left decrypted, got %q", todo.Description)
+	}
+
+	var stored, storedNotes string
+	if err := database.QueryRow(`SELECT description, notes FROM todos`).Scan(&stored, &storedNotes); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(stored, "ACME") || strings.Contains(storedNotes, "Incident") {
+		t.Errorf("Expected ciphertext in the database, got %q and %q", stored, storedNotes)
+	}
+
+	got, err := encrypted.ResolveTodoRef(t.Context(), todo.ID.String()[:8])
+	if err != nil || got.Description != todo.Description || *got.Notes != notes {
+		t.Errorf("Expected the decrypted todo, got %+v (%v)", got, err)
+	}
+
+	// Without the key, or with another one, reads fail instead of showing ciphertext
+	if _, err := NewEncrypted(plain, nil).GetTodoByID(t.Context(), todo.ID); !errors.Is(err, secret.ErrNoKey) {
+		t.Errorf("Expected ErrNoKey, got %v", err)
+	}
+	var wrongKey *secret.WrongKeyError
+	if _, err := NewEncrypted(plain, newKey(t)).ListTodos(t.Context(), nil, nil, nil, nil, nil); !errors.As(err, &wrongKey) {
+		t.Errorf("Expected WrongKeyError, got %v", err)
+	}
+}
+
+func TestRekey(t *testing.T) {
+	database, err := db.InitDB(t.Context(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+	plain := NewSQLite(database)
+
+	project := models.NewProject("p", nil)
+	if err := plain.CreateProject(t.Context(), project); err != nil {
+		t.Fatal(err)
+	}
+	before := models.NewTodo(project.ID, "written before encryption")
+	if err := plain.CreateTodo(t.Context(), before); err != nil {
+		t.Fatal(err)
+	}
+
+	// Encrypting existing text
+	oldKey := newKey(t)
+	if n, err := Rekey(t.Context(), plain, nil, oldKey); err != nil || n != 1 {
+		t.Fatalf("Rekey() = %d, %v", n, err)
+	}
+	if n, err := Rekey(t.Context(), plain, oldKey, oldKey); err != nil || n != 0 {
+		t.Errorf("Expected todos already encrypted to be skipped, got %d (%v)", n, err)
+	}
+	if _, err := NewEncrypted(plain, nil).GetTodoByID(t.Context(), before.ID); !errors.Is(err, secret.ErrNoKey) {
+		t.Errorf("Expected text encrypted, got %v", err)
+	}
+
+	// Rotating to a new key
+	newKey := newKey(t)
+	if _, err := Rekey(t.Context(), plain, newKey, newKey); err == nil {
+		t.Error("Expected rekeying from the wrong key to fail")
+	}
+	if _, err := Rekey(t.Context(), plain, oldKey, newKey); err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewEncrypted(plain, newKey).GetTodoByID(t.Context(), before.ID)
+	if err != nil || got.Description != before.Description {
+		t.Errorf("Expected the todo readable with the new key, got %v (%v)", got, err)
+	}
+	if _, err := NewEncrypted(plain, oldKey).GetTodoByID(t.Context(), before.ID); err == nil {
+		t.Error("Expected the old key to stop working")
+	}
+
+	// Decrypting
+	if _, err := Rekey(t.Context(), plain, newKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := plain.GetTodoByID(t.Context(), before.ID); err != nil || got.Description != before.Description {
+		t.Errorf("Expected plain text back, got %v (%v)", got, err)
+	}
+	if n, err := Rekey(t.Context(), plain, nil, nil); err != nil || n != 0 {
+		t.Errorf("Expected nothing left to decrypt, got %d (%v)", n, err)
+	}
+}
+
+func TestEncryptedCodeCommentSync(t *testing.T) {
+	database, err := db.InitDB(t.Context(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+	key := newKey(t)
+	encrypted := NewEncrypted(NewSQLite(database), key)
+
+	project := models.NewProject("p", nil)
+	if err := encrypted.CreateProject(t.Context(), project); err != nil {
+		t.Fatal(err)
+	}
+	comment := &models.CodeComment{Fingerprint: "fp", File: "main.go", Line: 3, Marker: "TODO", Text: "rotate ACME credentials"}
+	if _, err := SyncCodeComments(t.Context(), encrypted, project.ID, []*models.CodeComment{comment}); err != nil {
+		t.Fatal(err)
+	}
+
+	var stored, storedNotes string
+	if err := database.QueryRow(`SELECT description, notes FROM todos`).Scan(&stored, &storedNotes); err != nil {
+		t.Fatal(err)
+	}
+	if !secret.IsSealed(stored) || !secret.IsSealed(storedNotes) {
+		t.Errorf("Expected scanned todos encrypted, got %q and %q", stored, storedNotes)
+	}
+
+	// Generated notes still follow the comment when it moves
+	moved := &models.CodeComment{Fingerprint: "fp", File: "main.go", Line: 9, Marker: "TODO", Text: comment.Text}
+	if _, err := SyncCodeComments(t.Context(), encrypted, project.ID, []*models.CodeComment{moved}); err != nil {
+		t.Fatal(err)
+	}
+	todos, err := encrypted.ListTodos(t.Context(), nil, nil, nil, nil, nil)
+	if err != nil || len(todos) != 1 || todos[0].Notes == nil || *todos[0].Notes != "TODO at main.go:9" {
+		t.Errorf("Expected notes refreshed to the new line, got %+v (%v)", todos, err)
+	}
+}
@@ -0,0 +1,205 @@
+// ABOUTME: Evaluates filter expressions against todos in memory
+// ABOUTME: Mirrors the SQL compiled by package db, where a missing value never matches
+
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+)
+
+// matcher evaluates expressions for the Memory store. Like the SQL in package db, a test
+// on a missing value is false and not of it is true, and times compare to the second.
+type matcher struct {
+	now      time.Time
+	projects map[string]string // project ID to name
+	tags     func(todo *models.Todo) []string
+	// resolved caches the project ID each project term names.
+	resolved map[string]string
+}
+
+func (m *matcher) match(n filter.Node, todo *models.Todo) (bool, error) {
+	switch n := n.(type) {
+	case nil:
+		return true, nil
+	case filter.And:
+		left, err := m.match(n.Left, todo)
+		if err != nil || !left {
+			return false, err
+		}
+		return m.match(n.Right, todo)
+	case filter.Or:
+		left, err := m.match(n.Left, todo)
+		if err != nil || left {
+			return left, err
+		}
+		return m.match(n.Right, todo)
+	case filter.Not:
+		matched, err := m.match(n.X, todo)
+		return !matched && err == nil, err
+	case filter.Term:
+		if n.Op == filter.OpNe {
+			positive := n
+			positive.Op = filter.OpMatch
+			matched, err := m.matchTerm(positive, todo)
+			return !matched && err == nil, err
+		}
+		return m.matchTerm(n, todo)
+	default:
+		return false, fmt.Errorf("unsupported filter node %T", n)
+	}
+}
+
+// resolveProject returns the ID of the one project a project term names, like the SQL:
+// the project with that ID, else the one with that name, preferring the exact case and
+// then the first name in byte order. It returns "" when no project matches.
+func (m *matcher) resolveProject(value string) string {
+	if id, ok := m.resolved[value]; ok {
+		return id
+	}
+
+	var found, foundName string
+	if _, ok := m.projects[value]; ok {
+		found = value
+	}
+	for id, name := range m.projects {
+		if found == value || !strings.EqualFold(name, value) {
+			continue
+		}
+		exact, foundExact := name == value, foundName == value
+		if found == "" || (exact && !foundExact) || (exact == foundExact && name < foundName) {
+			found, foundName = id, name
+		}
+	}
+
+	if m.resolved == nil {
+		m.resolved = make(map[string]string)
+	}
+	m.resolved[value] = found
+	return found
+}
+
+func (m *matcher) matchTerm(term filter.Term, todo *models.Todo) (bool, error) {
+	value := term.Value
+
+	switch term.Field {
+	case filter.FieldProject:
+		return todo.ProjectID.String() == m.resolveProject(value), nil
+	case filter.FieldTag:
+		tags := m.tags(todo)
+		switch value {
+		case filter.ValueNone:
+			return len(tags) == 0, nil
+		case filter.ValueAny:
+			return len(tags) > 0, nil
+		}
+		for _, tag := range tags {
+			if tag == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	case filter.FieldBranch:
+		return matchOptional(todo.Branch, value), nil
+	case filter.FieldID:
+		return strings.HasPrefix(todo.ID.String(), value), nil
+	case filter.FieldText:
+		if term.Op == filter.OpEq {
+			return strings.EqualFold(todo.Description, value), nil
+		}
+		needle := strings.ToLower(value)
+		notes := ""
+		if todo.Notes != nil {
+			notes = *todo.Notes
+		}
+		return strings.Contains(strings.ToLower(todo.Description), needle) || strings.Contains(strings.ToLower(notes), needle), nil
+	case filter.FieldDone:
+		return todo.Done == (value == "true"), nil
+	case filter.FieldOverdue:
+		overdue := !todo.Done && todo.DueDate != nil && todo.DueDate.Unix() < m.now.Unix()
+		return overdue == (value == "true"), nil
+	case filter.FieldPriority:
+		if term.Op == filter.OpMatch || term.Op == filter.OpEq {
+			return matchOptional(todo.Priority, value), nil
+		}
+		if todo.Priority == nil || filter.PriorityRank(*todo.Priority) == 0 {
+			return false, nil
+		}
+		return compare(term.Op, int64(filter.PriorityRank(*todo.Priority)), int64(filter.PriorityRank(value))), nil
+	case filter.FieldDue:
+		return m.matchDate(todo.DueDate, term.Op, value)
+	case filter.FieldCreated:
+		return m.matchDate(&todo.CreatedAt, term.Op, value)
+	case filter.FieldUpdated:
+		return m.matchDate(&todo.UpdatedAt, term.Op, value)
+	case filter.FieldCompleted:
+		return m.matchDate(todo.CompletedAt, term.Op, value)
+	}
+	return false, fmt.Errorf("unsupported filter field '%s'", term.Field)
+}
+
+// matchOptional handles none and any for optional fields, else compares with value.
+func matchOptional(field *string, value string) bool {
+	switch value {
+	case filter.ValueNone:
+		return field == nil
+	case filter.ValueAny:
+		return field != nil
+	}
+	return field != nil && *field == value
+}
+
+// matchDate compares a time with the day range a value covers, like compileDate.
+func (m *matcher) matchDate(at *time.Time, op filter.Op, value string) (bool, error) {
+	if op == filter.OpMatch || op == filter.OpEq {
+		switch value {
+		case filter.ValueNone:
+			return at == nil, nil
+		case filter.ValueAny:
+			return at != nil, nil
+		}
+	}
+
+	start, end, err := filter.ResolveDate(value, m.now)
+	if err != nil {
+		return false, err
+	}
+	if at == nil {
+		return false, nil
+	}
+
+	seconds := at.Unix()
+	switch op {
+	case filter.OpMatch, filter.OpEq:
+		return seconds >= start.Unix() && seconds < end.Unix(), nil
+	case filter.OpLt:
+		return seconds < start.Unix(), nil
+	case filter.OpLe:
+		return seconds < end.Unix(), nil
+	case filter.OpGt:
+		return seconds >= end.Unix(), nil
+	case filter.OpGe:
+		return seconds >= start.Unix(), nil
+	default:
+		return false, fmt.Errorf("unsupported operator '%s' for dates", op)
+	}
+}
+
+func compare(op filter.Op, a, b int64) bool {
+	switch op {
+	case filter.OpLt:
+		return a < b
+	case filter.OpLe:
+		return a <= b
+	case filter.OpGt:
+		return a > b
+	case filter.OpGe:
+		return a >= b
+	default:
+		return a == b
+	}
+}
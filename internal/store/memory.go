@@ -0,0 +1,1021 @@
+// ABOUTME: In-memory Store for tests and throwaway sessions
+// ABOUTME: Keeps copies of records in maps and follows the SQLite store's semantics
+
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/sorting"
+)
+
+// Memory is a Store kept in memory. It is safe for concurrent use, but a failed
+// transaction also undoes changes made outside it meanwhile. Its pages are offsets
+// rather than keysets, so todos added between pages can shift them. Its operations are
+// too quick to cancel, so only Tx checks its context.
+type Memory struct {
+	// txMu serializes transactions; mu guards data.
+	txMu sync.Mutex
+	mu   sync.Mutex
+	data memoryData
+}
+
+var _ Store = (*Memory)(nil)
+
+// memoryData holds copies of the stored records, so callers changing the records they
+// pass in or get back don't change the store.
+type memoryData struct {
+	projects map[uuid.UUID]models.Project
+	// paths maps additional project directories to their project.
+	paths     map[string]uuid.UUID
+	todos     map[uuid.UUID]models.Todo
+	tags      map[string]models.Tag
+	nextTagID int64
+	// todoTags holds the names of each todo's tags.
+	todoTags map[uuid.UUID]map[string]bool
+	// timeEntries are in the order they were added.
+	timeEntries     []models.TimeEntry
+	nextTimeEntryID int64
+	views           map[string]models.View
+	// commits are each todo's commits in the order they were added.
+	commits map[uuid.UUID][]models.TodoCommit
+	// codeComments are each project's code comments by fingerprint.
+	codeComments map[uuid.UUID]map[string]models.CodeComment
+}
+
+// NewMemory returns an empty in-memory Store.
+func NewMemory() *Memory {
+	return &Memory{data: memoryData{
+		projects:  make(map[uuid.UUID]models.Project),
+		paths:     make(map[string]uuid.UUID),
+		todos:     make(map[uuid.UUID]models.Todo),
+		tags:      make(map[string]models.Tag),
+		nextTagID: 1,
+		todoTags:  make(map[uuid.UUID]map[string]bool),
+
+		nextTimeEntryID: 1,
+		views:           make(map[string]models.View),
+		commits:         make(map[uuid.UUID][]models.TodoCommit),
+		codeComments:    make(map[uuid.UUID]map[string]models.CodeComment),
+	}}
+}
+
+func (d memoryData) clone() memoryData {
+	clone := d
+	clone.projects = maps.Clone(d.projects)
+	clone.paths = maps.Clone(d.paths)
+	clone.todos = maps.Clone(d.todos)
+	clone.tags = maps.Clone(d.tags)
+	clone.todoTags = make(map[uuid.UUID]map[string]bool, len(d.todoTags))
+	for id, names := range d.todoTags {
+		clone.todoTags[id] = maps.Clone(names)
+	}
+	clone.timeEntries = slices.Clone(d.timeEntries)
+	clone.views = maps.Clone(d.views)
+	clone.commits = make(map[uuid.UUID][]models.TodoCommit, len(d.commits))
+	for id, commits := range d.commits {
+		clone.commits[id] = slices.Clone(commits)
+	}
+	clone.codeComments = make(map[uuid.UUID]map[string]models.CodeComment, len(d.codeComments))
+	for id, comments := range d.codeComments {
+		clone.codeComments[id] = maps.Clone(comments)
+	}
+	return clone
+}
+
+// memoryTx is the Store passed to a Memory transaction, which is already serialized.
+type memoryTx struct {
+	*Memory
+}
+
+// Tx implements Store.
+func (t memoryTx) Tx(ctx context.Context, fn func(Store) error) error {
+	return fn(t)
+}
+
+// Tx implements Store by restoring a snapshot of the data when fn fails.
+func (m *Memory) Tx(ctx context.Context, fn func(Store) error) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	m.mu.Lock()
+	snapshot := m.data.clone()
+	m.mu.Unlock()
+
+	if err := fn(memoryTx{m}); err != nil {
+		m.mu.Lock()
+		m.data = snapshot
+		m.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (m *Memory) CreateProject(ctx context.Context, project *models.Project) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.data.projects {
+		if existing.ID == project.ID || existing.Name == project.Name {
+			return fmt.Errorf("failed to create project: project '%s' already exists", project.Name)
+		}
+	}
+	m.data.projects[project.ID] = *project
+	return nil
+}
+
+func (m *Memory) GetProjectByID(ctx context.Context, id uuid.UUID) (*models.Project, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	project, ok := m.data.projects[id]
+	if !ok {
+		return nil, fmt.Errorf("project %w", db.ErrNotFound)
+	}
+	return &project, nil
+}
+
+func (m *Memory) GetProjectByName(ctx context.Context, name string) (*models.Project, error) {
+	return m.findProject(func(project *models.Project) bool { return project.Name == name })
+}
+
+func (m *Memory) GetProjectByPath(ctx context.Context, path string) (*models.Project, error) {
+	project, err := m.findProject(func(project *models.Project) bool {
+		return project.DirectoryPath != nil && *project.DirectoryPath == path
+	})
+	if err == nil {
+		return project, nil
+	}
+
+	m.mu.Lock()
+	id, ok := m.data.paths[path]
+	m.mu.Unlock()
+	if !ok {
+		return nil, err
+	}
+	return m.GetProjectByID(ctx, id)
+}
+
+func (m *Memory) GetProjectByRemote(ctx context.Context, remoteURL string) (*models.Project, error) {
+	return m.findProject(func(project *models.Project) bool {
+		return project.RemoteURL != nil && *project.RemoteURL == remoteURL
+	})
+}
+
+// findProject returns the oldest project for which match is true.
+func (m *Memory) findProject(match func(*models.Project) bool) (*models.Project, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var found *models.Project
+	for _, project := range m.data.projects {
+		if match(&project) && (found == nil || project.CreatedAt.Before(found.CreatedAt)) {
+			found = &project
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("project %w", db.ErrNotFound)
+	}
+	return found, nil
+}
+
+func (m *Memory) FindProjectForDir(ctx context.Context, dir string, opts git.Options) (*models.Project, *git.Repo, error) {
+	repo, err := git.DetectRepo(dir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if repo.RemoteURL != "" {
+		if project, err := m.GetProjectByRemote(ctx, repo.RemoteURL); err == nil {
+			// Like db.FindProjectForDir, only record a location no project has yet
+			if _, err := m.GetProjectByPath(ctx, repo.Root); errors.Is(err, db.ErrNotFound) {
+				if err := m.AddProjectPath(ctx, project.ID, repo.Root); err != nil {
+					return nil, repo, err
+				}
+			}
+			return project, repo, nil
+		}
+	}
+
+	project, err := m.GetProjectByPath(ctx, repo.Root)
+	if err != nil {
+		return nil, repo, err
+	}
+	return project, repo, nil
+}
+
+func (m *Memory) ResolveProjectRef(ctx context.Context, ref string) (*models.Project, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, fmt.Errorf("project reference is required")
+	}
+
+	if id, err := uuid.Parse(ref); err == nil {
+		return m.GetProjectByID(ctx, id)
+	}
+	if project, err := m.GetProjectByName(ctx, ref); err == nil {
+		return project, nil
+	}
+	if db.LooksLikePath(ref) {
+		normalized, err := git.NormalizePath(ref)
+		if err != nil {
+			return nil, err
+		}
+		if project, err := m.GetProjectByPath(ctx, normalized); err == nil {
+			return project, nil
+		}
+	}
+
+	if len(ref) >= db.MinPrefixLength && db.IsUUIDPrefix(ref) {
+		projects, err := m.ListProjects(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var candidates []db.RefCandidate
+		var match *models.Project
+		for _, project := range projects {
+			if strings.HasPrefix(project.ID.String(), strings.ToLower(ref)) {
+				match = project
+				candidates = append(candidates, db.RefCandidate{ID: project.ID.String(), Label: project.Name})
+			}
+		}
+		switch len(candidates) {
+		case 0:
+			return nil, fmt.Errorf("project %w with prefix: %s", db.ErrNotFound, ref)
+		case 1:
+			return match, nil
+		default:
+			return nil, &db.AmbiguousRefError{Kind: "project", Ref: ref, Candidates: candidates}
+		}
+	}
+
+	return nil, fmt.Errorf("project %w: %s", db.ErrNotFound, ref)
+}
+
+func (m *Memory) ListProjects(ctx context.Context) ([]*models.Project, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	projects := make([]*models.Project, 0, len(m.data.projects))
+	for _, project := range m.data.projects {
+		projects = append(projects, &project)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	return projects, nil
+}
+
+func (m *Memory) GetProjectMap(ctx context.Context) (map[uuid.UUID]*models.Project, error) {
+	projects, err := m.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	projectMap := make(map[uuid.UUID]*models.Project, len(projects))
+	for _, project := range projects {
+		projectMap[project.ID] = project
+	}
+	return projectMap, nil
+}
+
+func (m *Memory) UpdateProjectPath(ctx context.Context, id uuid.UUID, path *string) error {
+	return m.updateProject(id, func(project *models.Project) { project.DirectoryPath = path })
+}
+
+func (m *Memory) UpdateProjectRemote(ctx context.Context, id uuid.UUID, remoteURL *string) error {
+	return m.updateProject(id, func(project *models.Project) { project.RemoteURL = remoteURL })
+}
+
+// updateProject applies update to a stored project; a missing project is not an error.
+func (m *Memory) updateProject(id uuid.UUID, update func(*models.Project)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if project, ok := m.data.projects[id]; ok {
+		update(&project)
+		m.data.projects[id] = project
+	}
+	return nil
+}
+
+func (m *Memory) AddProjectPath(ctx context.Context, id uuid.UUID, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	project, ok := m.data.projects[id]
+	if !ok {
+		return fmt.Errorf("failed to add project path: project %w", db.ErrNotFound)
+	}
+	if project.DirectoryPath == nil || *project.DirectoryPath != path {
+		m.data.paths[path] = id
+	}
+	return nil
+}
+
+func (m *Memory) RemoveProjectPath(ctx context.Context, id uuid.UUID, path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.data.paths[path] == id {
+		delete(m.data.paths, path)
+	}
+	return nil
+}
+
+func (m *Memory) ListProjectPaths(ctx context.Context, id uuid.UUID) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var paths []string
+	for path, projectID := range m.data.paths {
+		if projectID == id {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func (m *Memory) DeleteProject(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data.projects, id)
+	for path, projectID := range m.data.paths {
+		if projectID == id {
+			delete(m.data.paths, path)
+		}
+	}
+	for todoID, todo := range m.data.todos {
+		if todo.ProjectID == id {
+			m.deleteTodoLocked(todoID)
+		}
+	}
+	delete(m.data.codeComments, id)
+	return nil
+}
+
+func (m *Memory) CreateTodo(ctx context.Context, todo *models.Todo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.createTodoLocked(todo)
+}
+
+func (m *Memory) CreateTodoWithTags(ctx context.Context, todo *models.Todo, tagNames []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.createTodoLocked(todo); err != nil {
+		return err
+	}
+	for _, name := range tagNames {
+		m.addTagLocked(todo.ID, name)
+	}
+	return nil
+}
+
+func (m *Memory) createTodoLocked(todo *models.Todo) error {
+	if _, ok := m.data.projects[todo.ProjectID]; !ok {
+		return fmt.Errorf("failed to create todo: project %w", db.ErrNotFound)
+	}
+	if _, ok := m.data.todos[todo.ID]; ok {
+		return fmt.Errorf("failed to create todo: todo %s already exists", todo.ID)
+	}
+	m.data.todos[todo.ID] = *todo
+	return nil
+}
+
+func (m *Memory) GetTodoByID(ctx context.Context, id uuid.UUID) (*models.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	todo, ok := m.data.todos[id]
+	if !ok {
+		return nil, fmt.Errorf("todo %w", db.ErrNotFound)
+	}
+	return &todo, nil
+}
+
+func (m *Memory) GetTodoByPrefix(ctx context.Context, prefix string) (*models.Todo, error) {
+	if len(prefix) < db.MinPrefixLength {
+		return nil, fmt.Errorf("prefix must be at least %d characters", db.MinPrefixLength)
+	}
+	if !db.IsUUIDPrefix(prefix) {
+		return nil, fmt.Errorf("todo %w with prefix: %s", db.ErrNotFound, prefix)
+	}
+
+	matches := m.todosWhere(func(todo *models.Todo) bool {
+		return strings.HasPrefix(todo.ID.String(), strings.ToLower(prefix))
+	})
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("todo %w with prefix: %s", db.ErrNotFound, prefix)
+	case 1:
+		return matches[0], nil
+	}
+
+	candidates := make([]db.RefCandidate, len(matches))
+	for i, todo := range matches {
+		candidates[i] = db.RefCandidate{ID: todo.ID.String(), Label: todo.Description}
+	}
+	return nil, &db.AmbiguousRefError{Kind: "todo", Ref: prefix, Candidates: candidates}
+}
+
+func (m *Memory) ResolveTodoRef(ctx context.Context, ref string) (*models.Todo, error) {
+	ref = strings.TrimSpace(ref)
+	if id, err := uuid.Parse(ref); err == nil {
+		return m.GetTodoByID(ctx, id)
+	}
+	return m.GetTodoByPrefix(ctx, ref)
+}
+
+func (m *Memory) ListTodos(ctx context.Context, projectID *uuid.UUID, done *bool, priority *string, tag *string, branch *string) ([]*models.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.todosWhereLocked(func(todo *models.Todo) bool {
+		return (projectID == nil || todo.ProjectID == *projectID) &&
+			(done == nil || todo.Done == *done) &&
+			(priority == nil || (todo.Priority != nil && *todo.Priority == *priority)) &&
+			(tag == nil || m.data.todoTags[todo.ID][*tag]) &&
+			(branch == nil || (todo.Branch != nil && *todo.Branch == *branch))
+	}), nil
+}
+
+// todosWhere returns copies of the todos for which match is true, newest first.
+func (m *Memory) todosWhere(match func(*models.Todo) bool) []*models.Todo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.todosWhereLocked(match)
+}
+
+func (m *Memory) todosWhereLocked(match func(*models.Todo) bool) []*models.Todo {
+	var todos []*models.Todo
+	for _, todo := range m.data.todos {
+		if match(&todo) {
+			todos = append(todos, &todo)
+		}
+	}
+	sort.Slice(todos, func(i, j int) bool {
+		if !todos[i].CreatedAt.Equal(todos[j].CreatedAt) {
+			return todos[i].CreatedAt.After(todos[j].CreatedAt)
+		}
+		return todos[i].ID.String() > todos[j].ID.String()
+	})
+	return todos
+}
+
+func (m *Memory) QueryTodos(ctx context.Context, expr filter.Node) ([]*models.Todo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	projects := make(map[string]string, len(m.data.projects))
+	for id, project := range m.data.projects {
+		projects[id.String()] = project.Name
+	}
+	matcher := &matcher{
+		now:      time.Now(),
+		projects: projects,
+		tags: func(todo *models.Todo) []string {
+			return slices.Collect(maps.Keys(m.data.todoTags[todo.ID]))
+		},
+	}
+
+	var matchErr error
+	todos := m.todosWhereLocked(func(todo *models.Todo) bool {
+		matched, err := matcher.match(expr, todo)
+		if err != nil {
+			matchErr = err
+		}
+		return matched
+	})
+	if matchErr != nil {
+		return nil, matchErr
+	}
+	return todos, nil
+}
+
+func (m *Memory) QueryTodosWithTags(ctx context.Context, expr filter.Node) ([]*models.Todo, map[uuid.UUID][]*models.Tag, error) {
+	todos, err := m.QueryTodos(ctx, expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	todoTags, err := m.GetTagsForTodos(ctx, todos)
+	if err != nil {
+		return nil, nil, err
+	}
+	return todos, todoTags, nil
+}
+
+func (m *Memory) QueryTodosPage(ctx context.Context, expr filter.Node, order db.Order, page db.Page) ([]*models.Todo, map[uuid.UUID][]*models.Tag, string, error) {
+	todos, todoTags, err := m.QueryTodosWithTags(ctx, expr)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	todos, next, err := pageInOrder(todos, todoTags, order, page)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return todos, todoTags, next, nil
+}
+
+func (m *Memory) CountTodos(ctx context.Context, expr filter.Node) (int, error) {
+	todos, err := m.QueryTodos(ctx, expr)
+	return len(todos), err
+}
+
+// pageInOrder sorts todos, newest first, into order and returns the page of them. Its
+// cursors are offsets, for stores that hold every todo at hand anyway.
+func pageInOrder(todos []*models.Todo, todoTags map[uuid.UUID][]*models.Tag, order db.Order, page db.Page) ([]*models.Todo, string, error) {
+	if order.Key != "" {
+		if order.Now.IsZero() {
+			order.Now = time.Now()
+		}
+		sorting.Sort(todos, order.Key, order.Reverse, func(todo *models.Todo) float64 {
+			return sorting.Urgency(todo, todoTags[todo.ID], order.Urgency, order.Now)
+		})
+	}
+	return db.PageSlice(todos, page)
+}
+
+func (m *Memory) UpdateTodo(ctx context.Context, todo *models.Todo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.data.todos[todo.ID]
+	if !ok {
+		return nil
+	}
+	updated := *todo
+	updated.ProjectID = stored.ProjectID
+	updated.CreatedAt = stored.CreatedAt
+	m.data.todos[todo.ID] = updated
+	return nil
+}
+
+func (m *Memory) DeleteTodo(ctx context.Context, id uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.deleteTodoLocked(id)
+	return nil
+}
+
+// deleteTodoLocked deletes a todo along with its tags, time entries, commits, and
+// code comment.
+func (m *Memory) deleteTodoLocked(id uuid.UUID) {
+	todo := m.data.todos[id]
+	delete(m.data.todos, id)
+	delete(m.data.todoTags, id)
+	m.data.timeEntries = slices.DeleteFunc(m.data.timeEntries, func(e models.TimeEntry) bool {
+		return e.TodoID == id
+	})
+	delete(m.data.commits, id)
+	maps.DeleteFunc(m.data.codeComments[todo.ProjectID], func(_ string, c models.CodeComment) bool {
+		return c.TodoID == id
+	})
+}
+
+func (m *Memory) ListTodoBranches(ctx context.Context, projectID uuid.UUID) ([]string, error) {
+	branches := make(map[string]bool)
+	for _, todo := range m.pendingBranchTodos(projectID, nil) {
+		branches[*todo.Branch] = true
+	}
+	return slices.Sorted(maps.Keys(branches)), nil
+}
+
+func (m *Memory) CompleteBranchTodos(ctx context.Context, projectID uuid.UUID, branch string) (int64, error) {
+	now := time.Now()
+	todos := m.pendingBranchTodos(projectID, &branch)
+	for _, todo := range todos {
+		todo.Done = true
+		todo.CompletedAt = &now
+		todo.UpdatedAt = now
+		if err := m.UpdateTodo(ctx, todo); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(todos)), nil
+}
+
+func (m *Memory) MoveBranchTodos(ctx context.Context, projectID uuid.UUID, from string, to *string) (int64, error) {
+	todos := m.pendingBranchTodos(projectID, &from)
+	for _, todo := range todos {
+		todo.Branch = to
+		todo.UpdatedAt = time.Now()
+		if err := m.UpdateTodo(ctx, todo); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(todos)), nil
+}
+
+// pendingBranchTodos returns a project's pending todos on branch, or on any branch when
+// branch is nil.
+func (m *Memory) pendingBranchTodos(projectID uuid.UUID, branch *string) []*models.Todo {
+	return m.todosWhere(func(todo *models.Todo) bool {
+		return todo.ProjectID == projectID && !todo.Done && todo.Branch != nil &&
+			(branch == nil || *todo.Branch == *branch)
+	})
+}
+
+func (m *Memory) GetOrCreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tag := m.getOrCreateTagLocked(name)
+	return &tag, nil
+}
+
+func (m *Memory) getOrCreateTagLocked(name string) models.Tag {
+	tag, ok := m.data.tags[name]
+	if !ok {
+		tag = models.Tag{ID: m.data.nextTagID, Name: name}
+		m.data.tags[name] = tag
+		m.data.nextTagID++
+	}
+	return tag
+}
+
+func (m *Memory) AddTagToTodo(ctx context.Context, todoID uuid.UUID, tagName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data.todos[todoID]; !ok {
+		return fmt.Errorf("failed to add tag to todo: todo %w", db.ErrNotFound)
+	}
+	m.addTagLocked(todoID, tagName)
+	return nil
+}
+
+// addTagLocked tags a todo that exists.
+func (m *Memory) addTagLocked(todoID uuid.UUID, tagName string) {
+	m.getOrCreateTagLocked(tagName)
+	if m.data.todoTags[todoID] == nil {
+		m.data.todoTags[todoID] = make(map[string]bool)
+	}
+	m.data.todoTags[todoID][tagName] = true
+}
+
+func (m *Memory) SetTodoTags(ctx context.Context, todoID uuid.UUID, tagNames []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data.todos[todoID]; !ok && len(tagNames) > 0 {
+		return fmt.Errorf("failed to add tag to todo: todo %w", db.ErrNotFound)
+	}
+	delete(m.data.todoTags, todoID)
+	for _, name := range tagNames {
+		m.addTagLocked(todoID, name)
+	}
+	return nil
+}
+
+func (m *Memory) RemoveTagFromTodo(ctx context.Context, todoID uuid.UUID, tagName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.data.todoTags[todoID], tagName)
+	if len(m.data.todoTags[todoID]) == 0 {
+		delete(m.data.todoTags, todoID)
+	}
+	return nil
+}
+
+func (m *Memory) GetTodoTags(ctx context.Context, todoID uuid.UUID) ([]*models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.todoTagsLocked(todoID), nil
+}
+
+func (m *Memory) todoTagsLocked(todoID uuid.UUID) []*models.Tag {
+	var tags []*models.Tag
+	for _, name := range slices.Sorted(maps.Keys(m.data.todoTags[todoID])) {
+		tag := m.data.tags[name]
+		tags = append(tags, &tag)
+	}
+	return tags
+}
+
+func (m *Memory) GetTagsForTodos(ctx context.Context, todos []*models.Todo) (map[uuid.UUID][]*models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	todoTags := make(map[uuid.UUID][]*models.Tag)
+	for _, todo := range todos {
+		if tags := m.todoTagsLocked(todo.ID); len(tags) > 0 {
+			todoTags[todo.ID] = tags
+		}
+	}
+	return todoTags, nil
+}
+
+func (m *Memory) ListAllTags(ctx context.Context) ([]*models.Tag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tags := make([]*models.Tag, 0, len(m.data.tags))
+	for _, name := range slices.Sorted(maps.Keys(m.data.tags)) {
+		tag := m.data.tags[name]
+		tags = append(tags, &tag)
+	}
+	return tags, nil
+}
+
+// activeTimerLocked returns the index of the running timer, or -1.
+func (m *Memory) activeTimerLocked() int {
+	return slices.IndexFunc(m.data.timeEntries, func(e models.TimeEntry) bool { return e.Running() })
+}
+
+func (m *Memory) ActiveTimer(ctx context.Context) (*models.TimeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.activeTimerLocked()
+	if i < 0 {
+		return nil, db.ErrNoTimer
+	}
+	entry := m.data.timeEntries[i]
+	return &entry, nil
+}
+
+func (m *Memory) StartTimer(ctx context.Context, todoID uuid.UUID, now time.Time) (*models.TimeEntry, *models.TimeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data.todos[todoID]; !ok {
+		return nil, nil, fmt.Errorf("failed to start timer: todo %w", db.ErrNotFound)
+	}
+
+	var stopped *models.TimeEntry
+	if i := m.activeTimerLocked(); i >= 0 {
+		active := m.data.timeEntries[i]
+		if active.TodoID == todoID {
+			return nil, nil, fmt.Errorf("a timer is already running for this todo (started %s ago)", active.Elapsed(now).Round(time.Second))
+		}
+		stopped = m.stopTimerLocked(i, now)
+	}
+
+	entry := models.TimeEntry{ID: m.data.nextTimeEntryID, TodoID: todoID, StartedAt: now}
+	m.data.nextTimeEntryID++
+	m.data.timeEntries = append(m.data.timeEntries, entry)
+	return &entry, stopped, nil
+}
+
+func (m *Memory) StopTimer(ctx context.Context, now time.Time) (*models.TimeEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	i := m.activeTimerLocked()
+	if i < 0 {
+		return nil, db.ErrNoTimer
+	}
+	return m.stopTimerLocked(i, now), nil
+}
+
+// stopTimerLocked stops the timer at index i at now and returns a copy of it.
+func (m *Memory) stopTimerLocked(i int, now time.Time) *models.TimeEntry {
+	entry := &m.data.timeEntries[i]
+	entry.Duration = max(now.Sub(entry.StartedAt).Round(time.Second), 0)
+	entry.EndedAt = &now
+	stopped := *entry
+	return &stopped
+}
+
+func (m *Memory) LogTime(ctx context.Context, todoID uuid.UUID, duration time.Duration, now time.Time) (*models.TimeEntry, error) {
+	duration = duration.Round(time.Second)
+	if duration <= 0 {
+		return nil, fmt.Errorf("duration must be at least one second")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data.todos[todoID]; !ok {
+		return nil, fmt.Errorf("failed to log time: todo %w", db.ErrNotFound)
+	}
+	entry := models.TimeEntry{ID: m.data.nextTimeEntryID, TodoID: todoID, StartedAt: now.Add(-duration), EndedAt: &now, Duration: duration}
+	m.data.nextTimeEntryID++
+	m.data.timeEntries = append(m.data.timeEntries, entry)
+	return &entry, nil
+}
+
+func (m *Memory) TrackedTime(ctx context.Context, now time.Time) (map[uuid.UUID]time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totals := make(map[uuid.UUID]time.Duration)
+	for _, entry := range m.data.timeEntries {
+		totals[entry.TodoID] += entry.Elapsed(now)
+	}
+	return totals, nil
+}
+
+func (m *Memory) GetTimeReport(ctx context.Context, from, to, now time.Time) (*models.TimeReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	report := &models.TimeReport{From: from, To: to}
+	projectTotals := make(map[string]time.Duration)
+	tagTotals := make(map[string]time.Duration)
+	for _, entry := range m.data.timeEntries {
+		tracked := entry.TrackedIn(from, to, now)
+		if tracked <= 0 {
+			continue
+		}
+		todo := m.data.todos[entry.TodoID]
+		report.Total += tracked
+		projectTotals[m.data.projects[todo.ProjectID].Name] += tracked
+
+		tags := m.data.todoTags[entry.TodoID]
+		if len(tags) == 0 {
+			tagTotals[""] += tracked
+		}
+		for name := range tags {
+			tagTotals[name] += tracked
+		}
+	}
+
+	report.Projects = models.SortTimeTotals(projectTotals)
+	report.Tags = models.SortTimeTotals(tagTotals)
+	return report, nil
+}
+
+func (m *Memory) SaveView(ctx context.Context, view *models.View) (bool, error) {
+	if err := db.ValidateViewName(view.Name); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, replaced := m.data.views[view.Name]
+	if view.CreatedAt.IsZero() {
+		view.CreatedAt = time.Now()
+	}
+	saved := *view
+	if replaced {
+		saved.CreatedAt = existing.CreatedAt
+	}
+	m.data.views[view.Name] = saved
+	return replaced, nil
+}
+
+func (m *Memory) GetView(ctx context.Context, name string) (*models.View, error) {
+	if view := db.BuiltinView(name); view != nil {
+		return view, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	view, ok := m.data.views[name]
+	if !ok {
+		return nil, fmt.Errorf("no view named '%s' (see 'toki view list')", name)
+	}
+	return &view, nil
+}
+
+func (m *Memory) ListViews(ctx context.Context) ([]*models.View, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	views := db.BuiltinViews()
+	for _, name := range slices.Sorted(maps.Keys(m.data.views)) {
+		view := m.data.views[name]
+		views = append(views, &view)
+	}
+	return views, nil
+}
+
+func (m *Memory) DeleteView(ctx context.Context, name string) error {
+	if db.BuiltinView(name) != nil {
+		return fmt.Errorf("'%s' is a built-in view and cannot be deleted", name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data.views[name]; !ok {
+		return fmt.Errorf("no view named '%s'", name)
+	}
+	delete(m.data.views, name)
+	return nil
+}
+
+func (m *Memory) AddTodoCommit(ctx context.Context, todoID uuid.UUID, sha, action string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data.todos[todoID]; !ok {
+		return false, fmt.Errorf("failed to add todo commit: todo %w", db.ErrNotFound)
+	}
+	if slices.ContainsFunc(m.data.commits[todoID], func(c models.TodoCommit) bool { return c.SHA == sha }) {
+		return false, nil
+	}
+	commit := models.TodoCommit{TodoID: todoID, SHA: sha, Action: action, CreatedAt: time.Now()}
+	m.data.commits[todoID] = append(m.data.commits[todoID], commit)
+	return true, nil
+}
+
+func (m *Memory) ListTodoCommits(ctx context.Context, todoID uuid.UUID) ([]*models.TodoCommit, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var commits []*models.TodoCommit
+	for _, commit := range m.data.commits[todoID] {
+		commits = append(commits, &commit)
+	}
+	return commits, nil
+}
+
+func (m *Memory) ListCodeComments(ctx context.Context, projectID uuid.UUID) ([]*models.CodeComment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var comments []*models.CodeComment
+	for _, comment := range m.data.codeComments[projectID] {
+		comments = append(comments, &comment)
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		if comments[i].File != comments[j].File {
+			return comments[i].File < comments[j].File
+		}
+		return comments[i].Line < comments[j].Line
+	})
+	return comments, nil
+}
+
+func (m *Memory) AddCodeComment(ctx context.Context, comment *models.CodeComment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.data.projects[comment.ProjectID]; !ok {
+		return fmt.Errorf("failed to add code comment: project %w", db.ErrNotFound)
+	}
+	if _, ok := m.data.todos[comment.TodoID]; !ok {
+		return fmt.Errorf("failed to add code comment: todo %w", db.ErrNotFound)
+	}
+	comments := m.data.codeComments[comment.ProjectID]
+	if comments == nil {
+		comments = make(map[string]models.CodeComment)
+		m.data.codeComments[comment.ProjectID] = comments
+	}
+	for _, existing := range comments {
+		if existing.Fingerprint == comment.Fingerprint || existing.TodoID == comment.TodoID {
+			return fmt.Errorf("failed to add code comment: %s is already recorded", comment.Fingerprint)
+		}
+	}
+	stored := *comment
+	stored.Text = ""
+	comments[comment.Fingerprint] = stored
+	return nil
+}
+
+func (m *Memory) UpdateCodeComment(ctx context.Context, comment *models.CodeComment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.data.codeComments[comment.ProjectID][comment.Fingerprint]
+	if !ok {
+		return nil
+	}
+	stored.File = comment.File
+	stored.Line = comment.Line
+	stored.Missing = comment.Missing
+	m.data.codeComments[comment.ProjectID][comment.Fingerprint] = stored
+	return nil
+}
+
+func (m *Memory) RenameCodeComment(ctx context.Context, projectID uuid.UUID, from, to string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	comments := m.data.codeComments[projectID]
+	stored, ok := comments[from]
+	if !ok {
+		return nil
+	}
+	if _, taken := comments[to]; taken {
+		return fmt.Errorf("failed to update code comment: %s is already recorded", to)
+	}
+	delete(comments, from)
+	stored.Fingerprint = to
+	comments[to] = stored
+	return nil
+}
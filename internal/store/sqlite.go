@@ -0,0 +1,255 @@
+// ABOUTME: SQLite Store backed by the functions in package db
+// ABOUTME: Runs on a database connection or, inside Tx, on a transaction
+
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+)
+
+// SQLite is the Store kept in a toki SQLite database.
+type SQLite struct {
+	db *sql.DB
+	// q is db, or the open transaction of a Store passed to a Tx function.
+	q db.Querier
+}
+
+var _ Store = (*SQLite)(nil)
+
+// NewSQLite returns the Store kept in database, which InitDB opened.
+func NewSQLite(database *sql.DB) *SQLite {
+	return &SQLite{db: database, q: database}
+}
+
+// Tx implements Store.
+func (s *SQLite) Tx(ctx context.Context, fn func(Store) error) error {
+	if _, ok := s.q.(*sql.Tx); ok {
+		return fn(s)
+	}
+
+	return db.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		return fn(&SQLite{db: s.db, q: tx})
+	})
+}
+
+// The methods below implement Store with the functions of the same name in package db.
+
+func (s *SQLite) CreateProject(ctx context.Context, project *models.Project) error {
+	return db.CreateProject(ctx, s.q, project)
+}
+
+func (s *SQLite) GetProjectByID(ctx context.Context, id uuid.UUID) (*models.Project, error) {
+	return db.GetProjectByID(ctx, s.q, id)
+}
+
+func (s *SQLite) GetProjectByName(ctx context.Context, name string) (*models.Project, error) {
+	return db.GetProjectByName(ctx, s.q, name)
+}
+
+func (s *SQLite) GetProjectByPath(ctx context.Context, path string) (*models.Project, error) {
+	return db.GetProjectByPath(ctx, s.q, path)
+}
+
+func (s *SQLite) GetProjectByRemote(ctx context.Context, remoteURL string) (*models.Project, error) {
+	return db.GetProjectByRemote(ctx, s.q, remoteURL)
+}
+
+func (s *SQLite) FindProjectForDir(ctx context.Context, dir string, opts git.Options) (*models.Project, *git.Repo, error) {
+	return db.FindProjectForDir(ctx, s.q, dir, opts)
+}
+
+func (s *SQLite) ResolveProjectRef(ctx context.Context, ref string) (*models.Project, error) {
+	return db.ResolveProjectRef(ctx, s.q, ref)
+}
+
+func (s *SQLite) ListProjects(ctx context.Context) ([]*models.Project, error) {
+	return db.ListProjects(ctx, s.q)
+}
+
+func (s *SQLite) GetProjectMap(ctx context.Context) (map[uuid.UUID]*models.Project, error) {
+	return db.GetProjectMap(ctx, s.q)
+}
+
+func (s *SQLite) UpdateProjectPath(ctx context.Context, id uuid.UUID, path *string) error {
+	return db.UpdateProjectPath(ctx, s.q, id, path)
+}
+
+func (s *SQLite) UpdateProjectRemote(ctx context.Context, id uuid.UUID, remoteURL *string) error {
+	return db.UpdateProjectRemote(ctx, s.q, id, remoteURL)
+}
+
+func (s *SQLite) AddProjectPath(ctx context.Context, id uuid.UUID, path string) error {
+	return db.AddProjectPath(ctx, s.q, id, path)
+}
+
+func (s *SQLite) RemoveProjectPath(ctx context.Context, id uuid.UUID, path string) error {
+	return db.RemoveProjectPath(ctx, s.q, id, path)
+}
+
+func (s *SQLite) ListProjectPaths(ctx context.Context, id uuid.UUID) ([]string, error) {
+	return db.ListProjectPaths(ctx, s.q, id)
+}
+
+func (s *SQLite) DeleteProject(ctx context.Context, id uuid.UUID) error {
+	return db.DeleteProject(ctx, s.q, id)
+}
+
+func (s *SQLite) CreateTodo(ctx context.Context, todo *models.Todo) error {
+	return db.CreateTodo(ctx, s.q, todo)
+}
+
+func (s *SQLite) CreateTodoWithTags(ctx context.Context, todo *models.Todo, tagNames []string) error {
+	return db.CreateTodoWithTags(ctx, s.q, todo, tagNames)
+}
+
+func (s *SQLite) GetTodoByID(ctx context.Context, id uuid.UUID) (*models.Todo, error) {
+	return db.GetTodoByID(ctx, s.q, id)
+}
+
+func (s *SQLite) GetTodoByPrefix(ctx context.Context, prefix string) (*models.Todo, error) {
+	return db.GetTodoByPrefix(ctx, s.q, prefix)
+}
+
+func (s *SQLite) ResolveTodoRef(ctx context.Context, ref string) (*models.Todo, error) {
+	return db.ResolveTodoRef(ctx, s.q, ref)
+}
+
+func (s *SQLite) ListTodos(ctx context.Context, projectID *uuid.UUID, done *bool, priority *string, tag *string, branch *string) ([]*models.Todo, error) {
+	return db.ListTodos(ctx, s.q, projectID, done, priority, tag, branch)
+}
+
+func (s *SQLite) QueryTodos(ctx context.Context, expr filter.Node) ([]*models.Todo, error) {
+	return db.QueryTodos(ctx, s.q, expr)
+}
+
+func (s *SQLite) QueryTodosWithTags(ctx context.Context, expr filter.Node) ([]*models.Todo, map[uuid.UUID][]*models.Tag, error) {
+	return db.QueryTodosWithTags(ctx, s.q, expr)
+}
+
+func (s *SQLite) QueryTodosPage(ctx context.Context, expr filter.Node, order db.Order, page db.Page) ([]*models.Todo, map[uuid.UUID][]*models.Tag, string, error) {
+	return db.QueryTodosPage(ctx, s.q, expr, order, page)
+}
+
+func (s *SQLite) CountTodos(ctx context.Context, expr filter.Node) (int, error) {
+	return db.CountTodos(ctx, s.q, expr)
+}
+
+func (s *SQLite) UpdateTodo(ctx context.Context, todo *models.Todo) error {
+	return db.UpdateTodo(ctx, s.q, todo)
+}
+
+func (s *SQLite) DeleteTodo(ctx context.Context, id uuid.UUID) error {
+	return db.DeleteTodo(ctx, s.q, id)
+}
+
+func (s *SQLite) ListTodoBranches(ctx context.Context, projectID uuid.UUID) ([]string, error) {
+	return db.ListTodoBranches(ctx, s.q, projectID)
+}
+
+func (s *SQLite) CompleteBranchTodos(ctx context.Context, projectID uuid.UUID, branch string) (int64, error) {
+	return db.CompleteBranchTodos(ctx, s.q, projectID, branch)
+}
+
+func (s *SQLite) MoveBranchTodos(ctx context.Context, projectID uuid.UUID, from string, to *string) (int64, error) {
+	return db.MoveBranchTodos(ctx, s.q, projectID, from, to)
+}
+
+func (s *SQLite) GetOrCreateTag(ctx context.Context, name string) (*models.Tag, error) {
+	return db.GetOrCreateTag(ctx, s.q, name)
+}
+
+func (s *SQLite) AddTagToTodo(ctx context.Context, todoID uuid.UUID, tagName string) error {
+	return db.AddTagToTodo(ctx, s.q, todoID, tagName)
+}
+
+func (s *SQLite) RemoveTagFromTodo(ctx context.Context, todoID uuid.UUID, tagName string) error {
+	return db.RemoveTagFromTodo(ctx, s.q, todoID, tagName)
+}
+
+func (s *SQLite) SetTodoTags(ctx context.Context, todoID uuid.UUID, tagNames []string) error {
+	return db.SetTodoTags(ctx, s.q, todoID, tagNames)
+}
+
+func (s *SQLite) GetTodoTags(ctx context.Context, todoID uuid.UUID) ([]*models.Tag, error) {
+	return db.GetTodoTags(ctx, s.q, todoID)
+}
+
+func (s *SQLite) GetTagsForTodos(ctx context.Context, todos []*models.Todo) (map[uuid.UUID][]*models.Tag, error) {
+	return db.GetTagsForTodos(ctx, s.q, todos)
+}
+
+func (s *SQLite) ListAllTags(ctx context.Context) ([]*models.Tag, error) {
+	return db.ListAllTags(ctx, s.q)
+}
+
+func (s *SQLite) ActiveTimer(ctx context.Context) (*models.TimeEntry, error) {
+	return db.ActiveTimer(ctx, s.q)
+}
+
+func (s *SQLite) StartTimer(ctx context.Context, todoID uuid.UUID, now time.Time) (*models.TimeEntry, *models.TimeEntry, error) {
+	return db.StartTimer(ctx, s.q, todoID, now)
+}
+
+func (s *SQLite) StopTimer(ctx context.Context, now time.Time) (*models.TimeEntry, error) {
+	return db.StopTimer(ctx, s.q, now)
+}
+
+func (s *SQLite) LogTime(ctx context.Context, todoID uuid.UUID, duration time.Duration, now time.Time) (*models.TimeEntry, error) {
+	return db.LogTime(ctx, s.q, todoID, duration, now)
+}
+
+func (s *SQLite) TrackedTime(ctx context.Context, now time.Time) (map[uuid.UUID]time.Duration, error) {
+	return db.TrackedTime(ctx, s.q, now)
+}
+
+func (s *SQLite) GetTimeReport(ctx context.Context, from, to, now time.Time) (*models.TimeReport, error) {
+	return db.GetTimeReport(ctx, s.q, from, to, now)
+}
+
+func (s *SQLite) SaveView(ctx context.Context, view *models.View) (bool, error) {
+	return db.SaveView(ctx, s.q, view)
+}
+
+func (s *SQLite) GetView(ctx context.Context, name string) (*models.View, error) {
+	return db.GetView(ctx, s.q, name)
+}
+
+func (s *SQLite) ListViews(ctx context.Context) ([]*models.View, error) {
+	return db.ListViews(ctx, s.q)
+}
+
+func (s *SQLite) DeleteView(ctx context.Context, name string) error {
+	return db.DeleteView(ctx, s.q, name)
+}
+
+func (s *SQLite) AddTodoCommit(ctx context.Context, todoID uuid.UUID, sha, action string) (bool, error) {
+	return db.AddTodoCommit(ctx, s.q, todoID, sha, action)
+}
+
+func (s *SQLite) ListTodoCommits(ctx context.Context, todoID uuid.UUID) ([]*models.TodoCommit, error) {
+	return db.ListTodoCommits(ctx, s.q, todoID)
+}
+
+func (s *SQLite) ListCodeComments(ctx context.Context, projectID uuid.UUID) ([]*models.CodeComment, error) {
+	return db.ListCodeComments(ctx, s.q, projectID)
+}
+
+func (s *SQLite) AddCodeComment(ctx context.Context, comment *models.CodeComment) error {
+	return db.AddCodeComment(ctx, s.q, comment)
+}
+
+func (s *SQLite) UpdateCodeComment(ctx context.Context, comment *models.CodeComment) error {
+	return db.UpdateCodeComment(ctx, s.q, comment)
+}
+
+func (s *SQLite) RenameCodeComment(ctx context.Context, projectID uuid.UUID, from, to string) error {
+	return db.RenameCodeComment(ctx, s.q, projectID, from, to)
+}
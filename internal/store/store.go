@@ -0,0 +1,146 @@
+// ABOUTME: Storage interface for projects, todos, tags, tracked time, views, and git links
+// ABOUTME: Lets the CLI and MCP server run on SQLite, in memory, or a wrapping layer
+
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/git"
+	"github.com/harper/toki/internal/models"
+)
+
+// Store keeps projects, todos, their tags, the time tracked on them, saved views, and
+// the commits and source comments linked to todos.
+// Implementations return errors wrapping
+// db.ErrNotFound for missing records and *db.AmbiguousRefError for ambiguous references,
+// like the functions in package db.
+type Store interface {
+	Projects
+	Todos
+	Tags
+	Timers
+	Views
+	Commits
+	CodeComments
+
+	// Tx calls fn with a Store whose changes are all kept if fn returns nil and all
+	// discarded if it returns an error, which Tx then returns. Calling Tx on the Store
+	// passed to fn runs in the same transaction.
+	Tx(ctx context.Context, fn func(Store) error) error
+}
+
+// Projects stores projects and the directories they are found in.
+type Projects interface {
+	CreateProject(ctx context.Context, project *models.Project) error
+	GetProjectByID(ctx context.Context, id uuid.UUID) (*models.Project, error)
+	GetProjectByName(ctx context.Context, name string) (*models.Project, error)
+	// GetProjectByPath finds the project whose primary or additional directory is path.
+	GetProjectByPath(ctx context.Context, path string) (*models.Project, error)
+	// GetProjectByRemote finds the oldest project linked to a normalized remote URL.
+	GetProjectByRemote(ctx context.Context, remoteURL string) (*models.Project, error)
+	// FindProjectForDir finds the project of the git repository containing dir; see
+	// db.FindProjectForDir.
+	FindProjectForDir(ctx context.Context, dir string, opts git.Options) (*models.Project, *git.Repo, error)
+	// ResolveProjectRef finds a project by UUID, name, directory path, or unique UUID prefix.
+	ResolveProjectRef(ctx context.Context, ref string) (*models.Project, error)
+	// ListProjects returns every project sorted by name.
+	ListProjects(ctx context.Context) ([]*models.Project, error)
+	GetProjectMap(ctx context.Context) (map[uuid.UUID]*models.Project, error)
+	UpdateProjectPath(ctx context.Context, id uuid.UUID, path *string) error
+	UpdateProjectRemote(ctx context.Context, id uuid.UUID, remoteURL *string) error
+	// AddProjectPath records an additional directory; see db.AddProjectPath.
+	AddProjectPath(ctx context.Context, id uuid.UUID, path string) error
+	RemoveProjectPath(ctx context.Context, id uuid.UUID, path string) error
+	ListProjectPaths(ctx context.Context, id uuid.UUID) ([]string, error)
+	// DeleteProject deletes a project along with its todos, atomically.
+	DeleteProject(ctx context.Context, id uuid.UUID) error
+}
+
+// Todos stores todos. Lists come newest first.
+type Todos interface {
+	CreateTodo(ctx context.Context, todo *models.Todo) error
+	// CreateTodoWithTags creates a todo and tags it, or does neither.
+	CreateTodoWithTags(ctx context.Context, todo *models.Todo, tagNames []string) error
+	GetTodoByID(ctx context.Context, id uuid.UUID) (*models.Todo, error)
+	GetTodoByPrefix(ctx context.Context, prefix string) (*models.Todo, error)
+	// ResolveTodoRef finds a todo by UUID or unique UUID prefix.
+	ResolveTodoRef(ctx context.Context, ref string) (*models.Todo, error)
+	ListTodos(ctx context.Context, projectID *uuid.UUID, done *bool, priority *string, tag *string, branch *string) ([]*models.Todo, error)
+	// QueryTodos returns the todos matching a filter expression; nil matches all.
+	QueryTodos(ctx context.Context, expr filter.Node) ([]*models.Todo, error)
+	QueryTodosWithTags(ctx context.Context, expr filter.Node) ([]*models.Todo, map[uuid.UUID][]*models.Tag, error)
+	// QueryTodosPage returns one page of QueryTodosWithTags in order and the next page's
+	// cursor.
+	QueryTodosPage(ctx context.Context, expr filter.Node, order db.Order, page db.Page) ([]*models.Todo, map[uuid.UUID][]*models.Tag, string, error)
+	// CountTodos returns how many todos match a filter expression.
+	CountTodos(ctx context.Context, expr filter.Node) (int, error)
+	UpdateTodo(ctx context.Context, todo *models.Todo) error
+	DeleteTodo(ctx context.Context, id uuid.UUID) error
+	// ListTodoBranches returns the branches of a project's pending todos, sorted.
+	ListTodoBranches(ctx context.Context, projectID uuid.UUID) ([]string, error)
+	CompleteBranchTodos(ctx context.Context, projectID uuid.UUID, branch string) (int64, error)
+	MoveBranchTodos(ctx context.Context, projectID uuid.UUID, from string, to *string) (int64, error)
+}
+
+// Tags stores tags and which todos have them. Tag lists are sorted by name.
+type Tags interface {
+	GetOrCreateTag(ctx context.Context, name string) (*models.Tag, error)
+	AddTagToTodo(ctx context.Context, todoID uuid.UUID, tagName string) error
+	RemoveTagFromTodo(ctx context.Context, todoID uuid.UUID, tagName string) error
+	// SetTodoTags replaces all the tags of a todo at once.
+	SetTodoTags(ctx context.Context, todoID uuid.UUID, tagNames []string) error
+	GetTodoTags(ctx context.Context, todoID uuid.UUID) ([]*models.Tag, error)
+	// GetTagsForTodos returns the tags of each todo; todos without tags are absent.
+	GetTagsForTodos(ctx context.Context, todos []*models.Todo) (map[uuid.UUID][]*models.Tag, error)
+	ListAllTags(ctx context.Context) ([]*models.Tag, error)
+}
+
+// Timers stores the time tracked on todos. At most one timer runs at a time.
+type Timers interface {
+	// ActiveTimer returns the running timer, or db.ErrNoTimer.
+	ActiveTimer(ctx context.Context) (*models.TimeEntry, error)
+	// StartTimer starts a timer on a todo, stopping and returning one running on
+	// another todo; see db.StartTimer.
+	StartTimer(ctx context.Context, todoID uuid.UUID, now time.Time) (*models.TimeEntry, *models.TimeEntry, error)
+	StopTimer(ctx context.Context, now time.Time) (*models.TimeEntry, error)
+	LogTime(ctx context.Context, todoID uuid.UUID, duration time.Duration, now time.Time) (*models.TimeEntry, error)
+	// TrackedTime returns the time tracked per todo; todos without time are absent.
+	TrackedTime(ctx context.Context, now time.Time) (map[uuid.UUID]time.Duration, error)
+	// GetTimeReport totals the time tracked in [from, to); see db.GetTimeReport.
+	GetTimeReport(ctx context.Context, from, to, now time.Time) (*models.TimeReport, error)
+}
+
+// Views stores saved views. Lookups include the built-in views.
+type Views interface {
+	// SaveView stores a view, reporting whether it replaced one with the same name.
+	SaveView(ctx context.Context, view *models.View) (bool, error)
+	GetView(ctx context.Context, name string) (*models.View, error)
+	// ListViews returns the built-in views followed by saved views, each sorted by name.
+	ListViews(ctx context.Context) ([]*models.View, error)
+	DeleteView(ctx context.Context, name string) error
+}
+
+// Commits records the git commits that closed or referenced todos.
+type Commits interface {
+	// AddTodoCommit records a commit against a todo, reporting false when it already was.
+	AddTodoCommit(ctx context.Context, todoID uuid.UUID, sha, action string) (bool, error)
+	// ListTodoCommits returns the commits recorded for a todo, oldest first.
+	ListTodoCommits(ctx context.Context, todoID uuid.UUID) ([]*models.TodoCommit, error)
+}
+
+// CodeComments records the source comments behind code todos, keyed by project and
+// fingerprint. SyncCodeComments keeps them in line with a scan.
+type CodeComments interface {
+	// ListCodeComments returns a project's comments, sorted by file and line.
+	ListCodeComments(ctx context.Context, projectID uuid.UUID) ([]*models.CodeComment, error)
+	AddCodeComment(ctx context.Context, comment *models.CodeComment) error
+	// UpdateCodeComment stores a comment's location and whether it is missing.
+	UpdateCodeComment(ctx context.Context, comment *models.CodeComment) error
+	// RenameCodeComment records a project's comment under a new fingerprint.
+	RenameCodeComment(ctx context.Context, projectID uuid.UUID, from, to string) error
+}
@@ -0,0 +1,544 @@
+// ABOUTME: Tests every Store implementation against the same expectations
+// ABOUTME: Checks that the in-memory and encrypted stores answer filters and transactions like SQLite
+
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/filter"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/secret"
+	"github.com/harper/toki/internal/sorting"
+)
+
+// stores returns a fresh instance of each Store implementation by name.
+func stores(t *testing.T) map[string]Store {
+	t.Helper()
+
+	database, err := db.InitDB(t.Context(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	encrypted, err := db.InitDB(t.Context(), filepath.Join(t.TempDir(), "encrypted.db"))
+	if err != nil {
+		t.Fatalf("Failed to init database: %v", err)
+	}
+	t.Cleanup(func() { _ = encrypted.Close() })
+	key, err := secret.ParseKey(secret.GenerateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return map[string]Store{
+		"sqlite":    NewSQLite(database),
+		"memory":    NewMemory(),
+		"encrypted": NewEncrypted(NewSQLite(encrypted), key),
+	}
+}
+
+// fixture holds the records seeded by seed.
+type fixture struct {
+	api, web               *models.Project
+	crash, docs, login, v1 *models.Todo
+}
+
+func seed(t *testing.T, s Store) fixture {
+	t.Helper()
+
+	path := "/src/api"
+	f := fixture{
+		api: models.NewProject("api", &path),
+		web: models.NewProject("web", nil),
+	}
+	for _, project := range []*models.Project{f.api, f.web} {
+		if err := s.CreateProject(t.Context(), project); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	high, low := "high", "low"
+	feature := "feature/login"
+	soon := now.Add(3 * 24 * time.Hour)
+	late := now.Add(-2 * 24 * time.Hour)
+	notes := "stack trace attached"
+
+	f.crash = models.NewTodo(f.api.ID, "fix crash")
+	f.crash.Priority = &high
+	f.crash.DueDate = &late
+	f.crash.Notes = &notes
+	f.crash.CreatedAt = now.Add(-4 * time.Hour)
+
+	f.docs = models.NewTodo(f.api.ID, "write docs")
+	f.docs.Priority = &low
+	f.docs.DueDate = &soon
+	f.docs.CreatedAt = now.Add(-3 * time.Hour)
+
+	f.login = models.NewTodo(f.web.ID, "login page")
+	f.login.Branch = &feature
+	f.login.CreatedAt = now.Add(-2 * time.Hour)
+
+	f.v1 = models.NewTodo(f.web.ID, "ship v1")
+	f.v1.CreatedAt = now.Add(-time.Hour)
+	f.v1.MarkDone()
+
+	for _, todo := range []*models.Todo{f.crash, f.docs, f.login, f.v1} {
+		if err := s.CreateTodo(t.Context(), todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for todo, tag := range map[*models.Todo]string{f.crash: "bug", f.login: "ui", f.v1: "ui"} {
+		if err := s.AddTagToTodo(t.Context(), todo.ID, tag); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return f
+}
+
+func descriptions(todos []*models.Todo) []string {
+	result := make([]string, len(todos))
+	for i, todo := range todos {
+		result[i] = todo.Description
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStoreQueryTodos(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", []string{"ship v1", "login page", "write docs", "fix crash"}},
+		{"project:API", []string{"write docs", "fix crash"}},
+		{"tag:ui and not done", []string{"login page"}},
+		{"tag:none", []string{"write docs"}},
+		{"priority>=medium or branch:any", []string{"login page", "fix crash"}},
+		{"priority:none", []string{"ship v1", "login page"}},
+		{"overdue", []string{"fix crash"}},
+		{"not overdue", []string{"ship v1", "login page", "write docs"}},
+		{"due<+7d and due>today", []string{"write docs"}},
+		{"not due<today", []string{"ship v1", "login page", "write docs"}},
+		{"text:STACK", []string{"fix crash"}},
+		{`text="LOGIN page"`, []string{"login page"}},
+		{"completed:today", []string{"ship v1"}},
+		{"tag!=ui", []string{"write docs", "fix crash"}},
+	}
+
+	for name, s := range stores(t) {
+		seed(t, s)
+		for _, tt := range tests {
+			var expr filter.Node
+			if tt.expr != "" {
+				var err error
+				if expr, err = filter.Parse(tt.expr); err != nil {
+					t.Fatalf("Failed to parse %q: %v", tt.expr, err)
+				}
+			}
+			todos, err := s.QueryTodos(t.Context(), expr)
+			if err != nil {
+				t.Fatalf("%s: %q: %v", name, tt.expr, err)
+			}
+			if got := descriptions(todos); !equal(got, tt.want) {
+				t.Errorf("%s: %q: expected %v, got %v", name, tt.expr, tt.want, got)
+			}
+		}
+	}
+}
+
+func TestStoreQueryProjectCase(t *testing.T) {
+	for name, s := range stores(t) {
+		f := seed(t, s)
+		// Project names are unique only with their case
+		upper := models.NewProject("API", nil)
+		if err := s.CreateProject(t.Context(), upper); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.CreateTodo(t.Context(), models.NewTodo(upper.ID, "other api")); err != nil {
+			t.Fatal(err)
+		}
+
+		for expr, want := range map[string][]string{
+			"project:api":                  {"write docs", "fix crash"},
+			"project:API":                  {"other api"},
+			"project:Api":                  {"other api"},
+			"project:" + f.api.ID.String(): {"write docs", "fix crash"},
+			"project:missing":              nil,
+			"not project:api and not done": {"other api", "login page"},
+		} {
+			node, err := filter.Parse(expr)
+			if err != nil {
+				t.Fatalf("Failed to parse %q: %v", expr, err)
+			}
+			todos, err := s.QueryTodos(t.Context(), node)
+			if err != nil {
+				t.Fatalf("%s: %q: %v", name, expr, err)
+			}
+			if got := descriptions(todos); !equal(got, want) {
+				t.Errorf("%s: %q: expected %v, got %v", name, expr, want, got)
+			}
+		}
+	}
+}
+
+func TestStoreRecords(t *testing.T) {
+	for name, s := range stores(t) {
+		f := seed(t, s)
+
+		project, err := s.ResolveProjectRef(t.Context(), "/src/api")
+		if err != nil || project.ID != f.api.ID {
+			t.Errorf("%s: expected to resolve the api project by path, got %v, %v", name, project, err)
+		}
+		if _, err := s.ResolveProjectRef(t.Context(), "nope"); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("%s: expected ErrNotFound, got %v", name, err)
+		}
+		if err := s.CreateProject(t.Context(), models.NewProject("api", nil)); err == nil {
+			t.Errorf("%s: expected an error for a duplicate project name", name)
+		}
+
+		todo, err := s.ResolveTodoRef(t.Context(), f.docs.ID.String()[:8])
+		if err != nil || todo.Description != "write docs" {
+			t.Errorf("%s: expected to resolve a todo by prefix, got %v, %v", name, todo, err)
+		}
+		todo.Description = "write more docs"
+		if err := s.UpdateTodo(t.Context(), todo); err != nil {
+			t.Fatal(err)
+		}
+		if todo, _ := s.GetTodoByID(t.Context(), f.docs.ID); todo.Description != "write more docs" {
+			t.Errorf("%s: expected the updated description, got %q", name, todo.Description)
+		}
+
+		pending := false
+		todos, err := s.ListTodos(t.Context(), &f.web.ID, &pending, nil, nil, nil)
+		if err != nil || !equal(descriptions(todos), []string{"login page"}) {
+			t.Errorf("%s: expected the pending web todo, got %v, %v", name, descriptions(todos), err)
+		}
+
+		if err := s.RemoveTagFromTodo(t.Context(), f.v1.ID, "ui"); err != nil {
+			t.Fatal(err)
+		}
+		todoTags, err := s.GetTagsForTodos(t.Context(), []*models.Todo{f.crash, f.docs, f.v1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(todoTags) != 1 || todoTags[f.crash.ID][0].Name != "bug" {
+			t.Errorf("%s: expected only the crash todo's tag, got %v", name, todoTags)
+		}
+		tags, err := s.ListAllTags(t.Context())
+		if err != nil || len(tags) != 2 || tags[0].Name != "bug" {
+			t.Errorf("%s: expected tags bug and ui, got %v, %v", name, tags, err)
+		}
+
+		if err := s.AddProjectPath(t.Context(), f.web.ID, "/src/web"); err != nil {
+			t.Fatal(err)
+		}
+		if project, err := s.GetProjectByPath(t.Context(), "/src/web"); err != nil || project.ID != f.web.ID {
+			t.Errorf("%s: expected the web project by its additional path, got %v, %v", name, project, err)
+		}
+
+		branches, err := s.ListTodoBranches(t.Context(), f.web.ID)
+		if err != nil || !equal(branches, []string{"feature/login"}) {
+			t.Errorf("%s: expected the feature branch, got %v, %v", name, branches, err)
+		}
+		if n, err := s.CompleteBranchTodos(t.Context(), f.web.ID, "feature/login"); err != nil || n != 1 {
+			t.Errorf("%s: expected 1 todo completed, got %d, %v", name, n, err)
+		}
+
+		if err := s.DeleteProject(t.Context(), f.web.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.GetTodoByID(t.Context(), f.login.ID); !errors.Is(err, db.ErrNotFound) {
+			t.Errorf("%s: expected the project's todos deleted with it, got %v", name, err)
+		}
+	}
+}
+
+func TestStoreTimersAndViews(t *testing.T) {
+	for name, s := range stores(t) {
+		f := seed(t, s)
+		now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+		if _, err := s.ActiveTimer(t.Context()); !errors.Is(err, db.ErrNoTimer) {
+			t.Errorf("%s: expected no timer, got %v", name, err)
+		}
+		if _, _, err := s.StartTimer(t.Context(), f.crash.ID, now.Add(-time.Hour)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		_, stopped, err := s.StartTimer(t.Context(), f.login.ID, now.Add(-30*time.Minute))
+		if err != nil || stopped == nil || stopped.Duration != 30*time.Minute {
+			t.Errorf("%s: expected the crash timer stopped after 30m, got %+v, %v", name, stopped, err)
+		}
+		if _, err := s.LogTime(t.Context(), f.docs.ID, 15*time.Minute, now); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		tracked, err := s.TrackedTime(t.Context(), now)
+		if err != nil || tracked[f.crash.ID] != 30*time.Minute || tracked[f.login.ID] != 30*time.Minute || tracked[f.docs.ID] != 15*time.Minute {
+			t.Errorf("%s: unexpected tracked time %v, %v", name, tracked, err)
+		}
+
+		report, err := s.GetTimeReport(t.Context(), now.Add(-45*time.Minute), now, now)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		// 15m of crash, 30m of login, 15m of docs
+		if report.Total != time.Hour || len(report.Projects) != 2 || report.Projects[0].Duration != 30*time.Minute {
+			t.Errorf("%s: unexpected report %+v", name, report)
+		}
+
+		if stopped, err := s.StopTimer(t.Context(), now); err != nil || stopped.TodoID != f.login.ID {
+			t.Errorf("%s: expected the login timer stopped, got %+v, %v", name, stopped, err)
+		}
+
+		if _, err := s.SaveView(t.Context(), &models.View{Name: "bugs", Filter: "tag:bug"}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if replaced, err := s.SaveView(t.Context(), &models.View{Name: "bugs", Filter: "tag:bug pending"}); err != nil || !replaced {
+			t.Errorf("%s: expected the view replaced, got %v, %v", name, replaced, err)
+		}
+		if view, err := s.GetView(t.Context(), "bugs"); err != nil || view.Filter != "tag:bug pending" {
+			t.Errorf("%s: expected the saved view, got %+v, %v", name, view, err)
+		}
+		views, err := s.ListViews(t.Context())
+		if err != nil || len(views) != 4 || views[3].Name != "bugs" {
+			t.Errorf("%s: expected the built-in views then bugs, got %v, %v", name, views, err)
+		}
+		if err := s.DeleteView(t.Context(), "bugs"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := s.GetView(t.Context(), "bugs"); err == nil {
+			t.Errorf("%s: expected the deleted view gone", name)
+		}
+
+		// Time entries go with their todo
+		if err := s.DeleteTodo(t.Context(), f.docs.ID); err != nil {
+			t.Fatal(err)
+		}
+		if tracked, _ := s.TrackedTime(t.Context(), now); tracked[f.docs.ID] != 0 {
+			t.Errorf("%s: expected the deleted todo's time gone, got %v", name, tracked)
+		}
+	}
+}
+
+func TestStoreCommits(t *testing.T) {
+	for name, s := range stores(t) {
+		f := seed(t, s)
+
+		if added, err := s.AddTodoCommit(t.Context(), f.crash.ID, "1111111", "refs"); err != nil || !added {
+			t.Errorf("%s: expected the commit added, got %v, %v", name, added, err)
+		}
+		if added, err := s.AddTodoCommit(t.Context(), f.crash.ID, "1111111", "closes"); err != nil || added {
+			t.Errorf("%s: expected the repeated commit ignored, got %v, %v", name, added, err)
+		}
+		if _, err := s.AddTodoCommit(t.Context(), f.crash.ID, "2222222", "closes"); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		commits, err := s.ListTodoCommits(t.Context(), f.crash.ID)
+		if err != nil || len(commits) != 2 || commits[0].SHA != "1111111" || commits[1].Action != "closes" {
+			t.Errorf("%s: unexpected commits %+v, %v", name, commits, err)
+		}
+
+		// Commits go with their todo
+		if err := s.DeleteTodo(t.Context(), f.crash.ID); err != nil {
+			t.Fatal(err)
+		}
+		if commits, _ := s.ListTodoCommits(t.Context(), f.crash.ID); len(commits) != 0 {
+			t.Errorf("%s: expected the deleted todo's commits gone, got %d", name, len(commits))
+		}
+	}
+}
+
+func TestStoreQueryTodosPage(t *testing.T) {
+	for name, s := range stores(t) {
+		seed(t, s)
+
+		var paged []string
+		page := db.Page{Limit: 3}
+		for {
+			todos, _, next, err := s.QueryTodosPage(t.Context(), nil, db.Order{}, page)
+			if err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			paged = append(paged, descriptions(todos)...)
+			if next == "" {
+				break
+			}
+			page.Cursor = next
+		}
+		if want := []string{"ship v1", "login page", "write docs", "fix crash"}; !equal(paged, want) {
+			t.Errorf("%s: expected %v across pages, got %v", name, want, paged)
+		}
+	}
+}
+
+func TestStoreQueryTodosPageSorted(t *testing.T) {
+	for name, s := range stores(t) {
+		f := seed(t, s)
+		// Updates a minute apart, since SQLite orders times to the millisecond
+		for i, todo := range []*models.Todo{f.docs, f.v1, f.crash, f.login} {
+			todo.UpdatedAt = time.Now().Add(-time.Duration(i) * time.Minute)
+			if err := s.UpdateTodo(t.Context(), todo); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		coefficients := sorting.DefaultCoefficients()
+		coefficients.Tag["ui"] = 5
+		for _, key := range sorting.Keys {
+			for _, reverse := range []bool{false, true} {
+				order := db.Order{Key: key, Reverse: reverse, Urgency: coefficients, Now: time.Now()}
+				all, todoTags, err := s.QueryTodosWithTags(t.Context(), nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sorting.Sort(all, key, reverse, func(todo *models.Todo) float64 {
+					return sorting.Urgency(todo, todoTags[todo.ID], coefficients, order.Now)
+				})
+
+				var paged []string
+				page := db.Page{Limit: 3}
+				for {
+					todos, _, next, err := s.QueryTodosPage(t.Context(), nil, order, page)
+					if err != nil {
+						t.Fatalf("%s: %v", name, err)
+					}
+					paged = append(paged, descriptions(todos)...)
+					if next == "" {
+						break
+					}
+					page.Cursor = next
+				}
+				if want := descriptions(all); !equal(paged, want) {
+					t.Errorf("%s: expected %v sorted by %s (reverse %v) across pages, got %v", name, want, key, reverse, paged)
+				}
+
+				todos, _, _, err := s.QueryTodosPage(t.Context(), nil, order, db.Page{Limit: 2, Offset: 1})
+				if err != nil {
+					t.Fatalf("%s: %v", name, err)
+				}
+				if want := descriptions(all[1:3]); !equal(descriptions(todos), want) {
+					t.Errorf("%s: expected %v at offset 1 sorted by %s, got %v", name, want, key, descriptions(todos))
+				}
+			}
+		}
+
+		if count, err := s.CountTodos(t.Context(), filter.NewTerm(filter.FieldDone, filter.OpMatch, "false")); err != nil || count != 3 {
+			t.Errorf("%s: expected 3 pending todos counted, got %d (%v)", name, count, err)
+		}
+	}
+}
+
+func TestStoreTx(t *testing.T) {
+	for name, s := range stores(t) {
+		f := seed(t, s)
+
+		failure := errors.New("tag failed")
+		err := s.Tx(t.Context(), func(tx Store) error {
+			todo := models.NewTodo(f.api.ID, "half done")
+			if err := tx.CreateTodo(t.Context(), todo); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Errorf("%s: expected the function's error, got %v", name, err)
+		}
+		if todos, _ := s.ListTodos(t.Context(), &f.api.ID, nil, nil, nil, nil); len(todos) != 2 {
+			t.Errorf("%s: expected the failed transaction rolled back, got %v", name, descriptions(todos))
+		}
+
+		var created *models.Todo
+		err = s.Tx(t.Context(), func(tx Store) error {
+			created = models.NewTodo(f.api.ID, "all done")
+			if err := tx.CreateTodo(t.Context(), created); err != nil {
+				return err
+			}
+			// A nested Tx joins the outer one
+			return tx.Tx(t.Context(), func(tx Store) error {
+				return tx.AddTagToTodo(t.Context(), created.ID, "release")
+			})
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if tags, _ := s.GetTodoTags(t.Context(), created.ID); len(tags) != 1 || tags[0].Name != "release" {
+			t.Errorf("%s: expected the committed todo's tag, got %v", name, tags)
+		}
+	}
+}
+
+func TestStoreTodoTags(t *testing.T) {
+	for name, s := range stores(t) {
+		f := seed(t, s)
+
+		todo := models.NewTodo(f.api.ID, "tagged at birth")
+		if err := s.CreateTodoWithTags(t.Context(), todo, []string{"b", "a"}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := tagNames(t, s, todo); got != "a,b" {
+			t.Errorf("%s: expected tags a,b, got %s", name, got)
+		}
+
+		if err := s.SetTodoTags(t.Context(), todo.ID, []string{"c", "b"}); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := tagNames(t, s, todo); got != "b,c" {
+			t.Errorf("%s: expected tags b,c, got %s", name, got)
+		}
+
+		if err := s.SetTodoTags(t.Context(), todo.ID, nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := tagNames(t, s, todo); got != "" {
+			t.Errorf("%s: expected no tags, got %s", name, got)
+		}
+
+		// Inside Tx, CreateTodoWithTags joins the transaction and rolls back with it
+		discarded := models.NewTodo(f.api.ID, "never kept")
+		failure := errors.New("abandon")
+		err := s.Tx(t.Context(), func(tx Store) error {
+			if err := tx.CreateTodoWithTags(t.Context(), discarded, []string{"gone"}); err != nil {
+				return err
+			}
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Errorf("%s: expected the function's error, got %v", name, err)
+		}
+		if _, err := s.GetTodoByID(t.Context(), discarded.ID); err == nil {
+			t.Errorf("%s: expected the todo rolled back", name)
+		}
+	}
+}
+
+func tagNames(t *testing.T, s Store, todo *models.Todo) string {
+	t.Helper()
+	tags, err := s.GetTodoTags(t.Context(), todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(tags))
+	for i, tag := range tags {
+		names[i] = tag.Name
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,199 @@
+// ABOUTME: Data loading and todo actions for the terminal UI
+// ABOUTME: Reads projects and todos through the store and applies edits from key presses
+
+package tui
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+)
+
+// priorityCycle is the order `p` steps through; "" clears the priority.
+var priorityCycle = []string{"", "low", "medium", "high"}
+
+// load reads projects, pending counts, and the todos for the current selection.
+func (m *Model) load() tea.Cmd {
+	ctx := m.ctx
+	var projectID *uuid.UUID
+	if project := m.selectedProject(); project != nil {
+		id := project.ID
+		projectID = &id
+	}
+	showDone := m.showDone
+	st := m.store
+
+	return func() tea.Msg {
+		projects, err := st.ListProjects(ctx)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		pending := false
+		pendingTodos, err := st.ListTodos(ctx, nil, &pending, nil, nil, nil)
+		if err != nil {
+			return errMsg{err}
+		}
+		counts := make(map[uuid.UUID]int)
+		for _, todo := range pendingTodos {
+			counts[todo.ProjectID]++
+		}
+
+		var done *bool
+		if !showDone {
+			done = &pending
+		}
+		todos, err := st.ListTodos(ctx, projectID, done, nil, nil, nil)
+		if err != nil {
+			return errMsg{err}
+		}
+		// Pending todos first, newest first within each group
+		sort.SliceStable(todos, func(i, j int) bool { return !todos[i].Done && todos[j].Done })
+
+		tags, err := st.GetTagsForTodos(ctx, todos)
+		if err != nil {
+			return errMsg{err}
+		}
+
+		return dataMsg{projects: projects, pendingCounts: counts, todos: todos, tags: tags}
+	}
+}
+
+// update saves a todo and reloads, reporting status on success.
+func (m *Model) update(todo *models.Todo, status string) tea.Cmd {
+	todo.UpdatedAt = time.Now()
+	if err := m.store.UpdateTodo(m.ctx, todo); err != nil {
+		m.status = "Error: " + err.Error()
+		return nil
+	}
+	m.status = status
+	return m.load()
+}
+
+func (m *Model) toggleDone(todo *models.Todo) tea.Cmd {
+	if todo.Done {
+		todo.MarkUndone()
+		return m.update(todo, "Marked not done")
+	}
+	todo.MarkDone()
+	return m.update(todo, "Marked done")
+}
+
+func (m *Model) cyclePriority(todo *models.Todo) tea.Cmd {
+	current := ""
+	if todo.Priority != nil {
+		current = *todo.Priority
+	}
+
+	next := priorityCycle[0]
+	for i, p := range priorityCycle {
+		if p == current {
+			next = priorityCycle[(i+1)%len(priorityCycle)]
+		}
+	}
+
+	if next == "" {
+		todo.Priority = nil
+	} else {
+		todo.Priority = &next
+	}
+	return m.update(todo, "Priority: "+priorityLabel(todo.Priority))
+}
+
+func (m *Model) editDescription(todo *models.Todo, description string) tea.Cmd {
+	todo.Description = description
+	return m.update(todo, "Description updated")
+}
+
+func (m *Model) editNotes(todo *models.Todo, notes string) tea.Cmd {
+	if notes == "" {
+		todo.Notes = nil
+	} else {
+		todo.Notes = &notes
+	}
+	return m.update(todo, "Notes updated")
+}
+
+// editTags applies a comma-separated list; names prefixed with "-" are removed. The
+// resulting tags replace the todo's in one step.
+func (m *Model) editTags(todo *models.Todo, value string) tea.Cmd {
+	current, err := m.store.GetTodoTags(m.ctx, todo.ID)
+	if err != nil {
+		m.status = "Error: " + err.Error()
+		return nil
+	}
+	tags := make([]string, 0, len(current))
+	for _, tag := range current {
+		tags = append(tags, tag.Name)
+	}
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "-" {
+			continue
+		}
+		if removed, ok := strings.CutPrefix(name, "-"); ok {
+			tags = slices.DeleteFunc(tags, func(tag string) bool { return tag == removed })
+		} else if !slices.Contains(tags, name) {
+			tags = append(tags, name)
+		}
+	}
+
+	if err := m.store.SetTodoTags(m.ctx, todo.ID, tags); err != nil {
+		m.status = "Error: " + err.Error()
+		return nil
+	}
+
+	m.status = "Tags updated"
+	return m.load()
+}
+
+func (m *Model) deleteTodo(todo *models.Todo) tea.Cmd {
+	if err := m.store.DeleteTodo(m.ctx, todo.ID); err != nil {
+		m.status = "Error: " + err.Error()
+		return nil
+	}
+	m.status = "Deleted " + todo.ID.String()[:6]
+	return m.load()
+}
+
+// addTodo creates a todo in the selected project, or the default project when all
+// projects are shown.
+func (m *Model) addTodo(description string) tea.Cmd {
+	var projectID uuid.UUID
+	if project := m.selectedProject(); project != nil {
+		projectID = project.ID
+	} else {
+		project, err := m.store.GetProjectByName(m.ctx, m.defaultProject)
+		if err != nil {
+			project = models.NewProject(m.defaultProject, nil)
+			if err := m.store.CreateProject(m.ctx, project); err != nil {
+				m.status = "Error: " + err.Error()
+				return nil
+			}
+		}
+		projectID = project.ID
+	}
+
+	todo := models.NewTodo(projectID, description)
+	if err := m.store.CreateTodo(m.ctx, todo); err != nil {
+		m.status = "Error: " + err.Error()
+		return nil
+	}
+
+	m.status = "Added " + todo.ID.String()[:6]
+	m.filter = ""
+	return m.load()
+}
+
+func priorityLabel(priority *string) string {
+	if priority == nil {
+		return "none"
+	}
+	return *priority
+}
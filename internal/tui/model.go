@@ -0,0 +1,423 @@
+// ABOUTME: Full-screen terminal UI state and keyboard handling
+// ABOUTME: Bubble Tea model with a project sidebar, filterable todo list, and detail pane
+
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/store"
+)
+
+// refreshInterval is how often the database is polled for changes made elsewhere.
+const refreshInterval = time.Second
+
+type focus int
+
+const (
+	focusList focus = iota
+	focusSidebar
+)
+
+type inputMode int
+
+const (
+	modeNormal inputMode = iota
+	modeFilter
+	modeAdd
+	modeEdit
+	modeNotes
+	modeTag
+	modeConfirmDelete
+)
+
+// Watcher reports changes made to the store by other processes; *db.ChangeWatcher is one.
+type Watcher interface {
+	Changed(ctx context.Context) (bool, error)
+}
+
+// Model is the Bubble Tea model for `toki tui`.
+type Model struct {
+	// ctx bounds the model's store calls, since Bubble Tea commands take no context.
+	ctx     context.Context
+	store   store.Store
+	watcher Watcher
+
+	projects      []*models.Project
+	pendingCounts map[uuid.UUID]int
+	todos         []*models.Todo
+	tags          map[uuid.UUID][]*models.Tag
+
+	// projectIdx selects a sidebar entry; 0 is "All projects".
+	projectIdx int
+	cursor     int
+	focus      focus
+	showDone   bool
+	filter     string
+
+	mode  inputMode
+	input []rune
+
+	status        string
+	width, height int
+
+	defaultProject string
+}
+
+type dataMsg struct {
+	projects      []*models.Project
+	pendingCounts map[uuid.UUID]int
+	todos         []*models.Todo
+	tags          map[uuid.UUID][]*models.Tag
+}
+
+type tickMsg time.Time
+
+type errMsg struct{ err error }
+
+// New creates a model. When projectID is set, that project is selected initially.
+// Todos added while all projects are shown go to the defaultProject project.
+func New(ctx context.Context, st store.Store, projectID *uuid.UUID, defaultProject string) (*Model, error) {
+	m := &Model{ctx: ctx, store: st, defaultProject: defaultProject}
+
+	projects, err := st.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	m.projects = projects
+	if projectID != nil {
+		for i, p := range projects {
+			if p.ID == *projectID {
+				m.projectIdx = i + 1
+			}
+		}
+	}
+
+	return m, nil
+}
+
+// Run starts the full-screen UI and blocks until the user quits or ctx is cancelled.
+// Changes made by other processes show up live when watcher is not nil.
+func Run(ctx context.Context, st store.Store, watcher Watcher, projectID *uuid.UUID, defaultProject string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	m, err := New(ctx, st, projectID, defaultProject)
+	if err != nil {
+		return err
+	}
+
+	m.watcher = watcher
+
+	if _, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithContext(ctx)).Run(); err != nil {
+		return fmt.Errorf("failed to run tui: %w", err)
+	}
+	return nil
+}
+
+// Init loads the initial data and starts polling for external changes.
+func (m *Model) Init() tea.Cmd {
+	return tea.Batch(m.load(), tick())
+}
+
+func tick() tea.Cmd {
+	return tea.Tick(refreshInterval, func(t time.Time) tea.Msg { return tickMsg(t) })
+}
+
+// Update handles messages and key presses.
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case dataMsg:
+		m.applyData(msg)
+		return m, nil
+
+	case errMsg:
+		m.status = "Error: " + msg.err.Error()
+		return m, nil
+
+	case tickMsg:
+		if m.watcher == nil {
+			return m, tick()
+		}
+		changed, err := m.watcher.Changed(m.ctx)
+		if err != nil {
+			m.status = "Error: " + err.Error()
+			return m, tick()
+		}
+		if changed {
+			return m, tea.Batch(m.load(), tick())
+		}
+		return m, tick()
+
+	case tea.KeyMsg:
+		if m.mode != modeNormal {
+			return m, m.handleInputKey(msg)
+		}
+		return m, m.handleKey(msg)
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleKey(msg tea.KeyMsg) tea.Cmd {
+	m.status = ""
+
+	switch msg.String() {
+	case "q", "ctrl+c":
+		return tea.Quit
+	case "tab", "h", "l", "left", "right":
+		if m.focus == focusList {
+			m.focus = focusSidebar
+		} else {
+			m.focus = focusList
+		}
+		return nil
+	case "j", "down":
+		return m.move(1)
+	case "k", "up":
+		return m.move(-1)
+	case "g", "home":
+		return m.move(-len(m.todos) - len(m.projects) - 1)
+	case "G", "end":
+		return m.move(len(m.todos) + len(m.projects) + 1)
+	case "/":
+		m.startInput(modeFilter, m.filter)
+		return nil
+	case "esc":
+		m.filter = ""
+		m.clampCursor()
+		return nil
+	case "c":
+		m.showDone = !m.showDone
+		return m.load()
+	case "r":
+		return m.load()
+	case "a":
+		m.startInput(modeAdd, "")
+		return nil
+	}
+
+	todo := m.selectedTodo()
+	if todo == nil {
+		return nil
+	}
+
+	switch msg.String() {
+	case " ", "x", "enter":
+		return m.toggleDone(todo)
+	case "p":
+		return m.cyclePriority(todo)
+	case "e":
+		m.startInput(modeEdit, todo.Description)
+	case "n":
+		notes := ""
+		if todo.Notes != nil {
+			notes = *todo.Notes
+		}
+		m.startInput(modeNotes, notes)
+	case "t":
+		m.startInput(modeTag, "")
+	case "d", "delete":
+		m.startInput(modeConfirmDelete, "")
+	}
+	return nil
+}
+
+func (m *Model) handleInputKey(msg tea.KeyMsg) tea.Cmd {
+	if m.mode == modeConfirmDelete {
+		m.mode = modeNormal
+		if msg.String() == "y" {
+			if todo := m.selectedTodo(); todo != nil {
+				return m.deleteTodo(todo)
+			}
+		}
+		m.status = "Delete cancelled"
+		return nil
+	}
+
+	switch msg.Type {
+	case tea.KeyEsc, tea.KeyCtrlC:
+		if m.mode == modeFilter {
+			m.filter = ""
+			m.clampCursor()
+		}
+		m.mode = modeNormal
+		return nil
+	case tea.KeyEnter:
+		return m.submitInput()
+	case tea.KeyBackspace:
+		if len(m.input) > 0 {
+			m.input = m.input[:len(m.input)-1]
+		}
+	case tea.KeyCtrlU:
+		m.input = nil
+	case tea.KeySpace:
+		m.input = append(m.input, ' ')
+	case tea.KeyRunes:
+		m.input = append(m.input, msg.Runes...)
+	default:
+		return nil
+	}
+
+	if m.mode == modeFilter {
+		// Filter as you type
+		m.filter = string(m.input)
+		m.clampCursor()
+	}
+	return nil
+}
+
+func (m *Model) startInput(mode inputMode, initial string) {
+	m.mode = mode
+	m.input = []rune(initial)
+	m.status = ""
+}
+
+func (m *Model) submitInput() tea.Cmd {
+	mode, value := m.mode, strings.TrimSpace(string(m.input))
+	m.mode = modeNormal
+
+	switch mode {
+	case modeFilter:
+		m.filter = value
+		m.clampCursor()
+		return nil
+	case modeAdd:
+		if len(value) < 3 {
+			m.status = "Description must be at least 3 characters"
+			return nil
+		}
+		return m.addTodo(value)
+	}
+
+	todo := m.selectedTodo()
+	if todo == nil {
+		return nil
+	}
+
+	switch mode {
+	case modeEdit:
+		if len(value) < 3 {
+			m.status = "Description must be at least 3 characters"
+			return nil
+		}
+		return m.editDescription(todo, value)
+	case modeNotes:
+		return m.editNotes(todo, value)
+	case modeTag:
+		return m.editTags(todo, value)
+	}
+	return nil
+}
+
+func (m *Model) move(delta int) tea.Cmd {
+	if m.focus == focusSidebar {
+		idx := max(0, min(len(m.projects), m.projectIdx+delta))
+		if idx == m.projectIdx {
+			return nil
+		}
+		m.projectIdx = idx
+		m.cursor = 0
+		return m.load()
+	}
+
+	m.cursor += delta
+	m.clampCursor()
+	return nil
+}
+
+func (m *Model) clampCursor() {
+	visible := len(m.visibleTodos())
+	m.cursor = max(0, min(m.cursor, visible-1))
+}
+
+// selectedProject returns the project chosen in the sidebar, or nil for all projects.
+func (m *Model) selectedProject() *models.Project {
+	if m.projectIdx == 0 || m.projectIdx > len(m.projects) {
+		return nil
+	}
+	return m.projects[m.projectIdx-1]
+}
+
+func (m *Model) selectedTodo() *models.Todo {
+	visible := m.visibleTodos()
+	if m.cursor < 0 || m.cursor >= len(visible) {
+		return nil
+	}
+	return visible[m.cursor]
+}
+
+// visibleTodos applies the filter, matching description, notes, tags, and branch
+// case-insensitively.
+func (m *Model) visibleTodos() []*models.Todo {
+	if m.filter == "" {
+		return m.todos
+	}
+
+	needle := strings.ToLower(m.filter)
+	var visible []*models.Todo
+	for _, todo := range m.todos {
+		if strings.Contains(m.searchText(todo), needle) {
+			visible = append(visible, todo)
+		}
+	}
+	return visible
+}
+
+func (m *Model) searchText(todo *models.Todo) string {
+	parts := []string{todo.Description}
+	if todo.Notes != nil {
+		parts = append(parts, *todo.Notes)
+	}
+	if todo.Branch != nil {
+		parts = append(parts, *todo.Branch)
+	}
+	for _, tag := range m.tags[todo.ID] {
+		parts = append(parts, "#"+tag.Name)
+	}
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+func (m *Model) applyData(msg dataMsg) {
+	var selectedID uuid.UUID
+	if todo := m.selectedTodo(); todo != nil {
+		selectedID = todo.ID
+	}
+	var projectID uuid.UUID
+	if project := m.selectedProject(); project != nil {
+		projectID = project.ID
+	}
+
+	m.projects = msg.projects
+	m.pendingCounts = msg.pendingCounts
+	m.todos = msg.todos
+	m.tags = msg.tags
+
+	// Keep the same project and todo selected when rows shift underneath us
+	if projectID != uuid.Nil {
+		m.projectIdx = 0
+		for i, p := range m.projects {
+			if p.ID == projectID {
+				m.projectIdx = i + 1
+			}
+		}
+	}
+	if selectedID != uuid.Nil {
+		for i, todo := range m.visibleTodos() {
+			if todo.ID == selectedID {
+				m.cursor = i
+			}
+		}
+	}
+	m.clampCursor()
+}
@@ -0,0 +1,289 @@
+// ABOUTME: Tests for the terminal UI model
+// ABOUTME: Drives Update with key presses against a temporary database and checks the view
+
+package tui
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/harper/toki/internal/db"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/store"
+)
+
+type fixture struct {
+	db      *sql.DB
+	model   *Model
+	project *models.Project
+}
+
+func setup(t *testing.T) *fixture {
+	t.Helper()
+
+	database, err := db.InitDB(t.Context(), filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to init DB: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	project := models.NewProject("work", nil)
+	if err := db.CreateProject(t.Context(), database, project); err != nil {
+		t.Fatal(err)
+	}
+	other := models.NewProject("home", nil)
+	if err := db.CreateProject(t.Context(), database, other); err != nil {
+		t.Fatal(err)
+	}
+	for _, todo := range []*models.Todo{
+		models.NewTodo(project.ID, "write report"),
+		models.NewTodo(project.ID, "review pull request"),
+		models.NewTodo(other.ID, "water plants"),
+	} {
+		if err := db.CreateTodo(t.Context(), database, todo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := New(t.Context(), store.NewSQLite(database), &project.ID, "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fixture{db: database, model: m, project: project}
+	f.send(t, tea.WindowSizeMsg{Width: 120, Height: 30})
+	f.run(t, m.load())
+	return f
+}
+
+// send delivers a message and runs any command it returns, as the Bubble Tea runtime would.
+func (f *fixture) send(t *testing.T, msg tea.Msg) {
+	t.Helper()
+	_, cmd := f.model.Update(msg)
+	f.run(t, cmd)
+}
+
+// run executes a command and feeds its result back. Ticks and batches are skipped so
+// tests stay synchronous.
+func (f *fixture) run(t *testing.T, cmd tea.Cmd) {
+	t.Helper()
+	if cmd == nil {
+		return
+	}
+	switch msg := cmd().(type) {
+	case dataMsg, errMsg:
+		f.send(t, msg)
+	}
+}
+
+func (f *fixture) keys(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		switch key {
+		case "enter":
+			f.send(t, tea.KeyMsg{Type: tea.KeyEnter})
+		case "esc":
+			f.send(t, tea.KeyMsg{Type: tea.KeyEsc})
+		case "ctrl+u":
+			f.send(t, tea.KeyMsg{Type: tea.KeyCtrlU})
+		case "tab":
+			f.send(t, tea.KeyMsg{Type: tea.KeyTab})
+		case " ":
+			f.send(t, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+		default:
+			f.send(t, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		}
+	}
+}
+
+func (f *fixture) selected(t *testing.T) *models.Todo {
+	t.Helper()
+	todo := f.model.selectedTodo()
+	if todo == nil {
+		t.Fatal("Expected a selected todo")
+	}
+	stored, err := db.GetTodoByID(t.Context(), f.db, todo.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestInitialSelection(t *testing.T) {
+	f := setup(t)
+
+	if p := f.model.selectedProject(); p == nil || p.ID != f.project.ID {
+		t.Fatalf("Expected project 'work' selected, got %v", p)
+	}
+	if len(f.model.todos) != 2 {
+		t.Errorf("Expected 2 todos, got %d", len(f.model.todos))
+	}
+
+	view := f.model.View()
+	for _, want := range []string{"PROJECTS", "All projects (3)", "work (2)", "home (1)", "write report", "review pull request"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected view to contain %q", want)
+		}
+	}
+	if strings.Contains(view, "water plants") {
+		t.Error("Expected todos of other projects to be hidden")
+	}
+}
+
+func TestSidebarNavigation(t *testing.T) {
+	f := setup(t)
+
+	f.keys(t, "tab", "g")
+	if f.model.selectedProject() != nil {
+		t.Fatal("Expected 'All projects' selected")
+	}
+	if len(f.model.todos) != 3 {
+		t.Errorf("Expected 3 todos across projects, got %d", len(f.model.todos))
+	}
+}
+
+func TestToggleDone(t *testing.T) {
+	f := setup(t)
+	id := f.model.selectedTodo().ID
+
+	f.keys(t, " ")
+	todo, err := db.GetTodoByID(t.Context(), f.db, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !todo.Done {
+		t.Error("Expected todo to be done")
+	}
+	if len(f.model.todos) != 1 {
+		t.Errorf("Expected completed todo to be hidden, got %d todos", len(f.model.todos))
+	}
+
+	f.keys(t, "c")
+	if len(f.model.todos) != 2 {
+		t.Errorf("Expected completed todos shown, got %d todos", len(f.model.todos))
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := setup(t)
+
+	f.keys(t, "/", "r", "e", "v")
+	if visible := f.model.visibleTodos(); len(visible) != 1 || visible[0].Description != "review pull request" {
+		t.Fatalf("Expected filter to match one todo, got %d", len(visible))
+	}
+
+	f.keys(t, "esc")
+	if f.model.filter != "" || len(f.model.visibleTodos()) != 2 {
+		t.Error("Expected esc to clear the filter")
+	}
+}
+
+func TestEditDescriptionAndNotes(t *testing.T) {
+	f := setup(t)
+
+	f.keys(t, "e", "ctrl+u", "file", " ", "taxes", "enter")
+	if got := f.selected(t).Description; got != "file taxes" {
+		t.Errorf("Expected description 'file taxes', got %q", got)
+	}
+
+	f.keys(t, "n", "due", " ", "friday", "enter")
+	todo := f.selected(t)
+	if todo.Notes == nil || *todo.Notes != "due friday" {
+		t.Errorf("Expected notes 'due friday', got %v", todo.Notes)
+	}
+	if !strings.Contains(f.model.View(), "due friday") {
+		t.Error("Expected detail pane to show notes")
+	}
+}
+
+func TestEditTagsAndPriority(t *testing.T) {
+	f := setup(t)
+
+	f.keys(t, "t", "urgent,", " ", "work", "enter")
+	tags, err := db.GetTodoTags(t.Context(), f.db, f.model.selectedTodo().ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 {
+		t.Fatalf("Expected 2 tags, got %d", len(tags))
+	}
+
+	f.keys(t, "t", "-work", "enter")
+	tags, _ = db.GetTodoTags(t.Context(), f.db, f.model.selectedTodo().ID)
+	if len(tags) != 1 || tags[0].Name != "urgent" {
+		t.Errorf("Expected only 'urgent' left, got %v", tags)
+	}
+
+	f.keys(t, "p", "p")
+	if p := f.selected(t).Priority; p == nil || *p != "medium" {
+		t.Errorf("Expected priority medium, got %v", p)
+	}
+}
+
+func TestDeleteRequiresConfirmation(t *testing.T) {
+	f := setup(t)
+	id := f.model.selectedTodo().ID
+
+	f.keys(t, "d", "n")
+	if _, err := db.GetTodoByID(t.Context(), f.db, id); err != nil {
+		t.Fatal("Expected todo to survive a declined delete")
+	}
+
+	f.keys(t, "d", "y")
+	if _, err := db.GetTodoByID(t.Context(), f.db, id); err == nil {
+		t.Fatal("Expected todo to be deleted")
+	}
+	if len(f.model.todos) != 1 {
+		t.Errorf("Expected 1 todo left, got %d", len(f.model.todos))
+	}
+}
+
+func TestAddTodo(t *testing.T) {
+	f := setup(t)
+
+	f.keys(t, "a", "call", " ", "bank", "enter")
+	pending := false
+	todos, err := db.ListTodos(t.Context(), f.db, &f.project.ID, &pending, nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 3 {
+		t.Errorf("Expected 3 todos in project, got %d", len(todos))
+	}
+}
+
+func TestRefreshOnExternalChange(t *testing.T) {
+	f := setup(t)
+
+	watcher, err := db.NewChangeWatcher(t.Context(), f.db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = watcher.Close() }()
+	f.model.watcher = watcher
+
+	// The watcher pins its own connection, so this write looks like another process
+	if err := db.CreateTodo(t.Context(), f.db, models.NewTodo(f.project.ID, "added elsewhere")); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cmd := f.model.Update(tickMsg{})
+	if cmd == nil {
+		t.Fatal("Expected a command after tick")
+	}
+	batch, ok := cmd().(tea.BatchMsg)
+	if !ok {
+		t.Fatal("Expected tick to trigger a reload")
+	}
+	for _, c := range batch {
+		if msg, ok := c().(dataMsg); ok {
+			f.send(t, msg)
+			break
+		}
+	}
+	if len(f.model.todos) != 3 {
+		t.Errorf("Expected reload to pick up new todo, got %d todos", len(f.model.todos))
+	}
+}
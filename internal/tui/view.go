@@ -0,0 +1,296 @@
+// ABOUTME: Rendering for the terminal UI
+// ABOUTME: Lays out the project sidebar, todo list, detail pane, and status line with the ui palette
+
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/harper/toki/internal/models"
+	"github.com/harper/toki/internal/ui"
+)
+
+const (
+	maxSidebarWidth = 28
+	detailHeight    = 8
+	separator       = " │ "
+)
+
+var (
+	selected = color.New(color.ReverseVideo)
+	bold     = color.New(color.Bold)
+	green    = color.New(color.FgGreen)
+)
+
+// segment is a run of text drawn in one color; a nil color draws plain text.
+type segment struct {
+	text  string
+	color *color.Color
+}
+
+// render draws segments truncated and padded to exactly width columns, so colored
+// cells line up regardless of escape codes.
+func render(width int, segments ...segment) string {
+	var builder strings.Builder
+	remaining := width
+	for _, s := range segments {
+		if remaining <= 0 {
+			break
+		}
+		text := []rune(s.text)
+		if len(text) > remaining {
+			text = append(text[:max(0, remaining-1)], '…')
+		}
+		remaining -= len(text)
+		if s.color != nil {
+			builder.WriteString(s.color.Sprint(string(text)))
+		} else {
+			builder.WriteString(string(text))
+		}
+	}
+	builder.WriteString(strings.Repeat(" ", max(0, remaining)))
+	return builder.String()
+}
+
+// View draws the whole screen.
+func (m *Model) View() string {
+	if m.width == 0 || m.height == 0 {
+		return "Loading..."
+	}
+
+	sidebarWidth := min(maxSidebarWidth, m.width/3)
+	listWidth := max(10, m.width-sidebarWidth-len([]rune(separator)))
+	bodyHeight := max(1, m.height-detailHeight-3)
+
+	sidebar := m.sidebarLines(sidebarWidth, bodyHeight)
+	list := m.listLines(listWidth, bodyHeight)
+
+	var builder strings.Builder
+	builder.WriteString(m.headerLine())
+	builder.WriteString("\n")
+	for i := 0; i < bodyHeight; i++ {
+		builder.WriteString(sidebar[i])
+		builder.WriteString(ui.Faint.Sprint(separator))
+		builder.WriteString(list[i])
+		builder.WriteString("\n")
+	}
+	builder.WriteString(ui.Faint.Sprint(strings.Repeat("─", m.width)))
+	builder.WriteString("\n")
+	for _, line := range m.detailLines(m.width) {
+		builder.WriteString(line)
+		builder.WriteString("\n")
+	}
+	builder.WriteString(m.statusLine())
+	builder.WriteString("\n")
+	builder.WriteString(render(m.width, segment{m.helpText(), ui.Faint}))
+
+	return builder.String()
+}
+
+func (m *Model) headerLine() string {
+	title := "All projects"
+	if project := m.selectedProject(); project != nil {
+		title = project.Name
+	}
+
+	segments := []segment{{"toki ", ui.BoldCyan}, {title, bold}}
+	visible := len(m.visibleTodos())
+	if m.showDone {
+		segments = append(segments, segment{fmt.Sprintf("  %d todo(s), including completed", visible), ui.Faint})
+	} else {
+		segments = append(segments, segment{fmt.Sprintf("  %d pending", visible), ui.Faint})
+	}
+	if m.filter != "" {
+		segments = append(segments, segment{"  filter: " + m.filter, ui.PriorityMedium})
+	}
+	return render(m.width, segments...)
+}
+
+func (m *Model) sidebarLines(width, height int) []string {
+	lines := make([]string, 0, height)
+	lines = append(lines, render(width, segment{"PROJECTS", ui.BoldCyan}))
+
+	total := 0
+	for _, count := range m.pendingCounts {
+		total += count
+	}
+	entries := []string{fmt.Sprintf("All projects (%d)", total)}
+	for _, p := range m.projects {
+		entries = append(entries, fmt.Sprintf("%s (%d)", p.Name, m.pendingCounts[p.ID]))
+	}
+
+	start := scrollStart(m.projectIdx, len(entries), height-1)
+	for i := start; i < len(entries) && len(lines) < height; i++ {
+		switch {
+		case i == m.projectIdx && m.focus == focusSidebar:
+			lines = append(lines, selected.Sprint(render(width, segment{"› " + entries[i], nil})))
+		case i == m.projectIdx:
+			lines = append(lines, render(width, segment{"› ", ui.BoldCyan}, segment{entries[i], bold}))
+		default:
+			lines = append(lines, render(width, segment{"  " + entries[i], nil}))
+		}
+	}
+
+	return padLines(lines, width, height)
+}
+
+func (m *Model) listLines(width, height int) []string {
+	visible := m.visibleTodos()
+	if len(visible) == 0 {
+		message := "No pending todos. Press a to add one."
+		if m.filter != "" {
+			message = "No todos match the filter."
+		}
+		return padLines([]string{render(width, segment{message, ui.Faint})}, width, height)
+	}
+
+	lines := make([]string, 0, height)
+	start := scrollStart(m.cursor, len(visible), height)
+	for i := start; i < len(visible) && len(lines) < height; i++ {
+		row := m.todoSegments(visible[i])
+		if i == m.cursor && m.focus == focusList {
+			// Reverse video needs one uncolored run so the highlight spans the row
+			var plain strings.Builder
+			for _, s := range row {
+				plain.WriteString(s.text)
+			}
+			lines = append(lines, selected.Sprint(render(width, segment{"› " + plain.String(), nil})))
+			continue
+		}
+
+		marker := segment{"  ", nil}
+		if i == m.cursor {
+			marker = segment{"› ", ui.BoldCyan}
+		}
+		lines = append(lines, render(width, append([]segment{marker}, row...)...))
+	}
+
+	return padLines(lines, width, height)
+}
+
+func (m *Model) todoSegments(todo *models.Todo) []segment {
+	check := segment{"○ ", nil}
+	if todo.Done {
+		check = segment{"✓ ", green}
+	}
+
+	segments := []segment{check, {todo.ID.String()[:6] + "  ", ui.Faint}}
+	if todo.Priority != nil {
+		if c := ui.PriorityColor(*todo.Priority); c != nil {
+			segments = append(segments, segment{"[" + strings.ToUpper(*todo.Priority) + "] ", c})
+		}
+	}
+
+	description := segment{todo.Description, nil}
+	if todo.Done {
+		description.color = ui.Faint
+	}
+	segments = append(segments, description)
+
+	if todo.DueDate != nil {
+		segments = append(segments, segment{"  due " + todo.DueDate.Format(ui.DateLayout), ui.PriorityMedium})
+	}
+	for _, tag := range m.tags[todo.ID] {
+		segments = append(segments, segment{" #" + tag.Name, ui.BoldCyan})
+	}
+	return segments
+}
+
+func (m *Model) detailLines(width int) []string {
+	todo := m.selectedTodo()
+	if todo == nil {
+		return padLines(nil, width, detailHeight)
+	}
+
+	lines := []string{render(width, segment{todo.Description, bold})}
+
+	meta := []segment{{"ID: " + todo.ID.String()[:8], ui.Faint}}
+	meta = append(meta, segment{"  Priority: " + priorityLabel(todo.Priority), ui.Faint})
+	meta = append(meta, segment{"  Created: " + todo.CreatedAt.Format(ui.DateLayout), ui.Faint})
+	if todo.CompletedAt != nil {
+		meta = append(meta, segment{"  Completed: " + todo.CompletedAt.Format(ui.DateLayout), ui.Faint})
+	}
+	if todo.Branch != nil {
+		meta = append(meta, segment{"  Branch: " + *todo.Branch, ui.Faint})
+	}
+	lines = append(lines, render(width, meta...))
+
+	if todo.Notes == nil || *todo.Notes == "" {
+		lines = append(lines, render(width, segment{"No notes. Press n to add some.", ui.Faint}))
+	} else {
+		for _, line := range wrap(*todo.Notes, width) {
+			lines = append(lines, render(width, segment{line, nil}))
+		}
+	}
+
+	if len(lines) > detailHeight {
+		lines = lines[:detailHeight]
+	}
+	return padLines(lines, width, detailHeight)
+}
+
+func (m *Model) statusLine() string {
+	prompts := map[inputMode]string{
+		modeFilter: "Filter: ",
+		modeAdd:    "New todo: ",
+		modeEdit:   "Description: ",
+		modeNotes:  "Notes: ",
+		modeTag:    "Tags (comma-separated, -tag removes): ",
+	}
+
+	if m.mode == modeConfirmDelete {
+		return render(m.width, segment{"Delete this todo? (y/n)", ui.Red})
+	}
+	if prompt, ok := prompts[m.mode]; ok {
+		return render(m.width, segment{prompt, ui.BoldCyan}, segment{string(m.input) + "█", nil})
+	}
+	if strings.HasPrefix(m.status, "Error:") {
+		return render(m.width, segment{m.status, ui.Red})
+	}
+	return render(m.width, segment{m.status, green})
+}
+
+func (m *Model) helpText() string {
+	if m.mode != modeNormal && m.mode != modeConfirmDelete {
+		return "enter save • esc cancel • ctrl+u clear"
+	}
+	return "tab switch pane • j/k move • space done • e edit • n notes • t tag • p priority • d delete • a add • / filter • c completed • q quit"
+}
+
+// scrollStart returns the first row to draw so that cursor stays on screen.
+func scrollStart(cursor, total, height int) int {
+	if height <= 0 || cursor < height {
+		return 0
+	}
+	return min(cursor-height+1, max(0, total-height))
+}
+
+func padLines(lines []string, width, height int) []string {
+	for len(lines) < height {
+		lines = append(lines, strings.Repeat(" ", width))
+	}
+	return lines
+}
+
+// wrap splits text into lines of at most width runes, breaking on spaces where possible.
+func wrap(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
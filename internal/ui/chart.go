@@ -0,0 +1,39 @@
+// ABOUTME: Text bar charts for terminal output
+// ABOUTME: Renders labeled horizontal bars scaled to a fixed width
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Bar is one row of a bar chart. Text is shown after the bar, usually the value.
+type Bar struct {
+	Label string
+	Value float64
+	Text  string
+}
+
+// BarChart renders one bar per row, scaled so the largest value spans width characters.
+// Nonzero values always get at least one character.
+func BarChart(bars []Bar, width int) string {
+	labelWidth := 0
+	maxValue := 0.0
+	for _, bar := range bars {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(bar.Label))
+		maxValue = max(maxValue, bar.Value)
+	}
+
+	var builder strings.Builder
+	for _, bar := range bars {
+		length := 0
+		if maxValue > 0 && bar.Value > 0 {
+			length = max(int(bar.Value/maxValue*float64(width)+0.5), 1)
+		}
+		padding := strings.Repeat(" ", labelWidth-utf8.RuneCountInString(bar.Label))
+		fmt.Fprintf(&builder, "  %s%s  %s %s\n", Faint.Sprint(bar.Label), padding, BoldCyan.Sprint(strings.Repeat("█", length)), bar.Text)
+	}
+	return builder.String()
+}
@@ -12,15 +12,30 @@ import (
 	"github.com/harper/toki/internal/models"
 )
 
+// Palette shared by the CLI output and the TUI.
 var (
-	boldCyan       = color.New(color.Bold, color.FgCyan)
-	faint          = color.New(color.Faint)
-	red            = color.New(color.FgRed)
-	priorityHigh   = color.New(color.FgRed, color.Bold)
-	priorityMedium = color.New(color.FgYellow)
-	priorityLow    = color.New(color.Faint)
+	BoldCyan       = color.New(color.Bold, color.FgCyan)
+	Faint          = color.New(color.Faint)
+	Red            = color.New(color.FgRed)
+	PriorityHigh   = color.New(color.FgRed, color.Bold)
+	PriorityMedium = color.New(color.FgYellow)
+	PriorityLow    = color.New(color.Faint)
 )
 
+// PriorityColor returns the palette color for a priority, or nil for unknown priorities.
+func PriorityColor(priority string) *color.Color {
+	switch priority {
+	case "high":
+		return PriorityHigh
+	case "medium":
+		return PriorityMedium
+	case "low":
+		return PriorityLow
+	default:
+		return nil
+	}
+}
+
 // FormatTodo formats a single todo for display.
 func FormatTodo(todo *models.Todo, tags []*models.Tag) string {
 	var builder strings.Builder
@@ -33,18 +48,12 @@ func FormatTodo(todo *models.Todo, tags []*models.Tag) string {
 		builder.WriteString("✓ ")
 	}
 
-	builder.WriteString(faint.Sprint(todo.ID.String()[:6]))
+	builder.WriteString(Faint.Sprint(todo.ID.String()[:6]))
 	builder.WriteString("  ")
 
 	if todo.Priority != nil {
-		priority := strings.ToUpper(*todo.Priority)
-		switch *todo.Priority {
-		case "high":
-			builder.WriteString(priorityHigh.Sprintf("[%s] ", priority))
-		case "medium":
-			builder.WriteString(priorityMedium.Sprintf("[%s] ", priority))
-		case "low":
-			builder.WriteString(priorityLow.Sprintf("[%s] ", priority))
+		if c := PriorityColor(*todo.Priority); c != nil {
+			builder.WriteString(c.Sprintf("[%s] ", strings.ToUpper(*todo.Priority)))
 		}
 	}
 
@@ -55,16 +64,24 @@ func FormatTodo(todo *models.Todo, tags []*models.Tag) string {
 	var metadata []string
 
 	if todo.DueDate != nil {
-		dueStr := todo.DueDate.Format("2006-01-02")
+		dueStr := todo.DueDate.Format(DateLayout)
 		// Compare dates only (not time) - truncate to start of day
 		today := time.Now().Truncate(24 * time.Hour)
 		dueDay := todo.DueDate.Truncate(24 * time.Hour)
 		if dueDay.Before(today) {
-			dueStr = red.Sprint(dueStr + " (overdue)")
+			dueStr = Red.Sprint(dueStr + " (overdue)")
 		}
 		metadata = append(metadata, "Due: "+dueStr)
 	}
 
+	if todo.Estimate != nil {
+		metadata = append(metadata, "Estimate: "+todo.Estimate.String())
+	}
+
+	if todo.Branch != nil {
+		metadata = append(metadata, "Branch: "+*todo.Branch)
+	}
+
 	if len(tags) > 0 {
 		tagNames := make([]string, len(tags))
 		for i, tag := range tags {
@@ -75,23 +92,55 @@ func FormatTodo(todo *models.Todo, tags []*models.Tag) string {
 
 	if len(metadata) > 0 {
 		builder.WriteString("          ")
-		builder.WriteString(faint.Sprint(strings.Join(metadata, " | ")))
+		builder.WriteString(Faint.Sprint(strings.Join(metadata, " | ")))
 		builder.WriteString("\n")
 	}
 
 	return builder.String()
 }
 
+// FormatUrgency formats a todo's urgency score as an extra metadata line.
+func FormatUrgency(score float64) string {
+	return "          " + Faint.Sprintf("Urgency: %.1f", score) + "\n"
+}
+
+// FormatTracked formats a todo's tracked time as an extra metadata line.
+func FormatTracked(tracked time.Duration, running bool) string {
+	text := "Tracked: " + FormatDuration(tracked)
+	if running {
+		text += " (timer running)"
+	}
+	return "          " + Faint.Sprint(text) + "\n"
+}
+
+// FormatDuration formats a duration in hours and minutes, like 1h30m or 45m. Durations
+// under a minute are shown in seconds.
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Round(time.Second)/time.Second))
+	}
+	d = d.Round(time.Minute)
+	hours, minutes := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	default:
+		return fmt.Sprintf("%dh%02dm", hours, minutes)
+	}
+}
+
 // FormatProjectHeader formats a project header.
 func FormatProjectHeader(project *models.Project) string {
-	header := fmt.Sprintf("PROJECT: %s", boldCyan.Sprint(project.Name))
+	header := fmt.Sprintf("PROJECT: %s", BoldCyan.Sprint(project.Name))
 	if project.DirectoryPath != nil {
-		header += faint.Sprintf(" (%s)", *project.DirectoryPath)
+		header += Faint.Sprintf(" (%s)", *project.DirectoryPath)
 	}
 	return header
 }
 
 // FormatSeparator creates a separator line.
 func FormatSeparator() string {
-	return faint.Sprint("─────────────────────────────────────────────")
+	return Faint.Sprint("─────────────────────────────────────────────")
 }
@@ -3,6 +3,7 @@ package ui
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/harper/toki/internal/models"
 )
@@ -71,3 +72,51 @@ func TestFormatTodo_DoneWithTags(t *testing.T) {
 		t.Error("Completed todo should still show tags")
 	}
 }
+
+func TestFormatUrgency(t *testing.T) {
+	if output := FormatUrgency(8.456); !strings.Contains(output, "Urgency: 8.5") {
+		t.Errorf("Expected rounded urgency, got %q", output)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{42 * time.Second, "42s"},
+		{45 * time.Minute, "45m"},
+		{90*time.Minute + 20*time.Second, "1h30m"},
+		{2 * time.Hour, "2h"},
+		{26*time.Hour + 5*time.Minute, "26h05m"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+
+	if output := FormatTracked(time.Hour, true); !strings.Contains(output, "Tracked: 1h (timer running)") {
+		t.Errorf("Unexpected tracked line %q", output)
+	}
+}
+
+func TestBarChart(t *testing.T) {
+	chart := BarChart([]Bar{
+		{Label: "Mon", Value: 10, Text: "10"},
+		{Label: "Tue", Value: 5, Text: "5"},
+		{Label: "Wed", Value: 0.1, Text: "0.1"},
+		{Label: "Thu", Value: 0, Text: "0"},
+	}, 20)
+
+	lines := strings.Split(strings.TrimSuffix(chart, "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 4 rows, got %q", chart)
+	}
+	for i, want := range []int{20, 10, 1, 0} {
+		if got := strings.Count(lines[i], "█"); got != want {
+			t.Errorf("Row %d: expected %d blocks, got %d in %q", i, want, got, lines[i])
+		}
+	}
+}
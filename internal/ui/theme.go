@@ -0,0 +1,44 @@
+// ABOUTME: Color themes and date display settings for CLI and TUI output
+// ABOUTME: Swaps the shared palette for light terminals or monochrome output
+
+package ui
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+)
+
+// DateLayout is the Go time layout used to display dates.
+var DateLayout = "2006-01-02"
+
+// SetTheme replaces the palette with a named theme: default, light, or mono.
+func SetTheme(name string) error {
+	switch name {
+	case "default":
+		BoldCyan = color.New(color.Bold, color.FgCyan)
+		Faint = color.New(color.Faint)
+		Red = color.New(color.FgRed)
+		PriorityHigh = color.New(color.FgRed, color.Bold)
+		PriorityMedium = color.New(color.FgYellow)
+		PriorityLow = color.New(color.Faint)
+	case "light":
+		// Yellow and cyan wash out on light backgrounds
+		BoldCyan = color.New(color.Bold, color.FgBlue)
+		Faint = color.New(color.FgHiBlack)
+		Red = color.New(color.FgRed)
+		PriorityHigh = color.New(color.FgRed, color.Bold)
+		PriorityMedium = color.New(color.FgMagenta)
+		PriorityLow = color.New(color.FgHiBlack)
+	case "mono":
+		BoldCyan = color.New(color.Bold)
+		Faint = color.New(color.Faint)
+		Red = color.New(color.Bold)
+		PriorityHigh = color.New(color.Bold)
+		PriorityMedium = color.New(color.Reset)
+		PriorityLow = color.New(color.Faint)
+	default:
+		return fmt.Errorf("unknown theme '%s'", name)
+	}
+	return nil
+}
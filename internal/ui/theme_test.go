@@ -0,0 +1,24 @@
+// ABOUTME: Tests for color themes
+// ABOUTME: Verifies theme switching and rejection of unknown names
+
+package ui
+
+import "testing"
+
+func TestSetTheme(t *testing.T) {
+	defer func() { _ = SetTheme("default") }()
+
+	for _, name := range []string{"default", "light", "mono"} {
+		if err := SetTheme(name); err != nil {
+			t.Errorf("SetTheme(%s) failed: %v", name, err)
+		}
+	}
+
+	before := PriorityHigh
+	if err := SetTheme("neon"); err == nil {
+		t.Error("Expected unknown theme to fail")
+	}
+	if PriorityHigh != before {
+		t.Error("Expected failed SetTheme to keep the palette")
+	}
+}
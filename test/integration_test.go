@@ -4,11 +4,15 @@
 package test
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestFullWorkflow(t *testing.T) {
@@ -73,6 +77,12 @@ func TestFullWorkflow(t *testing.T) {
 }
 
 func setupTestBinary(t *testing.T) func(args ...string) (string, error) {
+	t.Helper()
+	return setupTestBinaryIn(t, "")
+}
+
+// buildToki builds toki and returns the path of the binary.
+func buildToki(t *testing.T) string {
 	t.Helper()
 	projectRoot, err := filepath.Abs("..")
 	if err != nil {
@@ -87,13 +97,25 @@ func setupTestBinary(t *testing.T) func(args ...string) (string, error) {
 		t.Fatalf("Failed to build: %v\nOutput: %s", err, buildOutput)
 	}
 	t.Cleanup(func() { _ = os.Remove(tokiBinary) })
+	return tokiBinary
+}
+
+// setupTestBinaryIn builds toki and returns a runner that executes it from dir.
+func setupTestBinaryIn(t *testing.T, dir string) func(args ...string) (string, error) {
+	t.Helper()
+	tokiBinary := buildToki(t)
 
 	tmpDir := t.TempDir()
 	dbPath := filepath.Join(tmpDir, "test.db")
 
+	// Keep tests independent of the user's config file
+	configPath := filepath.Join(tmpDir, "config.toml")
+
 	run := func(args ...string) (string, error) {
 		fullArgs := append([]string{"--db", dbPath}, args...)
 		cmd := exec.Command(tokiBinary, fullArgs...) //nolint:gosec // Safe: executing our own test binary with controlled args
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "TOKI_CONFIG="+configPath)
 		output, err := cmd.CombinedOutput()
 		return string(output), err
 	}
@@ -192,3 +214,1048 @@ func TestListCommand_DefaultShowsPendingOnly(t *testing.T) {
 		t.Error("Summary should say 'pending'")
 	}
 }
+
+func TestListCommand_Sort(t *testing.T) {
+	run := setupTestBinary(t)
+
+	if _, err := run("project", "add", "sorted"); err != nil {
+		t.Fatalf("Failed to create project: %v", err)
+	}
+	todos := [][]string{
+		{"add", "medium task", "--project", "sorted", "--priority", "medium"},
+		{"add", "due task", "--project", "sorted", "--due", time.Now().AddDate(0, 0, -8).Format("2006-01-02")},
+		{"add", "high task", "--project", "sorted", "--priority", "high"},
+	}
+	for _, args := range todos {
+		if output, err := run(args...); err != nil {
+			t.Fatalf("Failed to add todo: %v\n%s", err, output)
+		}
+	}
+
+	order := func(output string, descriptions ...string) {
+		t.Helper()
+		last := -1
+		for _, description := range descriptions {
+			idx := strings.Index(output, description)
+			if idx <= last {
+				t.Fatalf("Expected order %v in output:\n%s", descriptions, output)
+			}
+			last = idx
+		}
+	}
+
+	output, err := run("list", "--project", "sorted", "--sort", "priority")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	order(output, "high task", "medium task", "due task")
+
+	output, err = run("list", "--project", "sorted", "--sort", "priority", "--reverse")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	order(output, "medium task", "high task", "due task")
+
+	output, err = run("list", "--project", "sorted", "--sort", "urgency")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	order(output, "due task", "high task", "medium task")
+	if !strings.Contains(output, "Urgency: 12.0") {
+		t.Errorf("Expected urgency scores in output:\n%s", output)
+	}
+
+	if output, err := run("list", "--sort", "size"); err == nil {
+		t.Errorf("Expected invalid sort to fail:\n%s", output)
+	}
+}
+
+func TestListCommand_Filter(t *testing.T) {
+	run := setupTestBinary(t)
+
+	for _, name := range []string{"api", "web"} {
+		if _, err := run("project", "add", name); err != nil {
+			t.Fatalf("Failed to create project: %v", err)
+		}
+	}
+	todos := [][]string{
+		{"add", "fix crash", "--project", "api", "--priority", "high", "--tags", "bug"},
+		{"add", "write docs", "--project", "api", "--priority", "low"},
+		{"add", "login page", "--project", "web", "--priority", "high"},
+	}
+	for _, args := range todos {
+		if output, err := run(args...); err != nil {
+			t.Fatalf("Failed to add todo: %v\n%s", err, output)
+		}
+	}
+
+	output, err := run("list", "--filter", "project:api and (tag:bug or priority:high)")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "fix crash") || strings.Contains(output, "write docs") || strings.Contains(output, "login page") {
+		t.Errorf("Expected only 'fix crash' in output:\n%s", output)
+	}
+
+	output, err = run("list", "--filter", "priority:high", "--project", "web")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "login page") || strings.Contains(output, "fix crash") {
+		t.Errorf("Expected --project to narrow the filter:\n%s", output)
+	}
+
+	output, err = run("list", "--filter", "priority:high and")
+	if err == nil {
+		t.Fatalf("Expected invalid filter to fail:\n%s", output)
+	}
+	if !strings.Contains(output, "column 18") || !strings.Contains(output, "^") {
+		t.Errorf("Expected error position with caret:\n%s", output)
+	}
+
+	output, err = run("search", "docs", "or", "login")
+	if err != nil {
+		t.Fatalf("Failed to search: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "write docs") || !strings.Contains(output, "login page") || strings.Contains(output, "fix crash") {
+		t.Errorf("Expected search to match 'write docs' and 'login page':\n%s", output)
+	}
+}
+
+func TestSavedViews(t *testing.T) {
+	run := setupTestBinary(t)
+
+	for _, name := range []string{"api", "web"} {
+		if _, err := run("project", "add", name); err != nil {
+			t.Fatalf("Failed to create project: %v", err)
+		}
+	}
+	todos := [][]string{
+		{"add", "fix crash", "--project", "api", "--priority", "high", "--tags", "bug"},
+		{"add", "flaky test", "--project", "api", "--priority", "low", "--tags", "bug"},
+		{"add", "web bug", "--project", "web", "--priority", "high", "--tags", "bug"},
+	}
+	for _, args := range todos {
+		if output, err := run(args...); err != nil {
+			t.Fatalf("Failed to add todo: %v\n%s", err, output)
+		}
+	}
+
+	output, err := run("view", "save", "apibugs", "-p", "api", "-t", "bug", "--sort", "priority")
+	if err != nil {
+		t.Fatalf("Failed to save view: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Saved view 'apibugs'") || !strings.Contains(output, "project:api") {
+		t.Errorf("Expected saved view summary:\n%s", output)
+	}
+
+	output, err = run("view", "list")
+	if err != nil {
+		t.Fatalf("Failed to list views: %v\n%s", err, output)
+	}
+	for _, name := range []string{"@apibugs", "@pending", "@overdue", "@high-priority"} {
+		if !strings.Contains(output, name) {
+			t.Errorf("Expected %s in view list:\n%s", name, output)
+		}
+	}
+
+	output, err = run("view", "run", "apibugs")
+	if err != nil {
+		t.Fatalf("Failed to run view: %v\n%s", err, output)
+	}
+	crash, flaky := strings.Index(output, "fix crash"), strings.Index(output, "flaky test")
+	if crash < 0 || flaky < crash || strings.Contains(output, "web bug") {
+		t.Errorf("Expected api bugs sorted by priority:\n%s", output)
+	}
+
+	output, err = run("@high-priority")
+	if err != nil {
+		t.Fatalf("Failed to run shortcut: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "fix crash") || !strings.Contains(output, "web bug") || strings.Contains(output, "flaky test") {
+		t.Errorf("Expected high-priority todos from every project:\n%s", output)
+	}
+
+	if output, err := run("view", "save", "pending", "-t", "bug"); err == nil {
+		t.Errorf("Expected saving over a built-in view to fail:\n%s", output)
+	}
+
+	if output, err := run("view", "delete", "apibugs"); err != nil {
+		t.Fatalf("Failed to delete view: %v\n%s", err, output)
+	}
+	if output, err := run("@apibugs"); err == nil {
+		t.Errorf("Expected deleted view to fail:\n%s", output)
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	// Outside a git repository, so new todos go to the default project
+	run := setupTestBinaryIn(t, t.TempDir())
+
+	if output, err := run("add", "inbox task"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run("config", "set", "default_project", "inbox"); err != nil {
+		t.Fatalf("Failed to set config: %v\n%s", err, output)
+	}
+	if output, err := run("add", "configured task", "--priority", "high"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+
+	output, err := run("list", "--project", "inbox")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "configured task") || strings.Contains(output, "inbox task") {
+		t.Errorf("Expected new todos in the configured default project:\n%s", output)
+	}
+
+	// Completed todos show up once list.status is all
+	prefix := extractTodoPrefix(output)
+	if output, err := run("done", prefix); err != nil {
+		t.Fatalf("Failed to mark done: %v\n%s", err, output)
+	}
+	if output, _ := run("list", "--project", "inbox"); strings.Contains(output, "configured task") {
+		t.Errorf("Expected completed todo to be hidden by default:\n%s", output)
+	}
+	if output, err := run("config", "set", "list.status", "all"); err != nil {
+		t.Fatalf("Failed to set config: %v\n%s", err, output)
+	}
+	if output, _ := run("list", "--project", "inbox"); !strings.Contains(output, "configured task") {
+		t.Errorf("Expected list.status=all to include completed todos:\n%s", output)
+	}
+
+	if output, err := run("config", "set", "output", "json"); err != nil {
+		t.Fatalf("Failed to set config: %v\n%s", err, output)
+	}
+	output, err = run("list", "--project", "inbox")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	var todos []map[string]any
+	if err := json.Unmarshal([]byte(output), &todos); err != nil {
+		t.Fatalf("Expected JSON output: %v\n%s", err, output)
+	}
+	if len(todos) != 1 || todos[0]["project"] != "inbox" || todos[0]["priority"] != "high" {
+		t.Errorf("Unexpected JSON todos: %v", todos)
+	}
+
+	output, err = run("config", "get", "list.status")
+	if err != nil || strings.TrimSpace(output) != "all" {
+		t.Errorf("Expected config get to print 'all', got %q (%v)", output, err)
+	}
+	if output, err := run("config", "set", "auto_create", "sometimes"); err == nil {
+		t.Errorf("Expected invalid value to fail:\n%s", output)
+	}
+}
+
+func TestConfigAutoCreate(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, repo, "init", "-q", "-b", "main")
+
+	run := setupTestBinaryIn(t, repo)
+
+	if output, err := run("config", "set", "auto_create", "never"); err != nil {
+		t.Fatalf("Failed to set config: %v\n%s", err, output)
+	}
+	output, err := run("add", "stays in default")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if strings.Contains(output, "Create project") {
+		t.Errorf("Expected no prompt with auto_create=never:\n%s", output)
+	}
+
+	if output, err := run("config", "set", "auto_create", "always", "--dir", repo); err != nil {
+		t.Fatalf("Failed to set config: %v\n%s", err, output)
+	}
+	output, err = run("add", "goes to repo project")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	name := filepath.Base(repo)
+	if strings.Contains(output, "Create project") || !strings.Contains(output, "Created project '"+name+"'") {
+		t.Errorf("Expected project to be created without a prompt:\n%s", output)
+	}
+
+	output, err = run("list", "--project", "default")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "stays in default") || strings.Contains(output, "goes to repo project") {
+		t.Errorf("Unexpected default project todos:\n%s", output)
+	}
+}
+
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=toki", "-c", "user.email=toki@example.com"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func TestBranchScopedTodos(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, repo, "init", "-q", "-b", "main")
+	gitIn(t, repo, "commit", "--allow-empty", "-q", "-m", "initial")
+	gitIn(t, repo, "checkout", "-q", "-b", "feature")
+
+	run := setupTestBinaryIn(t, repo)
+
+	if output, err := run("project", "add", "app", "--path", repo); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+	if output, err := run("add", "feature work", "--branch"); err != nil {
+		t.Fatalf("Failed to add branch todo: %v\n%s", err, output)
+	}
+	if output, err := run("add", "general work"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run("config", "set", "branches.track", "true", "--dir", repo); err != nil {
+		t.Fatalf("Failed to set branches.track: %v\n%s", err, output)
+	}
+	if output, err := run("add", "tracked work"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+
+	output, err := run("list", "--branch")
+	if err != nil {
+		t.Fatalf("Failed to list current branch: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "feature work") || !strings.Contains(output, "tracked work") || strings.Contains(output, "general work") {
+		t.Errorf("Expected only the feature and tracked todos on the current branch:\n%s", output)
+	}
+
+	output, err = run("list", "--branch", "main")
+	if err != nil {
+		t.Fatalf("Failed to list named branch: %v\n%s", err, output)
+	}
+	if strings.Contains(output, "feature work") {
+		t.Errorf("Feature todo should not be listed for main:\n%s", output)
+	}
+
+	gitIn(t, repo, "checkout", "-q", "main")
+	gitIn(t, repo, "branch", "-q", "-D", "feature")
+
+	output, err = run("branch-cleanup", "--complete")
+	if err != nil {
+		t.Fatalf("Failed to clean up branches: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "was deleted") || !strings.Contains(output, "Completed 2 todo(s)") {
+		t.Errorf("Expected the deleted branch's todo to be completed:\n%s", output)
+	}
+
+	output, err = run("list", "--done", "--branch", "feature")
+	if err != nil {
+		t.Fatalf("Failed to list completed: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "feature work") {
+		t.Errorf("Expected completed feature todo:\n%s", output)
+	}
+}
+
+func TestCommitHooksCloseTodos(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, repo, "init", "-q", "-b", "main")
+
+	run := setupTestBinaryIn(t, repo)
+
+	if output, err := run("project", "add", "app", "--path", repo); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+	addOutput, err := run("add", "fix the login bug")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, addOutput)
+	}
+	closed := extractTodoPrefix(addOutput)
+
+	if output, err := run("hooks", "install", "--hook", "all"); err != nil {
+		t.Fatalf("Failed to install hooks: %v\n%s", err, output)
+	}
+
+	// commit-msg rejects references to todos that do not exist
+	reject := exec.Command("git", "-c", "user.name=toki", "-c", "user.email=toki@example.com", "commit", "--allow-empty", "-m", "closes toki:ffffff")
+	reject.Dir = repo
+	if output, err := reject.CombinedOutput(); err == nil {
+		t.Fatalf("Expected commit with unknown todo reference to be rejected:\n%s", output)
+	}
+
+	gitIn(t, repo, "commit", "--allow-empty", "-q", "-m", "Fix login\n\ncloses toki:"+closed)
+
+	output, err := run("list", "--done")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "fix the login bug") {
+		t.Errorf("Expected todo to be closed by the post-commit hook:\n%s", output)
+	}
+
+	if output, err := run("hooks", "uninstall"); err != nil {
+		t.Fatalf("Failed to uninstall hooks: %v\n%s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".git", "hooks", "post-commit")); !os.IsNotExist(err) {
+		t.Error("Expected post-commit hook to be removed")
+	}
+
+	addOutput, err = run("add", "write the docs")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, addOutput)
+	}
+	referenced := extractTodoPrefix(addOutput)
+	gitIn(t, repo, "commit", "--allow-empty", "-q", "-m", "Start docs, refs toki:"+referenced)
+
+	output, err = run("log-scan", "HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("Failed to scan log: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "applied 1 todo reference(s)") {
+		t.Errorf("Expected one reference to be applied:\n%s", output)
+	}
+
+	output, err = run("log-scan", "HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("Failed to rescan log: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "applied 0 todo reference(s)") {
+		t.Errorf("Expected rescanning to be a no-op:\n%s", output)
+	}
+
+	// A short ID only closes todos of the repository's own project
+	if output, err := run("project", "add", "other"); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+	addOutput, err = run("add", "unrelated work", "--project", "other")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, addOutput)
+	}
+	elsewhere := extractTodoPrefix(addOutput)
+	gitIn(t, repo, "commit", "--allow-empty", "-q", "-m", "closes toki:"+elsewhere)
+
+	output, err = run("log-scan", "HEAD~1..HEAD")
+	if err != nil {
+		t.Fatalf("Failed to scan log: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "applied 0 todo reference(s)") {
+		t.Errorf("Expected the other project's todo to be left alone:\n%s", output)
+	}
+	if output, err := run("list", "--project", "other"); err != nil || !strings.Contains(output, "unrelated work") {
+		t.Errorf("Expected the other project's todo still open: %v\n%s", err, output)
+	}
+}
+
+func TestScanSyncsCodeComments(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, repo, "init", "-q")
+	source := filepath.Join(repo, "main.go")
+	if err := os.WriteFile(source, []byte("package main\n\n// TODO: handle retries\n// FIXME: close the file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, ".gitignore"), []byte("vendor/\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "vendor"), 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "vendor", "lib.go"), []byte("// TODO: vendored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := setupTestBinaryIn(t, repo)
+	if output, err := run("project", "add", "app", "--path", repo); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+
+	output, err := run("scan")
+	if err != nil {
+		t.Fatalf("Failed to scan: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "2 added") {
+		t.Errorf("Expected 2 todos added:\n%s", output)
+	}
+
+	output, err = run("scan")
+	if err != nil {
+		t.Fatalf("Failed to rescan: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "0 added") {
+		t.Errorf("Expected rescan not to add todos:\n%s", output)
+	}
+
+	if err := os.WriteFile(source, []byte("package main\n\n// TODO: handle retries\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	output, err = run("scan", "--markers", "TODO,FIXME")
+	if err != nil {
+		t.Fatalf("Failed to scan after edit: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "1 closed") {
+		t.Errorf("Expected removed comment's todo to be closed:\n%s", output)
+	}
+
+	output, err = run("list", "--tag", "code")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "handle retries") || strings.Contains(output, "close the file") || strings.Contains(output, "vendored") {
+		t.Errorf("Unexpected code todos:\n%s", output)
+	}
+
+	// The scan.markers setting replaces the default markers
+	if err := os.WriteFile(source, []byte("package main\n\n// TODO: handle retries\n// XXX: temporary\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := run("config", "set", "scan.markers", "XXX"); err != nil {
+		t.Fatalf("Failed to set scan.markers: %v\n%s", err, output)
+	}
+	output, err = run("scan", "--dry-run")
+	if err != nil {
+		t.Fatalf("Failed to scan: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "XXX temporary") || strings.Contains(output, "handle retries") {
+		t.Errorf("Expected only the XXX comment:\n%s", output)
+	}
+}
+
+func TestNonInteractivePrompts(t *testing.T) {
+	repo, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, repo, "init", "-q", "-b", "main")
+	name := filepath.Base(repo)
+
+	run := setupTestBinaryIn(t, repo)
+
+	// Stdin is not a terminal here, so toki must not prompt or create the project
+	output, err := run("add", "no terminal")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if strings.Contains(output, "Create project") || strings.Contains(output, "Created project") {
+		t.Errorf("Expected no prompt and no project without a terminal:\n%s", output)
+	}
+	if !strings.Contains(output, "pass --yes") {
+		t.Errorf("Expected a hint about --yes:\n%s", output)
+	}
+
+	t.Setenv("TOKI_NONINTERACTIVE", "1")
+	output, err = run("add", "still default", "--no-input")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if strings.Contains(output, "Created project") {
+		t.Errorf("Expected --no-input to decline project creation:\n%s", output)
+	}
+
+	output, err = run("--yes", "add", "goes to repo project")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if strings.Contains(output, "Create project") || !strings.Contains(output, "Created project '"+name+"'") {
+		t.Errorf("Expected --yes to create the project without prompting:\n%s", output)
+	}
+
+	output, err = run("list", "--project", "default")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "no terminal") || !strings.Contains(output, "still default") || strings.Contains(output, "goes to repo project") {
+		t.Errorf("Unexpected default project todos:\n%s", output)
+	}
+}
+
+func TestTimeTracking(t *testing.T) {
+	run := setupTestBinaryIn(t, t.TempDir())
+
+	if output, err := run("project", "add", "api"); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+	output, err := run("add", "fix login", "-p", "api", "--tags", "bug")
+	if err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	output, _ = run("list", "-p", "api")
+	prefix := extractTodoPrefix(output)
+
+	output, err = run("timer", "start", prefix)
+	if err != nil || !strings.Contains(output, "Started timer") {
+		t.Fatalf("Failed to start timer: %v\n%s", err, output)
+	}
+	output, err = run("timer", "status")
+	if err != nil || !strings.Contains(output, "Timer running") || !strings.Contains(output, "fix login") {
+		t.Errorf("Unexpected timer status: %v\n%s", err, output)
+	}
+	output, err = run("list", "-p", "api")
+	if err != nil || !strings.Contains(output, "(timer running)") {
+		t.Errorf("Expected the running timer in list: %v\n%s", err, output)
+	}
+	output, err = run("timer", "stop")
+	if err != nil || !strings.Contains(output, "Stopped timer") {
+		t.Fatalf("Failed to stop timer: %v\n%s", err, output)
+	}
+	if output, err := run("timer", "stop"); err == nil {
+		t.Errorf("Expected an error stopping with no timer running:\n%s", output)
+	}
+
+	output, err = run("timer", "log", prefix, "1h30m")
+	if err != nil || !strings.Contains(output, "Logged 1h30m") {
+		t.Fatalf("Failed to log time: %v\n%s", err, output)
+	}
+	if output, err := run("timer", "log", prefix, "0s"); err == nil || !strings.Contains(output, "at least one second") {
+		t.Errorf("Expected a zero duration to be rejected:\n%s", output)
+	}
+
+	output, err = run("list", "-p", "api")
+	if err != nil || !strings.Contains(output, "Tracked: 1h30m") {
+		t.Errorf("Expected tracked time in list: %v\n%s", err, output)
+	}
+
+	output, err = run("timer", "report", "--range", "today")
+	if err != nil {
+		t.Fatalf("Failed to report: %v\n%s", err, output)
+	}
+	for _, want := range []string{"BY PROJECT", "api", "BY TAG", "bug", "1h30m tracked"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in report:\n%s", want, output)
+		}
+	}
+	if output, err := run("timer", "report", "--range", "someday"); err == nil {
+		t.Errorf("Expected an invalid range to fail:\n%s", output)
+	}
+}
+
+func TestEstimatesAndStats(t *testing.T) {
+	run := setupTestBinaryIn(t, t.TempDir())
+
+	if output, err := run("project", "add", "api"); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+	if output, err := run("add", "write docs", "-p", "api", "--estimate", "3"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run("add", "fix login", "-p", "api", "--estimate", "soon"); err == nil {
+		t.Errorf("Expected an invalid estimate to be rejected:\n%s", output)
+	}
+	output, _ := run("list", "-p", "api")
+	prefix := extractTodoPrefix(output)
+	if !strings.Contains(output, "Estimate: 3pt") {
+		t.Errorf("Expected the estimate in list:\n%s", output)
+	}
+
+	output, err := run("estimate", prefix, "5pt")
+	if err != nil || !strings.Contains(output, "Estimated 5pt") {
+		t.Fatalf("Failed to set estimate: %v\n%s", err, output)
+	}
+	if output, err := run("done", prefix); err != nil {
+		t.Fatalf("Failed to complete todo: %v\n%s", err, output)
+	}
+
+	output, err = run("stats", "velocity", "-p", "api", "--weeks", "4", "--unit", "points")
+	if err != nil {
+		t.Fatalf("Failed to show velocity: %v\n%s", err, output)
+	}
+	for _, want := range []string{"VELOCITY: api", "5pt", "Average: 1.3 points/week"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in velocity:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "Week of") != 4 {
+		t.Errorf("Expected 4 weeks in velocity:\n%s", output)
+	}
+
+	output, err = run("stats", "burndown", "-p", "api", "--since", "yesterday")
+	if err != nil {
+		t.Fatalf("Failed to show burndown: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "BURNDOWN: api") || strings.Count(output, "\n  ") != 2 {
+		t.Errorf("Expected two days in burndown:\n%s", output)
+	}
+	if output, err := run("stats", "burndown", "--since", "tomorrow"); err == nil {
+		t.Errorf("Expected a future --since to fail:\n%s", output)
+	}
+
+	output, err = run("estimate", prefix, "--clear")
+	if err != nil || !strings.Contains(output, "Cleared estimate") {
+		t.Errorf("Failed to clear estimate: %v\n%s", err, output)
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	run := setupTestBinaryIn(t, t.TempDir())
+
+	output, err := run("stats")
+	if err != nil || !strings.Contains(output, "0 todos") {
+		t.Fatalf("Expected empty stats: %v\n%s", err, output)
+	}
+
+	if output, err := run("project", "add", "api"); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+	if output, err := run("add", "fix login", "-p", "api", "--tags", "bug", "--priority", "high", "--due", "2020-01-01"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run("add", "write docs", "-p", "api"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+
+	output, err = run("stats")
+	if err != nil {
+		t.Fatalf("Failed to show stats: %v\n%s", err, output)
+	}
+	for _, want := range []string{"2 todos: 2 pending, 0 completed, 1 overdue", "BY PROJECT", "api", "BY TAG", "bug", "AGE OF PENDING TODOS", "Oldest:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q in stats:\n%s", want, output)
+		}
+	}
+
+	output, err = run("stats", "--json")
+	if err != nil {
+		t.Fatalf("Failed to show stats as JSON: %v\n%s", err, output)
+	}
+	var report struct {
+		Summary struct {
+			TotalTodos int `json:"total_todos"`
+			Overdue    int `json:"overdue"`
+		} `json:"summary"`
+		ByProject []struct {
+			ProjectName string `json:"project_name"`
+			Overdue     int    `json:"overdue"`
+		} `json:"by_project"`
+		ByTag []struct {
+			Name string `json:"name"`
+		} `json:"by_tag"`
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse stats JSON: %v\n%s", err, output)
+	}
+	if report.Summary.TotalTodos != 2 || report.Summary.Overdue != 1 {
+		t.Errorf("Unexpected summary: %+v", report.Summary)
+	}
+	if len(report.ByProject) != 1 || report.ByProject[0].ProjectName != "api" || report.ByProject[0].Overdue != 1 {
+		t.Errorf("Unexpected projects: %+v", report.ByProject)
+	}
+	if len(report.ByTag) != 1 || report.ByTag[0].Name != "bug" {
+		t.Errorf("Unexpected tags: %+v", report.ByTag)
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	run := setupTestBinaryIn(t, t.TempDir())
+
+	if output, err := run("project", "add", "api"); err != nil {
+		t.Fatalf("Failed to create project: %v\n%s", err, output)
+	}
+	for _, description := range []string{"first task", "second task", "third task"} {
+		if output, err := run("add", description, "-p", "api"); err != nil {
+			t.Fatalf("Failed to add todo: %v\n%s", err, output)
+		}
+	}
+
+	output, err := run("list", "-p", "api", "--limit", "2")
+	if err != nil {
+		t.Fatalf("Failed to list todos: %v\n%s", err, output)
+	}
+	for _, want := range []string{"third task", "second task", "1-2 of 3 pending todo(s), page 1 of 2", "--page 2"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected %q on page 1:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "first task") {
+		t.Errorf("Expected the oldest todo on page 2:\n%s", output)
+	}
+
+	output, err = run("list", "-p", "api", "--limit", "2", "--page", "2")
+	if err != nil {
+		t.Fatalf("Failed to list page 2: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "first task") || strings.Contains(output, "second task") || !strings.Contains(output, "page 2 of 2") {
+		t.Errorf("Expected only the oldest todo on page 2:\n%s", output)
+	}
+
+	if output, err := run("list", "-p", "api", "--page", "2"); err == nil {
+		t.Errorf("Expected --page without --limit to fail:\n%s", output)
+	}
+}
+
+func TestConcurrentProcesses(t *testing.T) {
+	run := setupTestBinaryIn(t, t.TempDir())
+
+	// Every process starts on a fresh database, so migrations race too
+	const processes, perProcess = 6, 5
+	errs := make(chan string, processes*perProcess)
+	var wg sync.WaitGroup
+	for p := range processes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProcess {
+				description := fmt.Sprintf("process %d todo %d", p, i)
+				if output, err := run("add", description, "--tags", "hammer"); err != nil {
+					errs <- fmt.Sprintf("%s: %v\n%s", description, err, output)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Failed to add todo: %s", err)
+	}
+
+	output, err := run("stats", "--json")
+	if err != nil {
+		t.Fatalf("Failed to get stats: %v\n%s", err, output)
+	}
+	var report struct {
+		Summary struct {
+			TotalTodos int `json:"total_todos"`
+		} `json:"summary"`
+		ByTag []struct {
+			Name      string `json:"name"`
+			TodoCount int    `json:"todo_count"`
+		} `json:"by_tag"`
+	}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("Failed to parse stats JSON: %v\n%s", err, output)
+	}
+	if report.Summary.TotalTodos != processes*perProcess {
+		t.Errorf("Expected %d todos, got %d", processes*perProcess, report.Summary.TotalTodos)
+	}
+	if len(report.ByTag) != 1 || report.ByTag[0].TodoCount != processes*perProcess {
+		t.Errorf("Expected every todo tagged once, got %+v", report.ByTag)
+	}
+}
+
+func TestDatabaseBackupRestore(t *testing.T) {
+	run := setupTestBinaryIn(t, t.TempDir())
+	backupPath := filepath.Join(t.TempDir(), "copy.db")
+
+	if output, err := run("config", "set", "backup.auto", "true"); err != nil {
+		t.Fatalf("Failed to turn on automatic backups: %v\n%s", err, output)
+	}
+
+	if output, err := run("add", "before backup"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run("db", "backup", backupPath); err != nil {
+		t.Fatalf("Failed to back up: %v\n%s", err, output)
+	}
+	if output, err := run("add", "after backup"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+
+	// Without a terminal, restoring needs --yes
+	if output, err := run("db", "restore", backupPath); err == nil {
+		t.Errorf("Expected restore without --yes to be declined:\n%s", output)
+	}
+	output, err := run("--yes", "db", "restore", backupPath)
+	if err != nil {
+		t.Fatalf("Failed to restore: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Previous database saved") {
+		t.Errorf("Expected a backup of the replaced database:\n%s", output)
+	}
+
+	output, err = run("list")
+	if err != nil {
+		t.Fatalf("Failed to list: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "before backup") || strings.Contains(output, "after backup") {
+		t.Errorf("Expected the backed up todos only:\n%s", output)
+	}
+
+	// The first command of the day took an automatic backup
+	output, err = run("db", "backups")
+	if err != nil {
+		t.Fatalf("Failed to list backups: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "daily") || !strings.Contains(output, "pre-restore") {
+		t.Errorf("Expected daily and pre-restore backups:\n%s", output)
+	}
+
+	if output, err := run("db", "check"); err != nil || !strings.Contains(output, "intact") {
+		t.Errorf("Expected check to pass: %v\n%s", err, output)
+	}
+	if output, err := run("--yes", "db", "restore", filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Errorf("Expected restoring a missing file to fail:\n%s", output)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	run := setupTestBinaryIn(t, t.TempDir())
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "toki.key")
+	exportPath := filepath.Join(dir, "toki.enc")
+
+	if output, err := run("config", "set", "backup.auto", "true"); err != nil {
+		t.Fatalf("Failed to turn on automatic backups: %v\n%s", err, output)
+	}
+	if output, err := run("add", "written before the key"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run("key", "generate", keyPath); err != nil {
+		t.Fatalf("Failed to generate key: %v\n%s", err, output)
+	}
+	if output, err := run("config", "set", "encryption.key_file", keyPath); err != nil {
+		t.Fatalf("Failed to set key file: %v\n%s", err, output)
+	}
+	if output, err := run("key", "encrypt"); err != nil || !strings.Contains(output, "Encrypted 1 todos") {
+		t.Fatalf("Failed to encrypt: %v\n%s", err, output)
+	} else if !strings.Contains(output, "still hold the old text") {
+		t.Errorf("Expected a warning about backups taken before encrypting:\n%s", output)
+	}
+	if output, err := run("add", "call ACME", "--notes", "about the outage"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+
+	output, err := run("search", "ACME")
+	if err != nil || !strings.Contains(output, "call ACME") || strings.Contains(output, "toki:enc") {
+		t.Errorf("Expected search to match decrypted text: %v\n%s", err, output)
+	}
+	if output, err := run("db", "export", exportPath); err != nil {
+		t.Fatalf("Failed to export: %v\n%s", err, output)
+	}
+
+	// Without the key, reading fails instead of showing ciphertext
+	if output, err := run("config", "unset", "encryption.key_file"); err != nil {
+		t.Fatalf("Failed to unset key file: %v\n%s", err, output)
+	}
+	output, err = run("list")
+	if err == nil || !strings.Contains(output, "no key is set") {
+		t.Errorf("Expected a missing key error: %v\n%s", err, output)
+	}
+	if output, err := run("--yes", "db", "import", exportPath); err == nil {
+		t.Errorf("Expected import without the key to fail:\n%s", output)
+	}
+	if output, err := run("--yes", "db", "restore", exportPath); err == nil || !strings.Contains(output, "toki db import") {
+		t.Errorf("Expected restore to point at import: %v\n%s", err, output)
+	}
+
+	// Rotating, then importing the export made with the old key
+	if output, err := run("config", "set", "encryption.key_file", keyPath); err != nil {
+		t.Fatalf("Failed to set key file: %v\n%s", err, output)
+	}
+	newKeyPath := filepath.Join(dir, "new.key")
+	if output, err := run("key", "rotate", newKeyPath); err != nil || !strings.Contains(output, "Re-encrypted 2 todos") {
+		t.Fatalf("Failed to rotate: %v\n%s", err, output)
+	}
+	if output, err := run("list"); err == nil {
+		t.Errorf("Expected the old key to stop working:\n%s", output)
+	}
+	if output, err := run("--yes", "db", "import", exportPath); err != nil {
+		t.Fatalf("Failed to import: %v\n%s", err, output)
+	}
+	output, err = run("list")
+	if err != nil || !strings.Contains(output, "call ACME") {
+		t.Errorf("Expected the imported todos: %v\n%s", err, output)
+	}
+}
+
+func TestWorkspaces(t *testing.T) {
+	dataDir := t.TempDir()
+	workDir := t.TempDir()
+	tokiBinary, configPath := buildToki(t), filepath.Join(t.TempDir(), "config.toml")
+
+	// Without --db, so the config picks the database
+	run := func(dir string, env []string, args ...string) (string, error) {
+		cmd := exec.Command(tokiBinary, args...) //nolint:gosec // Safe: executing our own test binary with controlled args
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "TOKI_CONFIG="+configPath, "XDG_DATA_HOME="+dataDir, "TOKI_WORKSPACE=")
+		cmd.Env = append(cmd.Env, env...)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+	home := t.TempDir()
+
+	if output, err := run(home, nil, "workspace", "add", "work", "--dir", workDir); err != nil {
+		t.Fatalf("Failed to add workspace: %v\n%s", err, output)
+	}
+	personalDB := filepath.Join(t.TempDir(), "personal.db")
+	if output, err := run(home, nil, "workspace", "add", "personal", "--db", personalDB); err != nil {
+		t.Fatalf("Failed to add workspace: %v\n%s", err, output)
+	}
+	if output, err := run(home, nil, "workspace", "use", "personal"); err != nil {
+		t.Fatalf("Failed to use workspace: %v\n%s", err, output)
+	}
+
+	if output, err := run(home, nil, "add", "buy milk"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run(workDir, nil, "add", "ship the release"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "toki", "work.db")); err != nil {
+		t.Errorf("Expected the work database next to the default one: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		env     []string
+		args    []string
+		want    string
+		notWant string
+	}{
+		{"active workspace", home, nil, []string{"list"}, "buy milk", "ship the release"},
+		{"directory mapping", workDir, nil, []string{"list"}, "ship the release", "buy milk"},
+		{"environment", workDir, []string{"TOKI_WORKSPACE=personal"}, []string{"list"}, "buy milk", "ship the release"},
+		{"flag", home, []string{"TOKI_WORKSPACE=personal"}, []string{"--workspace", "work", "list"}, "ship the release", "buy milk"},
+		{"default workspace", home, nil, []string{"--workspace", "default", "list"}, "No todos", "buy milk"},
+		{"current", workDir, nil, []string{"workspace", "current"}, "work", "personal"},
+	}
+	for _, tt := range tests {
+		output, err := run(tt.dir, tt.env, tt.args...)
+		if err != nil {
+			t.Errorf("%s: %v\n%s", tt.name, err, output)
+			continue
+		}
+		if !strings.Contains(output, tt.want) || strings.Contains(output, tt.notWant) {
+			t.Errorf("%s: expected %q and not %q:\n%s", tt.name, tt.want, tt.notWant, output)
+		}
+	}
+
+	// The default and work workspaces share a backups directory, but each only sees its own
+	if output, err := run(workDir, nil, "db", "backup"); err != nil {
+		t.Fatalf("Failed to back up: %v\n%s", err, output)
+	}
+	if output, err := run(home, nil, "--workspace", "default", "add", "default todo"); err != nil {
+		t.Fatalf("Failed to add todo: %v\n%s", err, output)
+	}
+	if output, err := run(home, nil, "--workspace", "default", "db", "backup"); err != nil {
+		t.Fatalf("Failed to back up: %v\n%s", err, output)
+	}
+	if output, err := run(workDir, nil, "db", "backups"); err != nil || !strings.Contains(output, "work-") || strings.Contains(output, "toki-") {
+		t.Errorf("Expected the work workspace to list only its backups: %v\n%s", err, output)
+	}
+	if output, err := run(workDir, nil, "--yes", "db", "restore"); err != nil {
+		t.Fatalf("Failed to restore: %v\n%s", err, output)
+	}
+	if output, err := run(workDir, nil, "list"); err != nil || !strings.Contains(output, "ship the release") || strings.Contains(output, "default todo") {
+		t.Errorf("Expected restore to use the work workspace's backup: %v\n%s", err, output)
+	}
+
+	if output, err := run(home, nil, "--workspace", "nope", "list"); err == nil || !strings.Contains(output, "unknown workspace 'nope'") {
+		t.Errorf("Expected an unknown workspace error: %v\n%s", err, output)
+	}
+	if output, err := run(home, nil, "workspace", "remove", "work"); err != nil {
+		t.Fatalf("Failed to remove workspace: %v\n%s", err, output)
+	}
+	if output, err := run(workDir, nil, "list"); err != nil || !strings.Contains(output, "buy milk") {
+		t.Errorf("Expected the removed workspace's directory to fall back to personal: %v\n%s", err, output)
+	}
+}